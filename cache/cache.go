@@ -0,0 +1,133 @@
+// Package cache provides a middleware caching the responses of endpoints
+// which are pure functions of their request, for a while, like lookups of
+// feature flags or configuration, and one deduplicating concurrent identical
+// requests.
+package cache
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// KeyFunc returns the cache key of a request. Requests with the same key must
+// have the same response.
+type KeyFunc func(request interface{}) string
+
+// Entry is a cached result of an endpoint.
+type Entry struct {
+	Response interface{}
+	Err      error
+	Size     int       // in bytes, as reported by the Sizer
+	Expires  time.Time // the entry is stale afterwards
+}
+
+// Store stores cache entries. Implementations must be safe for concurrent
+// use. They may evict entries at any time, e.g. to bound their memory usage.
+// Expired entries are ignored, and eventually overwritten, by the middleware.
+type Store interface {
+	Get(key string) (Entry, bool)
+	Set(key string, e Entry)
+}
+
+// Option sets an optional parameter for the response cache.
+type Option func(*responseCache)
+
+// CacheErrors makes the middleware cache the errors for which the predicate
+// returns true, e.g. a not found error, for the given TTL, which is usually
+// shorter than the one of responses. By default, errors aren't cached.
+func CacheErrors(predicate func(error) bool, ttl time.Duration) Option {
+	return func(c *responseCache) { c.cacheError, c.errorTTL = predicate, ttl }
+}
+
+// Sizer sets the function returning the size, in bytes, of a response, which
+// stores like the LRU use to bound their memory usage. By default, the size
+// of strings and byte slices is their length, and the size of other
+// responses is zero.
+func Sizer(size func(response interface{}) int) Option {
+	return func(c *responseCache) { c.size = size }
+}
+
+type responseCache struct {
+	key        KeyFunc
+	ttl        time.Duration
+	store      Store
+	cacheError func(error) bool
+	errorTTL   time.Duration
+	size       func(response interface{}) int
+}
+
+// NewResponseCache returns an endpoint.Middleware caching the results of the
+// wrapped endpoint by the key of their request, for the TTL. Requests whose
+// result is cached are answered without invoking the endpoint.
+//
+// Concurrent requests with the same key which miss the cache are collapsed
+// into a single call to the endpoint, whose result they share, so an expiring
+// entry doesn't cause a stampede on the backend. The call is made with the
+// values of the context of the first of them, but isn't canceled with it:
+// each request stops waiting when its own context is done, while the call
+// runs on, and caches its result for the others. Only requests to the same endpoint are
+// collapsed, even if the middleware wraps several; the store, however, is
+// shared by all of them, so their keys mustn't collide. If the call panics,
+// the requests get an *endpoint.PanicError.
+func NewResponseCache(key KeyFunc, ttl time.Duration, store Store, options ...Option) endpoint.Middleware {
+	c := &responseCache{
+		key:        key,
+		ttl:        ttl,
+		store:      store,
+		cacheError: func(error) bool { return false },
+		size:       defaultSize,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		var calls group
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			k := c.key(request)
+			if e, ok := c.store.Get(k); ok && time.Now().Before(e.Expires) {
+				return e.Response, e.Err
+			}
+			detached := endpoint.WithoutCancel(ctx)
+			call, _ := calls.doAsync(k, func() (interface{}, error) {
+				response, err := next(detached, request)
+				c.set(k, response, err)
+				return response, err
+			})
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			return call.response, call.err
+		}
+	}
+}
+
+func (c *responseCache) set(key string, response interface{}, err error) {
+	ttl := c.ttl
+	if err != nil {
+		if !c.cacheError(err) {
+			return
+		}
+		ttl = c.errorTTL
+	}
+	c.store.Set(key, Entry{
+		Response: response,
+		Err:      err,
+		Size:     c.size(response),
+		Expires:  time.Now().Add(ttl),
+	})
+}
+
+func defaultSize(response interface{}) int {
+	switch r := response.(type) {
+	case string:
+		return len(r)
+	case []byte:
+		return len(r)
+	}
+	return 0
+}
@@ -0,0 +1,217 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/cache"
+	"github.com/go-kit/kit/endpoint"
+)
+
+// backend is an endpoint counting its calls, and returning the request's
+// flag value, or its error.
+type backend struct {
+	calls int64
+	err   error
+	delay time.Duration
+}
+
+func (b *backend) endpoint(_ context.Context, request interface{}) (interface{}, error) {
+	atomic.AddInt64(&b.calls, 1)
+	time.Sleep(b.delay)
+	if b.err != nil {
+		return nil, b.err
+	}
+	return "value of " + request.(string), nil
+}
+
+func (b *backend) numCalls() int64 { return atomic.LoadInt64(&b.calls) }
+
+func key(request interface{}) string { return request.(string) }
+
+func TestResponseCache(t *testing.T) {
+	b := &backend{}
+	e := cache.NewResponseCache(key, time.Hour, cache.NewLRU(0, 0))(b.endpoint)
+
+	for i := 0; i < 3; i++ {
+		response, err := e(context.Background(), "flag")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "value of flag", response; want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	}
+	if want, have := int64(1), b.numCalls(); want != have {
+		t.Errorf("want %d call, have %d", want, have)
+	}
+
+	e(context.Background(), "other flag")
+	if want, have := int64(2), b.numCalls(); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	b := &backend{}
+	e := cache.NewResponseCache(key, 10*time.Millisecond, cache.NewLRU(0, 0))(b.endpoint)
+
+	e(context.Background(), "flag")
+	e(context.Background(), "flag")
+	if want, have := int64(1), b.numCalls(); want != have {
+		t.Errorf("before expiry: want %d call, have %d", want, have)
+	}
+	time.Sleep(20 * time.Millisecond)
+	e(context.Background(), "flag")
+	if want, have := int64(2), b.numCalls(); want != have {
+		t.Errorf("after expiry: want %d calls, have %d", want, have)
+	}
+}
+
+func TestResponseCacheErrors(t *testing.T) {
+	var (
+		errNotFound = errors.New("not found")
+		errTimeout  = errors.New("timeout")
+		notFound    = cache.CacheErrors(func(err error) bool { return err == errNotFound }, time.Hour)
+	)
+	for _, tc := range []struct {
+		err       error
+		options   []cache.Option
+		wantCalls int64
+	}{
+		{errNotFound, nil, 2},
+		{errNotFound, []cache.Option{notFound}, 1},
+		{errTimeout, []cache.Option{notFound}, 2},
+	} {
+		b := &backend{err: tc.err}
+		e := cache.NewResponseCache(key, time.Hour, cache.NewLRU(0, 0), tc.options...)(b.endpoint)
+		for i := 0; i < 2; i++ {
+			if _, err := e(context.Background(), "flag"); err != tc.err {
+				t.Errorf("want %v, have %v", tc.err, err)
+			}
+		}
+		if want, have := tc.wantCalls, b.numCalls(); want != have {
+			t.Errorf("%v, %d option(s): want %d calls, have %d", tc.err, len(tc.options), want, have)
+		}
+	}
+}
+
+func TestResponseCacheCollapsesMisses(t *testing.T) {
+	b := &backend{delay: 50 * time.Millisecond}
+	e := cache.NewResponseCache(key, time.Hour, cache.NewLRU(0, 0))(b.endpoint)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := e(context.Background(), "flag")
+			if err != nil || response != "value of flag" {
+				t.Errorf("want %q, have %v, %v", "value of flag", response, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if want, have := int64(1), b.numCalls(); want != have {
+		t.Errorf("want %d call, have %d", want, have)
+	}
+}
+
+func TestResponseCacheCancel(t *testing.T) {
+	var (
+		b = &gatedBackend{gate: make(chan struct{})}
+		e = cache.NewResponseCache(key, time.Hour, cache.NewLRU(0, 0))(b.endpoint)
+	)
+
+	// The leader waits for the call, while a follower cancels.
+	leaderc := make(chan error, 1)
+	go func() {
+		_, err := e(context.Background(), "flag")
+		leaderc <- err
+	}()
+	for atomic.LoadInt64(&b.executions) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := e(ctx, "flag"); err != context.Canceled {
+		t.Errorf("follower: want %v, have %v", context.Canceled, err)
+	}
+
+	// The call wasn't aborted, and its result is cached.
+	close(b.gate)
+	if err := <-leaderc; err != nil {
+		t.Errorf("leader: %v", err)
+	}
+	response, err := e(context.Background(), "flag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "value of flag", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := int64(1), atomic.LoadInt64(&b.executions); want != have {
+		t.Errorf("want %d execution, have %d", want, have)
+	}
+}
+
+func TestResponseCachePanic(t *testing.T) {
+	var calls int64
+	e := cache.NewResponseCache(key, time.Hour, cache.NewLRU(0, 0))(func(context.Context, interface{}) (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		panic("boom")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := e(context.Background(), "flag")
+			perr, ok := err.(*endpoint.PanicError)
+			if !ok || perr.Value != "boom" || response != nil {
+				t.Errorf("want nil response and *endpoint.PanicError, have %v, %v", response, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if want, have := int64(1), atomic.LoadInt64(&calls); want != have {
+		t.Errorf("want %d call, have %d", want, have)
+	}
+}
+
+func TestResponseCacheCollapsesPerEndpoint(t *testing.T) {
+	middleware := cache.NewResponseCache(key, time.Hour, cache.NewLRU(0, 0))
+	wrap := func(value string) func(context.Context, interface{}) (interface{}, error) {
+		return middleware(func(context.Context, interface{}) (interface{}, error) {
+			time.Sleep(50 * time.Millisecond)
+			return value, nil
+		})
+	}
+	a, b := wrap("a"), wrap("b")
+
+	bc := make(chan interface{}, 1)
+	go func() {
+		response, _ := b(context.Background(), "flag")
+		bc <- response
+	}()
+	time.Sleep(10 * time.Millisecond) // let b's call start
+	response, err := a(context.Background(), "flag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "a", response; want != have {
+		t.Errorf("a: want %v, have %v", want, have)
+	}
+	if want, have := "b", <-bc; want != have {
+		t.Errorf("b: want %v, have %v", want, have)
+	}
+}
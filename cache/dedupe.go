@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+// DedupeOption sets an optional parameter for the Dedupe middleware.
+type DedupeOption func(*dedupe)
+
+type dedupe struct {
+	shareErrors bool
+	executed    metrics.Counter
+	shared      metrics.Counter
+}
+
+// DedupeNoSharedErrors makes followers, which joined a call that failed,
+// retry individually, with their own context, instead of receiving the
+// error. By default, the error is shared like a response.
+func DedupeNoSharedErrors() DedupeOption {
+	return func(d *dedupe) { d.shareErrors = false }
+}
+
+// DedupeExecutedCounter sets the counter incremented for each execution of
+// the wrapped endpoint, including individual retries of followers. By
+// default, executions aren't counted.
+func DedupeExecutedCounter(c metrics.Counter) DedupeOption {
+	return func(d *dedupe) { d.executed = c }
+}
+
+// DedupeSharedCounter sets the counter incremented for each request answered
+// with the result of another request's execution. By default, shared results
+// aren't counted.
+func DedupeSharedCounter(c metrics.Counter) DedupeOption {
+	return func(d *dedupe) { d.shared = c }
+}
+
+// Dedupe returns an endpoint.Middleware which deduplicates concurrent
+// requests: a request whose key matches the one of a request in flight
+// doesn't invoke the wrapped endpoint, but waits for that execution, and
+// receives its result. Unlike NewResponseCache, results are only shared while
+// the execution is in flight. It keeps a thundering herd of identical
+// requests, e.g. after a cache flush, from multiplying the load on a backend.
+//
+// The shared execution runs with a context carrying the values of the first
+// request's context, but detached from its cancellation and deadline, so no
+// single caller canceling aborts it for everyone. Each caller stops waiting
+// when its own context is done. Wrap the endpoint in a Timeout middleware to
+// bound the execution.
+//
+// Only requests to the same endpoint are deduplicated, even if the middleware
+// wraps several. If the shared execution panics, the panic is recovered, and
+// every waiting request gets an *endpoint.PanicError.
+func Dedupe(key KeyFunc, options ...DedupeOption) endpoint.Middleware {
+	d := &dedupe{
+		shareErrors: true,
+		executed:    discard.NewCounter("dedupe_executed"),
+		shared:      discard.NewCounter("dedupe_shared"),
+	}
+	for _, option := range options {
+		option(d)
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		var calls group
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			detached := endpoint.WithoutCancel(ctx)
+			c, leader := calls.doAsync(key(request), func() (interface{}, error) {
+				d.executed.Add(1)
+				return next(detached, request)
+			})
+			select {
+			case <-c.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if leader {
+				return c.response, c.err
+			}
+			if c.err != nil && !d.shareErrors {
+				d.executed.Add(1)
+				return next(ctx, request)
+			}
+			d.shared.Add(1)
+			return c.response, c.err
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/cache"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+)
+
+// gatedBackend is an endpoint counting its executions, which block until the
+// gate is opened.
+type gatedBackend struct {
+	executions int64
+	gate       chan struct{}
+	errs       []error // error of each execution, if any
+}
+
+func (b *gatedBackend) endpoint(ctx context.Context, request interface{}) (interface{}, error) {
+	n := atomic.AddInt64(&b.executions, 1)
+	select {
+	case <-b.gate:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if int(n) <= len(b.errs) && b.errs[n-1] != nil {
+		return nil, b.errs[n-1]
+	}
+	return "value of " + request.(string), nil
+}
+
+func TestDedupe(t *testing.T) {
+	const requests = 100
+
+	var (
+		b        = &gatedBackend{gate: make(chan struct{})}
+		executed = &counter{}
+		shared   = &counter{}
+		e        = cache.Dedupe(key, cache.DedupeExecutedCounter(executed), cache.DedupeSharedCounter(shared))(b.endpoint)
+		wg       sync.WaitGroup
+		joined   int64
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			atomic.AddInt64(&joined, 1)
+			response, err := e(context.Background(), "flag")
+			if err != nil || response != "value of flag" {
+				t.Errorf("want %q, have %v, %v", "value of flag", response, err)
+			}
+		}()
+	}
+	for atomic.LoadInt64(&joined) < requests {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // let the requests join the execution
+	close(b.gate)
+	wg.Wait()
+
+	if want, have := int64(1), atomic.LoadInt64(&b.executions); want != have {
+		t.Errorf("want %d execution, have %d", want, have)
+	}
+	if want, have := uint64(1), executed.value(); want != have {
+		t.Errorf("want %d counted execution, have %d", want, have)
+	}
+	if want, have := uint64(requests-1), shared.value(); want != have {
+		t.Errorf("want %d shared results, have %d", want, have)
+	}
+}
+
+func TestDedupeCancel(t *testing.T) {
+	var (
+		b = &gatedBackend{gate: make(chan struct{})}
+		e = cache.Dedupe(key)(b.endpoint)
+	)
+
+	// The leader, and then a follower, cancel while waiting.
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		if _, err := e(ctx, "flag"); err != context.Canceled {
+			t.Errorf("want %v, have %v", context.Canceled, err)
+		}
+	}
+
+	// The execution wasn't aborted, and is shared with the next follower.
+	time.AfterFunc(10*time.Millisecond, func() { close(b.gate) })
+	response, err := e(context.Background(), "flag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "value of flag", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := int64(1), atomic.LoadInt64(&b.executions); want != have {
+		t.Errorf("want %d execution, have %d", want, have)
+	}
+}
+
+func TestDedupeErrors(t *testing.T) {
+	errFailed := errors.New("failed")
+	for _, tc := range []struct {
+		options        []cache.DedupeOption
+		wantErr        error
+		wantExecutions int64
+	}{
+		{nil, errFailed, 1},
+		{[]cache.DedupeOption{cache.DedupeNoSharedErrors()}, nil, 2},
+	} {
+		var (
+			b        = &gatedBackend{gate: make(chan struct{}), errs: []error{errFailed}}
+			e        = cache.Dedupe(key, tc.options...)(b.endpoint)
+			leaderc  = make(chan error, 1)
+			followed = make(chan struct{})
+		)
+		go func() {
+			_, err := e(context.Background(), "flag")
+			leaderc <- err
+		}()
+		for atomic.LoadInt64(&b.executions) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		go func() {
+			time.Sleep(10 * time.Millisecond) // let the follower join
+			close(b.gate)
+			close(followed)
+		}()
+		_, err := e(context.Background(), "flag")
+		<-followed
+
+		if want, have := errFailed, <-leaderc; want != have {
+			t.Errorf("%d option(s): leader: want %v, have %v", len(tc.options), want, have)
+		}
+		if want, have := tc.wantErr, err; want != have {
+			t.Errorf("%d option(s): follower: want %v, have %v", len(tc.options), want, have)
+		}
+		if want, have := tc.wantExecutions, atomic.LoadInt64(&b.executions); want != have {
+			t.Errorf("%d option(s): want %d executions, have %d", len(tc.options), want, have)
+		}
+	}
+}
+
+func TestDedupePanic(t *testing.T) {
+	var (
+		gate = make(chan struct{})
+		e    = endpoint.Recover()(cache.Dedupe(key)(func(context.Context, interface{}) (interface{}, error) {
+			<-gate
+			panic("boom")
+		}))
+		errc = make(chan error, 3)
+	)
+	for i := 0; i < cap(errc); i++ {
+		go func() {
+			_, err := e(context.Background(), "flag")
+			errc <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond) // let the requests join the execution
+	close(gate)
+
+	for i := 0; i < cap(errc); i++ {
+		perr, ok := (<-errc).(*endpoint.PanicError)
+		if !ok || perr.Value != "boom" {
+			t.Errorf("want *endpoint.PanicError, have %v", perr)
+		}
+	}
+}
+
+func TestDedupePerEndpoint(t *testing.T) {
+	var (
+		middleware = cache.Dedupe(key)
+		gate       = make(chan struct{})
+		wrap       = func(value string) func(context.Context, interface{}) (interface{}, error) {
+			return middleware(func(context.Context, interface{}) (interface{}, error) {
+				<-gate
+				return value, nil
+			})
+		}
+		a, b = wrap("a"), wrap("b")
+		bc   = make(chan interface{}, 1)
+	)
+	go func() {
+		response, _ := b(context.Background(), "flag")
+		bc <- response
+	}()
+	time.AfterFunc(10*time.Millisecond, func() { close(gate) })
+	response, err := a(context.Background(), "flag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "a", response; want != have {
+		t.Errorf("a: want %v, have %v", want, have)
+	}
+	if want, have := "b", <-bc; want != have {
+		t.Errorf("b: want %v, have %v", want, have)
+	}
+}
+
+// counter is a metrics.Counter counting its increments.
+type counter struct{ n uint64 }
+
+func (c *counter) Name() string                       { return "counter" }
+func (c *counter) With(metrics.Field) metrics.Counter { return c }
+func (c *counter) Add(delta uint64)                   { atomic.AddUint64(&c.n, delta) }
+func (c *counter) value() uint64                      { return atomic.LoadUint64(&c.n) }
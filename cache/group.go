@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"runtime/debug"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// group collapses concurrent calls with the same key into one, whose result
+// is shared by all callers.
+type group struct {
+	mtx   sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done     chan struct{} // closed when the call returned
+	response interface{}
+	err      error
+}
+
+// doAsync calls f in a new goroutine, unless a call with the same key is in
+// flight, and returns the call right away, so the caller may stop waiting for
+// it. leader reports whether the caller started the call.
+func (g *group) doAsync(key string, f func() (interface{}, error)) (c *call, leader bool) {
+	c, leader = g.join(key)
+	if leader {
+		go g.run(key, c, f)
+	}
+	return c, leader
+}
+
+// join returns the call in flight with the key, or registers a new call,
+// which the caller leads.
+func (g *group) join(key string) (c *call, leader bool) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	if g.calls == nil {
+		g.calls = map[string]*call{}
+	}
+	if c, ok := g.calls[key]; ok {
+		return c, false
+	}
+	c = &call{done: make(chan struct{})}
+	g.calls[key] = c
+	return c, true
+}
+
+// run calls f, and shares its result with the callers joining the call until
+// it returns. A panic of f is recovered, and shared as an *endpoint.PanicError.
+func (g *group) run(key string, c *call, f func() (interface{}, error)) {
+	defer func() {
+		if v := recover(); v != nil {
+			c.response, c.err = nil, endpoint.NewPanicError(v, debug.Stack())
+		}
+		g.mtx.Lock()
+		delete(g.calls, key)
+		g.mtx.Unlock()
+		close(c.done)
+	}()
+	c.response, c.err = f()
+}
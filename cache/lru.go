@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRU is an in-memory Store, which evicts the least recently used entries
+// when it holds too many entries, or too many bytes, as reported by the
+// size of the entries.
+type LRU struct {
+	mtx        sync.Mutex
+	maxEntries int
+	maxBytes   int
+	bytes      int
+	ll         *list.List // of *lruEntry, most recently used first
+	entries    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	entry Entry
+}
+
+// NewLRU returns a new LRU store, holding at most maxEntries entries, and
+// maxBytes bytes. A limit of zero means no limit. Entries larger than
+// maxBytes aren't stored.
+func NewLRU(maxEntries, maxBytes int) *LRU {
+	return &LRU{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// Get implements Store.
+func (l *LRU) Get(key string) (Entry, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	elem, ok := l.entries[key]
+	if !ok {
+		return Entry{}, false
+	}
+	l.ll.MoveToFront(elem)
+	return elem.Value.(*lruEntry).entry, true
+}
+
+// Set implements Store.
+func (l *LRU) Set(key string, e Entry) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if elem, ok := l.entries[key]; ok {
+		l.remove(elem)
+	}
+	if l.maxBytes > 0 && e.Size > l.maxBytes {
+		return
+	}
+	l.entries[key] = l.ll.PushFront(&lruEntry{key, e})
+	l.bytes += e.Size
+	for (l.maxEntries > 0 && l.ll.Len() > l.maxEntries) || (l.maxBytes > 0 && l.bytes > l.maxBytes) {
+		l.remove(l.ll.Back())
+	}
+}
+
+// Len returns the number of entries in the store.
+func (l *LRU) Len() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.ll.Len()
+}
+
+func (l *LRU) remove(elem *list.Element) {
+	e := l.ll.Remove(elem).(*lruEntry)
+	delete(l.entries, e.key)
+	l.bytes -= e.entry.Size
+}
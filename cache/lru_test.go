@@ -0,0 +1,52 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/cache"
+)
+
+func TestLRUMaxEntries(t *testing.T) {
+	lru := cache.NewLRU(2, 0)
+	lru.Set("a", cache.Entry{Response: 1})
+	lru.Set("b", cache.Entry{Response: 2})
+	lru.Get("a") // b is now the least recently used
+	lru.Set("c", cache.Entry{Response: 3})
+
+	if _, ok := lru.Get("b"); ok {
+		t.Error("want b evicted, have it")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := lru.Get(key); !ok {
+			t.Errorf("want %s cached, have nothing", key)
+		}
+	}
+	if want, have := 2, lru.Len(); want != have {
+		t.Errorf("want %d entries, have %d", want, have)
+	}
+}
+
+func TestLRUMaxBytes(t *testing.T) {
+	lru := cache.NewLRU(0, 10)
+	lru.Set("a", cache.Entry{Size: 4})
+	lru.Set("b", cache.Entry{Size: 4})
+	lru.Set("c", cache.Entry{Size: 4}) // evicts a
+
+	if _, ok := lru.Get("a"); ok {
+		t.Error("want a evicted, have it")
+	}
+	if want, have := 2, lru.Len(); want != have {
+		t.Errorf("want %d entries, have %d", want, have)
+	}
+
+	// Replacing an entry accounts for its new size.
+	lru.Set("c", cache.Entry{Size: 6})
+	if want, have := 2, lru.Len(); want != have {
+		t.Errorf("want %d entries, have %d", want, have)
+	}
+
+	lru.Set("d", cache.Entry{Size: 11}) // larger than the store
+	if _, ok := lru.Get("d"); ok {
+		t.Error("want oversized entry not stored, have it")
+	}
+}
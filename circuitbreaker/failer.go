@@ -0,0 +1,27 @@
+package circuitbreaker
+
+import "github.com/go-kit/kit/endpoint"
+
+// failedResponse is the error reported to a circuit breaker for a response
+// implementing endpoint.Failer which failed. It carries the response, so it
+// can still be returned to the caller.
+type failedResponse struct {
+	response interface{}
+	err      error
+}
+
+func (f failedResponse) Error() string { return f.err.Error() }
+
+// breakerError returns the error which counts against the circuit breaker for
+// the result of an endpoint: its error, or the failure of its response.
+func breakerError(response interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	if f, ok := response.(endpoint.Failer); ok {
+		if err := f.Failed(); err != nil {
+			return failedResponse{response: response, err: err}
+		}
+	}
+	return nil
+}
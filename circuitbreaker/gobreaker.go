@@ -9,13 +9,21 @@ import (
 
 // Gobreaker returns an endpoint.Middleware that implements the circuit
 // breaker pattern using the sony/gobreaker package. Only errors returned by
-// the wrapped endpoint count against the circuit breaker's error count.
+// the wrapped endpoint, and responses implementing endpoint.Failer which
+// failed, count against the circuit breaker's error count.
 //
 // See http://godoc.org/github.com/sony/gobreaker for more information.
 func Gobreaker(cb *gobreaker.CircuitBreaker) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
-			return cb.Execute(func() (interface{}, error) { return next(ctx, request) })
+			response, err := cb.Execute(func() (interface{}, error) {
+				response, err := next(ctx, request)
+				return response, breakerError(response, err)
+			})
+			if f, ok := err.(failedResponse); ok {
+				return f.response, nil
+			}
+			return response, err
 		}
 	}
 }
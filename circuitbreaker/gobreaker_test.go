@@ -17,3 +17,13 @@ func TestGobreaker(t *testing.T) {
 	)
 	testFailingEndpoint(t, breaker, primeWith, shouldPass, 0, circuitOpenError)
 }
+
+func TestGobreakerFailer(t *testing.T) {
+	var (
+		breaker          = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))
+		primeWith        = 100
+		shouldPass       = func(n int) bool { return n <= 5 }
+		circuitOpenError = "circuit breaker is open"
+	)
+	testFailingResponse(t, breaker, primeWith, shouldPass, 0, circuitOpenError)
+}
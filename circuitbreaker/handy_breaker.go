@@ -11,7 +11,8 @@ import (
 
 // HandyBreaker returns an endpoint.Middleware that implements the circuit
 // breaker pattern using the streadway/handy/breaker package. Only errors
-// returned by the wrapped endpoint count against the circuit breaker's error
+// returned by the wrapped endpoint, and responses implementing
+// endpoint.Failer which failed, count against the circuit breaker's error
 // count.
 //
 // See http://godoc.org/github.com/streadway/handy/breaker for more
@@ -24,7 +25,7 @@ func HandyBreaker(cb breaker.Breaker) endpoint.Middleware {
 			}
 
 			defer func(begin time.Time) {
-				if err == nil {
+				if breakerError(response, err) == nil {
 					cb.Success(time.Since(begin))
 				} else {
 					cb.Failure(time.Since(begin))
@@ -18,3 +18,14 @@ func TestHandyBreaker(t *testing.T) {
 	)
 	testFailingEndpoint(t, breaker, primeWith, shouldPass, 0, openCircuitError)
 }
+
+func TestHandyBreakerFailer(t *testing.T) {
+	var (
+		failureRatio     = 0.05
+		breaker          = circuitbreaker.HandyBreaker(handybreaker.NewBreaker(failureRatio))
+		primeWith        = handybreaker.DefaultMinObservations * 10
+		shouldPass       = func(n int) bool { return (float64(n) / float64(primeWith+n)) <= failureRatio }
+		openCircuitError = handybreaker.ErrCircuitOpen.Error()
+	)
+	testFailingResponse(t, breaker, primeWith, shouldPass, 0, openCircuitError)
+}
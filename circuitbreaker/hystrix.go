@@ -8,7 +8,9 @@ import (
 )
 
 // Hystrix returns an endpoint.Middleware that implements the circuit
-// breaker pattern using the afex/hystrix-go package.
+// breaker pattern using the afex/hystrix-go package. Errors returned by the
+// wrapped endpoint, and responses implementing endpoint.Failer which failed,
+// count as failures of the command.
 //
 // When using this circuit breaker, please configure your commands separately.
 //
@@ -20,8 +22,11 @@ func Hystrix(commandName string) endpoint.Middleware {
 			var resp interface{}
 			if err := hystrix.Do(commandName, func() (err error) {
 				resp, err = next(ctx, request)
-				return err
+				return breakerError(resp, err)
 			}, nil); err != nil {
+				if f, ok := err.(failedResponse); ok {
+					return f.response, nil
+				}
 				return nil, err
 			}
 			return resp, nil
@@ -38,3 +38,26 @@ func TestHystrix(t *testing.T) {
 
 	testFailingEndpoint(t, breaker, primeWith, shouldPass, requestDelay, openCircuitError)
 }
+
+func TestHystrixFailer(t *testing.T) {
+	stdlog.SetOutput(ioutil.Discard)
+
+	const (
+		commandName   = "my-failing-response-endpoint"
+		errorPercent  = 5
+		maxConcurrent = 1000
+	)
+	hystrix.ConfigureCommand(commandName, hystrix.CommandConfig{
+		ErrorPercentThreshold: errorPercent,
+		MaxConcurrentRequests: maxConcurrent,
+	})
+
+	var (
+		breaker          = circuitbreaker.Hystrix(commandName)
+		primeWith        = hystrix.DefaultVolumeThreshold * 2
+		shouldPass       = func(n int) bool { return (float64(n) / float64(primeWith+n)) <= (float64(errorPercent-1) / 100.0) }
+		openCircuitError = hystrix.ErrCircuitOpen.Error()
+		requestDelay     = 5 * time.Millisecond
+	)
+	testFailingResponse(t, breaker, primeWith, shouldPass, requestDelay, openCircuitError)
+}
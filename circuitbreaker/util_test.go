@@ -11,6 +11,7 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
 )
 
 func testFailingEndpoint(
@@ -21,14 +22,45 @@ func testFailingEndpoint(
 	requestDelay time.Duration,
 	openCircuitError string,
 ) {
-	_, file, line, _ := runtime.Caller(1)
+	testFailures(t, breaker, primeWith, shouldPass, requestDelay, openCircuitError, false)
+}
+
+// testFailingResponse is like testFailingEndpoint, but the endpoint fails by
+// returning responses implementing endpoint.Failer, which must still be
+// returned to the caller while the circuit is closed.
+func testFailingResponse(
+	t *testing.T,
+	breaker endpoint.Middleware,
+	primeWith int,
+	shouldPass func(int) bool,
+	requestDelay time.Duration,
+	openCircuitError string,
+) {
+	testFailures(t, breaker, primeWith, shouldPass, requestDelay, openCircuitError, true)
+}
+
+func testFailures(
+	t *testing.T,
+	breaker endpoint.Middleware,
+	primeWith int,
+	shouldPass func(int) bool,
+	requestDelay time.Duration,
+	openCircuitError string,
+	failResponses bool,
+) {
+	_, file, line, _ := runtime.Caller(2)
 	caller := fmt.Sprintf("%s:%d", filepath.Base(file), line)
 
-	// Create a mock endpoint and wrap it with the breaker.
-	m := mock{}
-	var e endpoint.Endpoint
-	e = m.endpoint
-	e = breaker(e)
+	// Create an endpoint succeeding while the breaker is primed, and failing
+	// afterwards, and wrap it with the breaker.
+	failure := errors.New("tragedy+disaster")
+	fail := endpointtest.Step{Err: failure}
+	if failResponses {
+		fail = endpointtest.Step{Response: failedResponse{failure}}
+	}
+	steps := append(make([]endpointtest.Step, primeWith), fail)
+	r := endpointtest.NewRecorder(endpointtest.Scripted(steps...))
+	e := breaker(r.Endpoint)
 
 	// Prime the endpoint with successful requests.
 	for i := 0; i < primeWith; i++ {
@@ -38,18 +70,19 @@ func testFailingEndpoint(
 		time.Sleep(requestDelay)
 	}
 
-	// Switch the endpoint to start throwing errors.
-	m.err = errors.New("tragedy+disaster")
-	m.thru = 0
-
 	// The first several should be allowed through and yield our error.
 	for i := 0; shouldPass(i); i++ {
-		if _, err := e(context.Background(), struct{}{}); err != m.err {
-			t.Fatalf("%s: want %v, have %v", caller, m.err, err)
+		response, err := e(context.Background(), struct{}{})
+		if failResponses {
+			if f, ok := response.(failedResponse); !ok || err != nil || f.Failed() != failure {
+				t.Fatalf("%s: want failed response %v, have %v, %v", caller, failure, response, err)
+			}
+		} else if err != failure {
+			t.Fatalf("%s: want %v, have %v", caller, failure, err)
 		}
 		time.Sleep(requestDelay)
 	}
-	thru := m.thru
+	thru := r.NumCalls()
 
 	// But the rest should be blocked by an open circuit.
 	for i := 0; i < 10; i++ {
@@ -60,17 +93,12 @@ func testFailingEndpoint(
 	}
 
 	// Make sure none of those got through.
-	if want, have := thru, m.thru; want != have {
+	if want, have := thru, r.NumCalls(); want != have {
 		t.Errorf("%s: want %d, have %d", caller, want, have)
 	}
 }
 
-type mock struct {
-	thru int
-	err  error
-}
+// failedResponse implements endpoint.Failer.
+type failedResponse struct{ err error }
 
-func (m *mock) endpoint(context.Context, interface{}) (interface{}, error) {
-	m.thru++
-	return struct{}{}, m.err
-}
+func (r failedResponse) Failed() error { return r.err }
@@ -0,0 +1,23 @@
+package endpoint
+
+import "golang.org/x/net/context"
+
+// Conditional returns a Middleware applying mw only to the requests for which
+// the predicate returns true, e.g. to skip authentication and tracing for
+// internal health checks flagged in the context. Other requests are passed
+// directly to the next endpoint.
+//
+// The next endpoint is wrapped in mw once, when the returned Middleware is
+// applied, not per request, so the state of mw, like the state of a circuit
+// breaker, is kept across requests, however they're interleaved.
+func Conditional(mw Middleware, predicate func(ctx context.Context, request interface{}) bool) Middleware {
+	return func(next Endpoint) Endpoint {
+		wrapped := mw(next)
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if predicate(ctx, request) {
+				return wrapped(ctx, request)
+			}
+			return next(ctx, request)
+		}
+	}
+}
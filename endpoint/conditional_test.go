@@ -0,0 +1,57 @@
+package endpoint_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
+)
+
+type healthCheckKey struct{}
+
+func notHealthCheck(ctx context.Context, _ interface{}) bool {
+	return ctx.Value(healthCheckKey{}) == nil
+}
+
+// counting returns a middleware numbering the requests it sees, with a
+// counter created each time it's applied, like the state of a breaker.
+func counting(applied *int) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		*applied++
+		var seen int
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			seen++
+			return seen, nil
+		}
+	}
+}
+
+func TestConditional(t *testing.T) {
+	var (
+		applied     int
+		backend     = endpointtest.NewRecorder(endpointtest.Scripted(endpointtest.Step{Response: "direct"}))
+		e           = endpoint.Conditional(counting(&applied), notHealthCheck)(backend.Endpoint)
+		healthCheck = context.WithValue(context.Background(), healthCheckKey{}, true)
+	)
+	for i, tc := range []struct {
+		ctx  context.Context
+		want interface{}
+	}{
+		{context.Background(), 1},
+		{healthCheck, "direct"},
+		{context.Background(), 2},
+		{healthCheck, "direct"},
+		{healthCheck, "direct"},
+		{context.Background(), 3},
+	} {
+		if have, _ := e(tc.ctx, i); tc.want != have {
+			t.Errorf("request %d: want %v, have %v", i, tc.want, have)
+		}
+	}
+	if want, have := 1, applied; want != have {
+		t.Errorf("want middleware applied %d time, have %d", want, have)
+	}
+	backend.AssertCalls(t, 3)
+}
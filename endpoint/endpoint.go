@@ -13,6 +13,16 @@ type Endpoint func(ctx context.Context, request interface{}) (response interface
 // Middleware is a chainable behavior modifier for endpoints.
 type Middleware func(Endpoint) Endpoint
 
+// Failer may be implemented by response types which carry business errors,
+// e.g. to let transports encode them, instead of returning them as the
+// endpoint's error. Middlewares which react to errors, like circuit breakers
+// and tracing annotations, treat a response whose Failed method returns a
+// non-nil error as a failed request, as if the error had been returned. They
+// still return the response to the caller unchanged.
+type Failer interface {
+	Failed() error
+}
+
 // ErrBadCast indicates an unexpected concrete request or response struct was
 // received from an endpoint.
 var ErrBadCast = errors.New("bad cast")
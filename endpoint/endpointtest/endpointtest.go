@@ -0,0 +1,144 @@
+// Package endpointtest provides fake endpoints, to test middlewares and other
+// code calling endpoints. Nop does nothing, Scripted follows a script of
+// delays, responses and errors, and a Recorder records the calls to the
+// endpoint it wraps, to assert on them.
+package endpointtest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Nop is an endpoint which does nothing, and returns a nil response and error.
+func Nop(context.Context, interface{}) (interface{}, error) { return nil, nil }
+
+// Step is the outcome of a call to a Scripted endpoint.
+type Step struct {
+	// Delay is waited for before returning. If the context is done first, its
+	// error is returned instead.
+	Delay time.Duration
+
+	// Response and Err are returned by the call.
+	Response interface{}
+	Err      error
+
+	// Endpoint, if set, is called after Delay, and its results are returned
+	// instead of Response and Err. It's intended for the steps which need to
+	// coordinate with the test, e.g. to block until released.
+	Endpoint endpoint.Endpoint
+}
+
+// Scripted returns an endpoint whose nth call runs the nth step. Once the
+// script is exhausted, the last step is repeated; a script without steps
+// behaves like Nop. The endpoint is safe for concurrent use, in which case
+// the steps are run in the order the calls are made.
+func Scripted(steps ...Step) endpoint.Endpoint {
+	var (
+		mtx  sync.Mutex
+		next int
+	)
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if len(steps) == 0 {
+			return nil, nil
+		}
+		mtx.Lock()
+		step := steps[next]
+		if next < len(steps)-1 {
+			next++
+		}
+		mtx.Unlock()
+
+		if step.Delay > 0 {
+			timer := time.NewTimer(step.Delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if step.Endpoint != nil {
+			return step.Endpoint(ctx, request)
+		}
+		return step.Response, step.Err
+	}
+}
+
+// Call is a call recorded by a Recorder.
+type Call struct {
+	Context context.Context
+	Request interface{}
+}
+
+// Recorder wraps an endpoint, recording its calls. A Recorder is safe for
+// concurrent use.
+type Recorder struct {
+	next endpoint.Endpoint
+
+	mtx   sync.Mutex
+	calls []Call
+}
+
+// NewRecorder returns a Recorder wrapping the endpoint. If next is nil, Nop
+// is wrapped.
+func NewRecorder(next endpoint.Endpoint) *Recorder {
+	if next == nil {
+		next = Nop
+	}
+	return &Recorder{next: next}
+}
+
+// Endpoint records the call, then calls the wrapped endpoint. The call is
+// recorded before the wrapped endpoint returns, so calls in flight are
+// counted too.
+func (r *Recorder) Endpoint(ctx context.Context, request interface{}) (interface{}, error) {
+	r.mtx.Lock()
+	r.calls = append(r.calls, Call{Context: ctx, Request: request})
+	r.mtx.Unlock()
+	return r.next(ctx, request)
+}
+
+// Calls returns the calls recorded so far, in the order they were made.
+func (r *Recorder) Calls() []Call {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]Call{}, r.calls...)
+}
+
+// Requests returns the requests of the calls recorded so far, in the order
+// they were made.
+func (r *Recorder) Requests() []interface{} {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	requests := make([]interface{}, len(r.calls))
+	for i, c := range r.calls {
+		requests[i] = c.Request
+	}
+	return requests
+}
+
+// NumCalls returns the number of calls recorded so far.
+func (r *Recorder) NumCalls() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.calls)
+}
+
+// AssertCalls reports an error to t unless exactly want calls were recorded.
+func (r *Recorder) AssertCalls(t testing.TB, want int) {
+	t.Helper()
+	if have := r.NumCalls(); want != have {
+		t.Errorf("want %d call(s), have %d", want, have)
+	}
+}
+
+// AssertNoCalls reports an error to t if any call was recorded.
+func (r *Recorder) AssertNoCalls(t testing.TB) {
+	t.Helper()
+	r.AssertCalls(t, 0)
+}
@@ -0,0 +1,120 @@
+package endpointtest_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint/endpointtest"
+)
+
+func TestNop(t *testing.T) {
+	response, err := endpointtest.Nop(context.Background(), struct{}{})
+	if response != nil || err != nil {
+		t.Errorf("want nil, nil; have %v, %v", response, err)
+	}
+}
+
+func TestScripted(t *testing.T) {
+	errFailed := errors.New("failed")
+	e := endpointtest.Scripted(
+		endpointtest.Step{Err: errFailed},
+		endpointtest.Step{Delay: time.Millisecond, Response: "slow"},
+		endpointtest.Step{Endpoint: func(_ context.Context, request interface{}) (interface{}, error) {
+			return request, nil
+		}},
+	)
+	for i, want := range []struct {
+		response interface{}
+		err      error
+	}{
+		{nil, errFailed},
+		{"slow", nil},
+		{"echo", nil},
+		{"echo", nil}, // the last step is repeated
+	} {
+		response, err := e(context.Background(), "echo")
+		if response != want.response || err != want.err {
+			t.Errorf("call %d: want %v, %v; have %v, %v", i, want.response, want.err, response, err)
+		}
+	}
+
+	if response, err := endpointtest.Scripted()(context.Background(), struct{}{}); response != nil || err != nil {
+		t.Errorf("empty script: want nil, nil; have %v, %v", response, err)
+	}
+}
+
+func TestScriptedDelayCanceled(t *testing.T) {
+	e := endpointtest.Scripted(endpointtest.Step{Delay: time.Hour, Response: "late"})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if want, have := context.DeadlineExceeded, errOf(e(ctx, struct{}{})); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	type key struct{}
+	r := endpointtest.NewRecorder(endpointtest.Scripted(endpointtest.Step{Response: "ok"}))
+	r.AssertNoCalls(t)
+
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	if response, _ := r.Endpoint(ctx, "first"); response != "ok" {
+		t.Errorf("want the wrapped endpoint's response, have %v", response)
+	}
+	r.Endpoint(context.Background(), "second")
+	r.AssertCalls(t, 2)
+
+	if want, have := fmt.Sprint([]interface{}{"first", "second"}), fmt.Sprint(r.Requests()); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := "value", r.Calls()[0].Context.Value(key{}); want != have {
+		t.Errorf("want the call's context, have value %v", have)
+	}
+}
+
+func TestRecorderAssertCalls(t *testing.T) {
+	r := endpointtest.NewRecorder(nil)
+	r.Endpoint(context.Background(), struct{}{})
+
+	fake := &fakeT{}
+	r.AssertCalls(fake, 1)
+	if fake.failed {
+		t.Error("want no failure for the right count")
+	}
+	r.AssertNoCalls(fake)
+	if !fake.failed {
+		t.Error("want a failure for the wrong count")
+	}
+}
+
+func TestRecorderConcurrent(t *testing.T) {
+	const n = 100
+	r := endpointtest.NewRecorder(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Endpoint(context.Background(), i)
+			r.Calls()
+		}(i)
+	}
+	wg.Wait()
+	r.AssertCalls(t, n)
+}
+
+func errOf(_ interface{}, err error) error { return err }
+
+// fakeT records whether the assertions failed.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper()                       {}
+func (t *fakeT) Errorf(string, ...interface{}) { t.failed = true }
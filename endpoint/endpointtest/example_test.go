@@ -0,0 +1,34 @@
+package endpointtest_test
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
+)
+
+func ExampleRecorder() {
+	// A backend failing once, then succeeding.
+	backend := endpointtest.NewRecorder(endpointtest.Scripted(
+		endpointtest.Step{Err: errors.New("unavailable")},
+		endpointtest.Step{Response: "ok"},
+	))
+
+	// The fallback is only called when the backend fails.
+	fallback := endpointtest.NewRecorder(endpointtest.Scripted(endpointtest.Step{Response: "default"}))
+	e := endpoint.Fallback(fallback.Endpoint)(backend.Endpoint)
+
+	for i := 0; i < 2; i++ {
+		response, _ := e(context.Background(), i)
+		fmt.Println(response)
+	}
+	fmt.Println(backend.NumCalls(), fallback.Requests())
+
+	// Output:
+	// default
+	// ok
+	// 2 [0]
+}
@@ -0,0 +1,79 @@
+package endpoint
+
+import "golang.org/x/net/context"
+
+// FallbackOption sets an optional parameter for the Fallback middleware.
+type FallbackOption func(*fallback)
+
+type fallback struct {
+	onFailer  bool
+	predicate func(error) bool
+}
+
+// FallbackOnFailer makes the fallback also be invoked when the primary
+// endpoint returns a response implementing Failer, which failed. By default,
+// only returned errors trigger the fallback.
+func FallbackOnFailer() FallbackOption {
+	return func(f *fallback) { f.onFailer = true }
+}
+
+// FallbackIf restricts the fallback to the errors for which the predicate
+// returns true, e.g. to degrade gracefully on timeouts or an open circuit
+// breaker, but not on invalid requests. By default, all errors trigger the
+// fallback.
+func FallbackIf(predicate func(error) bool) FallbackOption {
+	return func(f *fallback) { f.predicate = predicate }
+}
+
+// Fallback returns a middleware which invokes the fallback endpoint fb, e.g.
+// one returning a cached or default response, when the wrapped endpoint
+// fails. The fallback is passed the original request, and a context carrying
+// the primary error, which it may retrieve with FallbackError, e.g. to log it.
+//
+// If the fallback fails as well, the middleware returns the result of the
+// primary endpoint, so callers see the original error, not the fallback's.
+func Fallback(fb Endpoint, options ...FallbackOption) Middleware {
+	f := &fallback{predicate: func(error) bool { return true }}
+	for _, option := range options {
+		option(f)
+	}
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, request)
+			primaryErr := f.failure(response, err)
+			if primaryErr == nil || !f.predicate(primaryErr) {
+				return response, err
+			}
+
+			fbResponse, fbErr := fb(context.WithValue(ctx, fallbackErrorKey, primaryErr), request)
+			if f.failure(fbResponse, fbErr) != nil {
+				return response, err
+			}
+			return fbResponse, nil
+		}
+	}
+}
+
+// failure returns the error of an endpoint's result, taking failed responses
+// into account if the FallbackOnFailer option is set.
+func (f *fallback) failure(response interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	if failer, ok := response.(Failer); ok && f.onFailer {
+		return failer.Failed()
+	}
+	return nil
+}
+
+type fallbackContextKey int
+
+const fallbackErrorKey fallbackContextKey = 0
+
+// FallbackError returns the error of the primary endpoint, from the context
+// passed to a fallback endpoint by the Fallback middleware. It returns nil in
+// other contexts.
+func FallbackError(ctx context.Context) error {
+	err, _ := ctx.Value(fallbackErrorKey).(error)
+	return err
+}
@@ -0,0 +1,117 @@
+package endpoint_test
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
+)
+
+var (
+	errTimeout = errors.New("timeout")
+	errInvalid = errors.New("invalid request")
+)
+
+// recommendations is a response implementing endpoint.Failer.
+type recommendations struct {
+	items []string
+	err   error
+}
+
+func (r recommendations) Failed() error { return r.err }
+
+func failing(response interface{}, err error) endpoint.Endpoint {
+	return endpointtest.Scripted(endpointtest.Step{Response: response, Err: err})
+}
+
+func TestFallback(t *testing.T) {
+	fb := endpointtest.NewRecorder(func(_ context.Context, request interface{}) (interface{}, error) {
+		return "default for " + request.(string), nil
+	})
+
+	e := endpoint.Fallback(fb.Endpoint)(failing(nil, errTimeout))
+	response, err := e(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "default for alice", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := errTimeout, endpoint.FallbackError(fb.Calls()[0].Context); want != have {
+		t.Errorf("want primary error %v, have %v", want, have)
+	}
+
+	e = endpoint.Fallback(fb.Endpoint)(failing("personalized", nil))
+	if response, _ := e(context.Background(), "alice"); response != "personalized" {
+		t.Errorf("want primary response, have %v", response)
+	}
+	fb.AssertCalls(t, 1)
+}
+
+func TestFallbackIf(t *testing.T) {
+	fb := endpointtest.NewRecorder(failing("default", nil))
+	onTimeout := endpoint.FallbackIf(func(err error) bool { return err == errTimeout })
+
+	for _, tc := range []struct {
+		err      error
+		response interface{}
+	}{
+		{errTimeout, "default"},
+		{errInvalid, nil},
+	} {
+		e := endpoint.Fallback(fb.Endpoint, onTimeout)(failing(nil, tc.err))
+		response, err := e(context.Background(), struct{}{})
+		if want, have := tc.response, response; want != have {
+			t.Errorf("%v: want %v, have %v", tc.err, want, have)
+		}
+		if tc.response == nil && err != tc.err {
+			t.Errorf("%v: want the primary error, have %v", tc.err, err)
+		}
+	}
+	fb.AssertCalls(t, 1)
+}
+
+func TestFallbackOnFailer(t *testing.T) {
+	var (
+		failed = recommendations{err: errTimeout}
+		fb     = failing(recommendations{items: []string{"bestseller"}}, nil)
+	)
+
+	// Failed responses are returned as they are by default.
+	e := endpoint.Fallback(fb)(failing(failed, nil))
+	if response, _ := e(context.Background(), struct{}{}); response.(recommendations).err != errTimeout {
+		t.Errorf("want failed response, have %v", response)
+	}
+
+	e = endpoint.Fallback(fb, endpoint.FallbackOnFailer())(failing(failed, nil))
+	response, err := e(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "bestseller", response.(recommendations).items[0]; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestFallbackFails(t *testing.T) {
+	errFallback := errors.New("cache unavailable")
+
+	e := endpoint.Fallback(failing(nil, errFallback))(failing(nil, errTimeout))
+	if want, have := errTimeout, errOf(e(context.Background(), struct{}{})); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// A failed fallback response is a failure, too.
+	failed := recommendations{err: errTimeout}
+	e = endpoint.Fallback(failing(recommendations{err: errFallback}, nil), endpoint.FallbackOnFailer())(failing(failed, nil))
+	response, err := e(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := errTimeout, response.(recommendations).Failed(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
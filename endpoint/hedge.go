@@ -0,0 +1,100 @@
+package endpoint
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// HedgeOption sets an optional parameter for the Hedge middleware.
+type HedgeOption func(*hedge)
+
+type hedge struct {
+	winner func(attempt int)
+}
+
+// HedgeWinner sets a function which is called with the index of the attempt
+// whose response is returned, after each successful call: 0 for the original
+// request, 1 for the first hedge, and so on. It's intended to record how
+// often hedging pays off, e.g. in a metric, to tune the delay.
+func HedgeWinner(f func(attempt int)) HedgeOption {
+	return func(h *hedge) { h.winner = f }
+}
+
+// Hedge returns a middleware which sends hedged requests, i.e. speculative
+// retries, to cut the tail latency caused by an occasional slow backend. If an
+// attempt hasn't returned within delay, another concurrent attempt is made,
+// up to maxAttempts in total, and the response of the first one to succeed is
+// returned. The context of the other attempts is then canceled.
+//
+// Only timeouts are hedged: once an attempt fails, no further attempts are
+// made, and the error is returned as soon as the attempts still in flight
+// have failed, too.
+//
+// Hedged requests may be executed more than once, so the middleware is only
+// safe for idempotent endpoints, e.g. read-only lookups.
+func Hedge(delay time.Duration, maxAttempts int, options ...HedgeOption) Middleware {
+	h := &hedge{winner: func(int) {}}
+	for _, option := range options {
+		option(h)
+	}
+	return func(next Endpoint) Endpoint {
+		if maxAttempts <= 1 {
+			return next
+		}
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			type result struct {
+				attempt  int
+				response interface{}
+				err      error
+			}
+			resultc := make(chan result, maxAttempts) // attempts never block
+			attempt := func(i int) {
+				response, err := next(ctx, request)
+				resultc <- result{i, response, err}
+			}
+
+			var (
+				attempts = 1
+				inFlight = 1
+				failure  error
+				timer    = time.NewTimer(delay)
+			)
+			defer timer.Stop()
+			go attempt(0)
+
+			for {
+				select {
+				case r := <-resultc:
+					inFlight--
+					if r.err == nil {
+						h.winner(r.attempt)
+						return r.response, nil
+					}
+					if failure == nil {
+						failure = r.err
+						timer.Stop()
+						attempts = maxAttempts // stop hedging
+					}
+					if inFlight == 0 {
+						return nil, failure
+					}
+
+				case <-timer.C:
+					if attempts < maxAttempts {
+						go attempt(attempts)
+						attempts++
+						inFlight++
+						timer.Reset(delay)
+					}
+
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+		}
+	}
+}
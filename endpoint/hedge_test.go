@@ -0,0 +1,130 @@
+package endpoint_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
+)
+
+// attempt returns a script step running f, for attempts which need to
+// coordinate with the test.
+func attempt(f func(ctx context.Context) (interface{}, error)) endpointtest.Step {
+	return endpointtest.Step{Endpoint: func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return f(ctx)
+	}}
+}
+
+func TestHedgeWins(t *testing.T) {
+	canceled := make(chan struct{})
+	backend := endpointtest.NewRecorder(endpointtest.Scripted(
+		attempt(func(ctx context.Context) (interface{}, error) {
+			<-ctx.Done() // slow replica
+			close(canceled)
+			return nil, ctx.Err()
+		}),
+		endpointtest.Step{Response: "hedge"},
+	))
+	var winner int
+	e := endpoint.Hedge(time.Millisecond, 2, endpoint.HedgeWinner(func(i int) { winner = i }))(backend.Endpoint)
+
+	response, err := e(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "hedge", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 1, winner; want != have {
+		t.Errorf("want winner %d, have %d", want, have)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("losing attempt wasn't canceled")
+	}
+}
+
+func TestHedgePrimaryWins(t *testing.T) {
+	var (
+		release  = make(chan struct{})
+		canceled = make(chan struct{})
+	)
+	backend := endpointtest.NewRecorder(endpointtest.Scripted(
+		attempt(func(context.Context) (interface{}, error) {
+			<-release
+			return "primary", nil
+		}),
+		attempt(func(ctx context.Context) (interface{}, error) {
+			close(release) // the primary returns once the hedge is in flight
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}),
+	))
+	winner := -1
+	e := endpoint.Hedge(time.Millisecond, 2, endpoint.HedgeWinner(func(i int) { winner = i }))(backend.Endpoint)
+
+	response, err := e(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "primary", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 0, winner; want != have {
+		t.Errorf("want winner %d, have %d", want, have)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("losing attempt wasn't canceled")
+	}
+}
+
+func TestHedgeBothFail(t *testing.T) {
+	var (
+		errPrimary = errors.New("primary failed")
+		errHedge   = errors.New("hedge failed")
+		release    = make(chan struct{})
+	)
+	backend := endpointtest.NewRecorder(endpointtest.Scripted(
+		attempt(func(context.Context) (interface{}, error) {
+			<-release
+			return nil, errPrimary
+		}),
+		attempt(func(context.Context) (interface{}, error) {
+			close(release)
+			return nil, errHedge
+		}),
+	))
+	e := endpoint.Hedge(time.Millisecond, 3, endpoint.HedgeWinner(func(i int) {
+		t.Errorf("want no winner, have %d", i)
+	}))(backend.Endpoint)
+
+	_, err := e(context.Background(), struct{}{})
+	if err != errPrimary && err != errHedge {
+		t.Errorf("want an attempt's error, have %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	backend.AssertCalls(t, 2)
+}
+
+func TestHedgeNotAfterError(t *testing.T) {
+	errFailed := errors.New("failed")
+	backend := endpointtest.NewRecorder(endpointtest.Scripted(
+		endpointtest.Step{Err: errFailed},
+		endpointtest.Step{Response: "hedge"},
+	))
+	e := endpoint.Hedge(time.Millisecond, 2)(backend.Endpoint)
+
+	if want, have := errFailed, errOf(e(context.Background(), struct{}{})); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	time.Sleep(10 * time.Millisecond)
+	backend.AssertCalls(t, 1)
+}
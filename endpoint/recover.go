@@ -0,0 +1,78 @@
+package endpoint
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"golang.org/x/net/context"
+)
+
+// PanicError is returned by the Recover middleware in place of a panic of the
+// wrapped endpoint.
+type PanicError struct {
+	Value interface{} // the value passed to panic
+	stack []byte
+}
+
+// NewPanicError returns a *PanicError for the value passed to panic, and the
+// stack trace of the goroutine which panicked, as returned by debug.Stack.
+// It lets middlewares recovering panics outside of Recover return the same
+// error.
+func NewPanicError(v interface{}, stack []byte) *PanicError {
+	return &PanicError{Value: v, stack: stack}
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Stack returns the stack trace of the goroutine which panicked, at the time
+// of the panic.
+func (e *PanicError) Stack() []byte {
+	return e.stack
+}
+
+// RecoverOption sets an optional parameter for the Recover middleware.
+type RecoverOption func(*recoverer)
+
+type recoverer struct {
+	callback func(ctx context.Context, err *PanicError)
+}
+
+// RecoverCallback sets a function which is called with each recovered panic,
+// e.g. to log it with its stack, or count it. By default, panics are only
+// returned as errors.
+func RecoverCallback(f func(ctx context.Context, err *PanicError)) RecoverOption {
+	return func(r *recoverer) { r.callback = f }
+}
+
+// Recover returns a middleware which recovers from panics of the wrapped
+// endpoint, and returns them as a *PanicError, independently of the
+// transport. The panic value http.ErrAbortHandler, which aborts an HTTP
+// handler on purpose, is not recovered, but panicked again, so the HTTP
+// server still handles it.
+func Recover(options ...RecoverOption) Middleware {
+	r := &recoverer{callback: func(context.Context, *PanicError) {}}
+	for _, option := range options {
+		option(r)
+	}
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				perr := NewPanicError(v, debug.Stack())
+				r.callback(ctx, perr)
+				response, err = nil, perr
+			}()
+			return next(ctx, request)
+		}
+	}
+}
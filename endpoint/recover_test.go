@@ -0,0 +1,73 @@
+package endpoint_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func panicking(v interface{}) endpoint.Endpoint {
+	return func(context.Context, interface{}) (interface{}, error) { panic(v) }
+}
+
+func TestRecover(t *testing.T) {
+	var recovered *endpoint.PanicError
+	e := endpoint.Recover(endpoint.RecoverCallback(func(_ context.Context, err *endpoint.PanicError) {
+		recovered = err
+	}))(panicking("boom"))
+
+	response, err := e(context.Background(), struct{}{})
+	if response != nil {
+		t.Errorf("want no response, have %v", response)
+	}
+	perr, ok := err.(*endpoint.PanicError)
+	if !ok {
+		t.Fatalf("want *endpoint.PanicError, have %#v", err)
+	}
+	if want, have := "boom", perr.Value; want != have {
+		t.Errorf("want panic value %v, have %v", want, have)
+	}
+	if want, have := "panic: boom", perr.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if !bytes.Contains(perr.Stack(), []byte("panicking")) {
+		t.Errorf("want stack of the panic, have\n%s", perr.Stack())
+	}
+	if recovered != perr {
+		t.Errorf("want callback called with %v, have %v", perr, recovered)
+	}
+}
+
+func TestRecoverPassesThrough(t *testing.T) {
+	errFailed := errors.New("failed")
+	called := false
+	e := endpoint.Recover(endpoint.RecoverCallback(func(context.Context, *endpoint.PanicError) {
+		called = true
+	}))(failing("response", errFailed))
+
+	response, err := e(context.Background(), struct{}{})
+	if want, have := "response", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := errFailed, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if called {
+		t.Error("want no callback without panic")
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	defer func() {
+		if want, have := http.ErrAbortHandler, recover(); want != have {
+			t.Errorf("want panic %v, have %v", want, have)
+		}
+	}()
+	endpoint.Recover()(panicking(http.ErrAbortHandler))(context.Background(), struct{}{})
+	t.Error("want panic, have none")
+}
@@ -0,0 +1,78 @@
+package endpoint
+
+// NamedMiddleware constructs a Middleware for an endpoint, given its name,
+// e.g. to name its spans or label its metrics.
+type NamedMiddleware func(name string) Middleware
+
+// Set is a collection of the named endpoints of a service, to which
+// middlewares can be applied in bulk, instead of wiring each endpoint by hand.
+// Transport bindings can then be constructed from the set. Endpoints are kept
+// in the order they were added, which is the order of Names, and in which
+// middlewares are applied.
+type Set struct {
+	names     []string
+	endpoints map[string]Endpoint
+}
+
+// NewSet returns an empty set of endpoints.
+func NewSet() *Set {
+	return &Set{endpoints: map[string]Endpoint{}}
+}
+
+// Add adds the endpoint under the name. If the set already has an endpoint
+// with the name, it's replaced, keeping its position.
+func (s *Set) Add(name string, e Endpoint) *Set {
+	if _, ok := s.endpoints[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.endpoints[name] = e
+	return s
+}
+
+// Get returns the endpoint with the name, if the set has one.
+func (s *Set) Get(name string) (Endpoint, bool) {
+	e, ok := s.endpoints[name]
+	return e, ok
+}
+
+// Names returns the names of the endpoints, in the order they were added.
+func (s *Set) Names() []string {
+	return append([]string(nil), s.names...)
+}
+
+// Wrap wraps the endpoint with the name in the middlewares. Like with Chain,
+// the first middleware is the outermost. Middlewares applied by later calls
+// wrap those applied by earlier ones. It panics if the set has no endpoint
+// with the name.
+func (s *Set) Wrap(name string, mw ...Middleware) *Set {
+	e, ok := s.endpoints[name]
+	if !ok {
+		panic("endpoint: no endpoint named " + name + " in set")
+	}
+	for i := len(mw) - 1; i >= 0; i-- {
+		e = mw[i](e)
+	}
+	s.endpoints[name] = e
+	return s
+}
+
+// WrapAll wraps each endpoint of the set in the middlewares, like Wrap.
+func (s *Set) WrapAll(mw ...Middleware) *Set {
+	for _, name := range s.names {
+		s.Wrap(name, mw...)
+	}
+	return s
+}
+
+// WrapAllNamed wraps each endpoint of the set in the middlewares constructed
+// for it, given its name, like Wrap.
+func (s *Set) WrapAllNamed(nmw ...NamedMiddleware) *Set {
+	for _, name := range s.names {
+		mw := make([]Middleware, len(nmw))
+		for i, f := range nmw {
+			mw[i] = f(name)
+		}
+		s.Wrap(name, mw...)
+	}
+	return s
+}
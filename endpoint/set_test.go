@@ -0,0 +1,82 @@
+package endpoint_test
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// trace returns a middleware appending its label to the response, which is
+// the list of labels of the middlewares traversed.
+func trace(label string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, append(request.([]string), label))
+			return response, err
+		}
+	}
+}
+
+func echo(_ context.Context, request interface{}) (interface{}, error) { return request, nil }
+
+func call(t *testing.T, s *endpoint.Set, name string) []string {
+	e, ok := s.Get(name)
+	if !ok {
+		t.Fatalf("no endpoint %s", name)
+	}
+	response, err := e(context.Background(), []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.([]string)
+}
+
+func TestSetWrap(t *testing.T) {
+	s := endpoint.NewSet().Add("sum", echo).Add("concat", echo)
+	s.WrapAll(trace("a"), trace("b"))
+	s.Wrap("sum", trace("c"))
+	s.WrapAll(trace("d"))
+
+	for name, want := range map[string][]string{
+		"sum":    {"d", "c", "a", "b"},
+		"concat": {"d", "a", "b"},
+	} {
+		if have := call(t, s, name); !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want %v, have %v", name, want, have)
+		}
+	}
+}
+
+func TestSetWrapAllNamed(t *testing.T) {
+	s := endpoint.NewSet().Add("sum", echo).Add("concat", echo)
+	var names []string
+	s.WrapAllNamed(func(name string) endpoint.Middleware {
+		names = append(names, name)
+		return trace(name)
+	}, func(string) endpoint.Middleware { return trace("inner") })
+
+	if want, have := []string{"sum", "concat"}, names; !reflect.DeepEqual(want, have) {
+		t.Errorf("want middlewares constructed for %v, have %v", want, have)
+	}
+	for _, name := range []string{"sum", "concat"} {
+		if want, have := []string{name, "inner"}, call(t, s, name); !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want %v, have %v", name, want, have)
+		}
+	}
+}
+
+func TestSetNames(t *testing.T) {
+	s := endpoint.NewSet()
+	for _, name := range []string{"c", "a", "b", "a"} {
+		s.Add(name, echo)
+	}
+	if want, have := []string{"c", "a", "b"}, s.Names(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if _, ok := s.Get("d"); ok {
+		t.Error("want no endpoint d, have one")
+	}
+}
@@ -0,0 +1,106 @@
+package endpoint
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// TimeoutOption sets an optional parameter for the Timeout middleware.
+type TimeoutOption func(*timeout)
+
+type timeout struct {
+	err            error
+	parentDeadline bool
+}
+
+// TimeoutError sets the error returned when the timeout expires. By default,
+// context.DeadlineExceeded is returned.
+func TimeoutError(err error) TimeoutOption {
+	return func(t *timeout) { t.err = err }
+}
+
+// TimeoutParentDeadline makes the timeout honor the deadline of the caller's
+// context, too, so the call is bounded by whichever of the two expires first.
+// By default, the caller's deadline is ignored.
+func TimeoutParentDeadline() TimeoutOption {
+	return func(t *timeout) { t.parentDeadline = true }
+}
+
+// Timeout returns a middleware which bounds each call to the wrapped endpoint
+// by the duration d, independently of the deadline set by the caller, so one
+// slow dependency can't consume a request's entire budget. The caller's
+// values and cancellation are still propagated.
+//
+// If the timeout expires before the endpoint returns, the middleware returns
+// context.DeadlineExceeded, or the error set by TimeoutError, without waiting
+// for it. The endpoint runs in its own goroutine, which delivers its result
+// to a buffered channel, so it exits, and the late result is discarded, as
+// soon as the endpoint returns.
+func Timeout(d time.Duration, options ...TimeoutOption) Middleware {
+	t := &timeout{err: context.DeadlineExceeded}
+	for _, option := range options {
+		option(t)
+	}
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			parent := ctx
+			if !t.parentDeadline {
+				var cancel context.CancelFunc
+				parent, cancel = withoutDeadline(ctx)
+				defer cancel()
+			}
+			ctx, cancel := context.WithTimeout(parent, d)
+			defer cancel()
+
+			type result struct {
+				response interface{}
+				err      error
+			}
+			resultc := make(chan result, 1)
+			go func() {
+				response, err := next(ctx, request)
+				resultc <- result{response, err}
+			}()
+
+			select {
+			case r := <-resultc:
+				return r.response, r.err
+			case <-ctx.Done():
+				if ctx.Err() == context.DeadlineExceeded {
+					return nil, t.err
+				}
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+// withoutDeadline returns a context with the values of ctx, which is canceled
+// when ctx is canceled, but not when its deadline expires.
+func withoutDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached, cancel := context.WithCancel(WithoutCancel(ctx))
+	go func() {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.Canceled {
+				cancel()
+			}
+		case <-detached.Done():
+		}
+	}()
+	return detached, cancel
+}
+
+// WithoutCancel returns a context which has the values of ctx, but never
+// expires, and is never canceled, for work which must outlive the request
+// which started it, e.g. a call shared by several requests.
+func WithoutCancel(ctx context.Context) context.Context {
+	return valuesOnly{ctx}
+}
+
+type valuesOnly struct{ context.Context }
+
+func (valuesOnly) Deadline() (deadline time.Time, ok bool) { return }
+func (valuesOnly) Done() <-chan struct{}                   { return nil }
+func (valuesOnly) Err() error                              { return nil }
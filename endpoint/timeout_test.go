@@ -0,0 +1,119 @@
+package endpoint_test
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
+)
+
+// sleepy returns an endpoint echoing the request after d.
+func sleepy(d time.Duration) endpoint.Endpoint {
+	return endpointtest.Scripted(endpointtest.Step{Delay: d, Endpoint: echo})
+}
+
+func TestTimeout(t *testing.T) {
+	e := endpoint.Timeout(50 * time.Millisecond)(sleepy(time.Millisecond))
+	response, err := e(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "hello", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	e = endpoint.Timeout(time.Millisecond)(sleepy(time.Second))
+	if want, have := context.DeadlineExceeded, errOf(e(context.Background(), "hello")); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	errSlow := errors.New("too slow")
+	e := endpoint.Timeout(time.Millisecond, endpoint.TimeoutError(errSlow))(sleepy(time.Second))
+	if want, have := errSlow, errOf(e(context.Background(), "hello")); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestTimeoutShorterParentDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	// The caller's deadline is ignored by default.
+	e := endpoint.Timeout(time.Second)(sleepy(50 * time.Millisecond))
+	if err := errOf(e(ctx, "hello")); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	errSlow := errors.New("too slow")
+	e = endpoint.Timeout(time.Second, endpoint.TimeoutParentDeadline(), endpoint.TimeoutError(errSlow))(sleepy(time.Second))
+	begin := time.Now()
+	if want, have := errSlow, errOf(e(ctx, "hello")); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if took := time.Since(begin); took > 500*time.Millisecond {
+		t.Errorf("want the caller's deadline to apply, took %v", took)
+	}
+}
+
+func TestTimeoutCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond, cancel)
+
+	e := endpoint.Timeout(time.Second)(sleepy(time.Second))
+	if want, have := context.Canceled, errOf(e(ctx, "hello")); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestTimeoutNoGoroutineLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// The endpoint ignores its context, and returns long after the timeout.
+	block := make(chan struct{})
+	e := endpoint.Timeout(time.Millisecond)(func(context.Context, interface{}) (interface{}, error) {
+		<-block
+		return "late", nil
+	})
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		if want, have := context.DeadlineExceeded, errOf(e(ctx, i)); want != have {
+			t.Fatalf("want %v, have %v", want, have)
+		}
+		cancel()
+	}
+	close(block)
+
+	if err := checkGoroutines(before); err != nil {
+		t.Error(err)
+	}
+}
+
+// checkGoroutines is a simple leak detector: it waits for the number of
+// goroutines to drop back to n, and fails if it doesn't within a second.
+func checkGoroutines(n int) error {
+	deadline := time.Now().Add(time.Second)
+	for {
+		have := runtime.NumGoroutine()
+		if have <= n {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			buf = buf[:runtime.Stack(buf, true)]
+			return errors.New("goroutines leaked:\n" + string(buf))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func errOf(_ interface{}, err error) error { return err }
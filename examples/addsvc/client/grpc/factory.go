@@ -2,15 +2,19 @@ package grpc
 
 import (
 	"io"
+	"strconv"
 
 	kitot "github.com/go-kit/kit/tracing/opentracing"
 	"github.com/opentracing/opentracing-go"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/examples/addsvc/pb"
 	"github.com/go-kit/kit/loadbalancer"
 	"github.com/go-kit/kit/log"
+	kitzipkin "github.com/go-kit/kit/tracing/zipkin"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 )
 
@@ -28,6 +32,7 @@ func MakeSumEndpointFactory(tracer opentracing.Tracer, tracingLogger log.Logger)
 			decodeSumResponse,
 			pb.SumReply{},
 			grpctransport.SetClientBefore(kitot.ToGRPCRequest(tracer, tracingLogger)),
+			grpctransport.SetClientAfter(logTraceID(tracingLogger)),
 		).Endpoint(), cc, err
 	}
 }
@@ -46,6 +51,18 @@ func MakeConcatEndpointFactory(tracer opentracing.Tracer, tracingLogger log.Logg
 			decodeConcatResponse,
 			pb.ConcatReply{},
 			grpctransport.SetClientBefore(kitot.ToGRPCRequest(tracer, tracingLogger)),
+			grpctransport.SetClientAfter(logTraceID(tracingLogger)),
 		).Endpoint(), cc, err
 	}
 }
+
+// logTraceID returns a ClientResponseFunc logging the trace ID the server
+// returns in the response trailer, if any, so users can look up the trace.
+func logTraceID(logger log.Logger) grpctransport.ClientResponseFunc {
+	return func(ctx context.Context, _ metadata.MD, trailer metadata.MD) context.Context {
+		if id, ok := kitzipkin.TraceIDFromGRPCTrailer(trailer); ok {
+			logger.Log("trace_id", strconv.FormatUint(uint64(id), 16))
+		}
+		return ctx
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/examples/addsvc/server"
+	"github.com/go-kit/kit/transport/netrpc"
 )
 
 // SumEndpointFactory transforms host:port strings into Endpoints.
@@ -17,13 +18,13 @@ func SumEndpointFactory(instance string) (endpoint.Endpoint, io.Closer, error) {
 		return nil, nil, err
 	}
 
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		var reply server.SumResponse
-		if err := client.Call("addsvc.Sum", request.(server.SumRequest), &reply); err != nil {
-			return server.SumResponse{}, err
-		}
-		return reply, nil
-	}, client, nil
+	return netrpc.NewClient(
+		client,
+		"addsvc.Sum",
+		encodeRequest,
+		decodeResponse,
+		server.SumResponse{},
+	).Endpoint(), client, nil
 }
 
 // ConcatEndpointFactory transforms host:port strings into Endpoints.
@@ -33,11 +34,21 @@ func ConcatEndpointFactory(instance string) (endpoint.Endpoint, io.Closer, error
 		return nil, nil, err
 	}
 
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		var reply server.ConcatResponse
-		if err := client.Call("addsvc.Concat", request.(server.ConcatRequest), &reply); err != nil {
-			return server.ConcatResponse{}, err
-		}
-		return reply, nil
-	}, client, nil
+	return netrpc.NewClient(
+		client,
+		"addsvc.Concat",
+		encodeRequest,
+		decodeResponse,
+		server.ConcatResponse{},
+	).Endpoint(), client, nil
+}
+
+// The addsvc request and response types are used on the wire as-is.
+
+func encodeRequest(_ context.Context, request interface{}) (interface{}, error) {
+	return request, nil
+}
+
+func decodeResponse(_ context.Context, response interface{}) (interface{}, error) {
+	return response, nil
 }
@@ -27,12 +27,16 @@ import (
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/examples/addsvc/pb"
 	"github.com/go-kit/kit/examples/addsvc/server"
+	servergrpc "github.com/go-kit/kit/examples/addsvc/server/grpc"
+	serverthrift "github.com/go-kit/kit/examples/addsvc/server/thrift"
 	thriftadd "github.com/go-kit/kit/examples/addsvc/thrift/gen-go/add"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/expvar"
 	"github.com/go-kit/kit/metrics/prometheus"
 	kitot "github.com/go-kit/kit/tracing/opentracing"
+	kitzipkin "github.com/go-kit/kit/tracing/zipkin"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
 	httptransport "github.com/go-kit/kit/transport/http"
 )
 
@@ -188,8 +192,25 @@ func main() {
 			errc <- err
 			return
 		}
+		var sum, concat endpoint.Endpoint
+		sum = makeSumEndpoint(svc)
+		sum = kitot.TraceServer(tracer, "sum")(sum)
+		concat = makeConcatEndpoint(svc)
+		concat = kitot.TraceServer(tracer, "concat")(concat)
+
+		// The Zipkin span of the request, if the caller sent one, carries
+		// the trace ID returned to the caller in the response trailer.
+		newSpan := kitzipkin.MakeNewSpanFunc(*grpcAddr, "addsvc", "")
+
 		s := grpc.NewServer() // uses its own, internal context
-		pb.RegisterAddServer(s, newGRPCBinding(root, tracer, svc, tracingLogger))
+		pb.RegisterAddServer(s, servergrpc.NewBinding(
+			root, sum, concat,
+			grpctransport.ServerBefore(
+				kitot.FromGRPCRequest(tracer, "", tracingLogger),
+				kitzipkin.ToGRPCContext(newSpan, tracingLogger),
+			),
+			grpctransport.ServerAfter(kitzipkin.ToGRPCTrailer()),
+		))
 		transportLogger.Log("addr", *grpcAddr)
 		errc <- s.Serve(ln)
 	}()
@@ -198,7 +219,7 @@ func main() {
 	go func() {
 		transportLogger := log.NewContext(logger).With("transport", "net/rpc")
 		s := rpc.NewServer()
-		if err := s.RegisterName("addsvc", netrpcBinding{svc}); err != nil {
+		if err := s.RegisterName("addsvc", newNetRPCBinding(root, tracer, svc)); err != nil {
 			errc <- err
 			return
 		}
@@ -237,10 +258,16 @@ func main() {
 			errc <- err
 			return
 		}
+		var sum, concat endpoint.Endpoint
+		sum = makeSumEndpoint(svc)
+		sum = kitot.TraceServer(tracer, "sum")(sum)
+		concat = makeConcatEndpoint(svc)
+		concat = kitot.TraceServer(tracer, "concat")(concat)
+
 		transportLogger := log.NewContext(logger).With("transport", "thrift")
 		transportLogger.Log("addr", *thriftAddr)
 		errc <- thrift.NewTSimpleServer4(
-			thriftadd.NewAddServiceProcessor(thriftBinding{svc}),
+			thriftadd.NewAddServiceProcessor(serverthrift.NewBinding(root, sum, concat)),
 			transport,
 			transportFactory,
 			protocolFactory,
@@ -1,21 +1,51 @@
 package main
 
 import (
+	"golang.org/x/net/context"
+
+	"github.com/opentracing/opentracing-go"
+
 	"github.com/go-kit/kit/examples/addsvc/server"
+	servernetrpc "github.com/go-kit/kit/examples/addsvc/server/netrpc"
+	kitot "github.com/go-kit/kit/tracing/opentracing"
+	"github.com/go-kit/kit/transport/netrpc"
 )
 
 type netrpcBinding struct {
-	server.AddService
+	sum, concat netrpc.Handler
+}
+
+func newNetRPCBinding(ctx context.Context, tracer opentracing.Tracer, svc server.AddService) netrpcBinding {
+	return netrpcBinding{
+		sum: netrpc.NewServer(
+			ctx,
+			kitot.TraceServer(tracer, "sum")(makeSumEndpoint(svc)),
+			servernetrpc.DecodeSumRequest,
+			servernetrpc.EncodeSumResponse,
+		),
+		concat: netrpc.NewServer(
+			ctx,
+			kitot.TraceServer(tracer, "concat")(makeConcatEndpoint(svc)),
+			servernetrpc.DecodeConcatRequest,
+			servernetrpc.EncodeConcatResponse,
+		),
+	}
 }
 
 func (b netrpcBinding) Sum(request server.SumRequest, response *server.SumResponse) error {
-	v := b.AddService.Sum(request.A, request.B)
-	(*response) = server.SumResponse{V: v}
+	resp, err := b.sum.ServeNetRPC(request)
+	if err != nil {
+		return err
+	}
+	*response = resp.(server.SumResponse)
 	return nil
 }
 
 func (b netrpcBinding) Concat(request server.ConcatRequest, response *server.ConcatResponse) error {
-	v := b.AddService.Concat(request.A, request.B)
-	(*response) = server.ConcatResponse{V: v}
+	resp, err := b.concat.ServeNetRPC(request)
+	if err != nil {
+		return err
+	}
+	*response = resp.(server.ConcatResponse)
 	return nil
 }
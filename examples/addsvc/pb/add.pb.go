@@ -6,9 +6,11 @@
 Package pb is a generated protocol buffer package.
 
 It is generated from these files:
+
 	add.proto
 
 It has these top-level messages:
+
 	SumRequest
 	SumReply
 	ConcatRequest
@@ -32,7 +34,7 @@ var _ = math.Inf
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the proto package it is being compiled against.
-const _ = proto.ProtoPackageIsVersion1
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 // The sum request contains two parameters.
 type SumRequest struct {
@@ -89,7 +91,7 @@ var _ grpc.ClientConn
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion2
+const _ = grpc.SupportPackageIsVersion4
 
 // Client API for Add service
 
@@ -188,9 +190,12 @@ var _Add_serviceDesc = grpc.ServiceDesc{
 			Handler:    _Add_Concat_Handler,
 		},
 	},
-	Streams: []grpc.StreamDesc{},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "add.proto",
 }
 
+func init() { proto.RegisterFile("add.proto", fileDescriptor0) }
+
 var fileDescriptor0 = []byte{
 	// 171 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x4c, 0x4c, 0x49, 0xd1,
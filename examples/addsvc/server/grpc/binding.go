@@ -0,0 +1,43 @@
+package grpc
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/examples/addsvc/pb"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+)
+
+// Binding implements pb.AddServer by serving Go kit endpoints via the gRPC
+// transport.
+type Binding struct {
+	sum, concat grpctransport.Handler
+}
+
+// NewBinding returns a pb.AddServer backed by the sum and concat endpoints,
+// which take *server.SumRequest and *server.ConcatRequest values. The server
+// options, e.g. ServerBefore and ServerAfter functions, apply to both.
+func NewBinding(ctx context.Context, sum, concat endpoint.Endpoint, options ...grpctransport.ServerOption) Binding {
+	return Binding{
+		sum:    grpctransport.NewServer(ctx, sum, DecodeSumRequest, EncodeSumResponse, options...),
+		concat: grpctransport.NewServer(ctx, concat, DecodeConcatRequest, EncodeConcatResponse, options...),
+	}
+}
+
+// Sum implements pb.AddServer.
+func (b Binding) Sum(ctx context.Context, req *pb.SumRequest) (*pb.SumReply, error) {
+	_, resp, err := b.sum.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.SumReply), nil
+}
+
+// Concat implements pb.AddServer.
+func (b Binding) Concat(ctx context.Context, req *pb.ConcatRequest) (*pb.ConcatReply, error) {
+	_, resp, err := b.concat.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.ConcatReply), nil
+}
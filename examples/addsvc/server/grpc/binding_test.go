@@ -0,0 +1,88 @@
+package grpc_test
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/examples/addsvc/pb"
+	"github.com/go-kit/kit/examples/addsvc/server"
+	servergrpc "github.com/go-kit/kit/examples/addsvc/server/grpc"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/tracing/zipkin"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+)
+
+func TestBindingTraceIDTrailer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	pb.RegisterAddServer(s, servergrpc.NewBinding(
+		context.Background(),
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			req := request.(*server.SumRequest)
+			return server.SumResponse{V: req.A + req.B}, nil
+		},
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			req := request.(*server.ConcatRequest)
+			return server.ConcatResponse{V: req.A + req.B}, nil
+		},
+		grpctransport.ServerBefore(zipkin.ToGRPCContext(zipkin.MakeNewSpanFunc("127.0.0.1:0", "addsvc", ""), log.NewNopLogger())),
+		grpctransport.ServerAfter(zipkin.ToGRPCTrailer()),
+	))
+	go s.Serve(ln)
+	defer s.Stop()
+
+	cc, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	sum := func(before ...grpctransport.RequestFunc) (response interface{}, traceID int64) {
+		var trailer metadata.MD
+		response, err := grpctransport.NewClient(
+			cc, "Add", "Sum",
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				req := request.(server.SumRequest)
+				return &pb.SumRequest{A: int64(req.A), B: int64(req.B)}, nil
+			},
+			func(_ context.Context, response interface{}) (interface{}, error) {
+				return server.SumResponse{V: int(response.(*pb.SumReply).V)}, nil
+			},
+			pb.SumReply{},
+			grpctransport.SetClientBefore(before...),
+			grpctransport.SetClientAfter(func(ctx context.Context, _ metadata.MD, t metadata.MD) context.Context {
+				trailer = t
+				return ctx
+			}),
+		).Endpoint()(context.Background(), server.SumRequest{A: 1, B: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		traceID, _ = zipkin.TraceIDFromGRPCTrailer(trailer)
+		return response, traceID
+	}
+
+	// The trace ID of the caller's span round-trips.
+	response, traceID := sum(
+		grpctransport.SetRequestHeader("X-B3-TraceId", "4bf92f3577b34da6"),
+		grpctransport.SetRequestHeader("X-B3-SpanId", "1"),
+	)
+	if want, have := 3, response.(server.SumResponse).V; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := int64(0x4bf92f3577b34da6), traceID; want != have {
+		t.Errorf("want trace ID %x, have %x", want, have)
+	}
+
+	// Without a trace, there's no trace ID to return.
+	if _, traceID := sum(); traceID != 0 {
+		t.Errorf("want no trace ID, have %x", traceID)
+	}
+}
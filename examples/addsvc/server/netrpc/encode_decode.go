@@ -0,0 +1,27 @@
+package netrpc
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/examples/addsvc/server"
+)
+
+func DecodeSumRequest(ctx context.Context, req interface{}) (interface{}, error) {
+	sumRequest := req.(server.SumRequest)
+
+	return &sumRequest, nil
+}
+
+func DecodeConcatRequest(ctx context.Context, req interface{}) (interface{}, error) {
+	concatRequest := req.(server.ConcatRequest)
+
+	return &concatRequest, nil
+}
+
+func EncodeSumResponse(ctx context.Context, resp interface{}) (interface{}, error) {
+	return resp.(server.SumResponse), nil
+}
+
+func EncodeConcatResponse(ctx context.Context, resp interface{}) (interface{}, error) {
+	return resp.(server.ConcatResponse), nil
+}
@@ -0,0 +1,47 @@
+package thrift
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/examples/addsvc/server"
+	thriftadd "github.com/go-kit/kit/examples/addsvc/thrift/gen-go/add"
+)
+
+// Binding implements the Thrift AddService handler interface by invoking Go
+// kit endpoints, so any endpoint middleware, e.g. for tracing or logging,
+// applies to Thrift requests as it does to other transports.
+type Binding struct {
+	ctx         context.Context
+	sum, concat endpoint.Endpoint
+}
+
+// NewBinding returns a Thrift AddService handler backed by the sum and concat
+// endpoints, which take *server.SumRequest and *server.ConcatRequest values.
+// Thrift handlers don't receive a context, so each request is served in a
+// context derived from ctx.
+func NewBinding(ctx context.Context, sum, concat endpoint.Endpoint) Binding {
+	return Binding{ctx: ctx, sum: sum, concat: concat}
+}
+
+// Sum implements thriftadd.AddService.
+func (b Binding) Sum(x, y int64) (*thriftadd.SumReply, error) {
+	ctx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+	response, err := b.sum(ctx, &server.SumRequest{A: int(x), B: int(y)})
+	if err != nil {
+		return nil, err
+	}
+	return &thriftadd.SumReply{Value: int64(response.(server.SumResponse).V)}, nil
+}
+
+// Concat implements thriftadd.AddService.
+func (b Binding) Concat(x, y string) (*thriftadd.ConcatReply, error) {
+	ctx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+	response, err := b.concat(ctx, &server.ConcatRequest{A: x, B: y})
+	if err != nil {
+		return nil, err
+	}
+	return &thriftadd.ConcatReply{Value: response.(server.ConcatResponse).V}, nil
+}
@@ -0,0 +1,123 @@
+package thrift_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	thriftclient "github.com/go-kit/kit/examples/addsvc/client/thrift"
+	"github.com/go-kit/kit/examples/addsvc/server"
+	serverthrift "github.com/go-kit/kit/examples/addsvc/server/thrift"
+	thriftadd "github.com/go-kit/kit/examples/addsvc/thrift/gen-go/add"
+	"github.com/go-kit/kit/log"
+)
+
+func TestBinding(t *testing.T) {
+	for _, tc := range []struct {
+		protocol   string
+		bufferSize int
+		framed     bool
+	}{
+		{"binary", 0, false},
+		{"binary", 4096, true},
+		{"compact", 0, false},
+		{"compact", 4096, true},
+	} {
+		name := fmt.Sprintf("protocol=%s buffer=%d framed=%v", tc.protocol, tc.bufferSize, tc.framed)
+
+		// Count invocations, to show that endpoint middleware applies.
+		var calls int
+		counting := func(next endpoint.Endpoint) endpoint.Endpoint {
+			return func(ctx context.Context, request interface{}) (interface{}, error) {
+				calls++
+				return next(ctx, request)
+			}
+		}
+		binding := serverthrift.NewBinding(
+			context.Background(),
+			counting(func(_ context.Context, request interface{}) (interface{}, error) {
+				req := request.(*server.SumRequest)
+				return server.SumResponse{V: req.A + req.B}, nil
+			}),
+			counting(func(_ context.Context, request interface{}) (interface{}, error) {
+				req := request.(*server.ConcatRequest)
+				return server.ConcatResponse{V: req.A + req.B}, nil
+			}),
+		)
+
+		addr, stop := startServer(t, binding, tc.protocol, tc.bufferSize, tc.framed)
+		client := thriftclient.New(tc.protocol, tc.bufferSize, tc.framed, log.NewLogfmtLogger(ioutil.Discard))
+
+		sum, sumCloser, err := client.SumEndpoint(addr)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		response, err := sum(context.Background(), server.SumRequest{A: 1, B: 2})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want, have := 3, response.(server.SumResponse).V; want != have {
+			t.Errorf("%s: Sum: want %d, have %d", name, want, have)
+		}
+		sumCloser.Close()
+
+		concat, concatCloser, err := client.ConcatEndpoint(addr)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		response, err = concat(context.Background(), server.ConcatRequest{A: "1", B: "2"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want, have := "12", response.(server.ConcatResponse).V; want != have {
+			t.Errorf("%s: Concat: want %q, have %q", name, want, have)
+		}
+		concatCloser.Close()
+
+		if want, have := 2, calls; want != have {
+			t.Errorf("%s: want %d endpoint call(s), have %d", name, want, have)
+		}
+		stop()
+	}
+}
+
+func startServer(t *testing.T, binding serverthrift.Binding, protocol string, bufferSize int, framed bool) (string, func()) {
+	var protocolFactory thrift.TProtocolFactory
+	switch protocol {
+	case "binary":
+		protocolFactory = thrift.NewTBinaryProtocolFactoryDefault()
+	case "compact":
+		protocolFactory = thrift.NewTCompactProtocolFactory()
+	default:
+		t.Fatalf("unsupported protocol %q", protocol)
+	}
+	var transportFactory thrift.TTransportFactory
+	if bufferSize > 0 {
+		transportFactory = thrift.NewTBufferedTransportFactory(bufferSize)
+	} else {
+		transportFactory = thrift.NewTTransportFactory()
+	}
+	if framed {
+		transportFactory = thrift.NewTFramedTransportFactory(transportFactory)
+	}
+
+	transport, err := thrift.NewTServerSocket("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := transport.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	s := thrift.NewTSimpleServer4(
+		thriftadd.NewAddServiceProcessor(binding),
+		transport,
+		transportFactory,
+		protocolFactory,
+	)
+	go s.Serve()
+	return transport.Addr().String(), func() { s.Stop() }
+}
@@ -39,18 +39,19 @@ type postProfileResponse struct {
 	Err error `json:"err,omitempty"`
 }
 
-func (r postProfileResponse) error() error { return r.Err }
+func (r postProfileResponse) Failed() error { return r.Err }
 
 // Regarding errors returned from service (business logic) methods, we have two
 // options. We could return the error via the endpoint itself. That makes
 // certain things a little bit easier, like providing non-200 HTTP responses to
 // the client. But Go kit assumes that endpoint errors are (or may be treated
-// as) transport-domain errors. For example, an endpoint error will count
-// against a circuit breaker error count. Therefore, it's almost certainly
-// better to return service (business logic) errors in the response object. This
-// means we have to do a bit more work in the HTTP response encoder to detect
-// e.g. a not-found error and provide a proper HTTP status code. That work is
-// done with the errorer interface, in transport.go.
+// as) transport-domain errors. Therefore, it's almost certainly better to
+// return service (business logic) errors in the response object. This means
+// we have to do a bit more work in the HTTP response encoder to detect e.g. a
+// not-found error and provide a proper HTTP status code. That work is done
+// with the endpoint.Failer interface, which all response types implement, in
+// transport.go. Middlewares like circuit breakers and tracing check it too, so
+// failed responses still count as failures there.
 
 func makePostProfileEndpoint(s ProfileService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
@@ -69,7 +70,7 @@ type getProfileResponse struct {
 	Err     error   `json:"err,omitempty"`
 }
 
-func (r getProfileResponse) error() error { return r.Err }
+func (r getProfileResponse) Failed() error { return r.Err }
 
 func makeGetProfileEndpoint(s ProfileService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
@@ -88,7 +89,7 @@ type putProfileResponse struct {
 	Err error `json:"err,omitempty"`
 }
 
-func (r putProfileResponse) error() error { return nil }
+func (r putProfileResponse) Failed() error { return nil }
 
 func makePutProfileEndpoint(s ProfileService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
@@ -107,7 +108,7 @@ type patchProfileResponse struct {
 	Err error `json:"err,omitempty"`
 }
 
-func (r patchProfileResponse) error() error { return r.Err }
+func (r patchProfileResponse) Failed() error { return r.Err }
 
 func makePatchProfileEndpoint(s ProfileService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
@@ -125,7 +126,7 @@ type deleteProfileResponse struct {
 	Err error `json:"err,omitempty"`
 }
 
-func (r deleteProfileResponse) error() error { return r.Err }
+func (r deleteProfileResponse) Failed() error { return r.Err }
 
 func makeDeleteProfileEndpoint(s ProfileService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
@@ -144,7 +145,7 @@ type getAddressesResponse struct {
 	Err       error     `json:"err,omitempty"`
 }
 
-func (r getAddressesResponse) error() error { return r.Err }
+func (r getAddressesResponse) Failed() error { return r.Err }
 
 func makeGetAddressesEndpoint(s ProfileService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
@@ -164,7 +165,7 @@ type getAddressResponse struct {
 	Err     error   `json:"err,omitempty"`
 }
 
-func (r getAddressResponse) error() error { return r.Err }
+func (r getAddressResponse) Failed() error { return r.Err }
 
 func makeGetAddressEndpoint(s ProfileService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
@@ -183,7 +184,7 @@ type postAddressResponse struct {
 	Err error `json:"err,omitempty"`
 }
 
-func (r postAddressResponse) error() error { return r.Err }
+func (r postAddressResponse) Failed() error { return r.Err }
 
 func makePostAddressEndpoint(s ProfileService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
@@ -202,7 +203,7 @@ type deleteAddressResponse struct {
 	Err error `json:"err,omitempty"`
 }
 
-func (r deleteAddressResponse) error() error { return r.Err }
+func (r deleteAddressResponse) Failed() error { return r.Err }
 
 func makeDeleteAddressEndpoint(s ProfileService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
@@ -8,6 +8,7 @@ import (
 	"github.com/gorilla/mux"
 	"golang.org/x/net/context"
 
+	"github.com/go-kit/kit/endpoint"
 	kitlog "github.com/go-kit/kit/log"
 	kithttp "github.com/go-kit/kit/transport/http"
 )
@@ -216,23 +217,20 @@ func decodeDeleteAddressRequest(_ context.Context, r *stdhttp.Request) (request
 	}, nil
 }
 
-// errorer is implemented by all concrete response types. It allows us to
-// change the HTTP response code without needing to trigger an endpoint
-// (transport-level) error. For more information, read the big comment in
-// endpoints.go.
-type errorer interface {
-	error() error
-}
-
 // encodeResponse is the common method to encode all response types to the
 // client. I chose to do it this way because I didn't know if something more
 // specific was necessary. It's certainly possible to specialize on a
 // per-response (per-method) basis.
+//
+// All concrete response types implement endpoint.Failer. It allows us to
+// change the HTTP response code without needing to trigger an endpoint
+// (transport-level) error. For more information, read the big comment in
+// endpoints.go.
 func encodeResponse(ctx context.Context, w stdhttp.ResponseWriter, response interface{}) error {
-	if e, ok := response.(errorer); ok && e.error() != nil {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
 		// Not a Go kit transport error, but a business-logic error.
 		// Provide those as HTTP errors.
-		encodeError(ctx, e.error(), w)
+		encodeError(ctx, f.Failed(), w)
 		return nil
 	}
 	return json.NewEncoder(w).Encode(response)
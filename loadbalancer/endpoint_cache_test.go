@@ -5,16 +5,15 @@ import (
 	"testing"
 	"time"
 
-	"golang.org/x/net/context"
-
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
 	"github.com/go-kit/kit/loadbalancer"
 	"github.com/go-kit/kit/log"
 )
 
 func TestEndpointCache(t *testing.T) {
 	var (
-		e  = func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil }
+		e  = endpointtest.Nop
 		ca = make(closer)
 		cb = make(closer)
 		c  = map[string]io.Closer{"a": ca, "b": cb}
@@ -72,7 +71,7 @@ func (c closer) Close() error { close(c); return nil }
 
 func BenchmarkEndpoints(b *testing.B) {
 	var (
-		e  = func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil }
+		e  = endpointtest.Nop
 		ca = make(closer)
 		cb = make(closer)
 		c  = map[string]io.Closer{"a": ca, "b": cb}
@@ -89,4 +88,4 @@ func BenchmarkEndpoints(b *testing.B) {
 			ec.Endpoints()
 		}
 	})
-}
\ No newline at end of file
+}
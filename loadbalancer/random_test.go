@@ -7,6 +7,7 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
 	"github.com/go-kit/kit/loadbalancer"
 	"github.com/go-kit/kit/loadbalancer/fixed"
 )
@@ -15,7 +16,7 @@ func TestRandomDistribution(t *testing.T) {
 	var (
 		n          = 3
 		endpoints  = make([]endpoint.Endpoint, n)
-		counts     = make([]int, n)
+		recorders  = make([]*endpointtest.Recorder, n)
 		seed       = int64(123)
 		ctx        = context.Background()
 		iterations = 100000
@@ -24,8 +25,8 @@ func TestRandomDistribution(t *testing.T) {
 	)
 
 	for i := 0; i < n; i++ {
-		i0 := i
-		endpoints[i] = func(context.Context, interface{}) (interface{}, error) { counts[i0]++; return struct{}{}, nil }
+		recorders[i] = endpointtest.NewRecorder(nil)
+		endpoints[i] = recorders[i].Endpoint
 	}
 
 	lb := loadbalancer.NewRandom(fixed.NewPublisher(endpoints), seed)
@@ -40,8 +41,8 @@ func TestRandomDistribution(t *testing.T) {
 		}
 	}
 
-	for i, have := range counts {
-		if math.Abs(float64(want-have)) > float64(tolerance) {
+	for i, r := range recorders {
+		if have := r.NumCalls(); math.Abs(float64(want-have)) > float64(tolerance) {
 			t.Errorf("%d: want %d, have %d", i, want, have)
 		}
 	}
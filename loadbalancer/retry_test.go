@@ -8,6 +8,7 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
 	"github.com/go-kit/kit/loadbalancer"
 	"github.com/go-kit/kit/loadbalancer/fixed"
 )
@@ -28,9 +29,9 @@ func TestRetryMaxTotalFail(t *testing.T) {
 func TestRetryMaxPartialFail(t *testing.T) {
 	var (
 		endpoints = []endpoint.Endpoint{
-			func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("error one") },
-			func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("error two") },
-			func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil /* OK */ },
+			endpointtest.Scripted(endpointtest.Step{Err: errors.New("error one")}),
+			endpointtest.Scripted(endpointtest.Step{Err: errors.New("error two")}),
+			endpointtest.Nop, // OK
 		}
 		retries = len(endpoints) - 1 // not quite enough retries
 		p       = fixed.NewPublisher(endpoints)
@@ -45,9 +46,9 @@ func TestRetryMaxPartialFail(t *testing.T) {
 func TestRetryMaxSuccess(t *testing.T) {
 	var (
 		endpoints = []endpoint.Endpoint{
-			func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("error one") },
-			func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("error two") },
-			func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil /* OK */ },
+			endpointtest.Scripted(endpointtest.Step{Err: errors.New("error one")}),
+			endpointtest.Scripted(endpointtest.Step{Err: errors.New("error two")}),
+			endpointtest.Nop, // OK
 		}
 		retries = len(endpoints) // exactly enough retries
 		p       = fixed.NewPublisher(endpoints)
@@ -61,23 +62,16 @@ func TestRetryMaxSuccess(t *testing.T) {
 
 func TestRetryTimeout(t *testing.T) {
 	var (
-		step    = make(chan struct{})
-		e       = func(context.Context, interface{}) (interface{}, error) { <-step; return struct{}{}, nil }
 		timeout = time.Millisecond
+		e       = endpointtest.Scripted(endpointtest.Step{}, endpointtest.Step{Delay: 10 * timeout})
 		retry   = loadbalancer.Retry(999, timeout, loadbalancer.NewRoundRobin(fixed.NewPublisher([]endpoint.Endpoint{e})))
-		errs    = make(chan error, 1)
-		invoke  = func() { _, err := retry(context.Background(), struct{}{}); errs <- err }
 	)
 
-	go func() { step <- struct{}{} }() // queue up a flush of the endpoint
-	invoke()                           // invoke the endpoint and trigger the flush
-	if err := <-errs; err != nil {     // that should succeed
+	if _, err := retry(context.Background(), struct{}{}); err != nil { // a prompt call should succeed
 		t.Error(err)
 	}
 
-	go func() { time.Sleep(10 * timeout); step <- struct{}{} }() // a delayed flush
-	invoke()                                                     // invoke the endpoint
-	if err := <-errs; err != context.DeadlineExceeded {          // that should not succeed
-		t.Errorf("wanted %v, got none", context.DeadlineExceeded)
+	if _, err := retry(context.Background(), struct{}{}); err != context.DeadlineExceeded { // a delayed one should not
+		t.Errorf("wanted %v, got %v", context.DeadlineExceeded, err)
 	}
 }
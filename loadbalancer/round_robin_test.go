@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
 	"github.com/go-kit/kit/loadbalancer"
 	"github.com/go-kit/kit/loadbalancer/fixed"
 	"golang.org/x/net/context"
@@ -13,13 +14,13 @@ import (
 func TestRoundRobinDistribution(t *testing.T) {
 	var (
 		ctx       = context.Background()
-		counts    = []int{0, 0, 0}
-		endpoints = []endpoint.Endpoint{
-			func(context.Context, interface{}) (interface{}, error) { counts[0]++; return struct{}{}, nil },
-			func(context.Context, interface{}) (interface{}, error) { counts[1]++; return struct{}{}, nil },
-			func(context.Context, interface{}) (interface{}, error) { counts[2]++; return struct{}{}, nil },
-		}
+		recorders = make([]*endpointtest.Recorder, 3)
+		endpoints = make([]endpoint.Endpoint, len(recorders))
 	)
+	for i := range recorders {
+		recorders[i] = endpointtest.NewRecorder(nil)
+		endpoints[i] = recorders[i].Endpoint
+	}
 
 	lb := loadbalancer.NewRoundRobin(fixed.NewPublisher(endpoints))
 
@@ -39,7 +40,11 @@ func TestRoundRobinDistribution(t *testing.T) {
 		if _, err := e(ctx, struct{}{}); err != nil {
 			t.Error(err)
 		}
-		if have := counts; !reflect.DeepEqual(want, have) {
+		have := make([]int, len(recorders))
+		for i, r := range recorders {
+			have[i] = r.NumCalls()
+		}
+		if !reflect.DeepEqual(want, have) {
 			t.Fatalf("%d: want %v, have %v", i, want, have)
 		}
 
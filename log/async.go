@@ -0,0 +1,198 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy is what an AsyncLogger does with a record logged while its
+// queue is full.
+type OverflowPolicy int
+
+const (
+	// Block makes Log wait until the queue has room. No record is dropped,
+	// but a slow destination slows down logging callers again.
+	Block OverflowPolicy = iota
+
+	// DropNewest drops the record being logged.
+	DropNewest
+
+	// DropOldest drops the oldest queued record to make room for the record
+	// being logged.
+	DropOldest
+)
+
+var (
+	// ErrLoggerStopped is returned by the Log method of a stopped
+	// AsyncLogger.
+	ErrLoggerStopped = errors.New("logger stopped")
+
+	// ErrFlushTimeout is returned by the Flush and Stop methods of an
+	// AsyncLogger if the queued records couldn't be logged in time.
+	ErrFlushTimeout = errors.New("flush timed out")
+)
+
+// AsyncOption sets a parameter for the AsyncLogger.
+type AsyncOption func(*AsyncLogger)
+
+// AsyncOverflowPolicy sets what to do with records logged while the queue is
+// full. By default, it's Block.
+func AsyncOverflowPolicy(policy OverflowPolicy) AsyncOption {
+	return func(l *AsyncLogger) { l.policy = policy }
+}
+
+// AsyncDropped sets a function called for each dropped record, e.g. to
+// increment a metrics counter, so data loss is visible. It's called from the
+// goroutine logging the record which caused the drop.
+func AsyncDropped(dropped func()) AsyncOption {
+	return func(l *AsyncLogger) { l.dropped = dropped }
+}
+
+// AsyncLogger is a Logger that queues records, to be logged by a background
+// goroutine with the wrapped logger, so a slow destination, like a remote
+// syslog, doesn't add latency to callers. Valuers are bound when the record
+// is queued, so timestamps reflect the time of the event. Call Stop to log
+// the queued records on shutdown.
+type AsyncLogger struct {
+	logger  Logger
+	queue   chan []interface{}
+	policy  OverflowPolicy
+	dropped func()
+
+	stopMtx sync.RWMutex // held for reading while sending to queue
+	stopped bool
+	done    chan struct{} // closed once the queue is drained after Stop
+
+	mtx       sync.Mutex
+	cond      *sync.Cond // signals progress of processed
+	queued    uint64     // records queued
+	processed uint64     // records logged or dropped from the queue
+}
+
+// NewAsyncLogger returns an AsyncLogger logging to logger, queueing up to
+// size records. A size below 1 is taken as 1.
+func NewAsyncLogger(logger Logger, size int, options ...AsyncOption) *AsyncLogger {
+	if size < 1 {
+		size = 1
+	}
+	l := &AsyncLogger{
+		logger:  logger,
+		queue:   make(chan []interface{}, size),
+		policy:  Block,
+		dropped: func() {},
+		done:    make(chan struct{}),
+	}
+	l.cond = sync.NewCond(&l.mtx)
+	for _, option := range options {
+		option(l)
+	}
+	go l.loop()
+	return l
+}
+
+// Log implements Logger. It returns ErrLoggerStopped once Stop was called.
+// Errors of the wrapped logger aren't returned, as the record is logged
+// afterwards.
+func (l *AsyncLogger) Log(keyvals ...interface{}) error {
+	kvs := append(make([]interface{}, 0, len(keyvals)), keyvals...)
+	bindValues(kvs)
+
+	l.stopMtx.RLock()
+	defer l.stopMtx.RUnlock()
+	if l.stopped {
+		return ErrLoggerStopped
+	}
+	switch l.policy {
+	case DropNewest:
+		select {
+		case l.queue <- kvs:
+		default:
+			l.dropped()
+			return nil
+		}
+	case DropOldest:
+		for sent := false; !sent; {
+			select {
+			case l.queue <- kvs:
+				sent = true
+			default:
+				select {
+				case <-l.queue:
+					l.progress()
+					l.dropped()
+				default:
+				}
+			}
+		}
+	default:
+		l.queue <- kvs
+	}
+	l.mtx.Lock()
+	l.queued++
+	l.mtx.Unlock()
+	return nil
+}
+
+func (l *AsyncLogger) loop() {
+	defer close(l.done)
+	for keyvals := range l.queue {
+		l.logger.Log(keyvals...)
+		l.progress()
+	}
+}
+
+func (l *AsyncLogger) progress() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.processed++
+	l.cond.Broadcast()
+}
+
+// Flush waits until the records queued before the call are logged, or
+// dropped. It returns ErrFlushTimeout if that takes longer than timeout.
+func (l *AsyncLogger) Flush(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+		l.cond.Broadcast()
+	})
+	defer timer.Stop()
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for target := l.queued; l.processed < target; {
+		if !time.Now().Before(deadline) {
+			return ErrFlushTimeout
+		}
+		l.cond.Wait()
+	}
+	return nil
+}
+
+// Stop stops accepting records, and waits until the queued ones are logged.
+// It returns ErrFlushTimeout if that takes longer than timeout; the
+// remaining records are still logged afterwards. Calling Stop more than once
+// is safe.
+func (l *AsyncLogger) Stop(timeout time.Duration) error {
+	go func() {
+		// Logging callers blocked on a full queue hold stopMtx until the
+		// background goroutine makes room.
+		l.stopMtx.Lock()
+		defer l.stopMtx.Unlock()
+		if !l.stopped {
+			l.stopped = true
+			close(l.queue)
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-l.done:
+		return nil
+	case <-timer.C:
+		return ErrFlushTimeout
+	}
+}
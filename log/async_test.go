@@ -0,0 +1,170 @@
+package log_test
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// gatedLogger records the records it logs, once the gate is open. It
+// signals each record it's about to wait for the gate with.
+type gatedLogger struct {
+	entered chan struct{}
+	gate    chan struct{}
+
+	mtx     sync.Mutex
+	records []string
+}
+
+func newGatedLogger() *gatedLogger {
+	return &gatedLogger{
+		entered: make(chan struct{}, 100),
+		gate:    make(chan struct{}),
+	}
+}
+
+func (l *gatedLogger) Log(keyvals ...interface{}) error {
+	l.entered <- struct{}{}
+	<-l.gate
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.records = append(l.records, fmt.Sprintf("%v", keyvals))
+	return nil
+}
+
+func (l *gatedLogger) Records() []string {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return append([]string(nil), l.records...)
+}
+
+func TestAsyncLoggerOverflowPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy  log.OverflowPolicy
+		want    []string
+		dropped int
+	}{
+		{log.Block, []string{"[n 1]", "[n 2]", "[n 3]"}, 0},
+		{log.DropNewest, []string{"[n 1]", "[n 2]"}, 1},
+		{log.DropOldest, []string{"[n 1]", "[n 3]"}, 1},
+	} {
+		var (
+			next    = newGatedLogger()
+			dropped int
+			logger  = log.NewAsyncLogger(next, 1,
+				log.AsyncOverflowPolicy(tc.policy),
+				log.AsyncDropped(func() { dropped++ }),
+			)
+		)
+
+		// The first record is being logged, the second one fills the queue.
+		logger.Log("n", 1)
+		<-next.entered
+		logger.Log("n", 2)
+
+		third := make(chan error)
+		go func() { third <- logger.Log("n", 3) }()
+		select {
+		case err := <-third:
+			if tc.policy == log.Block {
+				t.Errorf("%d: want Log to block on a full queue, have %v", tc.policy, err)
+			}
+		case <-time.After(50 * time.Millisecond):
+			if tc.policy != log.Block {
+				t.Fatalf("%d: want Log not to block on a full queue", tc.policy)
+			}
+		}
+
+		close(next.gate)
+		if tc.policy == log.Block {
+			if err := <-third; err != nil {
+				t.Errorf("%d: %v", tc.policy, err)
+			}
+		}
+		if err := logger.Flush(time.Second); err != nil {
+			t.Fatalf("%d: %v", tc.policy, err)
+		}
+		if have := next.Records(); !reflect.DeepEqual(tc.want, have) {
+			t.Errorf("%d: want %v, have %v", tc.policy, tc.want, have)
+		}
+		if want, have := tc.dropped, dropped; want != have {
+			t.Errorf("%d: want %d dropped, have %d", tc.policy, want, have)
+		}
+		logger.Stop(time.Second)
+	}
+}
+
+func TestAsyncLoggerStop(t *testing.T) {
+	next := newGatedLogger()
+	close(next.gate)
+	logger := log.NewAsyncLogger(next, 100)
+
+	var want []string
+	for i := 0; i < 100; i++ {
+		logger.Log("n", i)
+		want = append(want, fmt.Sprintf("[n %d]", i))
+	}
+	if err := logger.Stop(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if have := next.Records(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want all queued records logged on Stop, have %d of them", len(have))
+	}
+	if want, have := log.ErrLoggerStopped, logger.Log("n", 100); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if err := logger.Stop(time.Second); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+}
+
+func TestAsyncLoggerTimeouts(t *testing.T) {
+	next := newGatedLogger()
+	logger := log.NewAsyncLogger(next, 1)
+	logger.Log("n", 1)
+
+	if want, have := log.ErrFlushTimeout, logger.Flush(10*time.Millisecond); want != have {
+		t.Errorf("Flush: want %v, have %v", want, have)
+	}
+	if want, have := log.ErrFlushTimeout, logger.Stop(10*time.Millisecond); want != have {
+		t.Errorf("Stop: want %v, have %v", want, have)
+	}
+
+	close(next.gate)
+	if err := logger.Stop(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []string{"[n 1]"}, next.Records(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestAsyncLoggerBindsValuersWhenQueued(t *testing.T) {
+	next := newGatedLogger()
+	logger := log.NewAsyncLogger(next, 10)
+
+	var mtx sync.Mutex
+	stamp := "queued"
+	valuer := log.Valuer(func() interface{} {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return stamp
+	})
+	log.With(logger, "ts", valuer).Log("n", 1)
+	logger.Log("ts", valuer, "n", 2)
+
+	mtx.Lock()
+	stamp = "logged"
+	mtx.Unlock()
+	close(next.gate)
+	if err := logger.Stop(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []string{"[ts queued n 1]", "[ts queued n 2]"}, next.Records(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,175 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/go-logfmt/logfmt"
+)
+
+type auditLogger struct {
+	tee        teeLogger
+	logger     Logger
+	key, value interface{}
+}
+
+// NewAuditLogger returns a logger passing each record to logger, and the
+// records carrying the pair key=value, e.g. "audit", true, to audit as well,
+// like a tee. The audit logger is typically a hash chain logger writing to a
+// separate, append-only stream. Valuers are bound once per record, before the
+// pair is looked up, so both loggers log the same values. The errors of both
+// loggers are returned in a MultiError.
+func NewAuditLogger(logger, audit Logger, key, value interface{}) Logger {
+	return &auditLogger{
+		tee:    teeLogger{logger, audit},
+		logger: logger,
+		key:    key,
+		value:  value,
+	}
+}
+
+// Log implements Logger.
+func (l *auditLogger) Log(keyvals ...interface{}) error {
+	if containsValuer(keyvals) {
+		keyvals = append([]interface{}{}, keyvals...)
+		bindValues(keyvals)
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keysEqual(keyvals[i], l.key) && keysEqual(keyvals[i+1], l.value) {
+			return l.tee.Log(keyvals...)
+		}
+	}
+	return l.logger.Log(keyvals...)
+}
+
+// The keys of the pairs a hash chain logger appends to each record.
+const (
+	prevHashKey = "prev_hash"
+	hashKey     = "hash"
+)
+
+type hashChainLogger struct {
+	w io.Writer
+
+	mtx      sync.Mutex
+	prevHash string
+}
+
+// NewHashChainLogger returns a logger that encodes keyvals to the Writer in
+// logfmt format, like NewLogfmtLogger, appending to each record the hash of
+// the previous record, as prev_hash, and its own hash, as hash. A record's
+// hash is the hex encoded SHA-256 of its logfmt encoding, without the
+// appended pairs, followed by the hash of the previous record. The hashes
+// chain the records, so VerifyHashChain detects records modified, removed or
+// inserted anywhere but at the end of the stream.
+//
+// prevHash is the hash of the last record of the stream w appends to, as
+// returned by VerifyHashChain, or empty for a new stream. The logger is safe
+// for concurrent use, and makes a single Write per record.
+func NewHashChainLogger(w io.Writer, prevHash string) Logger {
+	return &hashChainLogger{w: w, prevHash: prevHash}
+}
+
+// Log implements Logger.
+func (l *hashChainLogger) Log(keyvals ...interface{}) error {
+	var buf bytes.Buffer
+	enc := logfmt.NewEncoder(&buf)
+	if err := enc.EncodeKeyvals(safeValues(keyvals)...); err != nil {
+		return err
+	}
+	record := buf.Len()
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	hash := chainHash(buf.Bytes()[:record], l.prevHash)
+	if err := enc.EncodeKeyvals(prevHashKey, l.prevHash, hashKey, hash); err != nil {
+		return err
+	}
+	if err := enc.EndRecord(); err != nil {
+		return err
+	}
+	if _, err := l.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	l.prevHash = hash
+	return nil
+}
+
+// chainHash returns the hash of a record chained to the previous hash.
+func chainHash(record []byte, prevHash string) string {
+	h := sha256.New()
+	h.Write(record)
+	io.WriteString(h, prevHash)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashChainError is returned by VerifyHashChain for the first invalid line of
+// a stream.
+type HashChainError struct {
+	Line   int // 1-based
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *HashChainError) Error() string {
+	return fmt.Sprintf("hash chain broken at line %d: %s", e.Line, e.Reason)
+}
+
+// VerifyHashChain reads a stream written by a hash chain logger, see
+// NewHashChainLogger, and checks that the hash of each record is valid, and
+// chained to the previous one, the first one being chained to an empty hash.
+// It returns the hash of the last record, to continue the chain, or a
+// *HashChainError identifying the first line which doesn't verify.
+func VerifyHashChain(r io.Reader) (lastHash string, err error) {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return lastHash, nil
+		}
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if !strings.HasSuffix(line, "\n") {
+			return "", &HashChainError{n, "truncated line"}
+		}
+		record, prevHash, hash, ok := splitHashChainLine(line[:len(line)-1])
+		if !ok {
+			return "", &HashChainError{n, "missing hashes"}
+		}
+		if prevHash != lastHash {
+			return "", &HashChainError{n, fmt.Sprintf("previous hash %q doesn't match %q", prevHash, lastHash)}
+		}
+		if hash != chainHash([]byte(record), prevHash) {
+			return "", &HashChainError{n, "hash doesn't match record"}
+		}
+		lastHash = hash
+	}
+}
+
+// splitHashChainLine splits a line written by a hash chain logger into the
+// logfmt encoded record, and the hashes appended to it.
+func splitHashChainLine(line string) (record, prevHash, hash string, ok bool) {
+	// The appended pairs come last, and hex encoded hashes can't contain
+	// their keys, so the last occurrence of the first key starts them.
+	i := strings.LastIndex(line, prevHashKey+"=")
+	if i < 0 || (i > 0 && line[i-1] != ' ') {
+		return "", "", "", false
+	}
+	pairs := strings.Split(line[i:], " ")
+	if len(pairs) != 2 ||
+		!strings.HasPrefix(pairs[0], prevHashKey+"=") ||
+		!strings.HasPrefix(pairs[1], hashKey+"=") {
+		return "", "", "", false
+	}
+	record = strings.TrimSuffix(line[:i], " ")
+	prevHash = strings.TrimPrefix(pairs[0], prevHashKey+"=")
+	hash = strings.TrimPrefix(pairs[1], hashKey+"=")
+	return record, prevHash, hash, true
+}
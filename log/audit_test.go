@@ -0,0 +1,134 @@
+package log_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestAuditLogger(t *testing.T) {
+	var (
+		main, audit = log.NewRingBuffer(10), log.NewRingBuffer(10)
+		calls       int
+		counter     = log.Valuer(func() interface{} { calls++; return calls })
+	)
+	logger := log.NewAuditLogger(main, audit, "audit", true)
+
+	for _, keyvals := range [][]interface{}{
+		{"msg", "request", "n", counter},
+		{"msg", "access denied", "audit", true, "n", counter},
+		{"msg", "not audited", "audit", false},
+	} {
+		if err := logger.Log(keyvals...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := [][]interface{}{
+		{"msg", "request", "n", 1},
+		{"msg", "access denied", "audit", true, "n", 2},
+		{"msg", "not audited", "audit", false},
+	}
+	if have := main.Snapshot(); !reflect.DeepEqual(want, have) {
+		t.Errorf("main: want %v, have %v", want, have)
+	}
+	if want, have := want[1:2], audit.Snapshot(); !reflect.DeepEqual(want, have) {
+		t.Errorf("audit: want %v, have %v", want, have)
+	}
+}
+
+func TestHashChainLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewHashChainLogger(&buf, "")
+	for _, keyvals := range [][]interface{}{
+		{"msg", "login", "user", "alice"},
+		{},
+		{"msg", `a value ending with prev_hash=`, "user", "bob"},
+	} {
+		if err := logger.Log(keyvals...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+	if want, have := 4, len(lines); want != have { // the last one is empty
+		t.Fatalf("want %d lines, have %d: %q", want, have, buf.String())
+	}
+	if want, have := "msg=login user=alice prev_hash= hash=", lines[0]; !strings.HasPrefix(have, want) {
+		t.Errorf("want prefix %q, have %q", want, have)
+	}
+
+	last, err := log.VerifyHashChain(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "hash="+last+"\n", lines[2]; !strings.HasSuffix(have, want) {
+		t.Errorf("want suffix %q, have %q", want, have)
+	}
+
+	// The chain continues across loggers.
+	if err := log.NewHashChainLogger(&buf, last).Log("msg", "logout"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := log.VerifyHashChain(strings.NewReader(buf.String())); err != nil {
+		t.Errorf("continued chain: %v", err)
+	}
+}
+
+func TestVerifyHashChainCorruption(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewHashChainLogger(&buf, "")
+	for _, user := range []string{"alice", "bob", "carol", "dave"} {
+		if err := logger.Log("msg", "export", "user", user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	for name, tc := range map[string]struct {
+		lines  []string
+		want   int
+		reason string
+	}{
+		"modified": {
+			lines:  []string{lines[0], lines[1], strings.Replace(lines[2], "carol", "mallory", 1), lines[3]},
+			want:   3,
+			reason: "hash doesn't match",
+		},
+		"removed": {
+			lines:  []string{lines[0], lines[2], lines[3]},
+			want:   2,
+			reason: "previous hash",
+		},
+		"head truncated": {
+			lines:  lines[1:],
+			want:   1,
+			reason: "previous hash",
+		},
+		"hashes stripped": {
+			lines:  []string{lines[0], "msg=export user=eve\n"},
+			want:   2,
+			reason: "missing hashes",
+		},
+		"partial line": {
+			lines:  []string{lines[0], lines[1][:20]},
+			want:   2,
+			reason: "truncated line",
+		},
+	} {
+		_, err := log.VerifyHashChain(strings.NewReader(strings.Join(tc.lines, "")))
+		chainErr, ok := err.(*log.HashChainError)
+		if !ok {
+			t.Errorf("%s: want *log.HashChainError, have %v", name, err)
+			continue
+		}
+		if want, have := tc.want, chainErr.Line; want != have {
+			t.Errorf("%s: want line %d, have %d (%v)", name, want, have, err)
+		}
+		if !strings.Contains(chainErr.Reason, tc.reason) {
+			t.Errorf("%s: want reason containing %q, have %q", name, tc.reason, chainErr.Reason)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package log_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 )
@@ -16,6 +18,10 @@ func benchmarkRunner(b *testing.B, logger log.Logger, f func(log.Logger)) {
 }
 
 var (
-	baseMessage = func(logger log.Logger) { logger.Log("foo_key", "foo_value") }
-	withMessage = func(logger log.Logger) { log.NewContext(logger).With("a", "b").Log("c", "d") }
+	baseMessage  = func(logger log.Logger) { logger.Log("foo_key", "foo_value") }
+	withMessage  = func(logger log.Logger) { log.NewContext(logger).With("a", "b").Log("c", "d") }
+	typedMessage = func(logger log.Logger) {
+		logger.Log("int", 42, "float", 3.14, "bool", true, "err", errBenchmark, "duration", time.Second)
+	}
+	errBenchmark = errors.New("benchmark error")
 )
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"sort"
+
+	"golang.org/x/net/context"
+)
+
+type loggerContextKey int
+
+const loggerKey loggerContextKey = 0
+
+var defaultContextLogger SwapLogger
+
+func init() {
+	defaultContextLogger.Swap(NewNopLogger())
+}
+
+// SetContextDefault sets the logger FromContext returns for contexts without
+// a logger. By default, it's a nop logger.
+func SetContextDefault(logger Logger) {
+	defaultContextLogger.Swap(logger)
+}
+
+// ToContext returns a copy of ctx carrying logger, for code deep in a call
+// stack to get it back with FromContext, rather than having it passed
+// explicitly.
+func ToContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the logger set by
+// SetContextDefault if ctx carries none; it never returns nil. A logger made
+// by WithContextValues is returned bound to ctx, so it logs the values of
+// ctx.
+func FromContext(ctx context.Context) Logger {
+	logger, ok := ctx.Value(loggerKey).(Logger)
+	if !ok {
+		return &defaultContextLogger
+	}
+	if l, ok := logger.(*contextValuesLogger); ok {
+		return l.bind(ctx)
+	}
+	return logger
+}
+
+type contextValuesLogger struct {
+	logger  Logger
+	keys    []string
+	ctxKeys map[string]interface{}
+	ctx     context.Context // nil unless bound by FromContext
+}
+
+// WithContextValues returns a logger appending to each event the values,
+// under the keys of ctxKeys, that the context carries under the
+// corresponding context keys, e.g. a request ID or a trace ID. The context is
+// the one the logger is returned from by FromContext; put the logger in
+// contexts with ToContext. Values are looked up when an event is logged, in
+// the order of their keys, and missing ones are skipped. Used directly, or
+// stored in the context wrapped by another logger, the logger appends
+// nothing; wrap the logger FromContext returns instead.
+//
+// The logger stored in a context keeps no reference to it: only the
+// loggers returned by FromContext do, so request-scoped values don't outlive
+// them.
+func WithContextValues(logger Logger, ctxKeys map[string]interface{}) Logger {
+	l := &contextValuesLogger{
+		logger:  logger,
+		ctxKeys: make(map[string]interface{}, len(ctxKeys)),
+	}
+	for k, ctxKey := range ctxKeys {
+		l.keys = append(l.keys, k)
+		l.ctxKeys[k] = ctxKey
+	}
+	sort.Strings(l.keys)
+	return l
+}
+
+func (l *contextValuesLogger) bind(ctx context.Context) *contextValuesLogger {
+	return &contextValuesLogger{
+		logger:  l.logger,
+		keys:    l.keys,
+		ctxKeys: l.ctxKeys,
+		ctx:     ctx,
+	}
+}
+
+// Log implements Logger.
+func (l *contextValuesLogger) Log(keyvals ...interface{}) error {
+	if l.ctx == nil {
+		return l.logger.Log(keyvals...)
+	}
+	kvs := append(make([]interface{}, 0, len(keyvals)+1+2*len(l.keys)), keyvals...)
+	if len(kvs)%2 != 0 {
+		kvs = append(kvs, ErrMissingValue)
+	}
+	for _, k := range l.keys {
+		if v := l.ctx.Value(l.ctxKeys[k]); v != nil {
+			kvs = append(kvs, k, v)
+		}
+	}
+	return l.logger.Log(kvs...)
+}
@@ -0,0 +1,132 @@
+package log_test
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/logtest"
+)
+
+type testContextKey int
+
+const (
+	requestIDKey testContextKey = iota
+	userIDKey
+)
+
+func TestFromContextDefault(t *testing.T) {
+	defer log.SetContextDefault(log.NewNopLogger())
+
+	logger := log.FromContext(context.Background())
+	if logger == nil {
+		t.Fatal("want default logger, have nil")
+	}
+	if err := logger.Log("msg", "discarded"); err != nil {
+		t.Error(err)
+	}
+
+	recorder := logtest.NewRecorder()
+	log.SetContextDefault(recorder)
+	log.FromContext(context.Background()).Log("msg", "default")
+	if !recorder.Contains("msg", "default") {
+		t.Errorf("want record logged to the default logger, have %v", recorder.Records())
+	}
+
+	other := logtest.NewRecorder()
+	log.FromContext(log.ToContext(context.Background(), other)).Log("msg", "carried")
+	if !other.Contains("msg", "carried") || recorder.Contains("msg", "carried") {
+		t.Error("want record logged to the logger in the context only")
+	}
+}
+
+func TestWithContextValues(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	logger := log.WithContextValues(recorder, map[string]interface{}{
+		"request_id": requestIDKey,
+		"user_id":    userIDKey,
+	})
+
+	ctx := log.ToContext(context.Background(), logger)
+	ctx = context.WithValue(ctx, requestIDKey, "abc")
+	log.FromContext(ctx).Log("msg", "no user")
+	ctx = context.WithValue(ctx, userIDKey, 42)
+	log.FromContext(ctx).Log("msg", "odd", "k")
+	logger.Log("msg", "unbound")
+
+	want := [][]interface{}{
+		{"msg", "no user", "request_id", "abc"},
+		{"msg", "odd", "k", log.ErrMissingValue, "request_id", "abc", "user_id", 42},
+		{"msg", "unbound"},
+	}
+	if have := recorder.Records(); !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %v\nhave %v", want, have)
+	}
+}
+
+// countingContext counts the lookups of its values.
+type countingContext struct {
+	context.Context
+	lookups int
+}
+
+func (c *countingContext) Value(key interface{}) interface{} {
+	if key == requestIDKey {
+		c.lookups++
+		return "abc"
+	}
+	return c.Context.Value(key)
+}
+
+func TestWithContextValuesLazy(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	ctx := &countingContext{Context: log.ToContext(context.Background(), log.WithContextValues(recorder, map[string]interface{}{
+		"request_id": requestIDKey,
+	}))}
+
+	logger := log.FromContext(ctx)
+	if want, have := 0, ctx.lookups; want != have {
+		t.Errorf("before Log: want %d lookups, have %d", want, have)
+	}
+	logger.Log("msg", "hello")
+	logger.Log("msg", "again")
+	if want, have := 2, ctx.lookups; want != have {
+		t.Errorf("after Log: want %d lookups, have %d", want, have)
+	}
+	if !recorder.Contains("msg", "again", "request_id", "abc") {
+		t.Errorf("want request ID logged, have %v", recorder.Records())
+	}
+}
+
+func TestWithContextValuesDoesNotRetainContext(t *testing.T) {
+	type requestData struct{ id string }
+
+	logger := log.WithContextValues(log.NewNopLogger(), map[string]interface{}{
+		"request_id": requestIDKey,
+	})
+
+	collected := make(chan struct{})
+	func() {
+		data := &requestData{"abc"}
+		runtime.SetFinalizer(data, func(*requestData) { close(collected) })
+		ctx := context.WithValue(log.ToContext(context.Background(), logger), requestIDKey, data)
+		log.FromContext(ctx).Log("msg", "request")
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for done := false; !done; {
+		runtime.GC()
+		select {
+		case <-collected:
+			done = true
+		case <-deadline:
+			t.Fatal("request-scoped value retained after the request")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	logger.Log("msg", "still in use") // keeps the long-lived logger alive
+}
@@ -0,0 +1,126 @@
+package log
+
+import "reflect"
+
+// dedupMapThreshold is the number of pairs from which duplicate keys are
+// looked up in a map, rather than by comparing the keys with each other,
+// which is faster for small records, and doesn't allocate.
+const dedupMapThreshold = 16
+
+type dedupLogger struct {
+	logger Logger
+}
+
+// NewDedupLogger returns a logger removing repeated keys from each record
+// before passing it to logger, e.g. a "component" key bound by several
+// middlewares. The last value of a key wins, and the surviving pairs keep
+// their order. Valuers are bound before, so a later value overrides an
+// earlier Valuer, without calling it. Keys are compared with ==; keys of
+// types which aren't comparable are never duplicates. Records without
+// duplicate keys are passed on unmodified.
+func NewDedupLogger(logger Logger) Logger {
+	return dedupLogger{logger}
+}
+
+// Log implements Logger.
+func (l dedupLogger) Log(keyvals ...interface{}) error {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals[:len(keyvals):len(keyvals)], ErrMissingValue)
+	}
+	if !hasDuplicateKeys(keyvals) {
+		if containsValuer(keyvals) {
+			keyvals = append([]interface{}{}, keyvals...)
+			bindValues(keyvals)
+		}
+		return l.logger.Log(keyvals...)
+	}
+	return l.logger.Log(dedupKeys(keyvals)...)
+}
+
+// hasDuplicateKeys returns true if a key of the even length keyvals is
+// repeated.
+func hasDuplicateKeys(keyvals []interface{}) bool {
+	if len(keyvals)/2 < dedupMapThreshold {
+		for i := 2; i < len(keyvals); i += 2 {
+			if containsKey(keyvals[:i], keyvals[i]) {
+				return true
+			}
+		}
+		return false
+	}
+	seen := make(map[interface{}]struct{}, len(keyvals)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		if !isComparable(k) {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			return true
+		}
+		seen[k] = struct{}{}
+	}
+	return false
+}
+
+// dedupKeys returns a copy of the even length keyvals with the last pair of
+// each key, in order, and Valuers bound. Pairs are kept from the end, so
+// only the values kept are bound.
+func dedupKeys(keyvals []interface{}) []interface{} {
+	var (
+		kvs  = make([]interface{}, len(keyvals))
+		j    = len(kvs)
+		seen map[interface{}]struct{}
+	)
+	if len(keyvals)/2 >= dedupMapThreshold {
+		seen = make(map[interface{}]struct{}, len(keyvals)/2)
+	}
+	for i := len(keyvals) - 2; i >= 0; i -= 2 {
+		k := keyvals[i]
+		if seen != nil && isComparable(k) {
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+		} else if seen == nil && containsKey(kvs[j:], k) {
+			continue
+		}
+		j -= 2
+		kvs[j], kvs[j+1] = k, keyvals[i+1]
+	}
+	kvs = kvs[j:]
+	bindValues(kvs)
+	return kvs
+}
+
+// containsKey returns true if k is a key of the even length keyvals.
+func containsKey(keyvals []interface{}, k interface{}) bool {
+	if s, ok := k.(string); ok { // fast path for the most common keys
+		for i := 0; i < len(keyvals); i += 2 {
+			if t, ok := keyvals[i].(string); ok && s == t {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < len(keyvals); i += 2 {
+		if keysEqual(keyvals[i], k) {
+			return true
+		}
+	}
+	return false
+}
+
+// keysEqual reports whether a and b are equal keys, without panicking on
+// keys which aren't comparable.
+func keysEqual(a, b interface{}) bool {
+	if as, ok := a.(string); ok { // fast path for the most common keys
+		bs, ok := b.(string)
+		return ok && as == bs
+	}
+	return isComparable(a) && a == b
+}
+
+func isComparable(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	return t == nil || t.Comparable()
+}
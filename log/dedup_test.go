@@ -0,0 +1,111 @@
+package log_test
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestDedupLogger(t *testing.T) {
+	var output []interface{}
+	logger := log.NewDedupLogger(log.LoggerFunc(func(keyvals ...interface{}) error {
+		output = keyvals
+		return nil
+	}))
+
+	calls := 0
+	valuer := log.Valuer(func() interface{} { calls++; return "bound" })
+
+	for _, tc := range []struct {
+		name string
+		in   []interface{}
+		want []interface{}
+	}{
+		{"no duplicates", []interface{}{"a", 1, "b", 2}, []interface{}{"a", 1, "b", 2}},
+		{"last value wins", []interface{}{"a", 1, "b", 2, "a", 3}, []interface{}{"b", 2, "a", 3}},
+		{"several duplicates", []interface{}{"c", 1, "a", 2, "c", 3, "b", 4, "a", 5, "c", 6}, []interface{}{"b", 4, "a", 5, "c", 6}},
+		{"odd", []interface{}{"a", 1, "a"}, []interface{}{"a", log.ErrMissingValue}},
+		{"uncomparable keys", []interface{}{[]int{1}, 1, []int{1}, 2}, []interface{}{[]int{1}, 1, []int{1}, 2}},
+		{"non-string keys", []interface{}{1, "a", "1", "b", 1, "c"}, []interface{}{"1", "b", 1, "c"}},
+		{"literal overrides valuer", []interface{}{"ts", valuer, "ts", "literal"}, []interface{}{"ts", "literal"}},
+		{"valuer overrides literal", []interface{}{"ts", "literal", "ts", valuer}, []interface{}{"ts", "bound"}},
+		{"valuer without duplicates", []interface{}{"ts", valuer}, []interface{}{"ts", "bound"}},
+	} {
+		if err := logger.Log(tc.in...); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := tc.want, output; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want %v, have %v", tc.name, want, have)
+		}
+	}
+	if want, have := 2, calls; want != have {
+		t.Errorf("want %d Valuer calls, have %d", want, have)
+	}
+}
+
+func TestDedupLoggerContext(t *testing.T) {
+	var output []interface{}
+	base := log.NewDedupLogger(log.LoggerFunc(func(keyvals ...interface{}) error {
+		output = keyvals
+		return nil
+	}))
+	logger := log.With(base, "component", "http", "request_id", 1)
+	logger = log.With(logger, "component", "auth")
+	logger.Log("msg", "hello", "request_id", 2)
+
+	want := []interface{}{"component", "auth", "msg", "hello", "request_id", 2}
+	if have := output; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestDedupLoggerManyKeys(t *testing.T) {
+	var output []interface{}
+	logger := log.NewDedupLogger(log.LoggerFunc(func(keyvals ...interface{}) error {
+		output = keyvals
+		return nil
+	}))
+
+	var in, want []interface{}
+	for i := 0; i < 40; i++ {
+		in = append(in, "k"+strconv.Itoa(i%20), i)
+	}
+	in = append(in, []int{}, "uncomparable")
+	for i := 20; i < 40; i++ {
+		want = append(want, "k"+strconv.Itoa(i%20), i)
+	}
+	want = append(want, []int{}, "uncomparable")
+
+	logger.Log(in...)
+	if have := output; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func BenchmarkDedupLogger(b *testing.B) {
+	for _, bc := range []struct {
+		name    string
+		keyvals []interface{}
+	}{
+		{"no duplicates", []interface{}{"component", "http", "request_id", 1, "method", "GET", "path", "/", "status", 200, "msg", "served"}},
+		{"duplicates", []interface{}{"component", "http", "request_id", 1, "component", "auth", "path", "/", "status", 200, "msg", "served"}},
+	} {
+		for _, logger := range []struct {
+			name   string
+			logger log.Logger
+		}{
+			{"logfmt", log.NewLogfmtLogger(ioutil.Discard)},
+			{"dedup", log.NewDedupLogger(log.NewLogfmtLogger(ioutil.Discard))},
+		} {
+			b.Run(bc.name+"/"+logger.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					logger.logger.Log(bc.keyvals...)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package log
+
+import "time"
+
+// NewSamplingLoggerWithClock is like NewSamplingLogger, with now returning
+// the current time.
+func NewSamplingLoggerWithClock(logger Logger, initial, thereafter int, tick time.Duration, keyFn func(keyvals ...interface{}) string, now func() time.Time) Logger {
+	return newSamplingLogger(logger, initial, thereafter, tick, keyFn, now)
+}
@@ -0,0 +1,161 @@
+package log
+
+import (
+	"strconv"
+	"time"
+)
+
+// Formatter formats log values, e.g. to render them human-readable.
+type Formatter interface {
+	// Format returns the value to log in place of v. It should return v
+	// unchanged if it doesn't know how to format it.
+	Format(v interface{}) interface{}
+}
+
+// FormatterFunc is an adapter to allow use of ordinary functions as
+// Formatters.
+type FormatterFunc func(v interface{}) interface{}
+
+// Format implements Formatter by calling f(v).
+func (f FormatterFunc) Format(v interface{}) interface{} { return f(v) }
+
+var (
+	// DurationFormatter renders durations, and integers counting
+	// nanoseconds, as strings with a unit and a decimal at most, e.g.
+	// "12.3ms", "1.5s" or "2m30s".
+	DurationFormatter Formatter = FormatterFunc(formatDuration)
+
+	// ByteSizeFormatter renders integers counting bytes as strings with an
+	// IEC unit and a decimal at most, e.g. "512B", "4.2MiB" or "1GiB".
+	ByteSizeFormatter Formatter = FormatterFunc(formatByteSize)
+)
+
+type formattingLogger struct {
+	logger     Logger
+	formatters map[string]Formatter
+}
+
+// NewFormattingLogger returns a logger formatting the values of the keys of
+// formatters with their Formatter, e.g. DurationFormatter for "took", before
+// passing each record to logger. Valuers are bound before values are
+// formatted. Values of other keys are left untouched, so encoders render
+// them as usual.
+func NewFormattingLogger(logger Logger, formatters map[string]Formatter) Logger {
+	l := &formattingLogger{
+		logger:     logger,
+		formatters: make(map[string]Formatter, len(formatters)),
+	}
+	for k, f := range formatters {
+		l.formatters[k] = f
+	}
+	return l
+}
+
+// Log implements Logger.
+func (l *formattingLogger) Log(keyvals ...interface{}) error {
+	kvs := append(make([]interface{}, 0, len(keyvals)+1), keyvals...)
+	if len(kvs)%2 != 0 {
+		kvs = append(kvs, ErrMissingValue)
+	}
+	bindValues(kvs)
+	for i := 0; i < len(kvs); i += 2 {
+		if k, ok := kvs[i].(string); ok {
+			if f, ok := l.formatters[k]; ok {
+				kvs[i+1] = f.Format(kvs[i+1])
+			}
+		}
+	}
+	return l.logger.Log(kvs...)
+}
+
+func formatDuration(v interface{}) interface{} {
+	var d time.Duration
+	switch x := v.(type) {
+	case time.Duration:
+		d = x
+	default:
+		n, ok := toInt64(v)
+		if !ok {
+			return v
+		}
+		d = time.Duration(n)
+	}
+	abs := d
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs < time.Microsecond:
+		return d.String()
+	case abs < time.Millisecond:
+		return formatUnit(float64(d)/float64(time.Microsecond), 1000, []string{"µs", "ms"})
+	case abs < time.Second:
+		return formatUnit(float64(d)/float64(time.Millisecond), 1000, []string{"ms", "s"})
+	case abs < time.Minute:
+		return formatUnit(float64(d)/float64(time.Second), 60, []string{"s", "m0s"})
+	}
+	return d.Round(time.Second).String()
+}
+
+var iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+func formatByteSize(v interface{}) interface{} {
+	n, ok := toInt64(v)
+	if !ok {
+		return v
+	}
+	f := float64(n)
+	i := 0
+	for (f >= 1024 || f <= -1024) && i < len(iecUnits)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	return formatUnit(f, 1024, iecUnits[i:])
+}
+
+// formatUnit formats f with a decimal at most, and units[0]. If f rounds to
+// max, it's formatted as 1 of units[1], if any.
+func formatUnit(f, max float64, units []string) string {
+	s := strconv.FormatFloat(f, 'f', 1, 64)
+	if r, _ := strconv.ParseFloat(s, 64); (r >= max || r <= -max) && len(units) > 1 {
+		sign := ""
+		if r < 0 {
+			sign = "-"
+		}
+		return sign + "1" + units[1]
+	}
+	if len(s) > 2 && s[len(s)-2:] == ".0" {
+		s = s[:len(s)-2]
+	}
+	return s + units[0]
+}
+
+// toInt64 returns the value of an integer of any type.
+func toInt64(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), true
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case uint:
+		return int64(x), true
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		return int64(x), true
+	}
+	return 0, false
+}
@@ -0,0 +1,82 @@
+package log_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestDurationFormatter(t *testing.T) {
+	for _, tc := range []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{time.Duration(0), "0s"},
+		{999 * time.Nanosecond, "999ns"},
+		{1500 * time.Nanosecond, "1.5µs"},
+		{12345678 * time.Nanosecond, "12.3ms"},
+		{999960 * time.Microsecond, "1s"},
+		{time.Second, "1s"},
+		{-1500 * time.Millisecond, "-1.5s"},
+		{59960 * time.Millisecond, "1m0s"},
+		{150 * time.Second, "2m30s"},
+		{int64(2500000), "2.5ms"},
+		{42, "42ns"},
+		{"12ms", "12ms"},
+		{1.5, 1.5},
+	} {
+		if want, have := tc.want, log.DurationFormatter.Format(tc.in); want != have {
+			t.Errorf("%T %v: want %v, have %v", tc.in, tc.in, want, have)
+		}
+	}
+}
+
+func TestByteSizeFormatter(t *testing.T) {
+	for _, tc := range []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1KiB"},
+		{1536, "1.5KiB"},
+		{int64(4404019), "4.2MiB"},
+		{uint64(1 << 30), "1GiB"},
+		{1048575, "1MiB"},
+		{int32(-2048), "-2KiB"},
+		{int64(1 << 62), "4EiB"},
+		{"4MiB", "4MiB"},
+		{2.5, 2.5},
+	} {
+		if want, have := tc.want, log.ByteSizeFormatter.Format(tc.in); want != have {
+			t.Errorf("%T %v: want %v, have %v", tc.in, tc.in, want, have)
+		}
+	}
+}
+
+func TestFormattingLogger(t *testing.T) {
+	var buf bytes.Buffer
+	tilde := log.FormatterFunc(func(v interface{}) interface{} {
+		if s, ok := v.(string); ok {
+			return "~" + s + "~"
+		}
+		return v
+	})
+	logger := log.NewFormattingLogger(log.NewJSONLogger(&buf), map[string]log.Formatter{
+		"took":   log.DurationFormatter,
+		"size":   log.ByteSizeFormatter,
+		"user":   tilde,
+		"absent": tilde,
+	})
+
+	took := log.Valuer(func() interface{} { return 12345678 * time.Nanosecond })
+	if err := logger.Log("took", took, "size", 4404019, "count", 4404019, "ratio", 0.5, "user", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"count":4404019,"ratio":0.5,"size":"4.2MiB","took":"12.3ms","user":"~alice~"}` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
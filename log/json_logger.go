@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
+	"strconv"
 )
 
 type jsonLogger struct {
@@ -13,7 +15,11 @@ type jsonLogger struct {
 }
 
 // NewJSONLogger returns a Logger that encodes keyvals to the Writer as a
-// single JSON object.
+// single JSON object, followed by a newline, in a single write. If keys are
+// repeated, the last value wins. Values JSON can't represent, like NaN and
+// infinite floats, are encoded as strings. Errors, fmt.Stringers and []byte
+// values are encoded like by the logfmt logger, unless they implement
+// json.Marshaler or encoding.TextMarshaler.
 func NewJSONLogger(w io.Writer) Logger {
 	return &jsonLogger{w}
 }
@@ -29,7 +35,18 @@ func (l *jsonLogger) Log(keyvals ...interface{}) error {
 		}
 		merge(m, k, v)
 	}
-	return json.NewEncoder(l.Writer).Encode(m)
+	err := json.NewEncoder(l.Writer).Encode(m)
+	if _, ok := err.(*json.UnsupportedValueError); ok {
+		// A nested value, like a NaN in a slice, can't be encoded. Render the
+		// values which can't as strings, rather than losing the record.
+		for k, v := range m {
+			if _, err := json.Marshal(v); err != nil {
+				m[k] = fmt.Sprintf("%+v", v)
+			}
+		}
+		err = json.NewEncoder(l.Writer).Encode(m)
+	}
+	return err
 }
 
 func merge(dst map[string]interface{}, k, v interface{}) {
@@ -43,7 +60,7 @@ func merge(dst map[string]interface{}, k, v interface{}) {
 		key = fmt.Sprint(x)
 	}
 	if x, ok := v.(error); ok {
-		v = safeError(x)
+		v = safeValue(x)
 	}
 
 	// We want json.Marshaler and encoding.TextMarshaller to take priority over
@@ -52,10 +69,16 @@ func merge(dst map[string]interface{}, k, v interface{}) {
 	switch x := v.(type) {
 	case json.Marshaler:
 	case encoding.TextMarshaler:
-	case error:
-		v = safeError(x)
-	case fmt.Stringer:
-		v = safeString(x)
+	case fmt.Stringer, []byte:
+		v = safeValue(x)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			v = strconv.FormatFloat(x, 'g', -1, 64)
+		}
+	case float32:
+		if f := float64(x); math.IsNaN(f) || math.IsInf(f, 0) {
+			v = strconv.FormatFloat(f, 'g', -1, 32)
+		}
 	}
 
 	dst[key] = v
@@ -74,17 +97,3 @@ func safeString(str fmt.Stringer) (s string) {
 	s = str.String()
 	return
 }
-
-func safeError(err error) (s interface{}) {
-	defer func() {
-		if panicVal := recover(); panicVal != nil {
-			if v := reflect.ValueOf(err); v.Kind() == reflect.Ptr && v.IsNil() {
-				s = nil
-			} else {
-				panic(panicVal)
-			}
-		}
-	}()
-	s = err.Error()
-	return
-}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"io/ioutil"
+	"math"
 	"testing"
 
 	"github.com/go-kit/kit/log"
@@ -18,7 +19,7 @@ func TestJSONLoggerCaller(t *testing.T) {
 	if err := logger.Log(); err != nil {
 		t.Fatal(err)
 	}
-	if want, have := `{"caller":"json_logger_test.go:18"}`+"\n", buf.String(); want != have {
+	if want, have := `{"caller":"json_logger_test.go:19"}`+"\n", buf.String(); want != have {
 		t.Errorf("\nwant %#v\nhave %#v", want, have)
 	}
 }
@@ -143,10 +144,66 @@ func (s stringError) Error() string {
 	return string(s)
 }
 
+func TestJSONLoggerValueTypes(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		keyvals []interface{}
+		want    string
+	}{
+		{"duplicate keys", []interface{}{"k", 1, "k", 2}, `{"k":2}`},
+		{"int key", []interface{}{42, "v"}, `{"42":"v"}`},
+		{"stringer key", []interface{}{stringer("key"), "v"}, `{"key":"v"}`},
+		{"marshaler", []interface{}{"v", aller{}}, `{"v":"json"}`},
+		{"error", []interface{}{"err", stringError("failed")}, `{"err":"failed"}`},
+		{"nil", []interface{}{"v", nil}, `{"v":null}`},
+		{"bool", []interface{}{"v", true}, `{"v":true}`},
+		{"float", []interface{}{"v", 1.5}, `{"v":1.5}`},
+		{"NaN", []interface{}{"v", math.NaN()}, `{"v":"NaN"}`},
+		{"+Inf", []interface{}{"v", math.Inf(1)}, `{"v":"+Inf"}`},
+		{"-Inf float32", []interface{}{"v", float32(math.Inf(-1))}, `{"v":"-Inf"}`},
+		{"nested NaN", []interface{}{"a", []float64{1, math.NaN()}, "b", 1}, `{"a":"[1 NaN]","b":1}`},
+		{"nested map", []interface{}{"v", map[string]interface{}{"a": []int{1}}}, `{"v":{"a":[1]}}`},
+		{"value with =", []interface{}{"q", "a=b c"}, `{"q":"a=b c"}`},
+	} {
+		buf := &bytes.Buffer{}
+		if err := log.NewJSONLogger(buf).Log(tc.keyvals...); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if want, have := tc.want+"\n", buf.String(); want != have {
+			t.Errorf("%s:\nwant %#v\nhave %#v", tc.name, want, have)
+		}
+	}
+}
+
+// writeCounter counts the calls to Write.
+type writeCounter struct{ writes int }
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return len(p), nil
+}
+
+func TestJSONLoggerSingleWrite(t *testing.T) {
+	t.Parallel()
+	w := &writeCounter{}
+	logger := log.NewJSONLogger(w)
+	logger.Log("a", 1, "b", []string{"x", "y"}, "c", map[string]int{"z": 0})
+	logger.Log("nested", []float64{math.NaN()}) // re-encoded after the failure
+	if want, have := 2, w.writes; want != have {
+		t.Errorf("want %d writes, have %d", want, have)
+	}
+}
+
 func BenchmarkJSONLoggerSimple(b *testing.B) {
 	benchmarkRunner(b, log.NewJSONLogger(ioutil.Discard), baseMessage)
 }
 
+func BenchmarkJSONLoggerTyped(b *testing.B) {
+	benchmarkRunner(b, log.NewJSONLogger(ioutil.Discard), typedMessage)
+}
+
 func BenchmarkJSONLoggerContextual(b *testing.B) {
 	benchmarkRunner(b, log.NewJSONLogger(ioutil.Discard), withMessage)
 }
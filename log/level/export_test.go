@@ -0,0 +1,13 @@
+package level
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// NewSwapHandlerWithClock is like NewSwapHandler, with now returning the
+// current time, and afterFunc scheduling reverts like time.AfterFunc.
+func NewSwapHandlerWithClock(swap *log.SwapLogger, next log.Logger, initial string, now func() time.Time, afterFunc func(time.Duration, func()) func() bool, options ...Option) (*SwapHandler, error) {
+	return newSwapHandler(swap, next, initial, now, afterFunc, options...)
+}
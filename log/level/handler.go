@@ -0,0 +1,171 @@
+package level
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// allowByName maps the level names accepted by SwapHandler to the options
+// allowing them.
+var allowByName = map[string]func() Option{
+	"debug": AllowDebug,
+	"info":  AllowInfo,
+	"warn":  AllowWarn,
+	"error": AllowError,
+	"none":  AllowNone,
+}
+
+// SwapHandler is an http.Handler changing the level of a logger at runtime,
+// e.g. to turn debug logging on in a live process from an admin endpoint. It
+// swaps a new filter of the logger, allowing the requested level, into a
+// log.SwapLogger, which the rest of the program logs to, so the change is
+// atomic with respect to concurrent logging.
+//
+// GET requests return the current level as JSON, e.g. {"level":"info"}. PUT
+// and POST requests set it to the value of the "level" form parameter: debug,
+// info, warn, error, or none. With a "ttl" form parameter, a duration like
+// "5m", the level reverts after the duration; the response then also holds
+// the level it reverts to, and when, as "revert_to" and "revert_at".
+type SwapHandler struct {
+	swap      *log.SwapLogger
+	next      log.Logger
+	options   []Option
+	now       func() time.Time
+	afterFunc func(time.Duration, func()) (stop func() bool)
+
+	mtx      sync.Mutex
+	current  string
+	base     string      // level set without a time-to-live
+	revertAt time.Time   // zero without a pending revert
+	stop     func() bool // stops the pending revert, if any
+	version  uint64      // incremented by each change, so stale reverts are ignored
+}
+
+// NewSwapHandler returns a SwapHandler filtering next, and swaps a filter
+// allowing the initial level into swap. The options are applied to each
+// filter, after the one allowing the level, e.g. to squelch unleveled events.
+func NewSwapHandler(swap *log.SwapLogger, next log.Logger, initial string, options ...Option) (*SwapHandler, error) {
+	return newSwapHandler(swap, next, initial, time.Now, func(d time.Duration, f func()) func() bool {
+		return time.AfterFunc(d, f).Stop
+	}, options...)
+}
+
+func newSwapHandler(swap *log.SwapLogger, next log.Logger, initial string, now func() time.Time, afterFunc func(time.Duration, func()) func() bool, options ...Option) (*SwapHandler, error) {
+	h := &SwapHandler{
+		swap:      swap,
+		next:      next,
+		options:   options,
+		now:       now,
+		afterFunc: afterFunc,
+	}
+	if err := h.SetLevel(initial); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SetLevel swaps a filter allowing the named level into the SwapLogger. It
+// cancels the pending revert of a level set with SetLevelFor, if any.
+func (h *SwapHandler) SetLevel(name string) error {
+	return h.SetLevelFor(name, 0)
+}
+
+// SetLevelFor is like SetLevel, but the level reverts after ttl to the one
+// last set by SetLevel, e.g. to log at the debug level for a few minutes.
+// A ttl of zero or less sets the level like SetLevel.
+func (h *SwapHandler) SetLevelFor(name string, ttl time.Duration) error {
+	if _, ok := allowByName[name]; !ok {
+		return fmt.Errorf("unknown level %q", name)
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.stop != nil {
+		h.stop()
+		h.stop = nil
+	}
+	h.revertAt = time.Time{}
+	h.version++
+	h.swapLevel(name)
+	if ttl <= 0 {
+		h.base = name
+		return nil
+	}
+	version := h.version
+	h.revertAt = h.now().Add(ttl)
+	h.stop = h.afterFunc(ttl, func() { h.revert(version) })
+	return nil
+}
+
+// revert swaps the base level back in, unless the level changed since the
+// revert was scheduled.
+func (h *SwapHandler) revert(version uint64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.version != version {
+		return
+	}
+	h.stop = nil
+	h.revertAt = time.Time{}
+	h.version++
+	h.swapLevel(h.base)
+}
+
+func (h *SwapHandler) swapLevel(name string) {
+	h.swap.Swap(NewFilter(h.next, append([]Option{allowByName[name]()}, h.options...)...))
+	h.current = name
+}
+
+// Level returns the name of the current level.
+func (h *SwapHandler) Level() string {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.current
+}
+
+type swapHandlerState struct {
+	Level    string     `json:"level"`
+	RevertTo string     `json:"revert_to,omitempty"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+func (h *SwapHandler) state() swapHandlerState {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	s := swapHandlerState{Level: h.current}
+	if !h.revertAt.IsZero() {
+		revertAt := h.revertAt
+		s.RevertTo, s.RevertAt = h.base, &revertAt
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (h *SwapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "PUT", "POST":
+		var ttl time.Duration
+		if s := r.FormValue("ttl"); s != "" {
+			var err error
+			if ttl, err = time.ParseDuration(s); err != nil || ttl <= 0 {
+				http.Error(w, fmt.Sprintf("invalid ttl %q", s), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := h.SetLevelFor(r.FormValue("level"), ttl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(h.state())
+}
@@ -0,0 +1,196 @@
+package level_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+func TestSwapHandler(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		logger log.SwapLogger
+	)
+	h, err := level.NewSwapHandler(&logger, log.NewLogfmtLogger(&buf), "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	level.Debug(&logger).Log("msg", "hidden")
+	level.Info(&logger).Log("msg", "shown")
+	if want, have := "level=info msg=shown\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	for _, tc := range []struct {
+		method, target string
+		code           int
+		body           string
+	}{
+		{"GET", "/", http.StatusOK, `{"level":"info"}` + "\n"},
+		{"PUT", "/?level=debug", http.StatusOK, `{"level":"debug"}` + "\n"},
+		{"GET", "/", http.StatusOK, `{"level":"debug"}` + "\n"},
+		{"POST", "/?level=verbose", http.StatusBadRequest, "unknown level \"verbose\"\n"},
+		{"DELETE", "/", http.StatusMethodNotAllowed, "method not allowed\n"},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+		if want, have := tc.code, rec.Code; want != have {
+			t.Errorf("%s %s: want %d, have %d", tc.method, tc.target, want, have)
+		}
+		if want, have := tc.body, rec.Body.String(); want != have {
+			t.Errorf("%s %s: want %q, have %q", tc.method, tc.target, want, have)
+		}
+	}
+
+	buf.Reset()
+	level.Debug(&logger).Log("msg", "shown")
+	if want, have := "level=debug msg=shown\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+// fakeClock runs the functions scheduled with AfterFunc when Advance moves
+// the time past their deadline.
+type fakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	t := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return func() bool {
+		wasActive := !t.stopped
+		t.stopped = true
+		return wasActive
+	}
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+	for _, t := range c.timers {
+		if !t.stopped && !t.at.After(c.now) {
+			t.stopped = true
+			t.f()
+		}
+	}
+}
+
+func TestSwapHandlerTTL(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		logger log.SwapLogger
+		clock  = &fakeClock{now: time.Date(2017, time.May, 1, 12, 0, 0, 0, time.UTC)}
+	)
+	h, err := level.NewSwapHandlerWithClock(&logger, log.NewLogfmtLogger(&buf), "info", clock.Now, clock.AfterFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(method, target string) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec.Code, rec.Body.String()
+	}
+	check := func(name string, method, target string, code int, body string) {
+		haveCode, haveBody := serve(method, target)
+		if want, have := code, haveCode; want != have {
+			t.Errorf("%s: want %d, have %d", name, want, have)
+		}
+		if want, have := body+"\n", haveBody; want != have {
+			t.Errorf("%s: want %q, have %q", name, want, have)
+		}
+	}
+
+	const pending = `{"level":"debug","revert_to":"info","revert_at":"2017-05-01T12:05:00Z"}`
+	check("temporary debug", "PUT", "/?level=debug&ttl=5m", http.StatusOK, pending)
+	check("pending revert", "GET", "/", http.StatusOK, pending)
+	level.Debug(&logger).Log("msg", "shown")
+	if want, have := "level=debug msg=shown\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	clock.Advance(4 * time.Minute)
+	check("before the ttl", "GET", "/", http.StatusOK, pending)
+	clock.Advance(time.Minute)
+	check("after the ttl", "GET", "/", http.StatusOK, `{"level":"info"}`)
+	buf.Reset()
+	level.Debug(&logger).Log("msg", "hidden")
+	if want, have := "", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// An explicit set cancels the pending revert.
+	check("temporary warn", "POST", "/?level=warn&ttl=1m", http.StatusOK, `{"level":"warn","revert_to":"info","revert_at":"2017-05-01T12:06:00Z"}`)
+	check("explicit debug", "PUT", "/?level=debug", http.StatusOK, `{"level":"debug"}`)
+	clock.Advance(time.Hour)
+	check("cancelled revert", "GET", "/", http.StatusOK, `{"level":"debug"}`)
+
+	check("invalid ttl", "PUT", "/?level=info&ttl=forever", http.StatusBadRequest, `invalid ttl "forever"`)
+	check("negative ttl", "PUT", "/?level=info&ttl=-1m", http.StatusBadRequest, `invalid ttl "-1m"`)
+	check("invalid level", "PUT", "/?level=loud&ttl=1m", http.StatusBadRequest, `unknown level "loud"`)
+	check("unchanged", "GET", "/", http.StatusOK, `{"level":"debug"}`)
+}
+
+func TestNewSwapHandlerUnknownLevel(t *testing.T) {
+	if _, err := level.NewSwapHandler(&log.SwapLogger{}, log.NewNopLogger(), "loud"); err == nil {
+		t.Error("want error, have none")
+	}
+}
+
+func TestSwapHandlerConcurrentLogging(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		logger log.SwapLogger
+		wg     sync.WaitGroup
+	)
+	h, err := level.NewSwapHandler(&logger, log.NewLogfmtLogger(log.NewSyncWriter(&buf)), "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				level.Debug(&logger).Log("msg", "debug")
+				level.Error(&logger).Log("msg", "error")
+			}
+		}()
+	}
+	for _, name := range []string{"debug", "error", "debug", "info"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("PUT", "/?level="+name, nil))
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var errors int
+	for _, line := range lines {
+		switch line {
+		case "level=error msg=error":
+			errors++
+		case "level=debug msg=debug":
+		default:
+			t.Fatalf("corrupted line %q", line)
+		}
+	}
+	if want, have := 1000, errors; want != have {
+		t.Errorf("want %d error records at any level, have %d", want, have)
+	}
+}
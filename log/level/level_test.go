@@ -0,0 +1,270 @@
+package level_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+func TestVariousLevels(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		allowed level.Option
+		want    string
+	}{
+		{
+			"AllowAll",
+			level.AllowAll(),
+			strings.Join([]string{
+				`{"level":"debug","this is":"debug log"}`,
+				`{"level":"info","this is":"info log"}`,
+				`{"level":"warn","this is":"warn log"}`,
+				`{"level":"error","this is":"error log"}`,
+			}, "\n"),
+		},
+		{
+			"AllowDebug",
+			level.AllowDebug(),
+			strings.Join([]string{
+				`{"level":"debug","this is":"debug log"}`,
+				`{"level":"info","this is":"info log"}`,
+				`{"level":"warn","this is":"warn log"}`,
+				`{"level":"error","this is":"error log"}`,
+			}, "\n"),
+		},
+		{
+			"AllowInfo",
+			level.AllowInfo(),
+			strings.Join([]string{
+				`{"level":"info","this is":"info log"}`,
+				`{"level":"warn","this is":"warn log"}`,
+				`{"level":"error","this is":"error log"}`,
+			}, "\n"),
+		},
+		{
+			"AllowWarn",
+			level.AllowWarn(),
+			strings.Join([]string{
+				`{"level":"warn","this is":"warn log"}`,
+				`{"level":"error","this is":"error log"}`,
+			}, "\n"),
+		},
+		{
+			"AllowError",
+			level.AllowError(),
+			strings.Join([]string{
+				`{"level":"error","this is":"error log"}`,
+			}, "\n"),
+		},
+		{
+			"AllowNone",
+			level.AllowNone(),
+			``,
+		},
+	} {
+		var buf bytes.Buffer
+		logger := level.NewFilter(log.NewJSONLogger(&buf), tc.allowed)
+
+		level.Debug(logger).Log("this is", "debug log")
+		level.Info(logger).Log("this is", "info log")
+		level.Warn(logger).Log("this is", "warn log")
+		level.Error(logger).Log("this is", "error log")
+
+		if want, have := tc.want, strings.TrimSpace(buf.String()); want != have {
+			t.Errorf("%s: want\n%s\nhave\n%s", tc.name, want, have)
+		}
+	}
+}
+
+func TestNoFilterOptions(t *testing.T) {
+	var buf bytes.Buffer
+	logger := level.NewFilter(log.NewLogfmtLogger(&buf))
+
+	level.Error(logger).Log("msg", "leveled")
+	logger.Log("msg", "unleveled")
+	if want, have := "msg=unleveled\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestErrNotAllowed(t *testing.T) {
+	myError := errors.New("squelched!")
+	opts := []level.Option{
+		level.AllowWarn(),
+		level.ErrNotAllowed(myError),
+	}
+	logger := level.NewFilter(log.NewNopLogger(), opts...)
+
+	if want, have := myError, level.Info(logger).Log("foo", "bar"); want != have {
+		t.Errorf("want %#+v, have %#+v", want, have)
+	}
+
+	if want, have := error(nil), level.Warn(logger).Log("foo", "bar"); want != have {
+		t.Errorf("want %#+v, have %#+v", want, have)
+	}
+}
+
+func TestErrNoLevel(t *testing.T) {
+	myError := errors.New("no level specified")
+
+	var buf bytes.Buffer
+	opts := []level.Option{
+		level.SquelchNoLevel(true),
+		level.ErrNoLevel(myError),
+	}
+	logger := level.NewFilter(log.NewJSONLogger(&buf), opts...)
+
+	if want, have := myError, logger.Log("foo", "bar"); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := ``, strings.TrimSpace(buf.String()); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestAllowNoLevel(t *testing.T) {
+	var buf bytes.Buffer
+	opts := []level.Option{
+		level.SquelchNoLevel(false),
+		level.ErrNoLevel(errors.New("I should never be returned!")),
+	}
+	logger := level.NewFilter(log.NewJSONLogger(&buf), opts...)
+
+	if want, have := error(nil), logger.Log("foo", "bar"); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := `{"foo":"bar"}`, strings.TrimSpace(buf.String()); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestDefaultLevel(t *testing.T) {
+	errNotAllowed := errors.New("not allowed")
+	for _, tc := range []struct {
+		name    string
+		options []level.Option
+		want    string
+		err     error
+	}{
+		{
+			"allowed",
+			[]level.Option{level.AllowInfo(), level.DefaultLevel(level.InfoValue())},
+			"level=info foo=bar\n",
+			nil,
+		},
+		{
+			"filtered",
+			[]level.Option{level.AllowWarn(), level.DefaultLevel(level.InfoValue()), level.ErrNotAllowed(errNotAllowed)},
+			"",
+			errNotAllowed,
+		},
+		{
+			"takes precedence over SquelchNoLevel",
+			[]level.Option{level.AllowAll(), level.SquelchNoLevel(true), level.DefaultLevel(level.DebugValue())},
+			"level=debug foo=bar\n",
+			nil,
+		},
+	} {
+		var buf bytes.Buffer
+		logger := level.NewFilter(log.NewLogfmtLogger(&buf), tc.options...)
+		if want, have := tc.err, logger.Log("foo", "bar"); want != have {
+			t.Errorf("%s: want error %v, have %v", tc.name, want, have)
+		}
+		if want, have := tc.want, buf.String(); want != have {
+			t.Errorf("%s: want %q, have %q", tc.name, want, have)
+		}
+	}
+}
+
+func TestLevelContext(t *testing.T) {
+	var buf bytes.Buffer
+
+	// Wrapping the level logger with a context allows users to use
+	// log.DefaultCaller as per normal.
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(&buf)
+	logger = level.NewFilter(logger, level.AllowAll())
+	logger = log.NewContext(logger).With("caller", log.DefaultCaller)
+
+	level.Info(logger).Log("foo", "bar")
+	if want, have := `level=info caller=level_test.go:194 foo=bar`, strings.TrimSpace(buf.String()); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestContextLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	// Wrapping a context with the level logger still works, but requires
+	// users to specify a higher callstack depth value.
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(&buf)
+	logger = log.NewContext(logger).With("caller", log.Caller(5))
+	logger = level.NewFilter(logger, level.AllowAll())
+
+	level.Info(logger).Log("foo", "bar")
+	if want, have := `caller=level_test.go:210 level=info foo=bar`, strings.TrimSpace(buf.String()); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestWithOrdering(t *testing.T) {
+	var buf bytes.Buffer
+	logger := level.NewFilter(log.NewLogfmtLogger(&buf), level.AllowInfo())
+
+	// The level is found wherever it is in the keyvals, so contexts may be
+	// built on either side of it.
+	log.NewContext(level.Info(logger)).With("a", 1).Log("b", 2)
+	level.Info(log.NewContext(logger).With("a", 1)).Log("b", 2)
+	log.NewContext(level.Debug(logger)).With("a", 1).Log("b", 2)
+	level.Debug(log.NewContext(logger).With("a", 1)).Log("b", 2)
+
+	want := "level=info a=1 b=2\n" + "level=info a=1 b=2\n"
+	if have := buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestDistinguishedKey(t *testing.T) {
+	var keyvals []interface{}
+	logger := log.LoggerFunc(func(kv ...interface{}) error { keyvals = kv; return nil })
+
+	level.Warn(logger).Log("level", "not a level")
+	if want, have := level.Key(), keyvals[0]; want != have {
+		t.Errorf("want the level key first, have %v", have)
+	}
+	if keyvals[0] == keyvals[2] {
+		t.Error("want the level key distinct from a \"level\" string")
+	}
+	if want, have := level.WarnValue(), keyvals[1]; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// A "level" string isn't taken for a level by the filter.
+	filtered := level.NewFilter(log.NewLogfmtLogger(ioutil.Discard), level.AllowAll(), level.SquelchNoLevel(true), level.ErrNoLevel(errors.New("no level")))
+	if err := filtered.Log("level", "error"); err == nil {
+		t.Error("want a \"level\" string ignored by the filter")
+	}
+}
+
+func TestLevelWithCaller(t *testing.T) {
+	var buf bytes.Buffer
+
+	// The caller stays the call site of Log through further contexts and
+	// the leveled context, which are merged with the one holding the caller.
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(&buf)
+	logger = level.NewFilter(logger, level.AllowAll())
+	logger = log.With(logger, "caller", log.DefaultCaller)
+	logger = log.With(logger, "component", "test")
+
+	level.Debug(logger).Log("foo", "bar")
+	if want, have := `level=debug caller=level_test.go:266 component=test foo=bar`, strings.TrimSpace(buf.String()); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -2,6 +2,13 @@
 //
 // The fundamental interface is Logger. Loggers create log events from
 // key/value data.
+//
+// Loggers must be safe for concurrent use. NewNopLogger, Context, SwapLogger
+// and LoggerFunc, given a safe function, are safe. NewLogfmtLogger and
+// NewJSONLogger are safe as long as their writer is: they emit each record
+// with a single Write, but most writers, like a bytes.Buffer or a bufio.Writer,
+// aren't safe for concurrent writes. Wrap such writers with NewSyncWriter, and
+// loggers which aren't safe with NewSyncLogger.
 package log
 
 import (
@@ -30,6 +37,21 @@ func NewContext(logger Logger) *Context {
 	return &Context{logger: logger}
 }
 
+// With returns a Context logging to logger with keyvals, which may contain
+// Valuers, appended to those of all log events. It is shorthand for
+// NewContext(logger).With(keyvals...); if logger is a Context, the keyvals
+// are merged with its own, so any number of layers log with a single slice.
+func With(logger Logger, keyvals ...interface{}) *Context {
+	return NewContext(logger).With(keyvals...)
+}
+
+// WithPrefix returns a Context logging to logger with keyvals, which may
+// contain Valuers, prepended to those of all log events. It is shorthand for
+// NewContext(logger).WithPrefix(keyvals...).
+func WithPrefix(logger Logger, keyvals ...interface{}) *Context {
+	return NewContext(logger).WithPrefix(keyvals...)
+}
+
 // Context must always have the same number of stack frames between calls to
 // its Log method and the eventual binding of Valuers to their value. This
 // requirement comes from the functional requirement to allow a context to
@@ -78,16 +100,24 @@ type Context struct {
 // stored context with their generated value, appends keyvals, and passes the
 // result to the wrapped Logger.
 func (l *Context) Log(keyvals ...interface{}) error {
-	kvs := append(l.keyvals, keyvals...)
+	if len(keyvals) == 0 && !l.hasValuer {
+		return l.logger.Log(l.keyvals...)
+	}
+	// Allocating the merged keyvals at their final size, including a
+	// missing value, makes a single allocation per event. It also copies
+	// l.keyvals, so that future log events will reevaluate the stored
+	// Valuers.
+	n := len(l.keyvals) + len(keyvals)
+	if n%2 != 0 {
+		n++
+	}
+	kvs := make([]interface{}, 0, n)
+	kvs = append(kvs, l.keyvals...)
+	kvs = append(kvs, keyvals...)
 	if len(kvs)%2 != 0 {
 		kvs = append(kvs, ErrMissingValue)
 	}
 	if l.hasValuer {
-		// If no keyvals were appended above then we must copy l.keyvals so
-		// that future log events will reevaluate the stored Valuers.
-		if len(keyvals) == 0 {
-			kvs = append([]interface{}{}, l.keyvals...)
-		}
 		bindValues(kvs[:len(l.keyvals)])
 	}
 	return l.logger.Log(kvs...)
@@ -253,3 +253,91 @@ func TestSwapLogger(t *testing.T) {
 func TestSwapLoggerConcurrency(t *testing.T) {
 	testConcurrency(t, &log.SwapLogger{})
 }
+
+func TestSwapLoggerConcurrentSwap(t *testing.T) {
+	var (
+		logger log.SwapLogger
+		bufs   = [2]*bytes.Buffer{{}, {}}
+		swaps  = [2]log.Logger{
+			log.NewLogfmtLogger(log.NewSyncWriter(bufs[0])),
+			log.NewJSONLogger(log.NewSyncWriter(bufs[1])),
+		}
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				logger.Swap(swaps[i%2])
+			}
+		}
+	}()
+	testConcurrency(t, &logger)
+	close(done)
+	wg.Wait()
+
+	if bufs[0].Len() == 0 && bufs[1].Len() == 0 {
+		t.Error("want records logged through the swapped loggers")
+	}
+}
+
+func TestWithFuncs(t *testing.T) {
+	t.Parallel()
+	var output []interface{}
+	logger := log.Logger(log.LoggerFunc(func(keyvals ...interface{}) error {
+		output = keyvals
+		return nil
+	}))
+
+	var n int
+	count := log.Valuer(func() interface{} { n++; return n })
+
+	parent := log.With(logger, "service", "svc", "n", count)
+	parent = log.WithPrefix(parent, "ts")
+
+	// Two children of the same parent mustn't see each other's keyvals.
+	a := log.With(parent, "component", "a")
+	b := log.With(parent, "component", "b", "odd")
+
+	a.Log("msg", "x")
+	if want, have := "[ts (MISSING) service svc n 1 component a msg x]", fmt.Sprint(output); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+	b.Log("msg", "y")
+	if want, have := "[ts (MISSING) service svc n 2 component b odd (MISSING) msg y]", fmt.Sprint(output); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+	a.Log()
+	if want, have := "[ts (MISSING) service svc n 3 component a]", fmt.Sprint(output); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func TestWithAllocs(t *testing.T) {
+	logger := log.With(log.NewNopLogger(), "service", "svc")
+	logger = log.With(logger, "component", "c")
+	logger = log.WithPrefix(logger, "request_id", "r")
+
+	// Logging through three layers merges the bound keyvals and the logged
+	// ones in a single slice.
+	allocs := testing.AllocsPerRun(100, func() { logger.Log("k", "v") })
+	if allocs > 1 {
+		t.Errorf("want at most 1 allocation per Log, have %v", allocs)
+	}
+}
+
+func BenchmarkThreeWithFuncs(b *testing.B) {
+	logger := log.With(log.NewNopLogger(), "service", "svc")
+	logger = log.With(logger, "component", "c")
+	logger = log.WithPrefix(logger, "request_id", "r")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Log("k", "v")
+	}
+}
@@ -31,8 +31,12 @@ type logfmtLogger struct {
 }
 
 // NewLogfmtLogger returns a logger that encodes keyvals to the Writer in
-// logfmt format. The passed Writer must be safe for concurrent use by
-// multiple goroutines if the returned Logger will be used concurrently.
+// logfmt format. Errors are encoded as their Error string, fmt.Stringers as
+// their String, and []byte values as strings, or in base64 if they aren't
+// valid UTF-8. A panicking Error or String method is encoded as "PANIC=" and
+// the panic value, and nil pointers as null. The passed Writer must be safe
+// for concurrent use by multiple goroutines if the returned Logger will be
+// used concurrently.
 func NewLogfmtLogger(w io.Writer) Logger {
 	return &logfmtLogger{w}
 }
@@ -42,7 +46,7 @@ func (l logfmtLogger) Log(keyvals ...interface{}) error {
 	enc.Reset()
 	defer logfmtEncoderPool.Put(enc)
 
-	if err := enc.EncodeKeyvals(keyvals...); err != nil {
+	if err := enc.EncodeKeyvals(safeValues(keyvals)...); err != nil {
 		return err
 	}
 
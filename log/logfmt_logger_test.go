@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-logfmt/logfmt"
@@ -38,10 +39,35 @@ func TestLogfmtLogger(t *testing.T) {
 	}
 }
 
+// TestLogfmtLoggerGolden pins the output of a contextual logger, which must
+// be preserved byte for byte by changes to the hot path.
+func TestLogfmtLoggerGolden(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.NewContext(log.NewLogfmtLogger(buf)).
+		With("service", "addsvc", "instance", "10.0.0.1:8080").
+		With("method", "sum", "odd")
+
+	logger.Log("a", 1, "b", -2.5, "took", 10*time.Millisecond, "err", errors.New(`bad "input"`), "ok", true, "msg", "a b=c")
+	logger.Log()
+	logger.Log("nil", nil, "bytes", []byte("raw"), "trailing")
+
+	want := `service=addsvc instance=10.0.0.1:8080 method=sum odd=(MISSING) a=1 b=-2.5 took=10ms err="bad \"input\"" ok=true msg="a b=c"
+service=addsvc instance=10.0.0.1:8080 method=sum odd=(MISSING)
+service=addsvc instance=10.0.0.1:8080 method=sum odd=(MISSING) nil=null bytes=raw trailing=(MISSING)
+`
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
 func BenchmarkLogfmtLoggerSimple(b *testing.B) {
 	benchmarkRunner(b, log.NewLogfmtLogger(ioutil.Discard), baseMessage)
 }
 
+func BenchmarkLogfmtLoggerTyped(b *testing.B) {
+	benchmarkRunner(b, log.NewLogfmtLogger(ioutil.Discard), typedMessage)
+}
+
 func BenchmarkLogfmtLoggerContextual(b *testing.B) {
 	benchmarkRunner(b, log.NewLogfmtLogger(ioutil.Discard), withMessage)
 }
@@ -53,3 +79,14 @@ func TestLogfmtLoggerConcurrency(t *testing.T) {
 type mymap map[int]int
 
 func (m mymap) String() string { return "special_behavior" }
+
+func BenchmarkLogfmtLoggerTwoWith(b *testing.B) {
+	logger := log.NewContext(log.NewLogfmtLogger(ioutil.Discard)).
+		With("service", "addsvc", "instance", "10.0.0.1:8080").
+		With("method", "sum")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Log("a", 1, "b", 2, "took", "10ms", "err", nil, "ok", true, "msg", "summed")
+	}
+}
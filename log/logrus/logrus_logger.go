@@ -0,0 +1,161 @@
+// Package logrus provides adapters between Go kit loggers and logrus
+// loggers, for codebases migrating from one to the other: NewLogrusLogger
+// sends the events of a Go kit logger to a logrus logger, and NewHook sends
+// the entries of a logrus logger to a Go kit logger.
+//
+// Values are passed as they are, not stringified, so errors, times and
+// numbers keep their type, and the formatter at the end decides how to render
+// them.
+package logrus
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Option sets a parameter for the adapters.
+type Option func(*config)
+
+// LevelKey sets the key of the level. By default, it's level.Key(), which
+// the filters of package level require, and renders as "level".
+func LevelKey(key string) Option {
+	return func(c *config) { c.levelKey = key }
+}
+
+// MessageKey sets the key of the message. By default, it's "msg".
+func MessageKey(key string) Option {
+	return func(c *config) { c.messageKey = key }
+}
+
+type config struct {
+	levelKey   interface{}
+	messageKey string
+}
+
+func newConfig(options []Option) config {
+	c := config{
+		levelKey:   level.Key(),
+		messageKey: "msg",
+	}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+type logrusLogger struct {
+	logger logrus.FieldLogger
+	config
+}
+
+// NewLogrusLogger returns a Go kit logger sending its events to a logrus
+// logger. The value under the level key, e.g. set by package level, selects
+// the logrus level: debug, info, warn or error; events without a known level
+// are logged at the info level. Panic and fatal levels are logged at the error
+// level, as a Log call mustn't panic or exit. The value under the message key
+// is the message of the entry, and the other keyvals become its fields.
+func NewLogrusLogger(logger logrus.FieldLogger, options ...Option) log.Logger {
+	return &logrusLogger{
+		logger: logger,
+		config: newConfig(options),
+	}
+}
+
+// Log implements log.Logger.
+func (l *logrusLogger) Log(keyvals ...interface{}) error {
+	var (
+		fields   = make(logrus.Fields, len(keyvals)/2)
+		levelKey = fmt.Sprint(l.levelKey)
+		lvl      = logrus.InfoLevel
+		msg      string
+	)
+	for i := 0; i < len(keyvals); i += 2 {
+		k := fmt.Sprint(keyvals[i])
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		switch k {
+		case levelKey:
+			if parsed, err := logrus.ParseLevel(fmt.Sprint(v)); err == nil {
+				lvl = parsed
+				continue
+			}
+		case l.messageKey:
+			msg = fmt.Sprint(v)
+			continue
+		}
+		fields[k] = v
+	}
+
+	entry := l.logger.WithFields(fields)
+	switch lvl {
+	case logrus.DebugLevel:
+		entry.Debug(msg)
+	case logrus.InfoLevel:
+		entry.Info(msg)
+	case logrus.WarnLevel:
+		entry.Warn(msg)
+	default:
+		entry.Error(msg)
+	}
+	return nil
+}
+
+// Hook is a logrus hook sending the entries of a logrus logger to a Go kit
+// logger. Add it to a logrus logger with AddHook.
+type Hook struct {
+	logger log.Logger
+	config
+}
+
+// NewHook returns a Hook logging each entry with the Go kit logger: its level,
+// as a value of package level, under the level key, so level filters apply;
+// its message under the message key; and its fields, sorted by key.
+func NewHook(logger log.Logger, options ...Option) *Hook {
+	return &Hook{
+		logger: logger,
+		config: newConfig(options),
+	}
+}
+
+// Levels implements logrus.Hook. The hook fires at all levels.
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	keyvals := make([]interface{}, 0, 4+2*len(keys))
+	keyvals = append(keyvals, h.levelKey, levelValue(entry.Level), h.messageKey, entry.Message)
+	for _, k := range keys {
+		keyvals = append(keyvals, k, entry.Data[k])
+	}
+	return h.logger.Log(keyvals...)
+}
+
+// levelValue maps a logrus level to the value of package level. Panic and
+// fatal are errors.
+func levelValue(lvl logrus.Level) level.Value {
+	switch lvl {
+	case logrus.DebugLevel:
+		return level.DebugValue()
+	case logrus.InfoLevel:
+		return level.InfoValue()
+	case logrus.WarnLevel:
+		return level.WarnValue()
+	default:
+		return level.ErrorValue()
+	}
+}
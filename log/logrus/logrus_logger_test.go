@@ -0,0 +1,166 @@
+package logrus_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	kitlogrus "github.com/go-kit/kit/log/logrus"
+)
+
+// dataFormatter leaves the data of entries alone, while logrus' formatters
+// rename fields clashing with their own, e.g. "level" to "fields.level".
+type dataFormatter struct{}
+
+func (dataFormatter) Format(*logrus.Entry) ([]byte, error) { return nil, nil }
+
+func TestLogrusLogger(t *testing.T) {
+	logrusLogger, hook := test.NewNullLogger()
+	logrusLogger.Formatter = dataFormatter{}
+	logrusLogger.Level = logrus.DebugLevel
+
+	var (
+		err = errors.New("connection refused")
+		ts  = time.Date(2017, time.May, 1, 12, 0, 0, 0, time.UTC)
+	)
+	for _, tc := range []struct {
+		name    string
+		log     func(log.Logger)
+		level   logrus.Level
+		message string
+		fields  logrus.Fields
+	}{
+		{
+			name:    "typed values",
+			log:     func(l log.Logger) { l.Log("msg", "hello", "err", err, "ts", ts, "n", 42, "ratio", 0.5) },
+			level:   logrus.InfoLevel,
+			message: "hello",
+			fields:  logrus.Fields{"err": err, "ts": ts, "n": 42, "ratio": 0.5},
+		},
+		{
+			name:    "level package",
+			log:     func(l log.Logger) { level.Warn(l).Log("msg", "slow", "took", time.Second) },
+			level:   logrus.WarnLevel,
+			message: "slow",
+			fields:  logrus.Fields{"took": time.Second},
+		},
+		{
+			name:    "string level",
+			log:     func(l log.Logger) { l.Log("level", "debug", "msg", "details") },
+			level:   logrus.DebugLevel,
+			message: "details",
+			fields:  logrus.Fields{},
+		},
+		{
+			name:    "unknown level",
+			log:     func(l log.Logger) { l.Log("level", "loud", "msg", "hi") },
+			level:   logrus.InfoLevel,
+			message: "hi",
+			fields:  logrus.Fields{"level": "loud"},
+		},
+		{
+			name:    "fatal level",
+			log:     func(l log.Logger) { l.Log("level", "fatal") },
+			level:   logrus.ErrorLevel,
+			message: "",
+			fields:  logrus.Fields{},
+		},
+		{
+			name:    "missing value",
+			log:     func(l log.Logger) { l.Log("msg", "odd", "k") },
+			level:   logrus.InfoLevel,
+			message: "odd",
+			fields:  logrus.Fields{"k": log.ErrMissingValue},
+		},
+	} {
+		hook.Reset()
+		tc.log(kitlogrus.NewLogrusLogger(logrusLogger))
+		entry := hook.LastEntry()
+		if entry == nil {
+			t.Fatalf("%s: no entry", tc.name)
+		}
+		if want, have := tc.level, entry.Level; want != have {
+			t.Errorf("%s: want level %v, have %v", tc.name, want, have)
+		}
+		if want, have := tc.message, entry.Message; want != have {
+			t.Errorf("%s: want message %q, have %q", tc.name, want, have)
+		}
+		if want, have := tc.fields, entry.Data; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want fields %#v, have %#v", tc.name, want, have)
+		}
+	}
+}
+
+func TestLogrusLoggerKeys(t *testing.T) {
+	logrusLogger, hook := test.NewNullLogger()
+	logrusLogger.Formatter = dataFormatter{}
+	logger := kitlogrus.NewLogrusLogger(logrusLogger, kitlogrus.LevelKey("severity"), kitlogrus.MessageKey("message"))
+
+	logger.Log("severity", "error", "message", "failed", "msg", "kept")
+	entry := hook.LastEntry()
+	if want, have := logrus.ErrorLevel, entry.Level; want != have {
+		t.Errorf("want level %v, have %v", want, have)
+	}
+	if want, have := "failed", entry.Message; want != have {
+		t.Errorf("want message %q, have %q", want, have)
+	}
+	if want, have := (logrus.Fields{"msg": "kept"}), entry.Data; !reflect.DeepEqual(want, have) {
+		t.Errorf("want fields %v, have %v", want, have)
+	}
+}
+
+func TestHook(t *testing.T) {
+	var output []interface{}
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		output = keyvals
+		return nil
+	})
+	logrusLogger, _ := test.NewNullLogger()
+	logrusLogger.Level = logrus.DebugLevel
+	logrusLogger.Hooks.Add(kitlogrus.NewHook(logger))
+
+	var (
+		err = errors.New("connection refused")
+		ts  = time.Date(2017, time.May, 1, 12, 0, 0, 0, time.UTC)
+	)
+	logrusLogger.WithFields(logrus.Fields{"n": 42, "ts": ts}).WithError(err).Warn("retrying")
+	want := []interface{}{level.Key(), level.WarnValue(), "msg", "retrying", "error", err, "n", 42, "ts", ts}
+	if !reflect.DeepEqual(want, output) {
+		t.Errorf("\nwant %v\nhave %v", want, output)
+	}
+
+	for _, tc := range []struct {
+		log  func(...interface{})
+		want level.Value
+	}{
+		{logrusLogger.Debug, level.DebugValue()},
+		{logrusLogger.Info, level.InfoValue()},
+		{logrusLogger.Error, level.ErrorValue()},
+	} {
+		tc.log("message")
+		if want, have := tc.want, output[1]; want != have {
+			t.Errorf("want level %v, have %v", want, have)
+		}
+	}
+}
+
+func TestHookLevelFilter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := level.NewFilter(log.NewLogfmtLogger(&buf), level.AllowWarn())
+	logrusLogger, _ := test.NewNullLogger()
+	logrusLogger.Level = logrus.DebugLevel
+	logrusLogger.Hooks.Add(kitlogrus.NewHook(logger))
+
+	logrusLogger.Info("dropped")
+	logrusLogger.Error("kept")
+	if want, have := "level=error msg=kept\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
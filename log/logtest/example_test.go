@@ -0,0 +1,22 @@
+package logtest_test
+
+import (
+	"fmt"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/logtest"
+)
+
+func ExampleRecorder() {
+	recorder := logtest.NewRecorder()
+	logger := log.NewContext(recorder).With("component", "billing")
+
+	logger.Log("msg", "charged", "amount", 42)
+
+	fmt.Println(recorder.Contains("msg", "charged", "amount", 42))
+	fmt.Println(recorder.Records())
+
+	// Output:
+	// true
+	// [[component billing msg charged amount 42]]
+}
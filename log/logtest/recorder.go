@@ -0,0 +1,121 @@
+// Package logtest provides a logger recording structured records, to assert
+// on the events logged by instrumented code in tests without parsing their
+// encoded output.
+package logtest
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Option sets an optional parameter for a Recorder.
+type Option func(*Recorder)
+
+// Forward makes the Recorder pass each record on to the logger after
+// recording it, e.g. to keep the console output of integration tests.
+func Forward(logger log.Logger) Option {
+	return func(r *Recorder) { r.next = logger }
+}
+
+// Recorder implements log.Logger by recording the records it logs. Valuers
+// are evaluated when a record is logged, so records are recorded as they'd
+// be encoded. A Recorder is safe for concurrent use.
+type Recorder struct {
+	mtx     sync.Mutex
+	records [][]interface{}
+	next    log.Logger
+}
+
+// NewRecorder returns a new, empty Recorder.
+func NewRecorder(options ...Option) *Recorder {
+	r := &Recorder{}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Log implements log.Logger. It returns the error of the logger records are
+// forwarded to, if any.
+func (r *Recorder) Log(keyvals ...interface{}) error {
+	record := make([]interface{}, len(keyvals))
+	for i, v := range keyvals {
+		if valuer, ok := v.(log.Valuer); ok && i%2 == 1 {
+			v = valuer()
+		}
+		record[i] = v
+	}
+
+	r.mtx.Lock()
+	r.records = append(r.records, record)
+	r.mtx.Unlock()
+
+	if r.next == nil {
+		return nil
+	}
+	return r.next.Log(record...)
+}
+
+// Records returns the records logged so far, in the order they were logged.
+func (r *Recorder) Records() [][]interface{} {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([][]interface{}{}, r.records...)
+}
+
+// FilterByKey returns the records logged so far with the value under the
+// key, in the order they were logged. Keys and values are compared with
+// reflect.DeepEqual.
+func (r *Recorder) FilterByKey(key, value interface{}) [][]interface{} {
+	var records [][]interface{}
+	for _, record := range r.Records() {
+		if has(record, key, value) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Contains returns true if a record logged so far has all the keyvals, in
+// any order, among others. Like in FilterByKey, keys and values are compared
+// with reflect.DeepEqual.
+func (r *Recorder) Contains(keyvals ...interface{}) bool {
+	for _, record := range r.Records() {
+		found := true
+		for i := 0; i < len(keyvals) && found; i += 2 {
+			var v interface{} = log.ErrMissingValue
+			if i+1 < len(keyvals) {
+				v = keyvals[i+1]
+			}
+			found = has(record, keyvals[i], v)
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset forgets the records logged so far, so the next assertions only see
+// the records logged afterwards.
+func (r *Recorder) Reset() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.records = nil
+}
+
+// has returns true if the record has the value under the key.
+func has(record []interface{}, key, value interface{}) bool {
+	for i := 0; i < len(record); i += 2 {
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(record) {
+			v = record[i+1]
+		}
+		if reflect.DeepEqual(record[i], key) && reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package logtest_test
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/log/logtest"
+)
+
+func TestRecorder(t *testing.T) {
+	r := logtest.NewRecorder()
+	n := 0
+	counter := log.Valuer(func() interface{} { n++; return n })
+
+	logger := log.NewContext(r).With("n", counter)
+	logger.Log("msg", "first")
+	level.Error(logger).Log("msg", "second", "odd")
+	r.Log("msg", "third", "n", counter)
+
+	want := [][]interface{}{
+		{"n", 1, "msg", "first"},
+		{level.Key(), level.ErrorValue(), "n", 2, "msg", "second", "odd", log.ErrMissingValue},
+		{"msg", "third", "n", 3},
+	}
+	if have := r.Records(); !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %v\nhave %v", want, have)
+	}
+
+	if want, have := want[1:2], r.FilterByKey(level.Key(), level.ErrorValue()); !reflect.DeepEqual(want, have) {
+		t.Errorf("FilterByKey: want %v, have %v", want, have)
+	}
+	if have := r.FilterByKey("msg", "fourth"); len(have) != 0 {
+		t.Errorf("FilterByKey: want no records, have %v", have)
+	}
+
+	for _, tc := range []struct {
+		keyvals []interface{}
+		want    bool
+	}{
+		{[]interface{}{"msg", "first"}, true},
+		{[]interface{}{"n", 3, "msg", "third"}, true},
+		{[]interface{}{"msg", "first", "n", 2}, false}, // in different records
+		{[]interface{}{"odd"}, true},
+		{[]interface{}{"msg", "fourth"}, false},
+	} {
+		if want, have := tc.want, r.Contains(tc.keyvals...); want != have {
+			t.Errorf("Contains(%v): want %v, have %v", tc.keyvals, want, have)
+		}
+	}
+
+	r.Reset()
+	if have := r.Records(); len(have) != 0 {
+		t.Errorf("after Reset: want no records, have %v", have)
+	}
+}
+
+func TestRecorderForward(t *testing.T) {
+	var buf bytes.Buffer
+	r := logtest.NewRecorder(logtest.Forward(log.NewLogfmtLogger(&buf)))
+	r.Log("msg", "hello", "ts", log.Valuer(func() interface{} { return "now" }))
+
+	if want, have := "msg=hello ts=now\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if !r.Contains("ts", "now") {
+		t.Errorf("want forwarded record recorded, have %v", r.Records())
+	}
+}
+
+func TestRecorderConcurrency(t *testing.T) {
+	r := logtest.NewRecorder()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Log("i", i)
+			r.Contains("i", i)
+		}(i)
+	}
+	wg.Wait()
+	if want, have := 10, len(r.Records()); want != have {
+		t.Errorf("want %d records, have %d", want, have)
+	}
+}
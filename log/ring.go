@@ -0,0 +1,51 @@
+package log
+
+import "sync"
+
+// RingBuffer is a logger keeping the last records in memory, e.g. to expose
+// them on a debug endpoint. It's safe for concurrent use.
+type RingBuffer struct {
+	mtx     sync.Mutex
+	records [][]interface{}
+	next    int
+	full    bool
+}
+
+// NewRingBuffer returns a RingBuffer keeping the last size records. Size is
+// at least 1.
+func NewRingBuffer(size int) *RingBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &RingBuffer{records: make([][]interface{}, size)}
+}
+
+// Log implements Logger. Valuers are bound, and the record is copied, so the
+// caller may reuse keyvals. It never returns an error.
+func (r *RingBuffer) Log(keyvals ...interface{}) error {
+	kvs := append(make([]interface{}, 0, len(keyvals)), keyvals...)
+	bindValues(kvs)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.records[r.next] = kvs
+	r.next++
+	if r.next == len(r.records) {
+		r.next = 0
+		r.full = true
+	}
+	return nil
+}
+
+// Snapshot returns the records in the buffer, from the oldest to the newest.
+// The records are shared with the buffer and must not be modified.
+func (r *RingBuffer) Snapshot() [][]interface{} {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if !r.full {
+		return append([][]interface{}(nil), r.records[:r.next]...)
+	}
+	snapshot := make([][]interface{}, 0, len(r.records))
+	snapshot = append(snapshot, r.records[r.next:]...)
+	return append(snapshot, r.records[:r.next]...)
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+)
+
+// safeValue returns the value the encoders of this package encode in place
+// of v, so that no value can crash a logger or garble its output:
+//
+//   - an error is its Error string, and a fmt.Stringer its String;
+//   - a method which panics is rendered as "PANIC=" and the panic value,
+//     e.g. "PANIC=String method: boom";
+//   - a nil pointer implementing one of those interfaces, or
+//     encoding.TextMarshaler, is nil, without calling the method;
+//   - a []byte is a string if it's valid UTF-8, and base64 encoded
+//     otherwise.
+//
+// Other values, including encoding.TextMarshalers, are returned unchanged.
+func safeValue(v interface{}) interface{} {
+	safe, _ := replaceValue(v)
+	return safe
+}
+
+// replaceValue returns safeValue(v), and whether it differs from v.
+func replaceValue(v interface{}) (interface{}, bool) {
+	switch x := v.(type) {
+	case nil, string:
+		return v, false
+	case []byte:
+		if utf8.Valid(x) {
+			return string(x), true
+		}
+		return base64.StdEncoding.EncodeToString(x), true
+	case encoding.TextMarshaler:
+		if isNilPointer(x) {
+			return nil, true
+		}
+		return v, false
+	case error:
+		if isNilPointer(x) {
+			return nil, true
+		}
+		return safeCall("Error", x.Error), true
+	case fmt.Stringer:
+		if isNilPointer(x) {
+			return nil, true
+		}
+		return safeCall("String", x.String), true
+	}
+	return v, false
+}
+
+// safeValues returns keyvals with their values replaced by safeValue. It
+// copies keyvals only if a value is replaced.
+func safeValues(keyvals []interface{}) []interface{} {
+	copied := false
+	for i := 1; i < len(keyvals); i += 2 {
+		switch keyvals[i].(type) {
+		case nil, string, int, int64, float64, bool:
+			continue // fast path for the most common types
+		}
+		v, replaced := replaceValue(keyvals[i])
+		if !replaced {
+			continue
+		}
+		if !copied {
+			keyvals = append([]interface{}(nil), keyvals...)
+			copied = true
+		}
+		keyvals[i] = v
+	}
+	return keyvals
+}
+
+// safeCall returns the result of method, named name, or a description of
+// its panic.
+func safeCall(name string, method func() string) (s string) {
+	defer func() {
+		if panicVal := recover(); panicVal != nil {
+			s = fmt.Sprintf("PANIC=%s method: %v", name, panicVal)
+		}
+	}()
+	return method()
+}
+
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
@@ -0,0 +1,82 @@
+package log_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// panicker panics in all its methods.
+type panicker struct{}
+
+func (panicker) String() string { panic("boom") }
+func (panicker) Error() string  { panic("bang") }
+
+// panickingStringer panics in String.
+type panickingStringer struct{}
+
+func (panickingStringer) String() string { panic("boom") }
+
+// multiline has a logfmt-hostile String.
+type multiline struct{}
+
+func (multiline) String() string { return "first line\nsecond=line" }
+
+// wrapped has a wrapping error, which renders as its Error string.
+type wrapped struct{ cause error }
+
+func (w wrapped) Error() string { return "wrapped: " + w.cause.Error() }
+
+// valueStringer has a value receiver, so its nil pointers panic in String.
+type valueStringer struct{ s string }
+
+func (v valueStringer) String() string { return v.s }
+
+func TestSafeValues(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		value  interface{}
+		logfmt string
+		json   string
+	}{
+		{"error", wrapped{stringError("refused")}, `v="wrapped: refused"`, `{"v":"wrapped: refused"}`},
+		{"panicking error", panicker{}, `v="PANIC=Error method: bang"`, `{"v":"PANIC=Error method: bang"}`},
+		{"panicking stringer", panickingStringer{}, `v="PANIC=String method: boom"`, `{"v":"PANIC=String method: boom"}`},
+		{"multiline stringer", multiline{}, `v="first line\nsecond=line"`, `{"v":"first line\nsecond=line"}`},
+		{"nil error pointer", (*wrapped)(nil), `v=null`, `{"v":null}`},
+		{"nil stringer pointer", (*valueStringer)(nil), `v=null`, `{"v":null}`},
+		{"nil text marshaler pointer", (*textstringer)(nil), `v=null`, `{"v":null}`},
+		{"UTF-8 bytes", []byte("héllo"), `v=héllo`, `{"v":"héllo"}`},
+		{"binary bytes", []byte{0xff, 0x00, 0xfe}, `v=/wD+`, `{"v":"/wD+"}`},
+	} {
+		var buf bytes.Buffer
+		if err := log.NewLogfmtLogger(&buf).Log("v", tc.value); err != nil {
+			t.Errorf("%s: logfmt: %v", tc.name, err)
+		}
+		if want, have := tc.logfmt+"\n", buf.String(); want != have {
+			t.Errorf("%s: logfmt:\nwant %#v\nhave %#v", tc.name, want, have)
+		}
+
+		buf.Reset()
+		if err := log.NewJSONLogger(&buf).Log("v", tc.value); err != nil {
+			t.Errorf("%s: JSON: %v", tc.name, err)
+		}
+		if want, have := tc.json+"\n", buf.String(); want != have {
+			t.Errorf("%s: JSON:\nwant %#v\nhave %#v", tc.name, want, have)
+		}
+	}
+}
+
+func TestSafeValuesKeepKeyvals(t *testing.T) {
+	keyvals := []interface{}{"err", wrapped{stringError("refused")}, "b", []byte("b")}
+	if err := log.NewLogfmtLogger(&bytes.Buffer{}).Log(keyvals...); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := keyvals[1].(wrapped); !ok {
+		t.Errorf("want the logged keyvals unchanged, have %#v", keyvals[1])
+	}
+	if _, ok := keyvals[3].([]byte); !ok {
+		t.Errorf("want the logged keyvals unchanged, have %#v", keyvals[3])
+	}
+}
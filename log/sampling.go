@@ -0,0 +1,141 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewSamplingLogger returns a Logger that caps the rate of records logged
+// with the same key, e.g. by a hot error path. Time is divided in windows of
+// length tick. In each window, the first initial records of a key are passed
+// to logger, then every thereafter-th one; the others are dropped. If
+// thereafter isn't positive, all records of a key beyond the first initial
+// ones of the window are dropped.
+//
+// So that dropped records don't go unnoticed, once a window is over, the
+// next record logged is preceded by a summary record for each key with
+// dropped records in the window, logged with the keyvals
+//
+//	"msg", "sampled records dropped", "key", key, "dropped", count
+//
+// keyFn returns the key of a record from its keyvals. If it's nil, the key
+// is the value of the "msg" key of the record, or of its "err" key if it has
+// no "msg" key.
+func NewSamplingLogger(logger Logger, initial, thereafter int, tick time.Duration, keyFn func(keyvals ...interface{}) string) Logger {
+	return newSamplingLogger(logger, initial, thereafter, tick, keyFn, time.Now)
+}
+
+func newSamplingLogger(logger Logger, initial, thereafter int, tick time.Duration, keyFn func(keyvals ...interface{}) string, now func() time.Time) Logger {
+	if keyFn == nil {
+		keyFn = defaultSamplingKey
+	}
+	return &samplingLogger{
+		logger:     logger,
+		initial:    initial,
+		thereafter: thereafter,
+		tick:       tick,
+		keyFn:      keyFn,
+		now:        now,
+		counts:     map[string]*sampleCount{},
+	}
+}
+
+type samplingLogger struct {
+	logger     Logger
+	initial    int
+	thereafter int
+	tick       time.Duration
+	keyFn      func(keyvals ...interface{}) string
+	now        func() time.Time
+
+	mtx       sync.Mutex
+	windowEnd time.Time
+	counts    map[string]*sampleCount
+}
+
+// sampleCount counts the records of a key in the current window.
+type sampleCount struct {
+	seen    int
+	dropped int
+}
+
+// Log implements Logger.
+func (l *samplingLogger) Log(keyvals ...interface{}) error {
+	key := l.keyFn(keyvals...)
+
+	l.mtx.Lock()
+	summaries := l.roll(l.now())
+	c, ok := l.counts[key]
+	if !ok {
+		c = &sampleCount{}
+		l.counts[key] = c
+	}
+	c.seen++
+	pass := c.seen <= l.initial || l.thereafter > 0 && (c.seen-l.initial)%l.thereafter == 0
+	if !pass {
+		c.dropped++
+	}
+	l.mtx.Unlock()
+
+	for _, summary := range summaries {
+		if err := l.logger.Log(summary...); err != nil {
+			return err
+		}
+	}
+	if !pass {
+		return nil
+	}
+	return l.logger.Log(keyvals...)
+}
+
+// roll starts a new window if the current one is over at now, and returns
+// the summary records of the keys with dropped records in the window that
+// ended, sorted by key. It must be called with l.mtx held.
+func (l *samplingLogger) roll(now time.Time) [][]interface{} {
+	if now.Before(l.windowEnd) {
+		return nil
+	}
+	var summaries [][]interface{}
+	if !l.windowEnd.IsZero() {
+		keys := make([]string, 0, len(l.counts))
+		for key, c := range l.counts {
+			if c.dropped > 0 {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			summaries = append(summaries, []interface{}{
+				"msg", "sampled records dropped", "key", key, "dropped", l.counts[key].dropped,
+			})
+		}
+		l.counts = map[string]*sampleCount{}
+	}
+	if l.windowEnd.IsZero() || l.tick <= 0 {
+		l.windowEnd = now.Add(l.tick)
+	} else {
+		// Keep windows aligned, skipping those without records.
+		l.windowEnd = l.windowEnd.Add((now.Sub(l.windowEnd)/l.tick + 1) * l.tick)
+	}
+	return summaries
+}
+
+func defaultSamplingKey(keyvals ...interface{}) string {
+	var key interface{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case "msg":
+			return fmt.Sprint(keyvals[i+1])
+		case "err":
+			if key == nil {
+				key = keyvals[i+1]
+			}
+		}
+	}
+	if key == nil {
+		return ""
+	}
+	return fmt.Sprint(key)
+}
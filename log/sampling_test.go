@@ -0,0 +1,127 @@
+package log_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// fakeClock is a clock advanced by tests, safe for concurrent use.
+type fakeClock struct {
+	mtx sync.Mutex
+	t   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// recordingLogger records the keyvals of the records it logs, formatted.
+type recordingLogger struct {
+	mtx     sync.Mutex
+	records []string
+}
+
+func (l *recordingLogger) Log(keyvals ...interface{}) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.records = append(l.records, fmt.Sprintf("%v", keyvals))
+	return nil
+}
+
+func (l *recordingLogger) take() map[string]int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	counts := map[string]int{}
+	for _, r := range l.records {
+		counts[r]++
+	}
+	l.records = nil
+	return counts
+}
+
+func TestSamplingLogger(t *testing.T) {
+	var (
+		clock  = &fakeClock{t: time.Date(2017, time.May, 1, 12, 0, 0, 0, time.UTC)}
+		next   = &recordingLogger{}
+		logger = log.NewSamplingLoggerWithClock(next, 2, 3, time.Second, nil, clock.Now)
+	)
+
+	logConcurrently := func(goroutines, n int, keyvals ...interface{}) {
+		var wg sync.WaitGroup
+		wg.Add(goroutines)
+		for i := 0; i < goroutines; i++ {
+			go func() {
+				defer wg.Done()
+				for j := 0; j < n; j++ {
+					logger.Log(keyvals...)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	// 80 records of a: the first 2, then every 3rd, 28 in all, pass. 5
+	// records of the error b: 2, then 1. Keys are counted separately.
+	logConcurrently(8, 10, "msg", "a")
+	logConcurrently(5, 1, "err", errors.New("b"))
+	want := map[string]int{"[msg a]": 28, "[err b]": 3}
+	if have := next.take(); fmt.Sprint(want) != fmt.Sprint(have) {
+		t.Errorf("first window: want %v, have %v", want, have)
+	}
+
+	// Still in the first window: a is at its 81st record, dropped.
+	clock.Add(999 * time.Millisecond)
+	logger.Log("msg", "a")
+	if have := next.take(); len(have) != 0 {
+		t.Errorf("end of first window: want no records, have %v", have)
+	}
+
+	// The next window starts with the summaries of the first one, and counts
+	// from scratch.
+	clock.Add(time.Millisecond)
+	logConcurrently(4, 1, "msg", "a")
+	want = map[string]int{
+		"[msg sampled records dropped key a dropped 53]": 1,
+		"[msg sampled records dropped key b dropped 2]":  1,
+		"[msg a]": 2,
+	}
+	if have := next.take(); fmt.Sprint(want) != fmt.Sprint(have) {
+		t.Errorf("second window: want %v, have %v", want, have)
+	}
+
+	// Windows without records are skipped, and their summaries are empty.
+	clock.Add(10 * time.Second)
+	logger.Log("msg", "a")
+	want = map[string]int{"[msg sampled records dropped key a dropped 2]": 1, "[msg a]": 1}
+	if have := next.take(); fmt.Sprint(want) != fmt.Sprint(have) {
+		t.Errorf("third window: want %v, have %v", want, have)
+	}
+}
+
+func TestSamplingLoggerKeyFunc(t *testing.T) {
+	next := &recordingLogger{}
+	byUser := func(keyvals ...interface{}) string { return fmt.Sprint(keyvals[1]) }
+	logger := log.NewSamplingLogger(next, 1, 0, time.Hour, byUser)
+
+	for i := 0; i < 3; i++ {
+		logger.Log("user", "alice", "n", i)
+		logger.Log("user", "bob", "n", i)
+	}
+	want := map[string]int{"[user alice n 0]": 1, "[user bob n 0]": 1}
+	if have := next.take(); fmt.Sprint(want) != fmt.Sprint(have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
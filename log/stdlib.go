@@ -23,12 +23,16 @@ func (w StdlibWriter) Write(p []byte) (int, error) {
 
 // StdlibAdapter wraps a Logger and allows it to be passed to the stdlib
 // logger's SetOutput. It will extract date/timestamps, filenames, and
-// messages, and place them under relevant keys.
+// messages, and place them under relevant keys. Lines it can't parse are
+// logged whole, as the message.
 type StdlibAdapter struct {
 	Logger
-	timestampKey string
-	fileKey      string
-	messageKey   string
+	timestampKey    string
+	dateKey         string
+	fileKey         string
+	messageKey      string
+	prefix          string
+	joinPrefixToMsg bool
 }
 
 // StdlibAdapterOption sets a parameter for the StdlibAdapter.
@@ -39,7 +43,14 @@ func TimestampKey(key string) StdlibAdapterOption {
 	return func(a *StdlibAdapter) { a.timestampKey = key }
 }
 
-// FileKey sets the key for the file and line field. By default, it's "file".
+// DateKey logs the date under its own key, rather than joined with the time
+// under the timestamp key. By default, the date and time are joined.
+func DateKey(key string) StdlibAdapterOption {
+	return func(a *StdlibAdapter) { a.dateKey = key }
+}
+
+// FileKey sets the key for the file and line field, e.g. "caller". By
+// default, it's "file".
 func FileKey(key string) StdlibAdapterOption {
 	return func(a *StdlibAdapter) { a.fileKey = key }
 }
@@ -49,6 +60,17 @@ func MessageKey(key string) StdlibAdapterOption {
 	return func(a *StdlibAdapter) { a.messageKey = key }
 }
 
+// Prefix sets the prefix of the stdlib logger, so it can be removed from the
+// lines before they're parsed, whether it's at the start of the line or, with
+// the stdlib Lmsgprefix flag, of the message. If joinPrefixToMsg is true, the
+// prefix is kept at the start of the message.
+func Prefix(prefix string, joinPrefixToMsg bool) StdlibAdapterOption {
+	return func(a *StdlibAdapter) {
+		a.prefix = prefix
+		a.joinPrefixToMsg = joinPrefixToMsg
+	}
+}
+
 // NewStdlibAdapter returns a new StdlibAdapter wrapper around the passed
 // logger. It's designed to be passed to log.SetOutput.
 func NewStdlibAdapter(logger Logger, options ...StdlibAdapterOption) io.Writer {
@@ -65,11 +87,20 @@ func NewStdlibAdapter(logger Logger, options ...StdlibAdapterOption) io.Writer {
 }
 
 func (a StdlibAdapter) Write(p []byte) (int, error) {
-	result := subexps(p)
+	line := strings.TrimSuffix(string(p), "\n")
+	prefixed := a.prefix != "" && strings.HasPrefix(line, a.prefix)
+	if prefixed {
+		line = line[len(a.prefix):]
+	}
+	result := subexps([]byte(line))
 	keyvals := []interface{}{}
 	var timestamp string
 	if date, ok := result["date"]; ok && date != "" {
-		timestamp = date
+		if a.dateKey != "" {
+			keyvals = append(keyvals, a.dateKey, date)
+		} else {
+			timestamp = date
+		}
 	}
 	if time, ok := result["time"]; ok && time != "" {
 		if timestamp != "" {
@@ -84,6 +115,13 @@ func (a StdlibAdapter) Write(p []byte) (int, error) {
 		keyvals = append(keyvals, a.fileKey, file)
 	}
 	if msg, ok := result["msg"]; ok {
+		if a.prefix != "" && !prefixed {
+			// The stdlib logger wrote the prefix before the message.
+			msg = strings.TrimPrefix(msg, a.prefix)
+		}
+		if a.joinPrefixToMsg {
+			msg = a.prefix + msg
+		}
 		keyvals = append(keyvals, a.messageKey, msg)
 	}
 	if err := a.Logger.Log(keyvals...); err != nil {
@@ -95,8 +133,8 @@ func (a StdlibAdapter) Write(p []byte) (int, error) {
 const (
 	logRegexpDate = `(?P<date>[0-9]{4}/[0-9]{2}/[0-9]{2})?[ ]?`
 	logRegexpTime = `(?P<time>[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?)?[ ]?`
-	logRegexpFile = `(?P<file>.+?:[0-9]+)?`
-	logRegexpMsg  = `(: )?(?P<msg>.*)`
+	logRegexpFile = `((?P<file>.+?\.go:[0-9]+|\?\?\?:[0-9]+): )?`
+	logRegexpMsg  = `(: )?(?P<msg>(?s:.*))`
 )
 
 var (
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 )
@@ -35,9 +37,9 @@ func TestStdlibAdapterUsage(t *testing.T) {
 		log.Ldate:                              "ts=" + date + " msg=hello\n",
 		log.Ltime:                              "ts=" + time + " msg=hello\n",
 		log.Ldate | log.Ltime:                  "ts=\"" + date + " " + time + "\" msg=hello\n",
-		log.Lshortfile:                         "file=stdlib_test.go:44 msg=hello\n",
-		log.Lshortfile | log.Ldate:             "ts=" + date + " file=stdlib_test.go:44 msg=hello\n",
-		log.Lshortfile | log.Ldate | log.Ltime: "ts=\"" + date + " " + time + "\" file=stdlib_test.go:44 msg=hello\n",
+		log.Lshortfile:                         "file=stdlib_test.go:46 msg=hello\n",
+		log.Lshortfile | log.Ldate:             "ts=" + date + " file=stdlib_test.go:46 msg=hello\n",
+		log.Lshortfile | log.Ldate | log.Ltime: "ts=\"" + date + " " + time + "\" file=stdlib_test.go:46 msg=hello\n",
 	} {
 		buf.Reset()
 		stdlog.SetFlags(flag)
@@ -53,11 +55,11 @@ func TestStdLibAdapterExtraction(t *testing.T) {
 	logger := NewLogfmtLogger(buf)
 	writer := NewStdlibAdapter(logger)
 	for input, want := range map[string]string{
-		"hello":                                            "msg=hello\n",
-		"2009/01/23: hello":                                "ts=2009/01/23 msg=hello\n",
-		"2009/01/23 01:23:23: hello":                       "ts=\"2009/01/23 01:23:23\" msg=hello\n",
-		"01:23:23: hello":                                  "ts=01:23:23 msg=hello\n",
-		"2009/01/23 01:23:23.123123: hello":                "ts=\"2009/01/23 01:23:23.123123\" msg=hello\n",
+		"hello":                             "msg=hello\n",
+		"2009/01/23: hello":                 "ts=2009/01/23 msg=hello\n",
+		"2009/01/23 01:23:23: hello":        "ts=\"2009/01/23 01:23:23\" msg=hello\n",
+		"01:23:23: hello":                   "ts=01:23:23 msg=hello\n",
+		"2009/01/23 01:23:23.123123: hello": "ts=\"2009/01/23 01:23:23.123123\" msg=hello\n",
 		"2009/01/23 01:23:23.123123 /a/b/c/d.go:23: hello": "ts=\"2009/01/23 01:23:23.123123\" file=/a/b/c/d.go:23 msg=hello\n",
 		"01:23:23.123123 /a/b/c/d.go:23: hello":            "ts=01:23:23.123123 file=/a/b/c/d.go:23 msg=hello\n",
 		"2009/01/23 01:23:23 /a/b/c/d.go:23: hello":        "ts=\"2009/01/23 01:23:23\" file=/a/b/c/d.go:23 msg=hello\n",
@@ -203,3 +205,123 @@ func TestStdlibAdapterSubexps(t *testing.T) {
 		}
 	}
 }
+
+func TestStdlibAdapterFlags(t *testing.T) {
+	var (
+		dateRE      = regexp.MustCompile(`^[0-9]{4}/[0-9]{2}/[0-9]{2}$`)
+		timeRE      = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}$`)
+		microRE     = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}\.[0-9]{6}$`)
+		shortfileRE = regexp.MustCompile(`^stdlib_test\.go:[0-9]+$`)
+		longfileRE  = regexp.MustCompile(`^/.+/log/stdlib_test\.go:[0-9]+$`)
+	)
+	const (
+		prefix  = "[app] "
+		message = "dial tcp 10.0.0.1:80: connection refused"
+	)
+	flags := []int{log.Ldate, log.Ltime, log.Lmicroseconds, log.Llongfile, log.Lshortfile, log.LUTC, log.Lmsgprefix}
+
+	for combination := 0; combination < 1<<uint(len(flags)); combination++ {
+		var flag int
+		for i, f := range flags {
+			if combination&(1<<uint(i)) != 0 {
+				flag |= f
+			}
+		}
+		for _, join := range []bool{false, true} {
+			have := map[string]string{}
+			logger := LoggerFunc(func(keyvals ...interface{}) error {
+				for i := 0; i < len(keyvals); i += 2 {
+					have[keyvals[i].(string)] = keyvals[i+1].(string)
+				}
+				return nil
+			})
+			options := []StdlibAdapterOption{Prefix(prefix, join)}
+			if combination%2 == 0 {
+				// Half of the combinations log the date under its own key.
+				options = append(options, DateKey("date"))
+			}
+			log.New(NewStdlibAdapter(logger, options...), prefix, flag).Print(message)
+
+			var (
+				name     = fmt.Sprintf("flag=%d join=%v", flag, join)
+				wantKeys = map[string]bool{"msg": true}
+				hasDate  = flag&log.Ldate != 0
+				hasTime  = flag&(log.Ltime|log.Lmicroseconds) != 0
+			)
+			var timestamp string
+			switch {
+			case hasDate && combination%2 == 0:
+				wantKeys["date"] = true
+				if !dateRE.MatchString(have["date"]) {
+					t.Errorf("%s: bad date %q", name, have["date"])
+				}
+				timestamp = have["ts"]
+			case hasDate:
+				timestamp = have["ts"]
+				if len(timestamp) < 10 || !dateRE.MatchString(timestamp[:10]) {
+					t.Errorf("%s: bad date in %q", name, timestamp)
+				}
+				timestamp = strings.TrimPrefix(timestamp[10:], " ")
+			default:
+				timestamp = have["ts"]
+			}
+			if hasTime || hasDate && combination%2 != 0 {
+				wantKeys["ts"] = true
+			}
+			if hasTime {
+				re := timeRE
+				if flag&log.Lmicroseconds != 0 {
+					re = microRE
+				}
+				if !re.MatchString(timestamp) {
+					t.Errorf("%s: bad time %q", name, timestamp)
+				}
+			}
+			switch {
+			case flag&log.Lshortfile != 0:
+				wantKeys["file"] = true
+				if !shortfileRE.MatchString(have["file"]) {
+					t.Errorf("%s: bad file %q", name, have["file"])
+				}
+			case flag&log.Llongfile != 0:
+				wantKeys["file"] = true
+				if !longfileRE.MatchString(have["file"]) {
+					t.Errorf("%s: bad file %q", name, have["file"])
+				}
+			}
+			wantMsg := message
+			if join {
+				wantMsg = prefix + message
+			}
+			if want, have := wantMsg, have["msg"]; want != have {
+				t.Errorf("%s: want msg %q, have %q", name, want, have)
+			}
+			for key := range have {
+				if !wantKeys[key] {
+					t.Errorf("%s: unexpected key %q", name, key)
+				}
+			}
+			for key := range wantKeys {
+				if _, ok := have[key]; !ok {
+					t.Errorf("%s: missing key %q", name, key)
+				}
+			}
+		}
+	}
+}
+
+func TestStdlibAdapterUnparsed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer := NewStdlibAdapter(NewLogfmtLogger(buf))
+	for input, want := range map[string]string{
+		"dial tcp 10.0.0.1:80: connection refused\n": "msg=\"dial tcp 10.0.0.1:80: connection refused\"\n",
+		"first line\nsecond line\n":                  "msg=\"first line\\nsecond line\"\n",
+		"???:0: no caller\n":                         "file=???:0 msg=\"no caller\"\n",
+	} {
+		buf.Reset()
+		fmt.Fprint(writer, input)
+		if have := buf.String(); want != have {
+			t.Errorf("%q: want %#v, have %#v", input, want, have)
+		}
+	}
+}
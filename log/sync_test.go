@@ -0,0 +1,113 @@
+package log_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// logLines logs n records of a long payload from each of g goroutines, and
+// returns an error unless the output has exactly the expected lines.
+func logLines(logger log.Logger, output func() string, g, n int) error {
+	payload := strings.Repeat("x", 8192)
+	var wg sync.WaitGroup
+	for i := 0; i < g; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				logger.Log("g", i, "n", j, "payload", payload)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(output(), "\n"), "\n")
+	if want, have := g*n, len(lines); want != have {
+		return fmt.Errorf("want %d lines, have %d", want, have)
+	}
+	seen := map[string]bool{}
+	for _, line := range lines {
+		var i, j int
+		var p string
+		if _, err := fmt.Sscanf(line, "g=%d n=%d payload=%s", &i, &j, &p); err != nil || p != payload {
+			return fmt.Errorf("corrupted line %.64q...", line)
+		}
+		seen[fmt.Sprint(i, j)] = true
+	}
+	if want, have := g*n, len(seen); want != have {
+		return fmt.Errorf("want %d distinct records, have %d", want, have)
+	}
+	return nil
+}
+
+func TestSyncWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := log.NewSyncWriter(&buf)
+	if err := logLines(log.NewLogfmtLogger(w), buf.String, 10, 50); err != nil {
+		t.Error(err)
+	}
+}
+
+// splitLogger is a logger which isn't safe for concurrent use: it writes a
+// record with a write per key/value pair.
+type splitLogger struct{ w io.Writer }
+
+func (l splitLogger) Log(keyvals ...interface{}) error {
+	for i := 0; i < len(keyvals); i += 2 {
+		sep := " "
+		if i+2 >= len(keyvals) {
+			sep = "\n"
+		}
+		fmt.Fprintf(l.w, "%v=%v%s", keyvals[i], keyvals[i+1], sep)
+	}
+	return nil
+}
+
+func TestSyncLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewSyncLogger(splitLogger{&buf})
+	if err := logLines(logger, buf.String, 10, 50); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSyncWriterFd(t *testing.T) {
+	f, err := os.Create(os.DevNull)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer f.Close()
+
+	w := log.NewSyncWriter(f)
+	fw, ok := w.(interface {
+		Fd() uintptr
+	})
+	if !ok {
+		t.Fatal("want the writer of a file to have an Fd method")
+	}
+	if want, have := f.Fd(), fw.Fd(); want != have {
+		t.Errorf("want fd %d, have %d", want, have)
+	}
+	if _, ok := log.NewSyncWriter(&bytes.Buffer{}).(interface {
+		Fd() uintptr
+	}); ok {
+		t.Error("want no Fd method for other writers")
+	}
+}
+
+func TestSyncLoggerConcurrency(t *testing.T) {
+	var buf bytes.Buffer
+	testConcurrency(t, log.NewSyncLogger(log.NewLogfmtLogger(&buf)))
+}
+
+func TestSyncWriterConcurrency(t *testing.T) {
+	var buf bytes.Buffer
+	testConcurrency(t, log.NewLogfmtLogger(log.NewSyncWriter(&buf)))
+}
@@ -0,0 +1,4 @@
+// Package syslog provides a Logger that writes to syslog, with the syslog
+// priority of each record chosen from its level. It isn't available on
+// Windows and Plan 9, which have no syslog.
+package syslog
@@ -0,0 +1,140 @@
+//go:build !windows && !plan9 && !nacl
+// +build !windows,!plan9,!nacl
+
+package syslog
+
+import (
+	"bytes"
+	"io"
+	gosyslog "log/syslog"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// SyslogWriter is the interface of the stdlib *syslog.Writer used by the
+// syslog logger, one method per priority.
+type SyslogWriter interface {
+	Write([]byte) (int, error)
+	Close() error
+	Emerg(string) error
+	Alert(string) error
+	Crit(string) error
+	Err(string) error
+	Warning(string) error
+	Notice(string) error
+	Info(string) error
+	Debug(string) error
+}
+
+// NewSyslogLogger returns a Logger writing to w, typically a *syslog.Writer.
+// Each record is encoded by a logger returned by newLogger, e.g.
+// log.NewLogfmtLogger or log.NewJSONLogger, and written with the priority
+// chosen by the PrioritySelector, which by default maps levels of the level
+// package to the debug, info, warning and err priorities, and records without
+// a level to info.
+func NewSyslogLogger(w SyslogWriter, newLogger func(io.Writer) log.Logger, options ...Option) log.Logger {
+	l := &syslogLogger{
+		w:                w,
+		newLogger:        newLogger,
+		prioritySelector: defaultPrioritySelector,
+		bufPool: sync.Pool{New: func() interface{} {
+			return &loggerBuf{}
+		}},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+type syslogLogger struct {
+	w                SyslogWriter
+	newLogger        func(io.Writer) log.Logger
+	prioritySelector PrioritySelector
+	bufPool          sync.Pool
+}
+
+// Log implements log.Logger.
+func (l *syslogLogger) Log(keyvals ...interface{}) error {
+	priority := l.prioritySelector(keyvals...)
+
+	lb := l.getLoggerBuf()
+	defer l.bufPool.Put(lb)
+	if err := lb.logger.Log(keyvals...); err != nil {
+		return err
+	}
+
+	switch priority {
+	case gosyslog.LOG_EMERG:
+		return l.w.Emerg(lb.buf.String())
+	case gosyslog.LOG_ALERT:
+		return l.w.Alert(lb.buf.String())
+	case gosyslog.LOG_CRIT:
+		return l.w.Crit(lb.buf.String())
+	case gosyslog.LOG_ERR:
+		return l.w.Err(lb.buf.String())
+	case gosyslog.LOG_WARNING:
+		return l.w.Warning(lb.buf.String())
+	case gosyslog.LOG_NOTICE:
+		return l.w.Notice(lb.buf.String())
+	case gosyslog.LOG_INFO:
+		return l.w.Info(lb.buf.String())
+	case gosyslog.LOG_DEBUG:
+		return l.w.Debug(lb.buf.String())
+	default:
+		_, err := l.w.Write(lb.buf.Bytes())
+		return err
+	}
+}
+
+// loggerBuf is an encoding logger with the buffer it writes to, reused
+// across records.
+type loggerBuf struct {
+	buf    *bytes.Buffer
+	logger log.Logger
+}
+
+func (l *syslogLogger) getLoggerBuf() *loggerBuf {
+	lb := l.bufPool.Get().(*loggerBuf)
+	if lb.buf == nil {
+		lb.buf = &bytes.Buffer{}
+		lb.logger = l.newLogger(lb.buf)
+	} else {
+		lb.buf.Reset()
+	}
+	return lb
+}
+
+// Option sets a parameter for syslog loggers.
+type Option func(*syslogLogger)
+
+// PrioritySelector inspects the keyvals of a record and selects its syslog
+// priority.
+type PrioritySelector func(keyvals ...interface{}) gosyslog.Priority
+
+// PrioritySelectorOption sets the function choosing the syslog priority of
+// records.
+func PrioritySelectorOption(selector PrioritySelector) Option {
+	return func(l *syslogLogger) { l.prioritySelector = selector }
+}
+
+func defaultPrioritySelector(keyvals ...interface{}) gosyslog.Priority {
+	for i := 0; i < len(keyvals); i += 2 {
+		if keyvals[i] != level.Key() || i+1 == len(keyvals) {
+			continue
+		}
+		switch keyvals[i+1] {
+		case level.DebugValue():
+			return gosyslog.LOG_DEBUG
+		case level.InfoValue():
+			return gosyslog.LOG_INFO
+		case level.WarnValue():
+			return gosyslog.LOG_WARNING
+		case level.ErrorValue():
+			return gosyslog.LOG_ERR
+		}
+	}
+	return gosyslog.LOG_INFO
+}
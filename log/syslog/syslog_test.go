@@ -0,0 +1,97 @@
+//go:build !windows && !plan9 && !nacl
+// +build !windows,!plan9,!nacl
+
+package syslog_test
+
+import (
+	"fmt"
+	gosyslog "log/syslog"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/log/syslog"
+)
+
+func TestSyslogLoggerDefaultPrioritySelector(t *testing.T) {
+	w := &testSyslogWriter{}
+	l := syslog.NewSyslogLogger(w, log.NewLogfmtLogger)
+
+	level.Warn(l).Log("msg", "one")
+	l.Log("level", "undefined", "msg", "two")
+	level.Info(l).Log("msg", "three")
+	level.Error(l).Log("msg", "four")
+	level.Debug(l).Log("msg", "five")
+	l.Log("msg", "six", level.Key(), level.ErrorValue())
+	l.Log("msg", "seven", level.Key())
+
+	want := []string{
+		"warning: level=warn msg=one\n",
+		"info: level=undefined msg=two\n",
+		"info: level=info msg=three\n",
+		"err: level=error msg=four\n",
+		"debug: level=debug msg=five\n",
+		"err: msg=six level=error\n",
+		"info: msg=seven level=null\n",
+	}
+	if have := w.writes; !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %q\nhave %q", want, have)
+	}
+}
+
+func TestSyslogLoggerPrioritySelectorOption(t *testing.T) {
+	w := &testSyslogWriter{}
+	selector := func(keyvals ...interface{}) gosyslog.Priority {
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			if keyvals[i] == "severity" {
+				switch keyvals[i+1] {
+				case "fatal":
+					return gosyslog.LOG_CRIT
+				case "audit":
+					return gosyslog.LOG_NOTICE
+				}
+			}
+		}
+		return gosyslog.LOG_ALERT
+	}
+	l := syslog.NewSyslogLogger(w, log.NewJSONLogger, syslog.PrioritySelectorOption(selector))
+
+	l.Log("severity", "fatal", "msg", "one")
+	l.Log("severity", "audit", "msg", "two")
+	l.Log("msg", "three")
+
+	want := []string{
+		`crit: {"msg":"one","severity":"fatal"}` + "\n",
+		`notice: {"msg":"two","severity":"audit"}` + "\n",
+		`alert: {"msg":"three"}` + "\n",
+	}
+	if have := w.writes; !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %q\nhave %q", want, have)
+	}
+}
+
+type testSyslogWriter struct {
+	writes []string
+}
+
+func (w *testSyslogWriter) Write(b []byte) (int, error) {
+	w.writes = append(w.writes, "write: "+string(b))
+	return len(b), nil
+}
+
+func (w *testSyslogWriter) Close() error { return nil }
+
+func (w *testSyslogWriter) Emerg(m string) error   { return w.write("emerg", m) }
+func (w *testSyslogWriter) Alert(m string) error   { return w.write("alert", m) }
+func (w *testSyslogWriter) Crit(m string) error    { return w.write("crit", m) }
+func (w *testSyslogWriter) Err(m string) error     { return w.write("err", m) }
+func (w *testSyslogWriter) Warning(m string) error { return w.write("warning", m) }
+func (w *testSyslogWriter) Notice(m string) error  { return w.write("notice", m) }
+func (w *testSyslogWriter) Info(m string) error    { return w.write("info", m) }
+func (w *testSyslogWriter) Debug(m string) error   { return w.write("debug", m) }
+
+func (w *testSyslogWriter) write(priority, m string) error {
+	w.writes = append(w.writes, fmt.Sprintf("%s: %s", priority, m))
+	return nil
+}
@@ -0,0 +1,45 @@
+package log
+
+import "strings"
+
+// MultiError is returned by a tee logger when some of its loggers fail. It
+// holds their errors, in the order of the loggers.
+type MultiError []error
+
+// Error implements the error interface.
+func (e MultiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type teeLogger []Logger
+
+// NewTee returns a logger passing each record to all the given loggers, in
+// order. A failing logger doesn't prevent the others from logging the
+// record; the errors are returned in a MultiError. Valuers are bound once per
+// record, before it's passed on, so all the loggers log the same values, e.g.
+// the same timestamp.
+func NewTee(loggers ...Logger) Logger {
+	return teeLogger(append([]Logger(nil), loggers...))
+}
+
+// Log implements Logger.
+func (t teeLogger) Log(keyvals ...interface{}) error {
+	if containsValuer(keyvals) {
+		keyvals = append([]interface{}{}, keyvals...)
+		bindValues(keyvals)
+	}
+	var errs MultiError
+	for _, logger := range t {
+		if err := logger.Log(keyvals...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestTee(t *testing.T) {
+	var (
+		first, second = log.NewRingBuffer(10), log.NewRingBuffer(10)
+		calls         int
+		counter       = log.Valuer(func() interface{} { calls++; return calls })
+	)
+	tee := log.NewTee(first, second)
+
+	if err := tee.Log("msg", "direct", "n", counter); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.NewContext(tee).With("n", counter).Log("msg", "context"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]interface{}{
+		{"msg", "direct", "n", 1},
+		{"n", 2, "msg", "context"},
+	}
+	if have := first.Snapshot(); !reflect.DeepEqual(want, have) {
+		t.Errorf("first sink: want %v, have %v", want, have)
+	}
+	if have := second.Snapshot(); !reflect.DeepEqual(want, have) {
+		t.Errorf("second sink: want %v, have %v", want, have)
+	}
+}
+
+func TestTeeErrors(t *testing.T) {
+	var (
+		errFirst  = errors.New("first")
+		errThird  = errors.New("third")
+		buf       bytes.Buffer
+		failing   = func(err error) log.Logger { return log.LoggerFunc(func(...interface{}) error { return err }) }
+		succeeded = log.NewLogfmtLogger(&buf)
+	)
+	err := log.NewTee(failing(errFirst), succeeded, failing(errThird)).Log("k", "v")
+	if want, have := (log.MultiError{errFirst, errThird}), err; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := "first; third", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "k=v\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	if err := log.NewTee(succeeded).Log("k", "v"); err != nil {
+		t.Errorf("want nil error, have %v", err)
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	r := log.NewRingBuffer(2)
+	if want, have := 0, len(r.Snapshot()); want != have {
+		t.Errorf("want %d records, have %d", want, have)
+	}
+
+	keyvals := []interface{}{"n", 1}
+	r.Log(keyvals...)
+	keyvals[1] = 2 // the buffer keeps its own copy
+	r.Log(keyvals...)
+	r.Log("n", 3)
+
+	want := [][]interface{}{{"n", 2}, {"n", 3}}
+	if have := r.Snapshot(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 )
 
 // Color represents an ANSI color. The zero value is Default.
@@ -74,6 +75,33 @@ func (c FgBgColor) isZero() bool {
 	return c.Fg == Default && c.Bg == Default
 }
 
+// LevelColor is a color function for NewColorLogger and NewLogger, which
+// colors records by their level: errors red, warnings yellow and debug
+// records dark gray. The level is the value under level.Key(), as added by
+// package level, or under a "level" string key, e.g. "error". Other records
+// aren't colored.
+func LevelColor(keyvals ...interface{}) FgBgColor {
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if keyvals[i] != level.Key() && keyvals[i] != "level" {
+			continue
+		}
+		name := keyvals[i+1]
+		if v, ok := name.(level.Value); ok {
+			name = v.String()
+		}
+		switch name {
+		case "error":
+			return FgBgColor{Fg: Red}
+		case "warn":
+			return FgBgColor{Fg: Yellow}
+		case "debug":
+			return FgBgColor{Fg: DarkGray}
+		}
+		return FgBgColor{}
+	}
+	return FgBgColor{}
+}
+
 // NewColorLogger returns a Logger which writes colored logs to w. ANSI color
 // codes for the colors returned by color are added to the formatted output
 // from the Logger returned by newLogger and the combined result written to w.
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/go-kit/kit/log/term"
 )
 
@@ -32,6 +33,31 @@ func TestColorLogger(t *testing.T) {
 	}
 }
 
+func TestLevelColor(t *testing.T) {
+	var buf bytes.Buffer
+	logger := term.NewColorLogger(&buf, log.NewLogfmtLogger, term.LevelColor)
+
+	for _, tc := range []struct {
+		log  func() error
+		want string
+	}{
+		{func() error { return level.Error(logger).Log("msg", "m") }, "\x1b[31;1mlevel=error msg=m\n\x1b[39;49m"},
+		{func() error { return level.Warn(logger).Log("msg", "m") }, "\x1b[33;1mlevel=warn msg=m\n\x1b[39;49m"},
+		{func() error { return level.Debug(logger).Log("msg", "m") }, "\x1b[30;1mlevel=debug msg=m\n\x1b[39;49m"},
+		{func() error { return logger.Log("level", "error", "msg", "m") }, "\x1b[31;1mlevel=error msg=m\n\x1b[39;49m"},
+		{func() error { return level.Info(logger).Log("msg", "m") }, "level=info msg=m\n"},
+		{func() error { return logger.Log("msg", "m") }, "msg=m\n"},
+	} {
+		buf.Reset()
+		if err := tc.log(); err != nil {
+			t.Fatal(err)
+		}
+		if have := buf.String(); tc.want != have {
+			t.Errorf("\nwant %#v\nhave %#v", tc.want, have)
+		}
+	}
+}
+
 func newColorLogger(w io.Writer) log.Logger {
 	return term.NewColorLogger(w, log.NewLogfmtLogger,
 		func(keyvals ...interface{}) term.FgBgColor {
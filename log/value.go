@@ -41,22 +41,24 @@ func Timestamp(t func() time.Time) Valuer {
 
 var (
 	// DefaultTimestamp is a Valuer that returns the current wallclock time,
-	// respecting time zones, when bound.
-	DefaultTimestamp Valuer = func() interface{} { return time.Now().Format(time.RFC3339) }
+	// respecting time zones, in RFC3339Nano format when bound.
+	DefaultTimestamp Valuer = func() interface{} { return time.Now().Format(time.RFC3339Nano) }
 
-	// DefaultTimestampUTC is a Valuer that returns the current time in UTC
-	// when bound.
-	DefaultTimestampUTC Valuer = func() interface{} { return time.Now().UTC().Format(time.RFC3339) }
+	// DefaultTimestampUTC is a Valuer that returns the current time in UTC,
+	// in RFC3339Nano format when bound.
+	DefaultTimestampUTC Valuer = func() interface{} { return time.Now().UTC().Format(time.RFC3339Nano) }
 )
 
 // Caller returns a Valuer that returns a file and line from a specified depth
-// in the callstack. Users will probably want to use DefaultCaller.
+// in the callstack, formatted as file:line with the file trimmed to its last
+// path element. Users will probably want to use DefaultCaller.
 func Caller(depth int) Valuer {
 	return func() interface{} { return stack.Caller(depth) }
 }
 
 var (
 	// DefaultCaller is a Valuer that returns the file and line where the Log
-	// method was invoked. It can only be used with log.With.
+	// method was invoked. It can only be used with log.With, or the With and
+	// WithPrefix methods of Context.
 	DefaultCaller = Caller(3)
 )
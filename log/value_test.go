@@ -107,3 +107,52 @@ func BenchmarkValueBindingCaller(b *testing.B) {
 		lc.Log("k", "v")
 	}
 }
+
+func TestDefaultTimestamp(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		valuer log.Valuer
+		utc    bool
+	}{
+		{"DefaultTimestamp", log.DefaultTimestamp, false},
+		{"DefaultTimestampUTC", log.DefaultTimestampUTC, true},
+	} {
+		s, ok := tc.valuer().(string)
+		if !ok {
+			t.Fatalf("%s: want string, have %T", tc.name, tc.valuer())
+		}
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if d := time.Since(ts); d < 0 || d > time.Minute {
+			t.Errorf("%s: want the current time, have %s", tc.name, s)
+		}
+		if tc.utc && ts.Location() != time.UTC {
+			t.Errorf("%s: want UTC, have %s", tc.name, s)
+		}
+	}
+}
+
+func TestValueBindingWithLayers(t *testing.T) {
+	var output []interface{}
+
+	logger := log.Logger(log.LoggerFunc(func(keyvals ...interface{}) error {
+		output = keyvals
+		return nil
+	}))
+
+	// However many times the context is extended, the caller is the call
+	// site of Log, since each layer is merged into a single Context.
+	lc := log.With(logger, "caller", log.DefaultCaller)
+	lc = lc.With("a", 1).WithPrefix("b", 2)
+	lc = log.With(lc, "c", 3)
+
+	lc.Log("foo", "bar")
+	if want, have := "value_test.go:151", fmt.Sprint(output[3]); want != have {
+		t.Errorf("output[3]: want %s, have %s", want, have)
+	}
+	if want, have := "b", output[0]; want != have {
+		t.Errorf("output[0]: want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"io"
+	"log"
+	"strings"
+)
+
+// NewWriter returns an io.Writer logging each line written to it with logger,
+// as the message of a log event under the "msg" key, without the line
+// terminator. It's designed for APIs logging to an io.Writer or a stdlib
+// *log.Logger, like http.Server's ErrorLog, so their output flows through the
+// structured logger; see NewStdlibLogger. Each Write is taken to hold whole
+// lines, as the stdlib logger writes them. Empty lines are skipped.
+func NewWriter(logger Logger, options ...WriterOption) io.Writer {
+	w := &writer{logger: logger}
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+// NewStdlibLogger returns a stdlib *log.Logger writing to NewWriter(logger,
+// options...). It has no prefix nor flags, so the lines are logged as they
+// were printed; timestamps and callers are the business of logger.
+func NewStdlibLogger(logger Logger, options ...WriterOption) *log.Logger {
+	return log.New(NewWriter(logger, options...), "", 0)
+}
+
+// WriterOption sets a parameter for the writer returned by NewWriter.
+type WriterOption func(*writer)
+
+// WriterKeyvals prepends keyvals to those of each log event, e.g. to tag the
+// output of an http.Server with a level:
+//
+//	log.WriterKeyvals(level.Key(), level.ErrorValue())
+func WriterKeyvals(keyvals ...interface{}) WriterOption {
+	return func(w *writer) { w.logger = WithPrefix(w.logger, keyvals...) }
+}
+
+type writer struct {
+	logger Logger
+}
+
+// Write implements io.Writer, logging one event per line of p.
+func (w *writer) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		if err := w.logger.Log("msg", line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,81 @@
+package log_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+func TestWriter(t *testing.T) {
+	var output []string
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		output = append(output, fmt.Sprint(keyvals...))
+		return nil
+	})
+	w := log.NewWriter(logger, log.WriterKeyvals("level", "warn"))
+
+	for _, tc := range []struct {
+		input string
+		want  []string
+	}{
+		{"one line\n", []string{"levelwarnmsgone line"}},
+		{"no terminator", []string{"levelwarnmsgno terminator"}},
+		{"first\nsecond\r\n\nthird\n", []string{"levelwarnmsgfirst", "levelwarnmsgsecond", "levelwarnmsgthird"}},
+		{"\n", nil},
+	} {
+		output = nil
+		n, err := fmt.Fprint(w, tc.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := len(tc.input), n; want != have {
+			t.Errorf("%q: want %d bytes written, have %d", tc.input, want, have)
+		}
+		if want, have := fmt.Sprint(tc.want), fmt.Sprint(output); want != have {
+			t.Errorf("%q: want %s, have %s", tc.input, want, have)
+		}
+	}
+}
+
+func TestStdlibLoggerHTTPServer(t *testing.T) {
+	records := make(chan []interface{}, 10)
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		records <- keyvals
+		return nil
+	})
+
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.Config.ErrorLog = log.NewStdlibLogger(logger, log.WriterKeyvals(level.Key(), level.ErrorValue()))
+	server.StartTLS()
+	defer server.Close()
+
+	// A client not speaking TLS fails the handshake.
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(conn, "not a TLS handshake\n")
+	defer conn.Close()
+
+	select {
+	case keyvals := <-records:
+		if want, have := 4, len(keyvals); want != have {
+			t.Fatalf("want %d keyvals, have %d: %v", want, have, keyvals)
+		}
+		if want, have := level.ErrorValue(), keyvals[1]; want != have {
+			t.Errorf("want level %v, have %v", want, have)
+		}
+		if msg := fmt.Sprint(keyvals[3]); !strings.HasPrefix(msg, "http: TLS handshake error from ") || strings.HasSuffix(msg, "\n") {
+			t.Errorf("want TLS handshake error message, have %q", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no record of the TLS handshake error")
+	}
+}
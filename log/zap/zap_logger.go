@@ -0,0 +1,179 @@
+// Package zap provides a Go kit logger sending its events to a zap logger,
+// for codebases migrating from one to the other.
+//
+// Values of common types are mapped to zap fields of the matching type, e.g.
+// zap.String or zap.Duration, which zap encodes without reflection. Other
+// values go through zap.Any.
+package zap
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Option sets a parameter for the logger.
+type Option func(*zapLogger)
+
+// LevelKey sets the key of the level. By default, it's level.Key(), which
+// the filters of package level require, and renders as "level".
+func LevelKey(key string) Option {
+	return func(l *zapLogger) { l.levelKey = key }
+}
+
+// MessageKey sets the key of the message. By default, it's "msg".
+func MessageKey(key string) Option {
+	return func(l *zapLogger) { l.messageKey = key }
+}
+
+// DefaultLevel sets the zap level of events without a known level. By
+// default, it's the info level.
+func DefaultLevel(lvl zapcore.Level) Option {
+	return func(l *zapLogger) { l.defaultLevel = lvl }
+}
+
+type zapLogger struct {
+	logger       *zap.Logger
+	levelKey     interface{}
+	levelName    string // string form of levelKey
+	messageKey   string
+	defaultLevel zapcore.Level
+}
+
+// NewZapLogger returns a Go kit logger sending its events to a zap logger.
+// The value under the level key, a value of package level or a zap level name
+// like "warn", selects the zap level; events without a known level are logged
+// at the default level. DPanic, panic and fatal levels are logged at the error
+// level, as a Log call mustn't panic or exit. The value under the message key
+// is the message of the entry, and the other keyvals become its fields.
+// Fields are only built if the zap logger is enabled at the level.
+func NewZapLogger(logger *zap.Logger, options ...Option) log.Logger {
+	l := &zapLogger{
+		logger:       logger,
+		levelKey:     level.Key(),
+		messageKey:   "msg",
+		defaultLevel: zapcore.InfoLevel,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	l.levelName = key(l.levelKey)
+	return l
+}
+
+// Log implements log.Logger.
+func (l *zapLogger) Log(keyvals ...interface{}) error {
+	var (
+		lvl      = l.defaultLevel
+		msg      string
+		levelIdx = -1
+		msgIdx   = -1
+	)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			break
+		}
+		switch {
+		case l.isLevelKey(keyvals[i]):
+			if parsed, ok := parseLevel(keyvals[i+1]); ok {
+				lvl, levelIdx = parsed, i
+			}
+		case key(keyvals[i]) == l.messageKey:
+			msg, msgIdx = fmt.Sprint(keyvals[i+1]), i
+		}
+	}
+	if lvl > zapcore.ErrorLevel {
+		lvl = zapcore.ErrorLevel
+	}
+
+	ce := l.logger.Check(lvl, msg)
+	if ce == nil {
+		return nil
+	}
+	fields := make([]zap.Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i == levelIdx || i == msgIdx {
+			continue
+		}
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		fields = append(fields, field(key(keyvals[i]), v))
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+func (l *zapLogger) isLevelKey(k interface{}) bool {
+	return k == l.levelKey || key(k) == l.levelName
+}
+
+// key returns the string form of a key, without allocating for string keys.
+func key(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprint(k)
+}
+
+// parseLevel maps a value of package level, or a zap level name, to a zap
+// level.
+func parseLevel(v interface{}) (zapcore.Level, bool) {
+	switch v {
+	case level.DebugValue():
+		return zapcore.DebugLevel, true
+	case level.InfoValue():
+		return zapcore.InfoLevel, true
+	case level.WarnValue():
+		return zapcore.WarnLevel, true
+	case level.ErrorValue():
+		return zapcore.ErrorLevel, true
+	}
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(fmt.Sprint(v))); err != nil {
+		return lvl, false
+	}
+	return lvl, true
+}
+
+// field returns a zap field of the type matching the value.
+func field(k string, v interface{}) zap.Field {
+	switch v := v.(type) {
+	case string:
+		return zap.String(k, v)
+	case bool:
+		return zap.Bool(k, v)
+	case int:
+		return zap.Int(k, v)
+	case int64:
+		return zap.Int64(k, v)
+	case int32:
+		return zap.Int32(k, v)
+	case uint:
+		return zap.Uint(k, v)
+	case uint64:
+		return zap.Uint64(k, v)
+	case uint32:
+		return zap.Uint32(k, v)
+	case float64:
+		return zap.Float64(k, v)
+	case float32:
+		return zap.Float32(k, v)
+	case time.Duration:
+		return zap.Duration(k, v)
+	case time.Time:
+		return zap.Time(k, v)
+	case error:
+		return zap.NamedError(k, v)
+	case fmt.Stringer:
+		return zap.Stringer(k, v)
+	default:
+		return zap.Any(k, v)
+	}
+}
@@ -0,0 +1,168 @@
+package zap_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	kitzap "github.com/go-kit/kit/log/zap"
+)
+
+func TestZapLoggerFieldTypes(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := kitzap.NewZapLogger(zap.New(core))
+
+	var (
+		err = errors.New("connection refused")
+		ts  = time.Date(2017, time.May, 1, 12, 0, 0, 0, time.UTC)
+	)
+	logger.Log(
+		"msg", "hello",
+		"s", "v",
+		"i", 42,
+		"i64", int64(43),
+		"f", 0.5,
+		"b", true,
+		"err", err,
+		"ts", ts,
+		"took", time.Second,
+		"other", []int{1},
+		"odd",
+	)
+
+	entries := logs.TakeAll()
+	if want, have := 1, len(entries); want != have {
+		t.Fatalf("want %d entries, have %d", want, have)
+	}
+	entry := entries[0]
+	if want, have := "hello", entry.Message; want != have {
+		t.Errorf("message: want %q, have %q", want, have)
+	}
+	if want, have := zapcore.InfoLevel, entry.Level; want != have {
+		t.Errorf("level: want %s, have %s", want, have)
+	}
+	want := []struct {
+		key string
+		typ zapcore.FieldType
+	}{
+		{"s", zapcore.StringType},
+		{"i", zapcore.Int64Type},
+		{"i64", zapcore.Int64Type},
+		{"f", zapcore.Float64Type},
+		{"b", zapcore.BoolType},
+		{"err", zapcore.ErrorType},
+		{"ts", zapcore.TimeType},
+		{"took", zapcore.DurationType},
+		{"other", zapcore.ArrayMarshalerType},
+		{"odd", zapcore.ErrorType},
+	}
+	if len(want) != len(entry.Context) {
+		t.Fatalf("want %d fields, have %v", len(want), entry.Context)
+	}
+	for i, f := range entry.Context {
+		if want[i].key != f.Key || want[i].typ != f.Type {
+			t.Errorf("field %d: want %s of type %d, have %s of type %d", i, want[i].key, want[i].typ, f.Key, f.Type)
+		}
+	}
+	if have := entry.ContextMap()["odd"]; have != log.ErrMissingValue.Error() {
+		t.Errorf("odd: want %q, have %v", log.ErrMissingValue, have)
+	}
+}
+
+func TestZapLoggerLevels(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options []kitzap.Option
+		log     func(log.Logger)
+		level   zapcore.Level
+		fields  int
+	}{
+		{"level package", nil, func(l log.Logger) { level.Warn(l).Log("msg", "m") }, zapcore.WarnLevel, 0},
+		{"zap level name", nil, func(l log.Logger) { l.Log("level", "debug", "msg", "m") }, zapcore.DebugLevel, 0},
+		{"fatal is error", nil, func(l log.Logger) { l.Log("level", "fatal", "msg", "m") }, zapcore.ErrorLevel, 0},
+		{"unknown level kept", nil, func(l log.Logger) { l.Log("level", "loud", "msg", "m") }, zapcore.InfoLevel, 1},
+		{"default level", []kitzap.Option{kitzap.DefaultLevel(zapcore.WarnLevel)}, func(l log.Logger) { l.Log("msg", "m") }, zapcore.WarnLevel, 0},
+		{"custom keys", []kitzap.Option{kitzap.LevelKey("severity"), kitzap.MessageKey("message")}, func(l log.Logger) { l.Log("severity", "error", "message", "m") }, zapcore.ErrorLevel, 0},
+	} {
+		core, logs := observer.New(zapcore.DebugLevel)
+		tc.log(kitzap.NewZapLogger(zap.New(core), tc.options...))
+
+		entries := logs.TakeAll()
+		if want, have := 1, len(entries); want != have {
+			t.Fatalf("%s: want %d entries, have %d", tc.name, want, have)
+		}
+		if want, have := tc.level, entries[0].Level; want != have {
+			t.Errorf("%s: want level %s, have %s", tc.name, want, have)
+		}
+		if want, have := "m", entries[0].Message; want != have {
+			t.Errorf("%s: want message %q, have %q", tc.name, want, have)
+		}
+		if want, have := tc.fields, len(entries[0].Context); want != have {
+			t.Errorf("%s: want %d fields, have %v", tc.name, want, entries[0].Context)
+		}
+	}
+}
+
+func TestZapLoggerDisabledLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := kitzap.NewZapLogger(zap.New(core))
+
+	level.Debug(logger).Log("msg", "dropped")
+	level.Info(logger).Log("msg", "kept")
+
+	entries := logs.TakeAll()
+	if want, have := 1, len(entries); want != have {
+		t.Fatalf("want %d entries, have %d", want, have)
+	}
+	if want, have := "kept", entries[0].Message; want != have {
+		t.Errorf("want message %q, have %q", want, have)
+	}
+}
+
+func newBenchmarkZapLogger() *zap.Logger {
+	return zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(ioutil.Discard),
+		zapcore.DebugLevel,
+	))
+}
+
+// anyLogger maps every value through zap.Any, the baseline the typed fields
+// of the adapter are compared against.
+type anyLogger struct{ logger *zap.Logger }
+
+func (l anyLogger) Log(keyvals ...interface{}) error {
+	fields := make([]zap.Field, 0, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields = append(fields, zap.Any(keyvals[i].(string), keyvals[i+1]))
+	}
+	l.logger.Info("", fields...)
+	return nil
+}
+
+func benchmarkZapLogger(b *testing.B, logger log.Logger) {
+	var (
+		err = errors.New("connection refused")
+		ts  = time.Date(2017, time.May, 1, 12, 0, 0, 0, time.UTC)
+	)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Log("s", "v", "i", 42, "f", 0.5, "b", true, "err", err, "ts", ts, "took", time.Second)
+	}
+}
+
+func BenchmarkZapLogger(b *testing.B) {
+	benchmarkZapLogger(b, kitzap.NewZapLogger(newBenchmarkZapLogger()))
+}
+
+func BenchmarkZapLoggerAny(b *testing.B) {
+	benchmarkZapLogger(b, anyLogger{newBenchmarkZapLogger()})
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+// ConcurrencyOption sets an optional parameter for the MaxConcurrent
+// middleware.
+type ConcurrencyOption func(*concurrencyLimiter)
+
+type concurrencyLimiter struct {
+	inFlight metrics.Gauge
+}
+
+// InFlightGauge sets the gauge tracking the number of requests in flight, i.e.
+// holding one of the limiter's tokens. It's incremented when a request
+// acquires a token, and decremented when it releases it. By default, the
+// number isn't reported.
+func InFlightGauge(g metrics.Gauge) ConcurrencyOption {
+	return func(l *concurrencyLimiter) { l.inFlight = g }
+}
+
+// MaxConcurrent returns an endpoint.Middleware that acts as a bulkhead: it
+// limits the number of concurrent requests to the wrapped endpoint to n, so
+// a spike of traffic to one endpoint can't exhaust resources, like goroutines
+// or database connections, shared with the rest of the process.
+//
+// When n requests are already in flight, further requests either wait for
+// one of them to finish, if wait is true, or are rejected with ErrLimited. A
+// waiting request is abandoned with the context's error if the context is
+// canceled first. The token of a request is released even if the wrapped
+// endpoint panics. The limit is shared by all endpoints wrapped by the
+// returned middleware. MaxConcurrent panics if n is less than 1, as no request
+// could ever get through.
+func MaxConcurrent(n int, wait bool, options ...ConcurrencyOption) endpoint.Middleware {
+	if n < 1 {
+		panic(fmt.Sprintf("ratelimit: MaxConcurrent limit must be at least 1, not %d", n))
+	}
+	l := &concurrencyLimiter{inFlight: discard.NewGauge("in_flight")}
+	for _, option := range options {
+		option(l)
+	}
+	sem := make(chan struct{}, n)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if wait {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			} else {
+				select {
+				case sem <- struct{}{}:
+				default:
+					return nil, ErrLimited
+				}
+			}
+			l.inFlight.Add(1)
+			defer func() {
+				l.inFlight.Add(-1)
+				<-sem
+			}()
+			return next(ctx, request)
+		}
+	}
+}
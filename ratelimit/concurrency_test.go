@@ -0,0 +1,189 @@
+package ratelimit_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/ratelimit"
+)
+
+// tracker is an endpoint recording the maximum number of concurrent calls.
+type tracker struct {
+	inFlight, max int64
+	release       chan struct{}
+}
+
+func (tr *tracker) endpoint(context.Context, interface{}) (interface{}, error) {
+	n := atomic.AddInt64(&tr.inFlight, 1)
+	defer atomic.AddInt64(&tr.inFlight, -1)
+	for {
+		max := atomic.LoadInt64(&tr.max)
+		if n <= max || atomic.CompareAndSwapInt64(&tr.max, max, n) {
+			break
+		}
+	}
+	<-tr.release
+	return struct{}{}, nil
+}
+
+func TestMaxConcurrentReject(t *testing.T) {
+	const limit, requests = 5, 100
+
+	var (
+		tr       = &tracker{release: make(chan struct{})}
+		gauge    = &maxGauge{}
+		e        = ratelimit.MaxConcurrent(limit, false, ratelimit.InFlightGauge(gauge))(tr.endpoint)
+		wg       sync.WaitGroup
+		served   int64
+		rejected int64
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch _, err := e(context.Background(), struct{}{}); err {
+			case nil:
+				atomic.AddInt64(&served, 1)
+			case ratelimit.ErrLimited:
+				atomic.AddInt64(&rejected, 1)
+			default:
+				t.Errorf("want ErrLimited, have %v", err)
+			}
+		}()
+	}
+
+	// The first requests hold all tokens until all others are rejected.
+	for atomic.LoadInt64(&rejected) < requests-limit {
+		time.Sleep(time.Millisecond)
+	}
+	close(tr.release)
+	wg.Wait()
+
+	if want, have := int64(limit), served; want != have {
+		t.Errorf("want %d served, have %d", want, have)
+	}
+	if want, have := int64(limit), tr.max; want != have {
+		t.Errorf("want max %d in flight, have %d", want, have)
+	}
+	if want, have := float64(limit), gauge.maxValue(); want != have {
+		t.Errorf("want gauge max %v, have %v", want, have)
+	}
+	if want, have := float64(0), gauge.Get(); want != have {
+		t.Errorf("want gauge %v after requests, have %v", want, have)
+	}
+}
+
+func TestMaxConcurrentWait(t *testing.T) {
+	const limit, requests = 5, 100
+
+	var (
+		tr = &tracker{release: make(chan struct{})}
+		e  = ratelimit.MaxConcurrent(limit, true)(tr.endpoint)
+		wg sync.WaitGroup
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := e(context.Background(), struct{}{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	for i := 0; i < requests; i++ {
+		tr.release <- struct{}{}
+	}
+	wg.Wait()
+
+	if have := tr.max; have > limit {
+		t.Errorf("want at most %d in flight, have %d", limit, have)
+	}
+}
+
+func TestMaxConcurrentCancelWhileWaiting(t *testing.T) {
+	tr := &tracker{release: make(chan struct{})}
+	e := ratelimit.MaxConcurrent(1, true)(tr.endpoint)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e(context.Background(), struct{}{}) // holds the only token
+	}()
+	for atomic.LoadInt64(&tr.inFlight) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := e(ctx, struct{}{}); err != context.Canceled {
+		t.Errorf("want %v, have %v", context.Canceled, err)
+	}
+
+	close(tr.release)
+	<-done
+}
+
+func TestMaxConcurrentPanic(t *testing.T) {
+	e := ratelimit.MaxConcurrent(1, false)(func(context.Context, interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	for i := 0; i < 2; i++ {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("want panic, have none")
+				}
+			}()
+			e(context.Background(), struct{}{}) // would return ErrLimited if the token leaked
+		}()
+	}
+}
+
+func TestMaxConcurrentInvalidLimit(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("n=%d: want panic, have none", n)
+				}
+			}()
+			ratelimit.MaxConcurrent(n, true)
+		}()
+	}
+}
+
+// maxGauge is a metrics.Gauge recording its maximum value.
+type maxGauge struct {
+	mtx        sync.Mutex
+	value, max float64
+}
+
+func (g *maxGauge) Name() string                     { return "gauge" }
+func (g *maxGauge) With(metrics.Field) metrics.Gauge { return g }
+func (g *maxGauge) Set(value float64)                { g.Add(value - g.Get()) }
+
+func (g *maxGauge) Add(delta float64) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.value += delta
+	if g.value > g.max {
+		g.max = g.value
+	}
+}
+
+func (g *maxGauge) Get() float64 {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.value
+}
+
+func (g *maxGauge) maxValue() float64 {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.max
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+// ErrThrottled is returned in the request path by the RetryAfterThrottle
+// middleware, while the downstream asked not to be called.
+var ErrThrottled = errors.New("throttled by downstream")
+
+// RetryAfterer may be implemented by errors of downstreams signaling
+// backpressure, e.g. from a 429 or 503 response with a Retry-After header.
+// RetryAfter returns how long the downstream shouldn't be called.
+type RetryAfterer interface {
+	RetryAfter() time.Duration
+}
+
+// ThrottleOption sets an optional parameter for the RetryAfterThrottle
+// middleware.
+type ThrottleOption func(*retryAfterThrottle)
+
+// ThrottleClock sets the function returning the current time. It's intended
+// for tests. By default, time.Now is used.
+func ThrottleClock(now func() time.Time) ThrottleOption {
+	return func(t *retryAfterThrottle) { t.now = now }
+}
+
+// ThrottleShedCounter sets the counter incremented for each call rejected
+// with ErrThrottled. By default, rejected calls aren't counted.
+func ThrottleShedCounter(c metrics.Counter) ThrottleOption {
+	return func(t *retryAfterThrottle) { t.shed = c }
+}
+
+type retryAfterThrottle struct {
+	now  func() time.Time
+	shed metrics.Counter
+
+	mtx   sync.Mutex
+	until time.Time
+}
+
+// RetryAfterThrottle returns an endpoint.Middleware that honors the
+// backpressure signaled by the wrapped endpoint. When it returns an error
+// implementing RetryAfterer, possibly wrapped, further calls are rejected
+// with ErrThrottled, without invoking the endpoint, until the retry-after
+// duration elapsed. The state is kept per middleware, so each downstream
+// should be wrapped by its own.
+func RetryAfterThrottle(options ...ThrottleOption) endpoint.Middleware {
+	t := &retryAfterThrottle{
+		now:  time.Now,
+		shed: discard.NewCounter("throttle_shed"),
+	}
+	for _, option := range options {
+		option(t)
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if t.throttled() {
+				t.shed.Add(1)
+				return nil, ErrThrottled
+			}
+			response, err := next(ctx, request)
+			var ra RetryAfterer
+			if errors.As(err, &ra) {
+				t.throttle(ra.RetryAfter())
+			}
+			return response, err
+		}
+	}
+}
+
+func (t *retryAfterThrottle) throttled() bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.now().Before(t.until)
+}
+
+// throttle extends the throttle window to last at least d from now.
+func (t *retryAfterThrottle) throttle(d time.Duration) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if until := t.now().Add(d); until.After(t.until) {
+		t.until = until
+	}
+}
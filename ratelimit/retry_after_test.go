@@ -0,0 +1,98 @@
+package ratelimit_test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint/endpointtest"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/ratelimit"
+)
+
+type retryAfterError time.Duration
+
+func (e retryAfterError) Error() string             { return "slow down" }
+func (e retryAfterError) RetryAfter() time.Duration { return time.Duration(e) }
+
+// fakeClock is a clock which only advances when told to.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestRetryAfterThrottle(t *testing.T) {
+	var (
+		clock     = &fakeClock{t: time.Unix(0, 0)}
+		shed      = &counter{}
+		errFailed = errors.New("failed")
+		backend   = endpointtest.NewRecorder(endpointtest.Scripted(
+			endpointtest.Step{Err: errFailed},
+			endpointtest.Step{Err: errFailed},
+			endpointtest.Step{Err: fmt.Errorf("calling downstream: %w", retryAfterError(10*time.Second))},
+			endpointtest.Step{},
+		))
+	)
+	e := ratelimit.RetryAfterThrottle(
+		ratelimit.ThrottleClock(clock.now),
+		ratelimit.ThrottleShedCounter(shed),
+	)(backend.Endpoint)
+
+	call := func() error {
+		_, err := e(context.Background(), struct{}{})
+		return err
+	}
+
+	// Other errors don't throttle.
+	call()
+	if have := call(); have != errFailed {
+		t.Errorf("want %v, have %v", errFailed, have)
+	}
+
+	// The downstream asks for 10s, even through a wrapped error.
+	call()
+	backend.AssertCalls(t, 3)
+	for _, d := range []time.Duration{0, time.Second, 8 * time.Second} {
+		clock.advance(d)
+		if have := call(); have != ratelimit.ErrThrottled {
+			t.Errorf("want %v, have %v", ratelimit.ErrThrottled, have)
+		}
+	}
+	backend.AssertCalls(t, 3)
+	if want, have := uint64(3), atomic.LoadUint64(&shed.n); want != have {
+		t.Errorf("want %d shed calls, have %d", want, have)
+	}
+
+	// The window closes.
+	clock.advance(time.Second)
+	if have := call(); have != nil {
+		t.Errorf("after the window: want no error, have %v", have)
+	}
+	backend.AssertCalls(t, 4)
+}
+
+func TestRetryAfterThrottlePerMiddleware(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	throttled := endpointtest.Scripted(endpointtest.Step{Err: retryAfterError(time.Minute)})
+
+	a := ratelimit.RetryAfterThrottle(ratelimit.ThrottleClock(clock.now))(throttled)
+	b := ratelimit.RetryAfterThrottle(ratelimit.ThrottleClock(clock.now))(endpointtest.Nop)
+	a(context.Background(), struct{}{})
+	if _, err := a(context.Background(), struct{}{}); err != ratelimit.ErrThrottled {
+		t.Errorf("want %v, have %v", ratelimit.ErrThrottled, err)
+	}
+	if _, err := b(context.Background(), struct{}{}); err != nil {
+		t.Errorf("want other middleware unaffected, have %v", err)
+	}
+}
+
+// counter is a metrics.Counter recording its value.
+type counter struct{ n uint64 }
+
+func (c *counter) Name() string                       { return "counter" }
+func (c *counter) With(metrics.Field) metrics.Counter { return c }
+func (c *counter) Add(delta uint64)                   { atomic.AddUint64(&c.n, delta) }
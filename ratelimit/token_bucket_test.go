@@ -9,14 +9,16 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/endpoint/endpointtest"
 	"github.com/go-kit/kit/ratelimit"
 )
 
 func TestTokenBucketLimiter(t *testing.T) {
-	e := func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil }
 	for _, n := range []int{1, 2, 100} {
 		tb := jujuratelimit.NewBucketWithRate(float64(n), int64(n))
-		testLimiter(t, ratelimit.NewTokenBucketLimiter(tb)(e), n)
+		r := endpointtest.NewRecorder(nil)
+		testLimiter(t, ratelimit.NewTokenBucketLimiter(tb)(r.Endpoint), n)
+		r.AssertCalls(t, n) // the limited request didn't get through
 	}
 }
 
@@ -24,8 +26,7 @@ func TestTokenBucketThrottler(t *testing.T) {
 	d := time.Duration(0)
 	s := func(d0 time.Duration) { d = d0 }
 
-	e := func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil }
-	e = ratelimit.NewTokenBucketThrottler(jujuratelimit.NewBucketWithRate(1, 1), s)(e)
+	e := ratelimit.NewTokenBucketThrottler(jujuratelimit.NewBucketWithRate(1, 1), s)(endpointtest.Nop)
 
 	// First request should go through with no delay.
 	e(context.Background(), struct{}{})
@@ -13,6 +13,10 @@ import (
 //
 // If `ctx` already has a Span, it is re-used and the operation name is
 // overwritten. If `ctx` does not yet have a Span, one is created here.
+//
+// If the endpoint returns an error, or a response implementing
+// endpoint.Failer which failed, the Span is tagged with error=true, and the
+// error is logged to it.
 func TraceServer(tracer opentracing.Tracer, operationName string) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
@@ -26,13 +30,16 @@ func TraceServer(tracer opentracing.Tracer, operationName string) endpoint.Middl
 			defer serverSpan.Finish()
 			otext.SpanKind.Set(serverSpan, otext.SpanKindRPCServer)
 			ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-			return next(ctx, request)
+			response, err := next(ctx, request)
+			setError(serverSpan, response, err)
+			return response, err
 		}
 	}
 }
 
 // TraceClient returns a Middleware that wraps the `next` Endpoint in an
-// OpenTracing Span called `operationName`.
+// OpenTracing Span called `operationName`. Errors, and failed endpoint.Failer
+// responses, are recorded like in TraceServer.
 func TraceClient(tracer opentracing.Tracer, operationName string) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
@@ -44,7 +51,25 @@ func TraceClient(tracer opentracing.Tracer, operationName string) endpoint.Middl
 			defer clientSpan.Finish()
 			otext.SpanKind.Set(clientSpan, otext.SpanKindRPCClient)
 			ctx = opentracing.ContextWithSpan(ctx, clientSpan)
-			return next(ctx, request)
+			response, err := next(ctx, request)
+			setError(clientSpan, response, err)
+			return response, err
 		}
 	}
 }
+
+// setError tags the span with error=true, and logs the error, if the
+// endpoint returned one, or a response implementing endpoint.Failer which
+// failed.
+func setError(span opentracing.Span, response interface{}, err error) {
+	if err == nil {
+		if f, ok := response.(endpoint.Failer); ok {
+			err = f.Failed()
+		}
+	}
+	if err == nil {
+		return
+	}
+	otext.Error.Set(span, true)
+	span.LogEventWithPayload("error", err.Error())
+}
@@ -1,6 +1,7 @@
 package opentracing_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/opentracing/opentracing-go"
@@ -94,3 +95,34 @@ func TestTraceClient(t *testing.T) {
 		t.Errorf("Want ParentID %q, have %q", want, have)
 	}
 }
+
+func TestTraceFailer(t *testing.T) {
+	for name, trace := range map[string]func(opentracing.Tracer, string) endpoint.Middleware{
+		"server": kitot.TraceServer,
+		"client": kitot.TraceClient,
+	} {
+		tracer := mocktracer.New()
+		response := failedResponse{errors.New("no such user")}
+		tracedEndpoint := trace(tracer, "testOp")(func(context.Context, interface{}) (interface{}, error) {
+			return response, nil
+		})
+
+		// The response is returned unchanged, but the span records the failure.
+		have, err := tracedEndpoint(context.Background(), struct{}{})
+		if err != nil || have != response {
+			t.Fatalf("%s: want %v, <nil>, have %v, %v", name, response, have, err)
+		}
+		span := tracer.FinishedSpans[0]
+		if want, have := true, span.Tag("error"); want != have {
+			t.Errorf("%s: want error tag %v, have %v", name, want, have)
+		}
+		if want, have := 1, len(span.Logs()); want != have {
+			t.Errorf("%s: want %d log(s), have %d", name, want, have)
+		}
+	}
+}
+
+// failedResponse implements endpoint.Failer.
+type failedResponse struct{ err error }
+
+func (r failedResponse) Failed() error { return r.err }
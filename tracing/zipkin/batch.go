@@ -0,0 +1,37 @@
+package zipkin
+
+import (
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/zipkincore"
+)
+
+// EncodeBatch creates the Thrift Spans of a batch of gokit Spans, like Encode.
+func EncodeBatch(spans []*Span) []*zipkincore.Span {
+	zs := make([]*zipkincore.Span, len(spans))
+	for i, s := range spans {
+		zs[i] = s.Encode()
+	}
+	return zs
+}
+
+// SerializeBatch returns a batch of spans, encoded in one pass as a single
+// Thrift list of spans with the binary protocol, rather than as one message
+// per span. It's the format Zipkin's HTTP API accepts for a POST of spans
+// with the content type "application/x-thrift".
+func SerializeBatch(spans []*Span) []byte {
+	t := thrift.NewTMemoryBuffer()
+	p := thrift.NewTBinaryProtocolTransport(t)
+	if err := p.WriteListBegin(thrift.STRUCT, len(spans)); err != nil {
+		panic(err)
+	}
+	for _, s := range spans {
+		if err := s.Encode().Write(p); err != nil {
+			panic(err)
+		}
+	}
+	if err := p.WriteListEnd(); err != nil {
+		panic(err)
+	}
+	return t.Buffer.Bytes()
+}
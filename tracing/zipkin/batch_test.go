@@ -0,0 +1,79 @@
+package zipkin_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/go-kit/kit/tracing/zipkin"
+	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/zipkincore"
+)
+
+func TestSerializeBatch(t *testing.T) {
+	batch := makeBatch(3)
+
+	buf := thrift.NewTMemoryBuffer()
+	buf.Write(zipkin.SerializeBatch(batch))
+	p := thrift.NewTBinaryProtocolTransport(buf)
+	elemType, size, err := p.ReadListBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := thrift.TType(thrift.STRUCT), elemType; want != have {
+		t.Errorf("want list of %v, have %v", want, have)
+	}
+	if want, have := len(batch), size; want != have {
+		t.Fatalf("want %d spans, have %d", want, have)
+	}
+
+	encoded := zipkin.EncodeBatch(batch)
+	for i := 0; i < size; i++ {
+		span := zipkincore.NewSpan()
+		if err := span.Read(p); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := encoded[i].String(), span.String(); want != have {
+			t.Errorf("span %d: want %s, have %s", i, want, have)
+		}
+	}
+	if err := p.ReadListEnd(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkSerializePerSpan(b *testing.B) {
+	batch := makeBatch(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		for _, s := range batch {
+			t := thrift.NewTMemoryBuffer()
+			p := thrift.NewTBinaryProtocolTransport(t)
+			s.Encode().Write(p)
+			buf.Write(t.Buffer.Bytes())
+		}
+	}
+}
+
+func BenchmarkSerializeBatch(b *testing.B) {
+	batch := makeBatch(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		zipkin.SerializeBatch(batch)
+	}
+}
+
+func makeBatch(n int) []*zipkin.Span {
+	batch := make([]*zipkin.Span, n)
+	for i := range batch {
+		span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, int64(i+1), 0)
+		span.Annotate(zipkin.ServerReceive)
+		span.AnnotateBinary("key", "value")
+		span.Annotate(zipkin.ServerSend)
+		batch[i] = span
+	}
+	return batch
+}
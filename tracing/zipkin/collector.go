@@ -1,6 +1,9 @@
 package zipkin
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+)
 
 // Collector represents a Zipkin trace collector, which is probably a set of
 // remote endpoints.
@@ -10,6 +13,20 @@ type Collector interface {
 	Close() error
 }
 
+// Flusher is implemented by collectors that buffer spans. Flush sends any
+// buffered spans, and returns once they've been sent or sending failed.
+type Flusher interface {
+	Flush() error
+}
+
+// HealthChecker is implemented by collectors that forward spans to a network
+// backend. Healthy checks that the backend is reachable, without sending a
+// span, and returns nil if it is. Use ReadyHandler to expose it to readiness
+// probes.
+type HealthChecker interface {
+	Healthy() error
+}
+
 // NopCollector implements Collector but performs no work.
 type NopCollector struct{}
 
@@ -33,6 +50,27 @@ func (c MultiCollector) Collect(s *Span) error {
 // ShouldSample implements Collector.
 func (c MultiCollector) ShouldSample(s *Span) bool { return false }
 
+// Flush implements Flusher by flushing all collectors that implement it.
+func (c MultiCollector) Flush() error {
+	return c.aggregateErrors(func(coll Collector) error {
+		if f, ok := coll.(Flusher); ok {
+			return f.Flush()
+		}
+		return nil
+	})
+}
+
+// Healthy implements HealthChecker by checking all collectors that implement
+// it. It returns nil only if all of them are healthy.
+func (c MultiCollector) Healthy() error {
+	return c.aggregateErrors(func(coll Collector) error {
+		if h, ok := coll.(HealthChecker); ok {
+			return h.Healthy()
+		}
+		return nil
+	})
+}
+
 // Close implements Collector.
 func (c MultiCollector) Close() error {
 	return c.aggregateErrors(func(coll Collector) error { return coll.Close() })
@@ -50,6 +88,9 @@ func (c MultiCollector) aggregateErrors(f func(c Collector) error) error {
 			e.errs[i] = err
 		}
 	}
+	if e == nil {
+		return nil // avoid a non-nil error interface holding a nil pointer
+	}
 	return e
 }
 
@@ -78,3 +119,64 @@ func (c *collectionError) Error() string {
 func (c *collectionError) GetErrors() []error {
 	return c.errs
 }
+
+// RedactingCollector returns a Collector that replaces the values of binary
+// annotations whose key is one of keys with redactValue, e.g. []byte("***"),
+// before forwarding spans to next. Redacted annotations become string
+// annotations. It's a safety net for personal data or secrets, like emails or
+// tokens, annotated by mistake. Collected spans aren't modified; next gets a
+// redacted copy.
+func RedactingCollector(next Collector, keys []string, redactValue []byte) Collector {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return redactingCollector{
+		next:  next,
+		match: func(key string) bool { return set[key] },
+		value: redactValue,
+	}
+}
+
+// RedactingRegexpCollector is like RedactingCollector, but redacts the values
+// of binary annotations whose key matches pattern.
+func RedactingRegexpCollector(next Collector, pattern *regexp.Regexp, redactValue []byte) Collector {
+	return redactingCollector{
+		next:  next,
+		match: pattern.MatchString,
+		value: redactValue,
+	}
+}
+
+type redactingCollector struct {
+	next  Collector
+	match func(key string) bool
+	value []byte
+}
+
+// Collect implements Collector.
+func (c redactingCollector) Collect(s *Span) error {
+	return c.next.Collect(s.redact(c.match, c.value))
+}
+
+// ShouldSample implements Collector.
+func (c redactingCollector) ShouldSample(s *Span) bool { return c.next.ShouldSample(s) }
+
+// Flush implements Flusher if the next collector does.
+func (c redactingCollector) Flush() error {
+	if f, ok := c.next.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Healthy implements HealthChecker if the next collector does.
+func (c redactingCollector) Healthy() error {
+	if h, ok := c.next.(HealthChecker); ok {
+		return h.Healthy()
+	}
+	return nil
+}
+
+// Close implements Collector.
+func (c redactingCollector) Close() error { return c.next.Close() }
@@ -1,10 +1,19 @@
 package zipkin_test
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
 	"testing"
 
+	"github.com/apache/thrift/lib/go/thrift"
+
 	"github.com/go-kit/kit/tracing/zipkin"
+	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/zipkincore"
+	"github.com/go-kit/kit/tracing/zipkin/zipkintest"
 )
 
 var s = zipkin.NewSpan("203.0.113.10:1234", "service1", "avg", 123, 456, 0)
@@ -97,3 +106,160 @@ func TestMultiCollectorClose(t *testing.T) {
 		}
 	}
 }
+
+// serializingCollector does the work of a real collector: it encodes each
+// span and serializes it to Thrift.
+type serializingCollector struct{}
+
+func (serializingCollector) Collect(s *zipkin.Span) error {
+	return s.Encode().Write(thrift.NewTBinaryProtocolTransport(thrift.NewTMemoryBuffer()))
+}
+
+func (serializingCollector) ShouldSample(*zipkin.Span) bool { return true }
+
+func (serializingCollector) Close() error { return nil }
+
+// BenchmarkMultiCollector shows the cost of encoding is paid once per span,
+// not once per backend: ns/op grows only by the cost of serialization.
+func BenchmarkMultiCollector(b *testing.B) {
+	for _, n := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			cs := make(zipkin.MultiCollector, n)
+			for i := range cs {
+				cs[i] = serializingCollector{}
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				span := zipkin.NewSpan("203.0.113.10:1234", "service1", "avg", 123, 456, 0)
+				span.Annotate(zipkin.ServerReceive)
+				span.AnnotateBinary("http.path", "/avg")
+				span.Annotate(zipkin.ServerSend)
+				if err := cs.Collect(span); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiCollectorFlush(t *testing.T) {
+	flusher := &flushingCollector{}
+	cs := zipkin.MultiCollector{&stubCollector{}, flusher, flusher}
+	if err := cs.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, flusher.flushes; want != have {
+		t.Errorf("want %d flush(es), have %d", want, have)
+	}
+}
+
+type flushingCollector struct {
+	stubCollector
+	flushes int
+}
+
+func (c *flushingCollector) Flush() error {
+	c.flushes++
+	return nil
+}
+
+func TestMultiCollectorNoError(t *testing.T) {
+	cs := zipkin.MultiCollector{&stubCollector{}, &stubCollector{}}
+	if err := cs.Collect(s); err != nil {
+		t.Errorf("want nil error, have %#v", err)
+	}
+	if err := cs.Close(); err != nil {
+		t.Errorf("want nil error, have %#v", err)
+	}
+}
+
+func TestMultiCollectorHealthy(t *testing.T) {
+	backend := &healthCheckingCollector{}
+	cs := zipkin.MultiCollector{&stubCollector{}, backend}
+	if err := cs.Healthy(); err != nil {
+		t.Errorf("want healthy, have %v", err)
+	}
+
+	backend.err = errors.New("backend unreachable")
+	err := cs.Healthy()
+	if err == nil {
+		t.Fatal("want unhealthy, have healthy")
+	}
+	if want, have := backend.err, err.(zipkin.CollectionError).GetErrors()[1]; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	backend := &healthCheckingCollector{}
+	handler := zipkin.ReadyHandler(backend)
+
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusOK},
+		{errors.New("backend unreachable"), http.StatusServiceUnavailable},
+		{nil, http.StatusOK},
+	} {
+		backend.err = tc.err
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, &http.Request{Method: "GET"})
+		if want, have := tc.want, rec.Code; want != have {
+			t.Errorf("health error %v: want %d, have %d", tc.err, want, have)
+		}
+	}
+
+	// Collectors that can't check their health are considered ready.
+	rec := httptest.NewRecorder()
+	zipkin.ReadyHandler(zipkin.NopCollector{}).ServeHTTP(rec, &http.Request{Method: "GET"})
+	if want, have := http.StatusOK, rec.Code; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+type healthCheckingCollector struct {
+	stubCollector
+	err error
+}
+
+func (c *healthCheckingCollector) Healthy() error { return c.err }
+
+func TestRedactingCollector(t *testing.T) {
+	for name, newCollector := range map[string]func(zipkin.Collector) zipkin.Collector{
+		"keys": func(next zipkin.Collector) zipkin.Collector {
+			return zipkin.RedactingCollector(next, []string{"user.email", "auth.token"}, []byte("***"))
+		},
+		"regexp": func(next zipkin.Collector) zipkin.Collector {
+			return zipkin.RedactingRegexpCollector(next, regexp.MustCompile(`^(user\.email|auth\..*)$`), []byte("***"))
+		},
+	} {
+		span := zipkin.NewSpan("203.0.113.10:1234", "service1", "avg", 123, 456, 0)
+		span.AnnotateBinary("user.email", "jane@example.com")
+		span.AnnotateBinary("auth.token", int64(1234))
+		span.AnnotateBinary("user.id", "jane")
+
+		recorder := zipkintest.NewRecorder()
+		if err := newCollector(recorder).Collect(span); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		have := map[string]string{}
+		for _, a := range recorder.LastSpan().BinaryAnnotations {
+			have[a.Key] = string(a.Value)
+			if a.Key != "user.id" && a.AnnotationType != zipkincore.AnnotationType_STRING {
+				t.Errorf("%s: %s: want string annotation, have %s", name, a.Key, a.AnnotationType)
+			}
+		}
+		want := map[string]string{"user.email": "***", "auth.token": "***", "user.id": "jane"}
+		if !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want %v, have %v", name, want, have)
+		}
+
+		// The collected span itself is left as it was.
+		for _, a := range span.Encode().BinaryAnnotations {
+			if string(a.Value) == "***" {
+				t.Errorf("%s: %s: collected span was redacted", name, a.Key)
+			}
+		}
+	}
+}
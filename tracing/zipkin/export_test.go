@@ -0,0 +1,33 @@
+package zipkin
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/zipkincore"
+)
+
+// CacheRequestName caches the span name of a request type, as if it had been
+// derived by SpanNameFromRequest.
+func CacheRequestName(t reflect.Type, name string) {
+	requestNames.Lock()
+	defer requestNames.Unlock()
+	requestNames.m[t] = name
+}
+
+// EncodeV2JSON returns the v2 JSON representation of the span, as posted by
+// the HTTPCollector.
+func EncodeV2JSON(s *zipkincore.Span) string {
+	b, err := json.Marshal(encodeV2(s))
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// Jitter returns the batch interval d randomized by up to fraction of it, as
+// the batching collectors do.
+func Jitter(d time.Duration, fraction float64, random func() float64) time.Duration {
+	return jitter(d, fraction, random)
+}
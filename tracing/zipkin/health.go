@@ -0,0 +1,19 @@
+package zipkin
+
+import "net/http"
+
+// ReadyHandler returns an http.Handler for readiness probes, e.g. mounted at
+// /ready. It responds with 200 OK if the collector is healthy, and with 503
+// Service Unavailable and the error otherwise. Collectors that don't
+// implement HealthChecker are considered healthy.
+func ReadyHandler(c Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := c.(HealthChecker); ok {
+			if err := h.Healthy(); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
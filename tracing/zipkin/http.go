@@ -0,0 +1,259 @@
+package zipkin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+var errHTTPBufferFull = errors.New("span buffer full; span dropped")
+
+// HTTPCollector implements Collector by posting spans, in batches, to the
+// /api/v2/spans endpoint of a Zipkin 2.x server, in the v2 JSON model. Spans
+// are converted from the v1 model of this package: core annotations become
+// the kind, timestamp, duration and local endpoint of the span, ServerAddress
+// and ClientAddress annotations its remote endpoint, and other binary
+// annotations its tags. Collect never blocks.
+type HTTPCollector struct {
+	url           string
+	client        *http.Client
+	dropped       metrics.Counter
+	bufferSize    int
+	spanc         chan *Span
+	flushc        chan chan error
+	batch         []*v2Span
+	batchInterval time.Duration
+	batchJitter   float64
+	batchSize     int
+	shouldSample  SpanSampler
+	logger        log.Logger
+	quit          chan struct{}
+}
+
+// NewHTTPCollector returns a new HTTP-backed Collector. rawurl is the URL of
+// the v2 spans endpoint, e.g. "http://zipkin:9411/api/v2/spans". Batches are
+// sent as soon as they reach the batch size, or the batch interval elapsed.
+func NewHTTPCollector(rawurl string, options ...HTTPOption) (Collector, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	c := &HTTPCollector{
+		url:           u.String(),
+		client:        &http.Client{Timeout: 5 * time.Second},
+		dropped:       discard.NewCounter("http_dropped_spans"),
+		bufferSize:    1000,
+		flushc:        make(chan chan error),
+		batch:         []*v2Span{},
+		batchInterval: defaultBatchInterval * time.Second,
+		batchSize:     100,
+		shouldSample:  traceIDSampler(SampleRate(1.0, rand.Int63())),
+		logger:        log.NewNopLogger(),
+		quit:          make(chan struct{}),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	c.spanc = make(chan *Span, c.bufferSize)
+	go c.loop()
+	return c, nil
+}
+
+// Collect implements Collector. It returns an error, and drops the span, if
+// the buffer of spans waiting to be batched is full.
+func (c *HTTPCollector) Collect(s *Span) error {
+	if c.ShouldSample(s) || s.Debug() {
+		select {
+		case c.spanc <- s:
+		default:
+			c.dropped.Add(1)
+			return errHTTPBufferFull
+		}
+	}
+	return nil // accepted
+}
+
+// ShouldSample implements Collector.
+func (c *HTTPCollector) ShouldSample(s *Span) bool {
+	return s.sample(c.shouldSample)
+}
+
+// Flush implements Flusher. It sends the current batch without waiting for
+// the batch size or interval to be reached.
+func (c *HTTPCollector) Flush() error {
+	errc := make(chan error)
+	select {
+	case c.flushc <- errc:
+		return <-errc
+	case <-c.quit:
+		return errors.New("collector closed")
+	}
+}
+
+// Close implements Collector.
+func (c *HTTPCollector) Close() error {
+	close(c.quit)
+	return nil
+}
+
+func (c *HTTPCollector) loop() {
+	timer := time.NewTimer(c.interval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case span := <-c.spanc:
+			c.batch = append(c.batch, encodeV2(span.Encode())...)
+			if len(c.batch) >= c.batchSize {
+				c.sendBatch()
+			}
+
+		case <-timer.C:
+			if len(c.batch) > 0 {
+				c.sendBatch()
+			}
+			timer.Reset(c.interval())
+
+		case errc := <-c.flushc:
+			c.drain()
+			var err error
+			if len(c.batch) > 0 {
+				err = c.send(c.batch)
+			}
+			c.batch = c.batch[:0]
+			errc <- err
+
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// interval returns the duration until the next batch is sent, the batch
+// interval with jitter.
+func (c *HTTPCollector) interval() time.Duration {
+	return jitter(c.batchInterval, c.batchJitter, rand.Float64)
+}
+
+// drain adds the spans waiting in the buffer to the batch.
+func (c *HTTPCollector) drain() {
+	for {
+		select {
+		case span := <-c.spanc:
+			c.batch = append(c.batch, encodeV2(span.Encode())...)
+		default:
+			return
+		}
+	}
+}
+
+func (c *HTTPCollector) sendBatch() {
+	if err := c.send(c.batch); err != nil {
+		c.logger.Log("err", err.Error())
+	}
+	c.batch = c.batch[:0]
+}
+
+func (c *HTTPCollector) send(batch []*v2Span) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		c.dropped.Add(uint64(len(batch)))
+		return err
+	}
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		c.dropped.Add(uint64(len(batch)))
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.dropped.Add(uint64(len(batch)))
+		return fmt.Errorf("during POST: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body) // so the connection can be reused
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		c.dropped.Add(uint64(len(batch)))
+		return fmt.Errorf("remote returned %s", resp.Status)
+	}
+	return nil
+}
+
+// HTTPOption sets a parameter for the HTTPCollector.
+type HTTPOption func(c *HTTPCollector)
+
+// HTTPClient sets the HTTP client used to post the spans. By default, a
+// client with a timeout of 5 seconds is used.
+func HTTPClient(client *http.Client) HTTPOption {
+	return func(c *HTTPCollector) { c.client = client }
+}
+
+// HTTPBatchSize sets the maximum batch size, after which a collect will be
+// triggered. The default batch size is 100 spans.
+func HTTPBatchSize(n int) HTTPOption {
+	return func(c *HTTPCollector) { c.batchSize = n }
+}
+
+// HTTPBatchInterval sets the maximum duration we will buffer spans before
+// emitting them to the collector. The default batch interval is 1 second.
+func HTTPBatchInterval(d time.Duration) HTTPOption {
+	return func(c *HTTPCollector) { c.batchInterval = d }
+}
+
+// HTTPBatchJitter randomizes each batch interval by up to the fraction of it,
+// either way, e.g. 0.2 for intervals between 0.8 and 1.2 times the batch
+// interval. It spreads the load of collectors started at the same time on
+// the Zipkin server. The default jitter is 0, i.e. the interval is fixed.
+func HTTPBatchJitter(fraction float64) HTTPOption {
+	return func(c *HTTPCollector) { c.batchJitter = fraction }
+}
+
+// HTTPBufferSize sets the number of spans that may wait to be added to a
+// batch. When the buffer is full, further spans are dropped. The default
+// buffer size is 1000 spans.
+func HTTPBufferSize(n int) HTTPOption {
+	return func(c *HTTPCollector) { c.bufferSize = n }
+}
+
+// HTTPSampleRate sets the sample rate used to determine if a trace will be
+// sent to the collector. By default, the sample rate is 1.0, i.e. all traces
+// are sent.
+func HTTPSampleRate(sr Sampler) HTTPOption {
+	return func(c *HTTPCollector) { c.shouldSample = traceIDSampler(sr) }
+}
+
+// HTTPSpanSampler sets the sampler used to determine if a trace will be sent
+// to the collector, based on the span itself, e.g. its method name. It
+// replaces the sample rate set by HTTPSampleRate.
+func HTTPSpanSampler(ss SpanSampler) HTTPOption {
+	return func(c *HTTPCollector) { c.shouldSample = ss }
+}
+
+// HTTPLogger sets the logger used to report errors in the collection
+// process. By default, a no-op logger is used, i.e. no errors are logged
+// anywhere. It's important to set this option in a production service.
+func HTTPLogger(logger log.Logger) HTTPOption {
+	return func(c *HTTPCollector) { c.logger = logger }
+}
+
+// HTTPDroppedCounter sets the counter incremented for each span dropped,
+// because the buffer was full, or its batch couldn't be sent. By default,
+// dropped spans aren't counted.
+func HTTPDroppedCounter(counter metrics.Counter) HTTPOption {
+	return func(c *HTTPCollector) { c.dropped = counter }
+}
@@ -0,0 +1,153 @@
+package zipkin_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/tracing/zipkin"
+)
+
+// v2Server is a fake Zipkin 2.x server, recording the spans posted to it.
+type v2Server struct {
+	*httptest.Server
+	status int
+
+	mtx   sync.Mutex
+	spans []map[string]interface{}
+	errs  []string
+}
+
+func newV2Server(status int) *v2Server {
+	s := &v2Server{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *v2Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if r.Method != "POST" || r.URL.Path != "/api/v2/spans" || r.Header.Get("Content-Type") != "application/json" {
+		s.errs = append(s.errs, r.Method+" "+r.URL.Path+" "+r.Header.Get("Content-Type"))
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	var spans []map[string]interface{}
+	if err := json.Unmarshal(body, &spans); err != nil {
+		s.errs = append(s.errs, err.Error())
+	}
+	s.spans = append(s.spans, spans...)
+	w.WriteHeader(s.status)
+}
+
+func (s *v2Server) received() ([]map[string]interface{}, []string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.spans, s.errs
+}
+
+func TestHTTPCollector(t *testing.T) {
+	server := newV2Server(http.StatusAccepted)
+	defer server.Close()
+
+	c, err := zipkin.NewHTTPCollector(server.URL+"/api/v2/spans", zipkin.HTTPBatchInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	client := zipkin.NewSpan("1.2.3.4:1234", "frontend", "get", 1, 2, 0)
+	client.Annotate(zipkin.ClientSend)
+	client.Annotate(zipkin.ClientReceive)
+	server1 := zipkin.NewSpan("1.2.3.5:1234", "backend", "get", 1, 3, 2)
+	server1.Annotate(zipkin.ServerReceive)
+	server1.AnnotateBinary("http.status_code", "200")
+	server1.Annotate(zipkin.ServerSend)
+	for _, s := range []*zipkin.Span{client, server1} {
+		if err := c.Collect(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.(zipkin.Flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans, errs := server.received()
+	if len(errs) > 0 {
+		t.Fatalf("bad requests: %v", errs)
+	}
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("want %d spans, have %d", want, have)
+	}
+	for i, want := range []map[string]string{
+		{"kind": "CLIENT", "id": "0000000000000002", "name": "get"},
+		{"kind": "SERVER", "id": "0000000000000003", "parentId": "0000000000000002"},
+	} {
+		for k, v := range want {
+			if have := spans[i][k]; v != have {
+				t.Errorf("span %d: %s: want %v, have %v", i, k, v, have)
+			}
+		}
+	}
+	if want, have := "200", spans[1]["tags"].(map[string]interface{})["http.status_code"]; want != have {
+		t.Errorf("want tag %v, have %v", want, have)
+	}
+	if want, have := "backend", spans[1]["localEndpoint"].(map[string]interface{})["serviceName"]; want != have {
+		t.Errorf("want local endpoint %v, have %v", want, have)
+	}
+}
+
+func TestHTTPCollectorBatchSize(t *testing.T) {
+	server := newV2Server(http.StatusAccepted)
+	defer server.Close()
+
+	c, err := zipkin.NewHTTPCollector(server.URL+"/api/v2/spans", zipkin.HTTPBatchSize(2), zipkin.HTTPBatchInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := int64(1); i <= 2; i++ {
+		c.Collect(zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, i, 0))
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if spans, _ := server.received(); len(spans) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("batch wasn't sent once full")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHTTPCollectorRemoteError(t *testing.T) {
+	server := newV2Server(http.StatusInternalServerError)
+	defer server.Close()
+
+	dropped := &countingCounter{}
+	c, err := zipkin.NewHTTPCollector(server.URL+"/api/v2/spans", zipkin.HTTPDroppedCounter(dropped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Collect(zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0))
+	if err := c.(zipkin.Flusher).Flush(); err == nil {
+		t.Error("want error, have none")
+	}
+	if want, have := uint64(1), atomic.LoadUint64(&dropped.n); want != have {
+		t.Errorf("want %d dropped span, have %d", want, have)
+	}
+}
+
+func TestNewHTTPCollectorBadURL(t *testing.T) {
+	if _, err := zipkin.NewHTTPCollector("localhost:9411"); err == nil {
+		t.Error("want error for a URL without scheme, have none")
+	}
+}
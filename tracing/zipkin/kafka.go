@@ -1,12 +1,17 @@
 package zipkin
 
 import (
+	"errors"
+	"fmt"
 	"math/rand"
+	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"gopkg.in/Shopify/sarama.v1"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 )
 
 // defaultKafkaTopic sets the standard Kafka topic our Collector will publish
@@ -14,13 +19,18 @@ import (
 // https://github.com/openzipkin/zipkin/tree/master/zipkin-receiver-kafka
 const defaultKafkaTopic = "zipkin"
 
+var errKafkaTimeout = errors.New("timeout; span dropped")
+
 // KafkaCollector implements Collector by publishing spans to a Kafka
 // broker.
 type KafkaCollector struct {
+	client       sarama.Client // nil if the producer was provided
 	producer     sarama.AsyncProducer
 	logger       log.Logger
 	topic        string
-	shouldSample Sampler
+	shouldSample SpanSampler
+	timeout      time.Duration
+	dropped      metrics.Counter
 }
 
 // KafkaOption sets a parameter for the KafkaCollector
@@ -47,7 +57,28 @@ func KafkaTopic(t string) KafkaOption {
 // sent to the collector. By default, the sample rate is 1.0, i.e. all traces
 // are sent.
 func KafkaSampleRate(sr Sampler) KafkaOption {
-	return func(c *KafkaCollector) { c.shouldSample = sr }
+	return func(c *KafkaCollector) { c.shouldSample = traceIDSampler(sr) }
+}
+
+// KafkaSpanSampler sets the sampler used to determine if a trace will be sent
+// to the collector, based on the span itself, e.g. its method name. It
+// replaces the sample rate set by KafkaSampleRate.
+func KafkaSpanSampler(ss SpanSampler) KafkaOption {
+	return func(c *KafkaCollector) { c.shouldSample = ss }
+}
+
+// KafkaTimeout bounds the submission of each span to the producer, so a
+// producer stuck on a broker connection can't block Collect, and the caller,
+// indefinitely. A span not accepted within the timeout is dropped, and
+// counted by the KafkaDroppedCounter. By default, there's no timeout.
+func KafkaTimeout(d time.Duration) KafkaOption {
+	return func(c *KafkaCollector) { c.timeout = d }
+}
+
+// KafkaDroppedCounter sets the counter incremented for each span dropped
+// because its submission timed out. By default, dropped spans aren't counted.
+func KafkaDroppedCounter(counter metrics.Counter) KafkaOption {
+	return func(c *KafkaCollector) { c.dropped = counter }
 }
 
 // NewKafkaCollector returns a new Kafka-backed Collector. addrs should be a
@@ -56,7 +87,8 @@ func NewKafkaCollector(addrs []string, options ...KafkaOption) (Collector, error
 	c := &KafkaCollector{
 		logger:       log.NewNopLogger(),
 		topic:        defaultKafkaTopic,
-		shouldSample: SampleRate(1.0, rand.Int63()),
+		shouldSample: traceIDSampler(SampleRate(1.0, rand.Int63())),
+		dropped:      discard.NewCounter("kafka_dropped_spans"),
 	}
 
 	for _, option := range options {
@@ -64,10 +96,16 @@ func NewKafkaCollector(addrs []string, options ...KafkaOption) (Collector, error
 	}
 
 	if c.producer == nil {
-		p, err := sarama.NewAsyncProducer(addrs, nil)
+		client, err := sarama.NewClient(addrs, nil)
+		if err != nil {
+			return nil, err
+		}
+		p, err := sarama.NewAsyncProducerFromClient(client)
 		if err != nil {
+			client.Close()
 			return nil, err
 		}
+		c.client = client
 		c.producer = p
 	}
 
@@ -82,33 +120,71 @@ func (c *KafkaCollector) logErrors() {
 	}
 }
 
-// Collect implements Collector.
+// Collect implements Collector. It returns an error, and drops the span, if
+// the producer doesn't accept it within the timeout set by KafkaTimeout.
 func (c *KafkaCollector) Collect(s *Span) error {
-	if c.ShouldSample(s) || s.debug {
-		c.producer.Input() <- &sarama.ProducerMessage{
-			Topic: c.topic,
-			Key:   nil,
-			Value: sarama.ByteEncoder(kafkaSerialize(s)),
-		}
+	if !c.ShouldSample(s) && !s.Debug() {
+		return nil
+	}
+	m := &sarama.ProducerMessage{
+		Topic: c.topic,
+		Key:   nil,
+		Value: sarama.ByteEncoder(thriftSerialize(s)),
+	}
+	if c.timeout <= 0 {
+		c.producer.Input() <- m
+		return nil
+	}
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case c.producer.Input() <- m:
+		return nil
+	case <-timer.C:
+		c.dropped.Add(1)
+		return errKafkaTimeout
 	}
-	return nil
 }
 
 // ShouldSample implements Collector.
 func (c *KafkaCollector) ShouldSample(s *Span) bool {
-	if !s.sampled && s.runSampler {
-		s.runSampler = false
-		s.sampled = c.shouldSample(s.TraceID())
+	return s.sample(c.shouldSample)
+}
+
+// Healthy implements HealthChecker. It refreshes the metadata of the topic,
+// and checks that some partition of it accepts writes. If the producer was
+// provided with the KafkaProducer option, the collector has no client to
+// check the brokers with, and always reports itself healthy.
+func (c *KafkaCollector) Healthy() error {
+	if c.client == nil {
+		return nil
+	}
+	if err := c.client.RefreshMetadata(c.topic); err != nil {
+		return err
 	}
-	return s.sampled
+	partitions, err := c.client.WritablePartitions(c.topic)
+	if err != nil {
+		return err
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("no writable partitions for topic %q", c.topic)
+	}
+	return nil
 }
 
 // Close implements Collector.
 func (c *KafkaCollector) Close() error {
-	return c.producer.Close()
+	err := c.producer.Close()
+	if c.client != nil {
+		if cerr := c.client.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
 }
 
-func kafkaSerialize(s *Span) []byte {
+// thriftSerialize returns the span, encoded with the Thrift binary protocol.
+func thriftSerialize(s *Span) []byte {
 	t := thrift.NewTMemoryBuffer()
 	p := thrift.NewTBinaryProtocolTransport(t)
 	if err := s.Encode().Write(p); err != nil {
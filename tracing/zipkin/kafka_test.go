@@ -2,6 +2,7 @@ package zipkin_test
 
 import (
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -125,6 +126,27 @@ func TestKafkaErrors(t *testing.T) {
 	}
 }
 
+func TestKafkaTimeout(t *testing.T) {
+	p := newStubProducer(false) // never consumes its input
+	dropped := &countingCounter{}
+	c, err := zipkin.NewKafkaCollector(
+		[]string{"192.0.2.10:9092"},
+		zipkin.KafkaProducer(p),
+		zipkin.KafkaTimeout(10*time.Millisecond),
+		zipkin.KafkaDroppedCounter(dropped),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Collect(spans[0]); err == nil {
+		t.Error("want error from timed out submission, have none")
+	}
+	if want, have := uint64(1), atomic.LoadUint64(&dropped.n); want != have {
+		t.Errorf("want %d dropped span, have %d", want, have)
+	}
+}
+
 func collectSpan(t *testing.T, c zipkin.Collector, p *stubProducer, s *zipkin.Span) *sarama.ProducerMessage {
 	var m *sarama.ProducerMessage
 	rcvd := make(chan bool, 1)
@@ -190,3 +212,34 @@ func testEqual(t *testing.T, want *zipkin.Span, got *zipkincore.Span) {
 		t.Errorf("parent_id %d, want %d", got.ParentId, want.ParentSpanID())
 	}
 }
+
+func TestKafkaHealthy(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("zipkin", 0, broker.BrokerID()),
+	})
+	defer broker.Close()
+
+	c, err := zipkin.NewKafkaCollector([]string{broker.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.(zipkin.HealthChecker).Healthy(); err != nil {
+		t.Errorf("want healthy, have %v", err)
+	}
+
+	// The topic exists, but no partition has a leader to write to.
+	noLeader := &sarama.MetadataResponse{}
+	noLeader.AddBroker(broker.Addr(), broker.BrokerID())
+	noLeader.AddTopicPartition("zipkin", 0, -1, nil, nil, sarama.ErrLeaderNotAvailable)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockWrapper(noLeader),
+	})
+	if err := c.(zipkin.HealthChecker).Healthy(); err == nil {
+		t.Error("want unhealthy, have healthy")
+	}
+}
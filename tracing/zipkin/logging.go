@@ -0,0 +1,37 @@
+package zipkin
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// LoggingMiddleware returns an endpoint middleware logging the start and the
+// end of each call of the wrapped endpoint, so logs and traces can be joined.
+// If the context carries a span, e.g. put there by ToContext, both records
+// have its IDs under "traceID" and "spanID", in the 16 character lower-hex
+// form Zipkin displays IDs in. The end record has the error returned by the
+// endpoint under "transport_error", and the duration of the call under
+// "took".
+func LoggingMiddleware(logger log.Logger) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			logger := logger
+			if span, ok := FromContext(ctx); ok {
+				logger = log.With(logger,
+					"traceID", fmt.Sprintf("%016x", uint64(span.TraceID())),
+					"spanID", fmt.Sprintf("%016x", uint64(span.SpanID())),
+				)
+			}
+			logger.Log("msg", "calling endpoint")
+			defer func(begin time.Time) {
+				logger.Log("msg", "called endpoint", "transport_error", err, "took", time.Since(begin))
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package zipkin_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/log/logtest"
+	"github.com/go-kit/kit/tracing/zipkin"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	recorder := logtest.NewRecorder()
+	failure := errors.New("failure")
+	ep := zipkin.LoggingMiddleware(recorder)(func(context.Context, interface{}) (interface{}, error) {
+		return nil, failure
+	})
+
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 0x12, 0x34, 0)
+	ep(zipkin.NewContext(context.Background(), span), struct{}{})
+
+	records := recorder.Records()
+	if want, have := 2, len(records); want != have {
+		t.Fatalf("want %d records, have %d", want, have)
+	}
+	ids := []interface{}{"traceID", "0000000000000012", "spanID", "0000000000000034"}
+	if want, have := append(ids, "msg", "calling endpoint"), records[0]; !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %v\nhave %v", want, have)
+	}
+	end := records[1]
+	if want, have := append(ids, "msg", "called endpoint", "transport_error", failure), end[:8]; !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %v\nhave %v", want, have)
+	}
+	if want, have := "took", end[8]; want != have {
+		t.Errorf("want %s, have %v", want, have)
+	}
+	if _, ok := end[9].(time.Duration); !ok {
+		t.Errorf("want took duration, have %T", end[9])
+	}
+
+	// Without a span, the IDs are left out.
+	recorder.Reset()
+	ep(context.Background(), struct{}{})
+	if want, have := []interface{}{"msg", "calling endpoint"}, recorder.Records()[0]; !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %v\nhave %v", want, have)
+	}
+}
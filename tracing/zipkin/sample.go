@@ -1,6 +1,12 @@
 package zipkin
 
-import "math"
+import (
+	"math"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
 
 // Sampler functions return if a Zipkin span should be sampled, based on its
 // traceID.
@@ -24,3 +30,86 @@ func SampleRate(rate float64, salt int64) Sampler {
 		return int64(math.Abs(float64(id^salt)))%10000 < int64(rate*10000)
 	}
 }
+
+// SpanSampler functions return if a Zipkin span should be sampled, based on
+// the span itself.
+type SpanSampler func(s *Span) bool
+
+// traceIDSampler adapts a Sampler to a SpanSampler, sampling based on the
+// span's traceID.
+func traceIDSampler(sr Sampler) SpanSampler {
+	return func(s *Span) bool { return sr(s.TraceID()) }
+}
+
+// NewPerOperationSampler returns a span sampler using a sample rate per
+// operation, i.e. per span method name, as returned by Span.Name. Operations
+// in overrides are sampled at their own rate, others at defaultRate. Like
+// SampleRate, the decision is based on the span's traceID.
+func NewPerOperationSampler(defaultRate float64, overrides map[string]float64) SpanSampler {
+	var (
+		defaultSampler = SampleRate(defaultRate, 0)
+		samplers       = make(map[string]Sampler, len(overrides))
+	)
+	for name, rate := range overrides {
+		samplers[name] = SampleRate(rate, 0)
+	}
+	return func(s *Span) bool {
+		if sampler, ok := samplers[s.Name()]; ok {
+			return sampler(s.TraceID())
+		}
+		return defaultSampler(s.TraceID())
+	}
+}
+
+// ForceSample forces the sampling of the span in the context, e.g. to always
+// trace the requests of a given customer, overriding the collector's sampler.
+// The decision propagates to the spans created from it afterwards, like child
+// and client spans, and downstream services via the B3 sampled flag. Core
+// annotations already skipped on an unsampled span aren't recovered, so it
+// should be called as early as possible. It returns false if the context has
+// no span.
+func ForceSample(ctx context.Context) bool {
+	return forceSampled(ctx, true)
+}
+
+// ForceUnsample suppresses the sampling of the span in the context,
+// overriding the collector's sampler. Like ForceSample, the decision
+// propagates to the spans created from it afterwards. Spans in debug mode are
+// still collected. It returns false if the context has no span.
+func ForceUnsample(ctx context.Context) bool {
+	return forceSampled(ctx, false)
+}
+
+func forceSampled(ctx context.Context, sampled bool) bool {
+	span, ok := FromContext(ctx)
+	if !ok {
+		return false
+	}
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.sampled = sampled
+	span.runSampler = false
+	return true
+}
+
+// SampleRequest returns an endpoint middleware deciding the sampling of the
+// span in the context from the request, e.g. to always trace requests with a
+// debug field. If decide returns force, the span is sampled as by
+// ForceSample; else if it returns deny, it's unsampled as by ForceUnsample;
+// else the collector's sampler decides. The span must be in the context
+// before the middleware runs, i.e. put there by the transport, and it should
+// wrap AnnotateServer, so the decision precedes the sampler and the
+// server-receive annotation.
+func SampleRequest(decide func(request interface{}) (force, deny bool)) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			switch force, deny := decide(request); {
+			case force:
+				ForceSample(ctx)
+			case deny:
+				ForceUnsample(ctx)
+			}
+			return next(ctx, request)
+		}
+	}
+}
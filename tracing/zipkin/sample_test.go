@@ -3,6 +3,8 @@ package zipkin_test
 import (
 	"testing"
 
+	"golang.org/x/net/context"
+
 	"github.com/go-kit/kit/tracing/zipkin"
 )
 
@@ -34,3 +36,118 @@ func TestSampleRate(t *testing.T) {
 		}
 	}
 }
+
+func TestPerOperationSampler(t *testing.T) {
+	sampler := zipkin.NewPerOperationSampler(0.01, map[string]float64{"/poll": 0.001})
+	for _, tc := range []struct {
+		name string
+		id   int64
+		want bool
+	}{
+		{"/poll", 1230005, true},
+		{"/poll", 1230050, false},
+		{"/users", 1230005, true},
+		{"/users", 1230050, true},
+		{"/users", 1230100, false},
+	} {
+		span := zipkin.NewSpan("1.2.3.4:1234", "service", tc.name, tc.id, 1, 0)
+		if want, have := tc.want, sampler(span); want != have {
+			t.Errorf("%s, traceID %d: want %v, have %v", tc.name, tc.id, want, have)
+		}
+	}
+}
+
+func TestForceSample(t *testing.T) {
+	if zipkin.ForceSample(context.Background()) {
+		t.Error("want false without a span in the context")
+	}
+
+	collector, err := zipkin.NewUDPCollector("127.0.0.1:1", zipkin.UDPSampleRate(zipkin.SampleRate(0, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer collector.Close()
+
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, span)
+	if !zipkin.ForceSample(ctx) {
+		t.Fatal("want true with a span in the context")
+	}
+	if !collector.ShouldSample(span) {
+		t.Error("want span sampled despite sample rate 0")
+	}
+
+	child, collect := zipkin.NewChildSpan(ctx, collector, "child")
+	defer collect()
+	if !collector.ShouldSample(child) {
+		t.Error("want child span sampled")
+	}
+}
+
+func TestForceUnsample(t *testing.T) {
+	if zipkin.ForceUnsample(context.Background()) {
+		t.Error("want false without a span in the context")
+	}
+
+	collector, err := zipkin.NewUDPCollector("127.0.0.1:1", zipkin.UDPSampleRate(zipkin.SampleRate(1, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer collector.Close()
+
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, span)
+	if !zipkin.ForceUnsample(ctx) {
+		t.Fatal("want true with a span in the context")
+	}
+	if collector.ShouldSample(span) {
+		t.Error("want span not sampled despite sample rate 1")
+	}
+
+	child, collect := zipkin.NewChildSpan(ctx, collector, "child")
+	defer collect()
+	if collector.ShouldSample(child) {
+		t.Error("want child span not sampled")
+	}
+}
+
+func TestSampleRequest(t *testing.T) {
+	type request struct{ debug, health bool }
+	decide := func(r interface{}) (force, deny bool) {
+		req := r.(request)
+		return req.debug, req.health
+	}
+	nop := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+
+	for _, tc := range []struct {
+		name    string
+		rate    float64
+		request request
+		want    bool
+	}{
+		{"forced", 0, request{debug: true}, true},
+		{"denied", 1, request{health: true}, false},
+		{"force wins", 0, request{debug: true, health: true}, true},
+		{"sampler decides", 1, request{}, true},
+	} {
+		collector, err := zipkin.NewUDPCollector("127.0.0.1:1", zipkin.UDPSampleRate(zipkin.SampleRate(tc.rate, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer collector.Close()
+
+		span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+		ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, span)
+		if _, err := zipkin.SampleRequest(decide)(nop)(ctx, tc.request); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := tc.want, collector.ShouldSample(span); want != have {
+			t.Errorf("%s: want sampled %v, have %v", tc.name, want, have)
+		}
+	}
+
+	// Without a span in the context, the middleware only calls the endpoint.
+	if _, err := zipkin.SampleRequest(decide)(nop)(context.Background(), request{debug: true}); err != nil {
+		t.Error(err)
+	}
+}
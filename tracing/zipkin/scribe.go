@@ -2,14 +2,19 @@ package zipkin
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/scribe"
 )
 
@@ -18,18 +23,52 @@ const defaultScribeCategory = "zipkin"
 // defaultBatchInterval in seconds
 const defaultBatchInterval = 1
 
+// jitter returns the batch interval d randomized by up to fraction of it,
+// either way, i.e. in [d-fraction*d, d+fraction*d), so the collectors of
+// replicas started together don't send their batches in lockstep. random
+// returns a number in [0.0,1.0), like rand.Float64. The fraction is clamped
+// to [0.0,1.0]; 0.0 disables jitter.
+func jitter(d time.Duration, fraction float64, random func() float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	return d + time.Duration((2*random()-1)*fraction*float64(d))
+}
+
+var (
+	errScribeBufferFull   = errors.New("span buffer full; span dropped")
+	errScribeNotConnected = errors.New("not connected; batch dropped")
+	errScribeTimeout      = errors.New("timeout; batch dropped")
+)
+
 // ScribeCollector implements Collector by forwarding spans to a Scribe
-// service, in batches.
+// service, in batches. If the connection to the Scribe service is lost, the
+// collector reconnects in the background, and drops spans until it succeeds.
+// Collect never blocks.
 type ScribeCollector struct {
+	mtx           sync.Mutex // protects client
 	client        scribe.Scribe
+	connected     int32 // atomic, 1 if connected
 	factory       func() (scribe.Scribe, error)
+	reconnectc    chan struct{}
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	reconnects    metrics.Counter
+	timeout       time.Duration
+	dropped       metrics.Counter
+	bufferSize    int
 	spanc         chan *Span
-	sendc         chan struct{}
+	flushc        chan chan error
+	healthc       chan chan error
 	batch         []*scribe.LogEntry
 	nextSend      time.Time
 	batchInterval time.Duration
+	batchJitter   float64
 	batchSize     int
-	shouldSample  Sampler
+	shouldSample  SpanSampler
 	logger        log.Logger
 	category      string
 	quit          chan struct{}
@@ -41,6 +80,8 @@ type ScribeCollector struct {
 // maximum size and interval of a batch of spans; as soon as either limit is
 // reached, the batch is sent. The logger is used to log errors, such as batch
 // send failures; users should provide an appropriate context, if desired.
+// The initial connection must succeed; later connection failures are handled
+// in the background.
 func NewScribeCollector(addr string, timeout time.Duration, options ...ScribeOption) (Collector, error) {
 	factory := scribeClientFactory(addr, timeout)
 	client, err := factory()
@@ -49,13 +90,20 @@ func NewScribeCollector(addr string, timeout time.Duration, options ...ScribeOpt
 	}
 	c := &ScribeCollector{
 		client:        client,
+		connected:     1,
 		factory:       factory,
-		spanc:         make(chan *Span),
-		sendc:         make(chan struct{}),
+		reconnectc:    make(chan struct{}, 1),
+		minBackoff:    100 * time.Millisecond,
+		maxBackoff:    10 * time.Second,
+		reconnects:    discard.NewCounter("scribe_reconnects"),
+		dropped:       discard.NewCounter("scribe_dropped_spans"),
+		bufferSize:    1000,
+		flushc:        make(chan chan error),
+		healthc:       make(chan chan error),
 		batch:         []*scribe.LogEntry{},
 		batchInterval: defaultBatchInterval * time.Second,
 		batchSize:     100,
-		shouldSample:  SampleRate(1.0, rand.Int63()),
+		shouldSample:  traceIDSampler(SampleRate(1.0, rand.Int63())),
 		logger:        log.NewNopLogger(),
 		category:      defaultScribeCategory,
 		quit:          make(chan struct{}),
@@ -63,26 +111,63 @@ func NewScribeCollector(addr string, timeout time.Duration, options ...ScribeOpt
 	for _, option := range options {
 		option(c)
 	}
-	c.nextSend = time.Now().Add(c.batchInterval)
+	c.spanc = make(chan *Span, c.bufferSize)
+	c.nextSend = time.Now().Add(c.interval())
 	go c.loop()
+	go c.reconnectLoop()
 	return c, nil
 }
 
-// Collect implements Collector.
+// Collect implements Collector. It returns an error, and drops the span, if
+// the buffer of spans waiting to be batched is full.
 func (c *ScribeCollector) Collect(s *Span) error {
-	if c.ShouldSample(s) || s.debug {
-		c.spanc <- s
+	if c.ShouldSample(s) || s.Debug() {
+		select {
+		case c.spanc <- s:
+		default:
+			return errScribeBufferFull
+		}
 	}
 	return nil // accepted
 }
 
+// Connected reports whether the collector is currently connected to the
+// Scribe service. While disconnected, batches are dropped.
+func (c *ScribeCollector) Connected() bool {
+	return atomic.LoadInt32(&c.connected) == 1
+}
+
 // ShouldSample implements Collector.
 func (c *ScribeCollector) ShouldSample(s *Span) bool {
-	if !s.sampled && s.runSampler {
-		s.runSampler = false
-		s.sampled = c.shouldSample(s.TraceID())
+	return s.sample(c.shouldSample)
+}
+
+// Flush implements Flusher. It sends the current batch without waiting for
+// the batch size or interval to be reached.
+func (c *ScribeCollector) Flush() error {
+	errc := make(chan error)
+	select {
+	case c.flushc <- errc:
+		return <-errc
+	case <-c.quit:
+		return errors.New("collector closed")
+	}
+}
+
+// Healthy implements HealthChecker. It makes a Log call without entries, a
+// no-op for the Scribe service, on the collector's connection. A failed call
+// drops the connection, and the collector reconnects in the background.
+func (c *ScribeCollector) Healthy() error {
+	if !c.Connected() {
+		return errors.New("not connected")
+	}
+	errc := make(chan error)
+	select {
+	case c.healthc <- errc:
+		return <-errc
+	case <-c.quit:
+		return errors.New("collector closed")
 	}
-	return s.sampled
 }
 
 // Close implements Collector.
@@ -102,47 +187,167 @@ func (c *ScribeCollector) loop() {
 				Message:  scribeSerialize(span),
 			})
 			if len(c.batch) >= c.batchSize {
-				go c.sendNow()
+				c.sendBatch()
 			}
 
 		case <-tickc:
 			if time.Now().After(c.nextSend) {
-				go c.sendNow()
+				c.sendBatch()
 			}
 
-		case <-c.sendc:
-			c.nextSend = time.Now().Add(c.batchInterval)
-			if err := c.send(c.batch); err != nil {
-				c.logger.Log("err", err.Error())
+		case errc := <-c.flushc:
+			c.drain()
+			c.nextSend = time.Now().Add(c.interval())
+			var err error
+			if len(c.batch) > 0 {
+				err = c.send(c.batch)
 			}
 			c.batch = c.batch[:0]
+			errc <- err
+
+		case errc := <-c.healthc:
+			errc <- c.send([]*scribe.LogEntry{})
+
 		case <-c.quit:
 			return
 		}
 	}
 }
 
-func (c *ScribeCollector) sendNow() {
-	c.sendc <- struct{}{}
+// drain adds the spans waiting in the buffer to the batch.
+func (c *ScribeCollector) drain() {
+	for {
+		select {
+		case span := <-c.spanc:
+			c.batch = append(c.batch, &scribe.LogEntry{
+				Category: c.category,
+				Message:  scribeSerialize(span),
+			})
+		default:
+			return
+		}
+	}
+}
+
+// interval returns the duration until the next batch is sent, the batch
+// interval with jitter.
+func (c *ScribeCollector) interval() time.Duration {
+	return jitter(c.batchInterval, c.batchJitter, rand.Float64)
+}
+
+// sendBatch sends the batch from the loop, as soon as it's full or its
+// interval elapsed, whichever comes first, and restarts the interval. Sending
+// from the loop, rather than from another goroutine, sends a burst of spans in
+// full batches, instead of spawning a send for each span collected while the
+// first one waits.
+func (c *ScribeCollector) sendBatch() {
+	c.nextSend = time.Now().Add(c.interval())
+	if err := c.send(c.batch); err != nil {
+		c.logger.Log("err", err.Error())
+	}
+	c.batch = c.batch[:0]
 }
 
 func (c *ScribeCollector) send(batch []*scribe.LogEntry) error {
-	if c.client == nil {
-		var err error
-		if c.client, err = c.factory(); err != nil {
-			return fmt.Errorf("during reconnect: %v", err)
-		}
+	c.mtx.Lock()
+	client := c.client
+	c.mtx.Unlock()
+	if client == nil {
+		c.dropped.Add(uint64(len(batch)))
+		c.reconnect()
+		return errScribeNotConnected
 	}
-	if rc, err := c.client.Log(c.batch); err != nil {
-		c.client = nil
+	if rc, err := c.log(client, batch); err != nil {
+		c.dropped.Add(uint64(len(batch)))
+		c.mtx.Lock()
+		if c.client == client {
+			c.client = nil
+			atomic.StoreInt32(&c.connected, 0)
+		}
+		c.mtx.Unlock()
+		c.reconnect()
 		return fmt.Errorf("during Log: %v", err)
 	} else if rc != scribe.ResultCode_OK {
 		// probably transient error; don't reset client
+		c.dropped.Add(uint64(len(batch)))
 		return fmt.Errorf("remote returned %s", rc)
 	}
 	return nil
 }
 
+// log sends the batch with the client, waiting at most for the submission
+// timeout, if one is set. A timed out call is interrupted by closing its
+// connection, so it doesn't linger.
+func (c *ScribeCollector) log(client scribe.Scribe, batch []*scribe.LogEntry) (scribe.ResultCode, error) {
+	if c.timeout <= 0 {
+		return client.Log(batch)
+	}
+	type result struct {
+		rc  scribe.ResultCode
+		err error
+	}
+	resultc := make(chan result, 1)
+	batch = append([]*scribe.LogEntry(nil), batch...) // the loop reuses the batch
+	go func() {
+		rc, err := client.Log(batch)
+		resultc <- result{rc, err}
+	}()
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-resultc:
+		return r.rc, r.err
+	case <-timer.C:
+		if sc, ok := client.(*scribeClient); ok {
+			sc.socket.Interrupt()
+		}
+		return 0, errScribeTimeout
+	}
+}
+
+// reconnect asks the reconnect loop to reestablish the connection, unless
+// it's already doing so.
+func (c *ScribeCollector) reconnect() {
+	select {
+	case c.reconnectc <- struct{}{}:
+	default:
+	}
+}
+
+// reconnectLoop reestablishes lost connections, with exponential backoff
+// between failed attempts, so neither Collect nor the batching loop ever
+// wait for a connection.
+func (c *ScribeCollector) reconnectLoop() {
+	for {
+		select {
+		case <-c.reconnectc:
+		case <-c.quit:
+			return
+		}
+		backoff := c.minBackoff
+		for !c.Connected() {
+			c.reconnects.Add(1)
+			client, err := c.factory()
+			if err == nil {
+				c.mtx.Lock()
+				c.client = client
+				atomic.StoreInt32(&c.connected, 1)
+				c.mtx.Unlock()
+				break
+			}
+			c.logger.Log("err", fmt.Sprintf("during reconnect: %v", err))
+			select {
+			case <-time.After(backoff):
+			case <-c.quit:
+				return
+			}
+			if backoff *= 2; backoff > c.maxBackoff {
+				backoff = c.maxBackoff
+			}
+		}
+	}
+}
+
 // ScribeOption sets a parameter for the StdlibAdapter.
 type ScribeOption func(s *ScribeCollector)
 
@@ -158,11 +363,26 @@ func ScribeBatchInterval(d time.Duration) ScribeOption {
 	return func(s *ScribeCollector) { s.batchInterval = d }
 }
 
+// ScribeBatchJitter randomizes each batch interval by up to the fraction of
+// it, either way, e.g. 0.2 for intervals between 0.8 and 1.2 times the batch
+// interval. It spreads the load of collectors started at the same time on
+// the Scribe service. The default jitter is 0, i.e. the interval is fixed.
+func ScribeBatchJitter(fraction float64) ScribeOption {
+	return func(s *ScribeCollector) { s.batchJitter = fraction }
+}
+
 // ScribeSampleRate sets the sample rate used to determine if a trace will be
 // sent to the collector. By default, the sample rate is 1.0, i.e. all traces
 // are sent.
 func ScribeSampleRate(sr Sampler) ScribeOption {
-	return func(s *ScribeCollector) { s.shouldSample = sr }
+	return func(s *ScribeCollector) { s.shouldSample = traceIDSampler(sr) }
+}
+
+// ScribeSpanSampler sets the sampler used to determine if a trace will be sent
+// to the collector, based on the span itself, e.g. its method name. It
+// replaces the sample rate set by ScribeSampleRate.
+func ScribeSpanSampler(ss SpanSampler) ScribeOption {
+	return func(s *ScribeCollector) { s.shouldSample = ss }
 }
 
 // ScribeLogger sets the logger used to report errors in the collection
@@ -172,6 +392,43 @@ func ScribeLogger(logger log.Logger) ScribeOption {
 	return func(s *ScribeCollector) { s.logger = logger }
 }
 
+// ScribeBufferSize sets the number of spans that may wait to be added to a
+// batch. When the buffer is full, further spans are dropped. The default
+// buffer size is 1000 spans.
+func ScribeBufferSize(n int) ScribeOption {
+	return func(s *ScribeCollector) { s.bufferSize = n }
+}
+
+// ScribeReconnectBackoff sets the minimum and maximum time to wait between
+// failed attempts to reconnect to the Scribe service. The wait doubles after
+// each failed attempt. The defaults are 100ms and 10s.
+func ScribeReconnectBackoff(min, max time.Duration) ScribeOption {
+	return func(s *ScribeCollector) { s.minBackoff, s.maxBackoff = min, max }
+}
+
+// ScribeReconnectCounter sets the counter incremented on each attempt to
+// reconnect to the Scribe service. By default, attempts aren't counted.
+func ScribeReconnectCounter(c metrics.Counter) ScribeOption {
+	return func(s *ScribeCollector) { s.reconnects = c }
+}
+
+// ScribeTimeout bounds each submission of a batch to the Scribe service, so a
+// stuck connection, e.g. a half-open one, can't stall the collector while the
+// buffer fills up. A batch not sent within the timeout is dropped, and counted
+// by the ScribeDroppedCounter, and the connection is reestablished. Unlike the
+// timeout passed to NewScribeCollector, which applies to each read and write
+// on the socket, it bounds the whole call. By default, there's no timeout.
+func ScribeTimeout(d time.Duration) ScribeOption {
+	return func(s *ScribeCollector) { s.timeout = d }
+}
+
+// ScribeDroppedCounter sets the counter incremented for each span dropped
+// because its batch couldn't be sent, e.g. because the submission timed out,
+// or the collector wasn't connected. By default, dropped spans aren't counted.
+func ScribeDroppedCounter(c metrics.Counter) ScribeOption {
+	return func(s *ScribeCollector) { s.dropped = c }
+}
+
 // ScribeCategory sets the Scribe category used to transmit the spans.
 func ScribeCategory(category string) ScribeOption {
 	return func(s *ScribeCollector) { s.category = category }
@@ -191,10 +448,17 @@ func scribeClientFactory(addr string, timeout time.Duration) func() (scribe.Scri
 		}
 		proto := thrift.NewTBinaryProtocolTransport(transport)
 		client := scribe.NewScribeClientProtocol(transport, proto, proto)
-		return client, nil
+		return &scribeClient{client, socket}, nil
 	}
 }
 
+// scribeClient is a Scribe client, whose socket is kept so calls can be
+// interrupted.
+type scribeClient struct {
+	scribe.Scribe
+	socket *thrift.TSocket
+}
+
 func scribeSerialize(s *Span) string {
 	t := thrift.NewTMemoryBuffer()
 	p := thrift.NewTBinaryProtocolTransport(t)
@@ -3,14 +3,17 @@ package zipkin_test
 import (
 	"encoding/base64"
 	"fmt"
+	"io"
 	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 
+	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/tracing/zipkin"
 	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/scribe"
 	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/zipkincore"
@@ -81,6 +84,304 @@ func TestScribeCollector(t *testing.T) {
 	}
 }
 
+func TestScribeCollectorFlush(t *testing.T) {
+	server := newScribeServer(t)
+
+	// Neither batch limit will be reached during the test.
+	c, err := zipkin.NewScribeCollector(server.addr(), time.Second, zipkin.ScribeBatchSize(100), zipkin.ScribeBatchInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Collect(zipkin.NewSpan("1.2.3.4:1234", "service", "method", 123, 456, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(zipkin.Flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(server.spans()); want != have {
+		t.Fatalf("want %d span(s) after flush, have %d", want, have)
+	}
+
+	// Flushing an empty batch is a no-op.
+	if err := c.(zipkin.Flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(server.spans()); want != have {
+		t.Errorf("want %d span(s), have %d", want, have)
+	}
+}
+
+func TestScribeCollectorBatchSize(t *testing.T) {
+	server := newScribeServer(t)
+
+	// The interval won't elapse during the test; only full batches are sent.
+	c, err := zipkin.NewScribeCollector(server.addr(), time.Second, zipkin.ScribeBatchSize(10), zipkin.ScribeBatchInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := int64(1); i <= 25; i++ {
+		if err := c.Collect(zipkin.NewSpan("1.2.3.4:1234", "service", "method", 123, i, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(server.spans()) < 20 {
+		if time.Now().After(deadline) {
+			t.Fatalf("want 2 full batches sent early, have %d span(s)", len(server.spans()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(10 * time.Millisecond) // let any extra send through
+	if want, have := 20, len(server.spans()); want != have {
+		t.Errorf("want %d span(s), have %d", want, have)
+	}
+	if want, have := 2, server.handler.logCalls(); want != have {
+		t.Errorf("want %d batch(es), have %d", want, have)
+	}
+}
+
+func TestScribeCollectorReconnect(t *testing.T) {
+	server := newScribeServer(t)
+	proxy := newTCPProxy(t, server.addr())
+	defer proxy.close()
+
+	reconnects := &countingCounter{}
+	c, err := zipkin.NewScribeCollector(
+		proxy.addr(),
+		100*time.Millisecond,
+		zipkin.ScribeBatchSize(0),
+		zipkin.ScribeBatchInterval(time.Millisecond),
+		zipkin.ScribeReconnectBackoff(time.Millisecond, 10*time.Millisecond),
+		zipkin.ScribeReconnectCounter(reconnects),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc := c.(*zipkin.ScribeCollector)
+
+	collect := func(methodName string) {
+		if err := c.Collect(zipkin.NewSpan("1.2.3.4:1234", "service", methodName, 1, 2, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor := func(what string, f func() bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !f() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timeout waiting for %s", what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	collect("before")
+	waitFor("first span", func() bool { return len(server.spans()) == 1 })
+
+	// Drop the connection, and keep the Scribe service unreachable. Sending
+	// the next batch fails, and the collector starts reconnecting.
+	proxy.cut()
+	waitFor("disconnect", func() bool { collect("during"); return !sc.Connected() })
+	waitFor("reconnect attempts", func() bool { return atomic.LoadUint64(&reconnects.n) > 1 })
+
+	// Collect must not block while disconnected.
+	begin := time.Now()
+	for i := 0; i < 100; i++ {
+		c.Collect(zipkin.NewSpan("1.2.3.4:1234", "service", "during", 1, 2, 0))
+	}
+	if elapsed := time.Since(begin); elapsed > 100*time.Millisecond {
+		t.Errorf("Collect blocked for %s while disconnected", elapsed)
+	}
+
+	proxy.restore()
+	waitFor("reconnect", sc.Connected)
+	waitFor("span after reconnect", func() bool {
+		collect("after")
+		for _, span := range server.spans() {
+			if span.GetName() == "after" {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func TestScribeCollectorHealthy(t *testing.T) {
+	server := newScribeServer(t)
+	proxy := newTCPProxy(t, server.addr())
+	defer proxy.close()
+
+	c, err := zipkin.NewScribeCollector(
+		proxy.addr(),
+		100*time.Millisecond,
+		zipkin.ScribeReconnectBackoff(time.Millisecond, 10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	h := c.(zipkin.HealthChecker)
+
+	waitFor := func(what string, f func() bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !f() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timeout waiting for %s", what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if err := h.Healthy(); err != nil {
+		t.Fatalf("want healthy, have %v", err)
+	}
+	if want, have := 0, len(server.spans()); want != have {
+		t.Errorf("want %d span(s) sent by health check, have %d", want, have)
+	}
+
+	proxy.cut()
+	waitFor("unhealthy", func() bool { return h.Healthy() != nil })
+
+	proxy.restore()
+	waitFor("healthy", func() bool { return h.Healthy() == nil })
+}
+
+func TestScribeCollectorTimeout(t *testing.T) {
+	// The server accepts connections, but never reads from them, like the
+	// peer of a half-open connection.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	dropped := &countingCounter{}
+	c, err := zipkin.NewScribeCollector(
+		ln.Addr().String(),
+		time.Minute, // the socket timeout alone would block the collector
+		zipkin.ScribeBatchSize(100),
+		zipkin.ScribeBatchInterval(time.Hour),
+		zipkin.ScribeTimeout(50*time.Millisecond),
+		zipkin.ScribeDroppedCounter(dropped),
+		zipkin.ScribeReconnectBackoff(time.Hour, time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, span := range spans {
+		if err := c.Collect(span); err != nil {
+			t.Fatal(err)
+		}
+	}
+	begin := time.Now()
+	if err := c.(zipkin.Flusher).Flush(); err == nil {
+		t.Fatal("want error from timed out flush, have none")
+	}
+	if took := time.Since(begin); took > time.Second {
+		t.Errorf("want flush to time out, took %v", took)
+	}
+	if want, have := uint64(len(spans)), atomic.LoadUint64(&dropped.n); want != have {
+		t.Errorf("want %d dropped spans, have %d", want, have)
+	}
+}
+
+// countingCounter is a metrics.Counter counting its increments.
+type countingCounter struct{ n uint64 }
+
+func (c *countingCounter) Name() string                       { return "counter" }
+func (c *countingCounter) With(metrics.Field) metrics.Counter { return c }
+func (c *countingCounter) Add(delta uint64)                   { atomic.AddUint64(&c.n, delta) }
+
+// tcpProxy forwards connections to a backend, and can simulate the backend
+// becoming unreachable.
+type tcpProxy struct {
+	t       *testing.T
+	address string
+	backend string
+
+	mtx   sync.Mutex
+	ln    net.Listener
+	conns []net.Conn
+}
+
+func newTCPProxy(t *testing.T, backend string) *tcpProxy {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &tcpProxy{t: t, address: ln.Addr().String(), backend: backend, ln: ln}
+	go p.serve(ln)
+	return p
+}
+
+func (p *tcpProxy) addr() string { return p.address }
+
+func (p *tcpProxy) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		backend, err := net.Dial("tcp", p.backend)
+		if err != nil {
+			conn.Close()
+			continue
+		}
+		p.mtx.Lock()
+		p.conns = append(p.conns, conn, backend)
+		p.mtx.Unlock()
+		go io.Copy(conn, backend)
+		go io.Copy(backend, conn)
+	}
+}
+
+// cut closes all connections, and stops accepting new ones.
+func (p *tcpProxy) cut() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.ln.Close()
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+}
+
+// restore accepts connections again, on the same address.
+func (p *tcpProxy) restore() {
+	ln, err := net.Listen("tcp", p.address)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	p.mtx.Lock()
+	p.ln = ln
+	p.mtx.Unlock()
+	go p.serve(ln)
+}
+
+func (p *tcpProxy) close() { p.cut() }
+
 type scribeServer struct {
 	t         *testing.T
 	transport *thrift.TServerSocket
@@ -146,6 +447,7 @@ type scribeHandler struct {
 	t *testing.T
 	sync.RWMutex
 	entries []*scribe.LogEntry
+	calls   int
 }
 
 func newScribeHandler(t *testing.T) *scribeHandler {
@@ -155,12 +457,19 @@ func newScribeHandler(t *testing.T) *scribeHandler {
 func (h *scribeHandler) Log(messages []*scribe.LogEntry) (scribe.ResultCode, error) {
 	h.Lock()
 	defer h.Unlock()
+	h.calls++
 	for _, m := range messages {
 		h.entries = append(h.entries, m)
 	}
 	return scribe.ResultCode_OK, nil
 }
 
+func (h *scribeHandler) logCalls() int {
+	h.RLock()
+	defer h.RUnlock()
+	return h.calls
+}
+
 func (h *scribeHandler) spans() []*zipkincore.Span {
 	h.RLock()
 	defer h.RUnlock()
@@ -195,3 +504,42 @@ func canConnect(port int) bool {
 	c.Close()
 	return true
 }
+
+func TestJitter(t *testing.T) {
+	const d = time.Second
+	for _, tc := range []struct {
+		fraction float64
+		random   float64
+		want     time.Duration
+	}{
+		{0, 0.9, d},
+		{0.2, 0, 800 * time.Millisecond},
+		{0.2, 0.5, d},
+		{0.2, 0.75, 1100 * time.Millisecond},
+		{2, 0, 0},
+		{-1, 0, d},
+	} {
+		random := func() float64 { return tc.random }
+		if want, have := tc.want, zipkin.Jitter(d, tc.fraction, random); want != have {
+			t.Errorf("fraction %v, random %v: want %v, have %v", tc.fraction, tc.random, want, have)
+		}
+	}
+
+	// Batch intervals vary within the bounds of the jitter.
+	const fraction = 0.2
+	var (
+		min = time.Duration((1 - fraction) * float64(d))
+		max = time.Duration((1 + fraction) * float64(d))
+	)
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 1000; i++ {
+		have := zipkin.Jitter(d, fraction, rand.Float64)
+		if have < min || have >= max {
+			t.Fatalf("want interval in [%v, %v), have %v", min, max, have)
+		}
+		seen[have] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("want varying intervals, have %v", seen)
+	}
+}
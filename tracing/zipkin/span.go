@@ -2,10 +2,13 @@ package zipkin
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"net"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/context"
@@ -17,9 +20,17 @@ import (
 // information about a single method call, i.e. a single request against a
 // service. Clients should annotate the span, and submit it when the request
 // that generated it is complete.
+//
+// A span may be annotated from several goroutines. Annotations and binary
+// annotations are encoded in the order they were added. Annotations added by
+// concurrent calls have no defined relative order, as the mutex guarding the
+// span doesn't grant the lock to waiters in arrival order; callers needing a
+// deterministic order must serialize their annotate calls.
 type Span struct {
-	host       *zipkincore.Endpoint
-	methodName string
+	host           *zipkincore.Endpoint
+	remoteEndpoint *zipkincore.Endpoint
+	serviceName    string // of the host, kept for placeholder endpoints
+	methodName     string
 
 	traceID      int64
 	spanID       int64
@@ -28,29 +39,191 @@ type Span struct {
 	annotations       []annotation
 	binaryAnnotations []binaryAnnotation
 
+	start  time.Time     // set by StartTime until the first annotation
+	offset time.Duration // added to the timestamps of annotations
+
+	kind string
+
 	debug      bool
 	sampled    bool
 	runSampler bool
+
+	parent *Span       // the span of the context the span was created from, if any
+	budget *spanBudget // shared by the spans of the trace created from this one
+	noop   bool        // created beyond the budget; never recorded or collected
+
+	mu      sync.Mutex
+	encoded *zipkincore.Span // memoized Encode result, nil when stale
 }
 
 // NewSpan returns a new Span, which can be annotated and collected by a
 // collector. Spans are passed through the request context to each middleware
 // under the SpanContextKey.
+//
+// A parentSpanID of zero makes the span the root of its trace. Like in B3
+// propagation and Zipkin itself, zero is never a valid span ID, so a span
+// can't have a parent with ID zero; generated IDs are never zero.
 func NewSpan(hostport, serviceName, methodName string, traceID, spanID, parentSpanID int64) *Span {
-	return &Span{
-		host:         makeEndpoint(hostport, serviceName),
+	span := &Span{
+		host:         MakeEndpoint(hostport, serviceName),
+		serviceName:  serviceName,
 		methodName:   methodName,
 		traceID:      traceID,
 		spanID:       spanID,
 		parentSpanID: parentSpanID,
 		runSampler:   true,
 	}
+	if max := atomic.LoadInt64(&maxSpansPerTrace); max > 0 {
+		span.budget = &spanBudget{spans: 1, max: max}
+	}
+	span.annotateServiceVersion()
+	return span
+}
+
+var maxSpansPerTrace int64 // atomic
+
+// SetMaxSpansPerTrace caps the number of spans created in a trace by this
+// process, from each span created by NewSpan, e.g. by AnnotateServer for an
+// incoming request: the span itself, its children, their children, and so
+// on, including client spans of AnnotateClient. Spans beyond the cap are
+// no-op spans, with IDs, which are never annotated nor collected, and
+// propagate as unsampled. It guards collectors from runaway traces, like
+// those of a buggy recursion. It applies to spans created afterwards by
+// NewSpan. By default, or if n isn't positive, there's no cap.
+func SetMaxSpansPerTrace(n int) {
+	atomic.StoreInt64(&maxSpansPerTrace, int64(n))
+}
+
+// spanBudget counts the spans created in a trace from its first span, which
+// all of them share a pointer to.
+type spanBudget struct {
+	spans int64 // atomic
+	max   int64
+}
+
+// take counts a new span, and reports whether it's within the budget. A nil
+// budget is unlimited.
+func (b *spanBudget) take() bool {
+	return b == nil || atomic.AddInt64(&b.spans, 1) <= b.max
 }
 
-// makeEndpoint takes the hostport and service name that represent this Zipkin
+// inherit makes the span, a new child of parent, share the budget of the
+// parent, and turns it into a no-op span if the budget is exhausted.
+func (s *Span) inherit(parent *Span) {
+	s.budget = parent.budget
+	if parent.noop || !s.budget.take() {
+		s.noop = true
+		s.annotations = nil
+		s.binaryAnnotations = nil
+		s.encoded = nil
+	}
+}
+
+// redact returns a copy of the span, in which the values of the binary
+// annotations with keys matching match are replaced by the string value. It
+// returns the span itself if no key matches.
+func (s *Span) redact(match func(key string) bool, value []byte) *Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var binaryAnnotations []binaryAnnotation
+	for i, a := range s.binaryAnnotations {
+		if !match(a.key) {
+			continue
+		}
+		if binaryAnnotations == nil {
+			binaryAnnotations = append([]binaryAnnotation(nil), s.binaryAnnotations...)
+		}
+		binaryAnnotations[i].value = value
+		binaryAnnotations[i].annotationType = zipkincore.AnnotationType_STRING
+	}
+	if binaryAnnotations == nil {
+		return s
+	}
+	return &Span{
+		host:              s.host,
+		remoteEndpoint:    s.remoteEndpoint,
+		serviceName:       s.serviceName,
+		methodName:        s.methodName,
+		traceID:           s.traceID,
+		spanID:            s.spanID,
+		parentSpanID:      s.parentSpanID,
+		annotations:       s.annotations,
+		binaryAnnotations: binaryAnnotations,
+		kind:              s.kind,
+		debug:             s.debug,
+		sampled:           s.sampled,
+		runSampler:        s.runSampler,
+		budget:            s.budget,
+		noop:              s.noop,
+	}
+}
+
+var intAnnotationWidth int32 = 64 // atomic
+
+// IntAnnotationWidth sets how AnnotateBinary encodes values of the platform
+// dependent int and uint types: as I64 annotations if bits is 64, the
+// default, or as I32 annotations, truncated to their low 32 bits, if bits is
+// 32. The default never loses bits, as int and uint have at most 64. Choose
+// 32 for small values, like counters or status codes, if a consumer of the
+// spans expects I32 annotations. It panics if bits is neither 32 nor 64.
+func IntAnnotationWidth(bits int) {
+	if bits != 32 && bits != 64 {
+		panic(fmt.Sprintf("zipkin: int annotation width must be 32 or 64 bits, not %d", bits))
+	}
+	atomic.StoreInt32(&intAnnotationWidth, int32(bits))
+}
+
+// encodeInt encodes the bits of an int or a uint value as an annotation of
+// the width set by IntAnnotationWidth.
+func encodeInt(v uint64) (zipkincore.AnnotationType, []byte) {
+	if atomic.LoadInt32(&intAnnotationWidth) == 32 {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(v))
+		return zipkincore.AnnotationType_I32, b
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return zipkincore.AnnotationType_I64, b
+}
+
+var serviceVersion atomic.Value // string
+
+// SetServiceVersion sets the version of the service, e.g. its release or
+// commit. Spans created afterwards by NewSpan and NewChildSpan are annotated
+// with it under the ServiceVersion key, so traces can be filtered by the
+// deployed version. By default, or if v is empty, spans aren't annotated.
+func SetServiceVersion(v string) {
+	serviceVersion.Store(v)
+}
+
+func (s *Span) annotateServiceVersion() {
+	if v, _ := serviceVersion.Load().(string); v != "" {
+		s.AnnotateBinary(ServiceVersion, v)
+	}
+}
+
+var placeholderEndpoints int32 // atomic
+
+// SetPlaceholderEndpoints sets whether Encode substitutes a placeholder
+// endpoint, with only the service name of the span, for the missing endpoint
+// of annotations made while the span had no host, e.g. because it couldn't be
+// resolved. Some Thrift decoders reject spans with nil endpoints. It's
+// disabled by default, and applies to spans encoded afterwards.
+func SetPlaceholderEndpoints(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&placeholderEndpoints, v)
+}
+
+// MakeEndpoint takes the hostport and service name that represent this Zipkin
 // service, and returns an endpoint that's embedded into the Zipkin core Span
 // type. It will return a nil endpoint if the input parameters are malformed.
-func makeEndpoint(hostport, serviceName string) *zipkincore.Endpoint {
+// The host is resolved by a DNS lookup, unless it's an IP address, so callers
+// creating many spans for the same host should make the endpoint once, and
+// pass it with the HostEndpoint option.
+func MakeEndpoint(hostport, serviceName string) *zipkincore.Endpoint {
 	host, port, err := net.SplitHostPort(hostport)
 	if err != nil {
 		return nil
@@ -92,6 +265,9 @@ func MakeNewSpanFunc(hostport, serviceName, methodName string) NewSpanFunc {
 // NewSpanFunc takes trace, span, & parent span IDs to produce a Span object.
 type NewSpanFunc func(traceID, spanID, parentSpanID int64) *Span
 
+// Name returns the method name of this span.
+func (s *Span) Name() string { return s.methodName }
+
 // TraceID returns the ID of the trace that this span is a member of.
 func (s *Span) TraceID() int64 { return s.traceID }
 
@@ -99,31 +275,159 @@ func (s *Span) TraceID() int64 { return s.traceID }
 func (s *Span) SpanID() int64 { return s.spanID }
 
 // ParentSpanID returns the ID of the span which invoked this span.
-// It may be zero.
+// It's zero for root spans.
 func (s *Span) ParentSpanID() int64 { return s.parentSpanID }
 
+// IsRoot reports whether the span is the root of its trace, i.e. whether it
+// has no parent. Root spans are encoded without a parent ID.
+func (s *Span) IsRoot() bool { return s.parentSpanID == 0 }
+
 // Sample forces sampling of this span.
 func (s *Span) Sample() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.sampled = true
 }
 
+// sample runs the sampler on the span, unless the sampling decision was made
+// already, and returns the decision. The sampler runs without the span's lock
+// held, so it may call the span's methods; a decision made concurrently, e.g.
+// by ForceSample, takes precedence over the sampler's.
+func (s *Span) sample(sampler SpanSampler) bool {
+	s.mu.Lock()
+	if s.noop || s.sampled || !s.runSampler {
+		defer s.mu.Unlock()
+		return s.sampled && !s.noop
+	}
+	s.mu.Unlock()
+
+	sampled := sampler(s)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.runSampler {
+		s.runSampler = false
+		s.sampled = sampled
+	}
+	return s.sampled && !s.noop
+}
+
 // SetDebug forces debug mode on this span.
 func (s *Span) SetDebug() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.debug = true
+	s.encoded = nil
+}
+
+// The kinds of spans, as named by Zipkin's v2 model.
+const (
+	// KindClient is the kind of a span of a request sent to a server, timed
+	// by the ClientSend and ClientReceive annotations.
+	KindClient = "CLIENT"
+
+	// KindServer is the kind of a span of a request handled by a server,
+	// timed by the ServerReceive and ServerSend annotations.
+	KindServer = "SERVER"
+
+	// KindProducer is the kind of a span of a message sent to a broker,
+	// marked by the MessageSend annotation.
+	KindProducer = "PRODUCER"
+
+	// KindConsumer is the kind of a span of a message received from a
+	// broker, marked by the MessageReceive annotation.
+	KindConsumer = "CONSUMER"
+)
+
+// kindAnnotations are the annotations expressing each kind in the v1 model:
+// a begin annotation, and an end annotation for kinds having one.
+var kindAnnotations = map[string][2]string{
+	KindClient:   {ClientSend, ClientReceive},
+	KindServer:   {ServerReceive, ServerSend},
+	KindProducer: {MessageSend},
+	KindConsumer: {MessageReceive},
+}
+
+// SetKind sets the kind of the span: KindClient, KindServer, KindProducer or
+// KindConsumer. An empty or unknown kind clears it. The v1 model has no kind;
+// it's expressed by annotations, so the encoded span gets the annotations of
+// its kind it lacks, e.g. ClientSend and ClientReceive for a client span,
+// timed by its first and last annotations. Collectors of the v2 model, like
+// the HTTPCollector, set the kind of the spans they send from them.
+func (s *Span) SetKind(kind string) {
+	if _, ok := kindAnnotations[kind]; !ok {
+		kind = ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kind = kind
+	s.encoded = nil
 }
 
-// Annotate annotates the span with the given value.
+// Annotate annotates the span with the given value. The annotation is
+// timestamped, and appended, while the span is locked, so timestamps never
+// decrease in encoding order.
+//
+// Annotating with the package's constants, like ServerReceive, is cheap: on a
+// span known not to be sampled, it's skipped, and doesn't allocate.
 func (s *Span) Annotate(value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.noop || s.unsampled() && isCoreAnnotation(value) {
+		return
+	}
+	s.encoded = nil
+	now := time.Now()
+	if !s.start.IsZero() {
+		s.offset, s.start = s.start.Sub(now), time.Time{}
+	}
 	s.annotations = append(s.annotations, annotation{
-		timestamp: time.Now(),
+		timestamp: now.Add(s.offset),
 		value:     value,
 		host:      s.host,
 	})
 }
 
+// unsampled reports whether the span is known not to be collected: the
+// sampling decision was made, and no debug flag overrides it.
+func (s *Span) unsampled() bool {
+	return !s.sampled && !s.runSampler && !s.debug
+}
+
+// isCoreAnnotation reports whether value is one of the package's annotation
+// constants.
+func isCoreAnnotation(value string) bool {
+	switch value {
+	case ClientSend, ClientReceive, ServerSend, ServerReceive, MessageSend, MessageReceive:
+		return true
+	}
+	return false
+}
+
 // AnnotateBinary annotates the span with a key and a value that will be []byte
-// encoded.
+// encoded by EncodeBinaryAnnotation.
 func (s *Span) AnnotateBinary(key string, value interface{}) {
+	a, b := EncodeBinaryAnnotation(value)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.noop {
+		return
+	}
+	s.encoded = nil
+	s.binaryAnnotations = append(s.binaryAnnotations, binaryAnnotation{
+		key:            key,
+		value:          b,
+		annotationType: a,
+		host:           s.host,
+	})
+}
+
+// EncodeBinaryAnnotation returns the type and the bytes AnnotateBinary
+// encodes a value as: booleans as BOOL; []byte as BYTES; integers of up to 32
+// bits as I32, 64-bit ones as I64, and int and uint as set by
+// IntAnnotationWidth; floats as DOUBLE; strings as STRING, and other values as
+// the STRING of their %+v formatting.
+func EncodeBinaryAnnotation(value interface{}) (zipkincore.AnnotationType, []byte) {
 	var a zipkincore.AnnotationType
 	var b []byte
 	// We are not using zipkincore.AnnotationType_I16 for types that could fit
@@ -170,13 +474,9 @@ func (s *Span) AnnotateBinary(key string, value interface{}) {
 		b = make([]byte, 8)
 		binary.BigEndian.PutUint64(b, uint64(v))
 	case int:
-		a = zipkincore.AnnotationType_I32
-		b = make([]byte, 8)
-		binary.BigEndian.PutUint32(b, uint32(v))
+		a, b = encodeInt(uint64(v))
 	case uint:
-		a = zipkincore.AnnotationType_I32
-		b = make([]byte, 8)
-		binary.BigEndian.PutUint32(b, uint32(v))
+		a, b = encodeInt(uint64(v))
 	case uint64:
 		a = zipkincore.AnnotationType_I64
 		b = make([]byte, 8)
@@ -200,17 +500,18 @@ func (s *Span) AnnotateBinary(key string, value interface{}) {
 		a = zipkincore.AnnotationType_STRING
 		b = []byte(fmt.Sprintf("%+v", value))
 	}
-	s.binaryAnnotations = append(s.binaryAnnotations, binaryAnnotation{
-		key:            key,
-		value:          b,
-		annotationType: a,
-		host:           s.host,
-	})
+	return a, b
 }
 
 // AnnotateString annotates the span with a key and a string value.
 // Deprecated: use AnnotateBinary instead.
 func (s *Span) AnnotateString(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.noop {
+		return
+	}
+	s.encoded = nil
 	s.binaryAnnotations = append(s.binaryAnnotations, binaryAnnotation{
 		key:            key,
 		value:          []byte(value),
@@ -219,6 +520,35 @@ func (s *Span) AnnotateString(key, value string) {
 	})
 }
 
+// AnnotateError annotates the span with a failure: a boolean "error" binary
+// annotation set to true, and the error message under "error.message". If err
+// wraps other errors, each error in the chain unwrapped by errors.Unwrap is
+// recorded in turn under "error.cause.1", "error.cause.2", and so on. A nil
+// error is ignored.
+func (s *Span) AnnotateError(err error) {
+	if err == nil {
+		return
+	}
+	s.AnnotateBinary("error", true)
+	s.AnnotateBinary("error.message", err.Error())
+	for i, cause := 1, errors.Unwrap(err); cause != nil; i, cause = i+1, errors.Unwrap(cause) {
+		s.AnnotateBinary("error.cause."+strconv.Itoa(i), cause.Error())
+	}
+}
+
+// AddLink links the span to another span, possibly of another trace, which
+// caused it without being its parent, e.g. the span of the producer of a
+// message the span consumes from a queue. The Thrift model has no links, so
+// the IDs of the linked span are recorded as string binary annotations under
+// LinkTraceID ("link.traceId") and LinkSpanID ("link.spanId"), in the 16
+// character lower-hex form Zipkin displays IDs in, so the linked trace can be
+// looked up. Each link adds one pair of annotations, in the order they were
+// added.
+func (s *Span) AddLink(traceID, spanID int64) {
+	s.AnnotateBinary(LinkTraceID, fmt.Sprintf("%016x", uint64(traceID)))
+	s.AnnotateBinary(LinkSpanID, fmt.Sprintf("%016x", uint64(spanID)))
+}
+
 // SpanOption sets an optional parameter for Spans.
 type SpanOption func(s *Span)
 
@@ -227,7 +557,7 @@ type SpanOption func(s *Span)
 // used to annotate non Zipkin aware resources like databases and caches.
 func ServerAddr(hostport, serviceName string) SpanOption {
 	return func(s *Span) {
-		e := makeEndpoint(hostport, serviceName)
+		e := MakeEndpoint(hostport, serviceName)
 		if e != nil {
 			host := s.host
 			s.host = e                            // set temporary Endpoint
@@ -238,11 +568,46 @@ func ServerAddr(hostport, serviceName string) SpanOption {
 }
 
 // Host will update the default zipkin Endpoint of the Span it is used with.
+// It resolves the host each time it's applied; see HostEndpoint.
 func Host(hostport, serviceName string) SpanOption {
 	return func(s *Span) {
-		e := makeEndpoint(hostport, serviceName)
+		HostEndpoint(MakeEndpoint(hostport, serviceName))(s)
+	}
+}
+
+// HostEndpoint is like Host, but takes an endpoint made once with
+// MakeEndpoint, so creating spans in a hot loop doesn't repeat the DNS lookup
+// of the host. A nil endpoint leaves the span's endpoint unchanged.
+func HostEndpoint(e *zipkincore.Endpoint) SpanOption {
+	return func(s *Span) {
 		if e != nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
 			s.host = e // update
+			s.serviceName = e.ServiceName
+			s.encoded = nil
+		}
+	}
+}
+
+// RemoteEndpoint records the endpoint on the other side of the operation:
+// the server a client span talks to, or the client a server span serves.
+// Modern Zipkin span models carry this as an explicit remoteEndpoint field.
+// The Thrift model used by this package predates that field, so Encode can
+// only render the remote endpoint as a binary annotation: ClientAddress ("ca")
+// for server spans, see SetKind, and ServerAddress ("sa") for the others,
+// which older collectors understand as well. There is no option to choose
+// another rendering, as Encode has none; collectors of the v2 model, like the
+// HTTPCollector, turn these annotations into the remoteEndpoint field. Unlike
+// ServerAddr, the endpoint is also retained on the Span and available via its
+// RemoteEndpoint method.
+func RemoteEndpoint(hostport, serviceName string) SpanOption {
+	return func(s *Span) {
+		if e := MakeEndpoint(hostport, serviceName); e != nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.remoteEndpoint = e
+			s.encoded = nil
 		}
 	}
 }
@@ -250,7 +615,99 @@ func Host(hostport, serviceName string) SpanOption {
 // Debug will set the Span to debug mode forcing Samplers to pass the Span.
 func Debug(debug bool) SpanOption {
 	return func(s *Span) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
 		s.debug = debug
+		s.encoded = nil
+	}
+}
+
+// StartTime sets the start time of the span, e.g. to backfill spans of past
+// operations: the first annotation of the span is timestamped with t, and the
+// others keep their offset from it, so durations are preserved. It applies
+// to the annotations already recorded, like ClientSend by NewChildSpan, and
+// to the ones recorded afterwards.
+func StartTime(t time.Time) SpanOption {
+	return func(s *Span) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.annotations) == 0 {
+			s.start = t
+			return
+		}
+		shift := t.Sub(s.annotations[0].timestamp)
+		// Encoded copies of the span may share the annotations.
+		s.annotations = append([]annotation(nil), s.annotations...)
+		for i := range s.annotations {
+			s.annotations[i].timestamp = s.annotations[i].timestamp.Add(shift)
+		}
+		s.offset += shift
+		s.encoded = nil
+	}
+}
+
+// WithSpanID sets an explicit span ID, instead of a randomly generated one.
+// It's intended for idempotent replay, e.g. of events, where the same input
+// must always produce the same span, so Zipkin deduplicates re-ingested spans.
+// The caller becomes responsible for the uniqueness of the ID within the
+// trace; colliding IDs corrupt the trace.
+func WithSpanID(id int64) SpanOption {
+	return func(s *Span) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.spanID = id
+		s.encoded = nil
+	}
+}
+
+// ComposeOptions bundles the options into a single SpanOption, which applies
+// them in order, e.g. to define the options shared by many NewChildSpan call
+// sites once. Nil options are skipped.
+func ComposeOptions(options ...SpanOption) SpanOption {
+	options = append([]SpanOption(nil), options...)
+	return func(s *Span) {
+		for _, option := range options {
+			if option != nil {
+				option(s)
+			}
+		}
+	}
+}
+
+// InheritAnnotations copies the binary annotations with the given keys from
+// the parent span to the child span created by NewChildSpan or
+// NewLocalChildSpan, e.g. to scope all the spans of an operation to the
+// tenant the parent is annotated with. Keys the parent isn't annotated with
+// are skipped. It has no effect on spans without a parent in the context.
+func InheritAnnotations(keys ...string) SpanOption {
+	return func(s *Span) {
+		if s.parent == nil {
+			return
+		}
+		var inherited []binaryAnnotation
+		s.parent.mu.Lock()
+		for _, a := range s.parent.binaryAnnotations {
+			for _, key := range keys {
+				if a.key == key {
+					inherited = append(inherited, a)
+					break
+				}
+			}
+		}
+		s.parent.mu.Unlock()
+		if len(inherited) == 0 {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.noop {
+			return
+		}
+		for _, a := range inherited {
+			a.host = s.host
+			s.binaryAnnotations = append(s.binaryAnnotations, a)
+		}
+		s.encoded = nil
 	}
 }
 
@@ -262,12 +719,71 @@ type CollectFunc func()
 // etc. and treat them as if they are a regular service. For tracing client
 // endpoints use AnnotateClient instead.
 func NewChildSpan(ctx context.Context, collector Collector, methodName string, options ...SpanOption) (*Span, CollectFunc) {
+	childSpan := newChildSpan(ctx, methodName)
+	if childSpan == nil {
+		return nil, func() {}
+	}
+	childSpan.Annotate(ClientSend)
+	for _, option := range options {
+		option(childSpan)
+	}
+	if childSpan.noop {
+		return childSpan, func() {}
+	}
+	collectFunc := func() {
+		if childSpan != nil {
+			childSpan.Annotate(ClientReceive)
+			collector.Collect(childSpan)
+			childSpan = nil
+		}
+	}
+	return childSpan, collectFunc
+}
+
+// NewLocalChildSpan is like NewChildSpan, but for local work, like a
+// CPU-bound computation, rather than a call to another service. The span
+// isn't annotated with ClientSend and ClientReceive, which would make it a
+// client span. Instead, it's annotated with LocalComponent, whose value is
+// the service name, as Zipkin expects of local spans. Annotate the span to
+// time steps of the work.
+func NewLocalChildSpan(ctx context.Context, collector Collector, methodName string, options ...SpanOption) (*Span, CollectFunc) {
+	childSpan := newChildSpan(ctx, methodName)
+	if childSpan == nil {
+		return nil, func() {}
+	}
+	for _, option := range options {
+		option(childSpan)
+	}
+	var serviceName string
+	childSpan.mu.Lock()
+	if childSpan.host != nil {
+		serviceName = childSpan.host.ServiceName
+	}
+	childSpan.mu.Unlock()
+	childSpan.AnnotateBinary(LocalComponent, serviceName)
+	if childSpan.noop {
+		return childSpan, func() {}
+	}
+	collectFunc := func() {
+		if childSpan != nil {
+			collector.Collect(childSpan)
+			childSpan = nil
+		}
+	}
+	return childSpan, collectFunc
+}
+
+// newChildSpan returns a child of the span in the context, without
+// annotations, or nil if the context has no span.
+func newChildSpan(ctx context.Context, methodName string) *Span {
 	span, ok := FromContext(ctx)
 	if !ok {
-		return nil, func() {}
+		return nil
 	}
+	span.mu.Lock()
 	childSpan := &Span{
 		host:         span.host,
+		serviceName:  span.serviceName,
 		methodName:   methodName,
 		traceID:      span.traceID,
 		spanID:       newID(),
@@ -275,28 +791,104 @@ func NewChildSpan(ctx context.Context, collector Collector, methodName string, o
 		debug:        span.debug,
 		sampled:      span.sampled,
 		runSampler:   span.runSampler,
+		parent:       span,
 	}
-	childSpan.Annotate(ClientSend)
-	for _, option := range options {
-		option(childSpan)
+	span.mu.Unlock()
+	childSpan.inherit(span)
+	childSpan.annotateServiceVersion()
+	return childSpan
+}
+
+// NewChildSpans returns sibling child Spans of the parent Span extracted from
+// the passed context, one per name, e.g. for parallel sub-operations fanned
+// out by the parent. Their span IDs are distinct from each other and from the
+// parent's. If the context has no span, the spans are nil, and the
+// CollectFuncs do nothing.
+func NewChildSpans(ctx context.Context, collector Collector, names []string) ([]*Span, []CollectFunc) {
+	var (
+		spans        = make([]*Span, len(names))
+		collectFuncs = make([]CollectFunc, len(names))
+		seen         = map[int64]bool{}
+	)
+	if parent, ok := FromContext(ctx); ok {
+		seen[parent.spanID] = true
 	}
-	collectFunc := func() {
-		if childSpan != nil {
-			childSpan.Annotate(ClientReceive)
-			collector.Collect(childSpan)
-			childSpan = nil
+	for i, name := range names {
+		id := newID()
+		for seen[id] {
+			id = newID()
 		}
+		seen[id] = true
+		spans[i], collectFuncs[i] = NewChildSpan(ctx, collector, name, WithSpanID(id))
 	}
-	return childSpan, collectFunc
+	return spans, collectFuncs
+}
+
+// RemoteEndpoint returns the remote endpoint of the span, if one was set via
+// the RemoteEndpoint option. It may be nil.
+func (s *Span) RemoteEndpoint() *zipkincore.Endpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remoteEndpoint
+}
+
+// Sampled returns if the span is set to be sampled. It's safe to call
+// concurrently with the sampler of a collector.
+func (s *Span) Sampled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sampled && !s.noop
 }
 
 // IsSampled returns if the span is set to be sampled.
+//
+// Deprecated: use Sampled instead.
 func (s *Span) IsSampled() bool {
-	return s.sampled
+	return s.Sampled()
+}
+
+// AnnotationTime is an annotation of a span, with the time it was recorded.
+type AnnotationTime struct {
+	Value string
+	Time  time.Time
+}
+
+// AnnotationTimes returns the annotations recorded so far, with their
+// timestamps, in the order they were recorded, e.g. to measure the time from
+// ClientSend to a custom annotation before the span is collected. The result
+// is a copy; modifying it doesn't affect the span.
+func (s *Span) AnnotationTimes() []AnnotationTime {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	times := make([]AnnotationTime, len(s.annotations))
+	for i, a := range s.annotations {
+		times[i] = AnnotationTime{Value: a.value, Time: a.timestamp}
+	}
+	return times
 }
 
-// Encode creates a Thrift Span from the gokit Span.
+// Debug returns if the span is in debug mode, i.e. if it must be collected
+// regardless of sampling. Propagation code uses it to emit the B3 debug flag.
+func (s *Span) Debug() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.debug && !s.noop
+}
+
+// Encode creates a Thrift Span from the gokit Span. The result is memoized
+// until the span is next modified, e.g. annotated, so collectors fanned out by
+// a MultiCollector share a single encoding. Callers must therefore treat the
+// returned Thrift Span as read-only.
 func (s *Span) Encode() *zipkincore.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.encoded == nil {
+		s.encoded = s.encode()
+	}
+	return s.encoded
+}
+
+func (s *Span) encode() *zipkincore.Span {
 	// TODO lots of garbage here. We can improve by preallocating e.g. the
 	// Thrift stuff into an encoder struct, owned by the ScribeCollector.
 	zs := zipkincore.Span{
@@ -306,33 +898,118 @@ func (s *Span) Encode() *zipkincore.Span {
 		Debug:   s.debug,
 	}
 
-	if s.parentSpanID != 0 {
+	if !s.IsRoot() {
 		zs.ParentId = new(int64)
 		(*zs.ParentId) = s.parentSpanID
 	}
 
+	// With placeholder endpoints, a nil host is replaced by a single endpoint
+	// shared by all the annotations.
+	var placeholder *zipkincore.Endpoint
+	if atomic.LoadInt32(&placeholderEndpoints) != 0 {
+		placeholder = &zipkincore.Endpoint{ServiceName: s.serviceName}
+	}
+	host := func(e *zipkincore.Endpoint) *zipkincore.Endpoint {
+		if e == nil {
+			return placeholder
+		}
+		return e
+	}
+
 	zs.Annotations = make([]*zipkincore.Annotation, len(s.annotations))
 	for i, a := range s.annotations {
 		zs.Annotations[i] = &zipkincore.Annotation{
 			Timestamp: a.timestamp.UnixNano() / 1e3,
 			Value:     a.value,
-			Host:      a.host,
+			Host:      host(a.host),
 		}
 	}
 
-	zs.BinaryAnnotations = make([]*zipkincore.BinaryAnnotation, len(s.binaryAnnotations))
+	if kind, ok := kindAnnotations[s.kind]; ok {
+		zs.Annotations = annotateKind(zs.Annotations, host(s.host), kind[0], kind[1])
+	}
+
+	zs.BinaryAnnotations = make([]*zipkincore.BinaryAnnotation, len(s.binaryAnnotations), len(s.binaryAnnotations)+1)
 	for i, a := range s.binaryAnnotations {
 		zs.BinaryAnnotations[i] = &zipkincore.BinaryAnnotation{
 			Key:            a.key,
 			Value:          a.value,
 			AnnotationType: a.annotationType,
-			Host:           a.host,
+			Host:           host(a.host),
 		}
 	}
 
+	if s.remoteEndpoint != nil {
+		// No remoteEndpoint field in this Thrift model; use the legacy form.
+		zs.BinaryAnnotations = append(zs.BinaryAnnotations, &zipkincore.BinaryAnnotation{
+			Key:            s.remoteAddressKey(),
+			Value:          []byte("\x01"),
+			AnnotationType: zipkincore.AnnotationType_BOOL,
+			Host:           s.remoteEndpoint,
+		})
+	}
+
 	return &zs
 }
 
+// remoteAddressKey returns the key of the binary annotation of the remote
+// endpoint: ClientAddress for server spans, whose remote peer is the client,
+// and ServerAddress otherwise. Spans without a kind are server spans if they
+// have server annotations and no client ones.
+func (s *Span) remoteAddressKey() string {
+	if s.kind != "" {
+		if s.kind == KindServer {
+			return ClientAddress
+		}
+		return ServerAddress
+	}
+	var server bool
+	for _, a := range s.annotations {
+		switch a.value {
+		case ClientSend, ClientReceive:
+			return ServerAddress
+		case ServerReceive, ServerSend:
+			server = true
+		}
+	}
+	if server {
+		return ClientAddress
+	}
+	return ServerAddress
+}
+
+// annotateKind adds the begin and end annotations of the kind of the span to
+// the encoded annotations, with the host of the span, unless they're already
+// there. The begin annotation is timed by the first annotation, and the end
+// one by the last one, or by the current time if there are none.
+func annotateKind(annotations []*zipkincore.Annotation, host *zipkincore.Endpoint, begin, end string) []*zipkincore.Annotation {
+	var hasBegin, hasEnd bool
+	for _, a := range annotations {
+		hasBegin = hasBegin || a.Value == begin
+		hasEnd = hasEnd || a.Value == end
+	}
+	now := time.Now().UnixNano() / 1e3
+	first, last := now, now
+	if n := len(annotations); n > 0 {
+		first, last = annotations[0].Timestamp, annotations[n-1].Timestamp
+	}
+	if !hasBegin {
+		annotations = append([]*zipkincore.Annotation{{
+			Timestamp: first,
+			Value:     begin,
+			Host:      host,
+		}}, annotations...)
+	}
+	if end != "" && !hasEnd {
+		annotations = append(annotations, &zipkincore.Annotation{
+			Timestamp: last,
+			Value:     end,
+			Host:      host,
+		})
+	}
+	return annotations
+}
+
 type annotation struct {
 	timestamp time.Time
 	value     string
@@ -2,9 +2,23 @@ package zipkin_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint/endpointtest"
+	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/tracing/zipkin"
+	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/zipkincore"
+	"github.com/go-kit/kit/tracing/zipkin/zipkintest"
 )
 
 func TestAnnotateBinaryEncodesKeyValueAsBytes(t *testing.T) {
@@ -30,6 +44,54 @@ func TestAnnotateBinaryEncodesKeyValueAsBytes(t *testing.T) {
 	}
 }
 
+func TestEncodeBinaryAnnotation(t *testing.T) {
+	var (
+		i32    = zipkincore.AnnotationType_I32
+		i64    = zipkincore.AnnotationType_I64
+		double = zipkincore.AnnotationType_DOUBLE
+		str    = zipkincore.AnnotationType_STRING
+	)
+	for _, tc := range []struct {
+		value interface{}
+		typ   zipkincore.AnnotationType
+		bytes []byte
+	}{
+		{true, zipkincore.AnnotationType_BOOL, []byte{1}},
+		{false, zipkincore.AnnotationType_BOOL, []byte{0}},
+		{[]byte("raw"), zipkincore.AnnotationType_BYTES, []byte("raw")},
+		{byte(0xff), i32, []byte{0, 0, 0, 0xff}},
+		{int8(-1), i32, []byte{0xff, 0xff, 0xff, 0xff}},
+		{int16(0x102), i32, []byte{0, 0, 1, 2}},
+		{uint16(0xffff), i32, []byte{0, 0, 0xff, 0xff}},
+		{int32(-2), i32, []byte{0xff, 0xff, 0xff, 0xfe}},
+		{uint32(0x1020304), i32, []byte{1, 2, 3, 4}},
+		{int64(-1), i64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{uint64(0x102030405060708), i64, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{int(258), i64, []byte{0, 0, 0, 0, 0, 0, 1, 2}},
+		{uint(258), i64, []byte{0, 0, 0, 0, 0, 0, 1, 2}},
+		{float32(1.5), double, []byte{0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{float64(-2), double, []byte{0xc0, 0, 0, 0, 0, 0, 0, 0}},
+		{"text", str, []byte("text")},
+		{struct{ A int }{1}, str, []byte("{A:1}")},
+		{nil, str, []byte("<nil>")},
+	} {
+		typ, b := zipkin.EncodeBinaryAnnotation(tc.value)
+		if want, have := tc.typ, typ; want != have {
+			t.Errorf("%T %v: want type %v, have %v", tc.value, tc.value, want, have)
+		}
+		if want, have := tc.bytes, b; !bytes.Equal(want, have) {
+			t.Errorf("%T %v: want bytes %x, have %x", tc.value, tc.value, want, have)
+		}
+	}
+
+	// AnnotateBinary records the same encoding.
+	span := &zipkin.Span{}
+	span.AnnotateBinary("k", int16(0x102))
+	if a := span.Encode().GetBinaryAnnotations()[0]; a.AnnotationType != i32 || !bytes.Equal(a.Value, []byte{0, 0, 1, 2}) {
+		t.Errorf("want I32 0x00000102, have %v %x", a.AnnotationType, a.Value)
+	}
+}
+
 func TestAnnotateStringEncodesKeyValueAsBytes(t *testing.T) {
 	key := "awesome-string-test"
 	value := "this is neat"
@@ -52,3 +114,871 @@ func TestAnnotateStringEncodesKeyValueAsBytes(t *testing.T) {
 		t.Errorf("want %s, got %s", want, have)
 	}
 }
+
+func TestAnnotateError(t *testing.T) {
+	root := errors.New("connection refused")
+	wrapped := fmt.Errorf("query users: %w", fmt.Errorf("dial db: %w", root))
+
+	span := &zipkin.Span{}
+	span.AnnotateError(nil) // no-op
+	span.AnnotateError(wrapped)
+
+	have := map[string]string{}
+	for _, a := range span.Encode().GetBinaryAnnotations() {
+		have[a.Key] = string(a.Value)
+	}
+	for key, want := range map[string]string{
+		"error":         "\x01",
+		"error.message": "query users: dial db: connection refused",
+		"error.cause.1": "dial db: connection refused",
+		"error.cause.2": "connection refused",
+	} {
+		if want != have[key] {
+			t.Errorf("%s: want %q, have %q", key, want, have[key])
+		}
+	}
+	if want, have := 4, len(have); want != have {
+		t.Errorf("want %d binary annotations, have %d", want, have)
+	}
+}
+
+func TestEncodeAnnotationOrder(t *testing.T) {
+	// Annotations are handed from goroutine to goroutine, so each annotate
+	// call happens before the next, like in a request passing through
+	// middlewares running on different goroutines.
+	annotate := func(span *zipkin.Span, values []string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for _, v := range values {
+				next := make(chan struct{})
+				go func(v string) {
+					defer close(next)
+					span.Annotate(v)
+					span.AnnotateBinary(v, v)
+					span.Encode() // invalidated by the next annotation
+				}(v)
+				<-next
+			}
+		}()
+		<-done
+	}
+
+	values := []string{"sr", "db.query", "cache.miss", "db.query", "ss"}
+	for i := 0; i < 100; i++ {
+		span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+		annotate(span, values)
+
+		encoded := span.Encode()
+		if want, have := len(values), len(encoded.GetAnnotations()); want != have {
+			t.Fatalf("want %d annotations, have %d", want, have)
+		}
+		var last int64
+		for j, a := range encoded.GetAnnotations() {
+			if want, have := values[j], a.GetValue(); want != have {
+				t.Fatalf("iteration %d: annotation %d: want %q, have %q", i, j, want, have)
+			}
+			if a.GetTimestamp() < last {
+				t.Errorf("iteration %d: annotation %d: timestamp decreased", i, j)
+			}
+			last = a.GetTimestamp()
+		}
+		for j, a := range encoded.GetBinaryAnnotations() {
+			if want, have := values[j], a.GetKey(); want != have {
+				t.Fatalf("iteration %d: binary annotation %d: want %q, have %q", i, j, want, have)
+			}
+		}
+	}
+}
+
+func TestRemoteEndpoint(t *testing.T) {
+	parent := zipkin.NewSpan("1.2.3.4:1234", "service", "parent", 1, 2, 0)
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, parent)
+
+	span, _ := zipkin.NewChildSpan(ctx, zipkin.NopCollector{}, "child", zipkin.RemoteEndpoint("5.6.7.8:5678", "db"))
+	if span.RemoteEndpoint() == nil {
+		t.Fatal("want remote endpoint, have nil")
+	}
+
+	annotations := span.Encode().GetBinaryAnnotations()
+	if want, have := 1, len(annotations); want != have {
+		t.Fatalf("want %d binary annotation(s), have %d", want, have)
+	}
+	a := annotations[0]
+	if want, have := zipkin.ServerAddress, a.Key; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := zipkincore.AnnotationType_BOOL, a.AnnotationType; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := "db", a.Host.GetServiceName(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := int32(0x05060708), a.Host.GetIpv4(); want != have {
+		t.Errorf("want %x, have %x", want, have)
+	}
+	if want, have := int16(5678), a.Host.GetPort(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestRemoteEndpointServerSpan(t *testing.T) {
+	for name, annotate := range map[string]func(*zipkin.Span){
+		"kind":        func(s *zipkin.Span) { s.SetKind(zipkin.KindServer) },
+		"annotations": func(s *zipkin.Span) { s.Annotate(zipkin.ServerReceive); s.Annotate(zipkin.ServerSend) },
+	} {
+		span := zipkin.NewSpan("1.2.3.4:1234", "service", "handle", 1, 2, 0)
+		zipkin.RemoteEndpoint("5.6.7.8:5678", "frontend")(span)
+		annotate(span)
+
+		annotations := span.Encode().GetBinaryAnnotations()
+		if want, have := 1, len(annotations); want != have {
+			t.Fatalf("%s: want %d binary annotation(s), have %d", name, want, have)
+		}
+		if want, have := zipkin.ClientAddress, annotations[0].Key; want != have {
+			t.Errorf("%s: want %q, have %q", name, want, have)
+		}
+		if want, have := "frontend", annotations[0].Host.GetServiceName(); want != have {
+			t.Errorf("%s: want %q, have %q", name, want, have)
+		}
+	}
+}
+
+func TestNewChildSpans(t *testing.T) {
+	parent := zipkin.NewSpan("1.2.3.4:1234", "service", "parent", 1, 2, 0)
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, parent)
+
+	names := make([]string, 1000)
+	for i := range names {
+		names[i] = fmt.Sprintf("shard-%d", i)
+	}
+	spans, collectFuncs := zipkin.NewChildSpans(ctx, zipkin.NopCollector{}, names)
+	if want, have := len(names), len(spans); want != have {
+		t.Fatalf("want %d spans, have %d", want, have)
+	}
+	if want, have := len(names), len(collectFuncs); want != have {
+		t.Fatalf("want %d collect funcs, have %d", want, have)
+	}
+
+	ids := map[int64]bool{parent.SpanID(): true}
+	for i, span := range spans {
+		if ids[span.SpanID()] {
+			t.Fatalf("span %d: duplicate span ID %d", i, span.SpanID())
+		}
+		ids[span.SpanID()] = true
+		if want, have := parent.TraceID(), span.TraceID(); want != have {
+			t.Errorf("span %d: want trace ID %d, have %d", i, want, have)
+		}
+		if want, have := parent.SpanID(), span.ParentSpanID(); want != have {
+			t.Errorf("span %d: want parent span ID %d, have %d", i, want, have)
+		}
+		if want, have := names[i], span.Encode().GetName(); want != have {
+			t.Errorf("span %d: want name %q, have %q", i, want, have)
+		}
+	}
+	if want, have := len(names)+1, len(ids); want != have {
+		t.Errorf("want %d distinct span IDs, have %d", want, have)
+	}
+
+	// Without a parent span, there's nothing to fan out from.
+	spans, collectFuncs = zipkin.NewChildSpans(context.Background(), zipkin.NopCollector{}, []string{"a", "b"})
+	for i := range spans {
+		if spans[i] != nil {
+			t.Errorf("span %d: want nil, have %v", i, spans[i])
+		}
+		collectFuncs[i]()
+	}
+}
+
+func TestRemoteEndpointUnresolvable(t *testing.T) {
+	parent := zipkin.NewSpan("1.2.3.4:1234", "service", "parent", 1, 2, 0)
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, parent)
+
+	span, _ := zipkin.NewChildSpan(ctx, zipkin.NopCollector{}, "child", zipkin.RemoteEndpoint("malformed", "db"))
+	if have := span.RemoteEndpoint(); have != nil {
+		t.Errorf("want nil remote endpoint, have %v", have)
+	}
+	if want, have := 0, len(span.Encode().GetBinaryAnnotations()); want != have {
+		t.Errorf("want %d binary annotation(s), have %d", want, have)
+	}
+}
+
+func TestEncodeMemoized(t *testing.T) {
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	span.Annotate(zipkin.ServerReceive)
+
+	first := span.Encode()
+	if second := span.Encode(); first != second {
+		t.Error("want unmodified span to reuse its encoding")
+	}
+
+	span.Annotate(zipkin.ServerSend)
+	encoded := span.Encode()
+	if encoded == first {
+		t.Fatal("want annotated span to be re-encoded")
+	}
+	if want, have := 2, len(encoded.GetAnnotations()); want != have {
+		t.Errorf("want %d annotation(s), have %d", want, have)
+	}
+
+	span.AnnotateBinary("key", "value")
+	if want, have := 1, len(span.Encode().GetBinaryAnnotations()); want != have {
+		t.Errorf("want %d binary annotation(s), have %d", want, have)
+	}
+
+	span.SetDebug()
+	if !span.Encode().GetDebug() {
+		t.Error("want debug flag after SetDebug")
+	}
+}
+
+func TestSpanFlags(t *testing.T) {
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	if span.Debug() || span.Sampled() || span.IsSampled() {
+		t.Fatalf("want fresh span without flags, have debug=%v sampled=%v", span.Debug(), span.Sampled())
+	}
+
+	span.SetDebug()
+	if !span.Debug() {
+		t.Error("want debug after SetDebug")
+	}
+	if span.Sampled() {
+		t.Error("want SetDebug to leave the sampled flag alone")
+	}
+
+	span.Sample()
+	if want, have := true, span.Sampled(); want != have {
+		t.Errorf("Sampled: want %v, have %v", want, have)
+	}
+	if want, have := span.Sampled(), span.IsSampled(); want != have {
+		t.Errorf("IsSampled: want %v, have %v", want, have)
+	}
+}
+
+func TestWithSpanID(t *testing.T) {
+	parent := zipkin.NewSpan("1.2.3.4:1234", "service", "parent", 1, 2, 0)
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, parent)
+
+	for i := 0; i < 2; i++ {
+		span, _ := zipkin.NewChildSpan(ctx, zipkin.NopCollector{}, "replay", zipkin.WithSpanID(42))
+		if want, have := int64(42), span.SpanID(); want != have {
+			t.Errorf("want span ID %d, have %d", want, have)
+		}
+		if want, have := int64(42), span.Encode().GetId(); want != have {
+			t.Errorf("want encoded span ID %d, have %d", want, have)
+		}
+		if want, have := parent.SpanID(), span.ParentSpanID(); want != have {
+			t.Errorf("want parent span ID %d, have %d", want, have)
+		}
+	}
+}
+
+func TestStartTime(t *testing.T) {
+	start := time.Date(2017, time.May, 1, 12, 0, 0, 0, time.UTC)
+	micros := func(t time.Time) int64 { return t.UnixNano() / 1e3 }
+
+	// Applied before the first annotation.
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "backfill", 1, 2, 0)
+	zipkin.StartTime(start)(span)
+	span.Annotate(zipkin.ServerReceive)
+	span.Annotate(zipkin.ServerSend)
+	annotations := span.Encode().GetAnnotations()
+	if want, have := micros(start), annotations[0].Timestamp; want != have {
+		t.Errorf("want first annotation at %d, have %d", want, have)
+	}
+	if d := time.Duration(annotations[1].Timestamp-annotations[0].Timestamp) * time.Microsecond; d < 0 || d > time.Second {
+		t.Errorf("want duration measured from the start time, have %v", d)
+	}
+
+	// Applied after the first annotation, by NewChildSpan.
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, span)
+	child, _ := zipkin.NewChildSpan(ctx, zipkin.NopCollector{}, "child", zipkin.StartTime(start.Add(time.Second)))
+	child.Annotate("custom")
+	annotations = child.Encode().GetAnnotations()
+	if want, have := zipkin.ClientSend, annotations[0].Value; want != have {
+		t.Fatalf("want first annotation %q, have %q", want, have)
+	}
+	if want, have := micros(start.Add(time.Second)), annotations[0].Timestamp; want != have {
+		t.Errorf("want first annotation at %d, have %d", want, have)
+	}
+	if d := time.Duration(annotations[1].Timestamp-annotations[0].Timestamp) * time.Microsecond; d < 0 || d > time.Second {
+		t.Errorf("want later annotations offset from the start time, have %v", d)
+	}
+	if want, have := start.Add(time.Second), child.AnnotationTimes()[0].Time; !want.Equal(have) {
+		t.Errorf("want annotation time %v, have %v", want, have)
+	}
+}
+
+func TestAnnotateUnsampledConstant(t *testing.T) {
+	span := unsampledSpan(t)
+	if allocs := testing.AllocsPerRun(100, func() { span.Annotate(zipkin.ServerReceive) }); allocs != 0 {
+		t.Errorf("want no allocations, have %v", allocs)
+	}
+	span.Annotate("custom")
+	if want, have := 1, len(span.Encode().GetAnnotations()); want != have {
+		t.Errorf("want %d annotation(s), have %d", want, have)
+	}
+
+	// Debug spans are collected regardless of sampling.
+	span.SetDebug()
+	span.Annotate(zipkin.ServerSend)
+	if want, have := 2, len(span.Encode().GetAnnotations()); want != have {
+		t.Errorf("want %d annotation(s), have %d", want, have)
+	}
+}
+
+func BenchmarkAnnotateUnsampledConstant(b *testing.B) {
+	span := unsampledSpan(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		span.Annotate(zipkin.ServerReceive)
+	}
+}
+
+// unsampledSpan returns a span whose upstream decided not to sample it.
+func unsampledSpan(tb testing.TB) *zipkin.Span {
+	r, _ := http.NewRequest("GET", "http://example.com", nil)
+	r.Header.Set("X-B3-TraceId", "1")
+	r.Header.Set("X-B3-SpanId", "2")
+	r.Header.Set("X-B3-Sampled", "0")
+	newSpan := zipkin.MakeNewSpanFunc("1.2.3.4:1234", "service", "method")
+	span, ok := zipkin.FromContext(zipkin.ToContext(newSpan, log.NewNopLogger())(context.Background(), r))
+	if !ok {
+		tb.Fatal("no span in context")
+	}
+	return span
+}
+
+func TestSetServiceVersion(t *testing.T) {
+	defer zipkin.SetServiceVersion("")
+
+	version := func(span *zipkin.Span) (string, bool) {
+		for _, a := range span.Encode().GetBinaryAnnotations() {
+			if a.Key == zipkin.ServiceVersion {
+				return string(a.Value), true
+			}
+		}
+		return "", false
+	}
+
+	if v, ok := version(zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)); ok {
+		t.Errorf("want no version before it's set, have %q", v)
+	}
+
+	zipkin.SetServiceVersion("v1.2.3-abcdef")
+	parent := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	child, _ := zipkin.NewChildSpan(context.WithValue(context.Background(), zipkin.SpanContextKey, parent), nil, "child")
+	for name, span := range map[string]*zipkin.Span{"span": parent, "child span": child} {
+		if v, ok := version(span); !ok || v != "v1.2.3-abcdef" {
+			t.Errorf("%s: want version %q, have %q", name, "v1.2.3-abcdef", v)
+		}
+	}
+}
+
+func TestSetPlaceholderEndpoints(t *testing.T) {
+	defer zipkin.SetPlaceholderEndpoints(false)
+
+	newSpan := func() *zipkin.Span {
+		span := zipkin.NewSpan("no port", "service", "method", 1, 2, 0) // unresolvable host
+		span.SetKind(zipkin.KindServer)
+		span.Annotate("custom")
+		span.AnnotateBinary("key", "value")
+		return span
+	}
+	hosts := func(zs *zipkincore.Span) []*zipkincore.Endpoint {
+		var hosts []*zipkincore.Endpoint
+		for _, a := range zs.GetAnnotations() {
+			hosts = append(hosts, a.Host)
+		}
+		for _, a := range zs.GetBinaryAnnotations() {
+			hosts = append(hosts, a.Host)
+		}
+		return hosts
+	}
+
+	for _, host := range hosts(newSpan().Encode()) {
+		if host != nil {
+			t.Errorf("by default, want nil endpoint, have %v", host)
+		}
+	}
+
+	zipkin.SetPlaceholderEndpoints(true)
+	encoded := hosts(newSpan().Encode())
+	if want, have := 4, len(encoded); want != have {
+		t.Fatalf("want %d annotations, have %d", want, have) // sr, custom, ss, key
+	}
+	for _, host := range encoded {
+		if host == nil {
+			t.Fatal("want placeholder endpoint, have nil")
+		}
+		if want, have := "service", host.ServiceName; want != have {
+			t.Errorf("want service name %q, have %q", want, have)
+		}
+	}
+
+	// Resolved endpoints are left alone.
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	span.Annotate("custom")
+	if want, have := int16(1234), span.Encode().GetAnnotations()[0].Host.Port; want != have {
+		t.Errorf("want port %d, have %d", want, have)
+	}
+}
+
+func TestEncodeParentID(t *testing.T) {
+	root := zipkin.NewSpan("1.2.3.4:1234", "service", "root", 1, 2, 0)
+	if !root.IsRoot() {
+		t.Error("want root span, have non-root")
+	}
+	if have := root.Encode().ParentId; have != nil {
+		t.Errorf("root span: want nil parent ID, have %d", *have)
+	}
+
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, root)
+	child, _ := zipkin.NewChildSpan(ctx, nil, "child")
+	if child.IsRoot() {
+		t.Error("want non-root span, have root")
+	}
+	have := child.Encode().ParentId
+	if have == nil {
+		t.Fatal("child span: want parent ID, have nil")
+	}
+	if want := root.SpanID(); want != *have {
+		t.Errorf("child span: want parent ID %d, have %d", want, *have)
+	}
+}
+
+func TestAddLink(t *testing.T) {
+	span := zipkin.NewSpan("1.2.3.4:1234", "consumer", "consume", 1, 2, 0)
+	span.AddLink(0x1234, 0xabcdef)
+	span.AddLink(0x5678, 0x9)
+
+	var have []string
+	for _, a := range span.Encode().GetBinaryAnnotations() {
+		if a.AnnotationType != zipkincore.AnnotationType_STRING {
+			t.Errorf("%s: want string annotation, have %s", a.Key, a.AnnotationType)
+		}
+		have = append(have, a.Key+"="+string(a.Value))
+	}
+	want := []string{
+		zipkin.LinkTraceID + "=0000000000001234",
+		zipkin.LinkSpanID + "=0000000000abcdef",
+		zipkin.LinkTraceID + "=0000000000005678",
+		zipkin.LinkSpanID + "=0000000000000009",
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestAnnotateQueryParams(t *testing.T) {
+	values, err := url.ParseQuery("tenant=acme&region=eu&region=us&token=s3cr3t&password=hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := zipkin.NewSpan("1.2.3.4:1234", "gateway", "route", 1, 2, 0)
+	zipkin.AnnotateQueryParams(span, values, []string{"tenant", "region", "missing"})
+
+	var have []string
+	for _, a := range span.Encode().GetBinaryAnnotations() {
+		if a.AnnotationType != zipkincore.AnnotationType_STRING {
+			t.Errorf("%s: want string annotation, have %s", a.Key, a.AnnotationType)
+		}
+		have = append(have, a.Key+"="+string(a.Value))
+	}
+	want := []string{
+		zipkin.QueryParamPrefix + "tenant=acme",
+		zipkin.QueryParamPrefix + "region=eu,us",
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestAnnotationTimes(t *testing.T) {
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	span.Annotate(zipkin.ClientSend)
+	time.Sleep(time.Millisecond)
+	span.Annotate("dequeued")
+	span.Annotate(zipkin.ClientReceive)
+
+	times := span.AnnotationTimes()
+	var values []string
+	for i, a := range times {
+		values = append(values, a.Value)
+		if i > 0 && a.Time.Before(times[i-1].Time) {
+			t.Errorf("%s recorded before %s", a.Value, times[i-1].Value)
+		}
+	}
+	if want, have := []string{zipkin.ClientSend, "dequeued", zipkin.ClientReceive}, values; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if queued := times[1].Time.Sub(times[0].Time); queued < time.Millisecond {
+		t.Errorf("want at least 1ms between cs and dequeued, have %v", queued)
+	}
+	for i, a := range span.Encode().GetAnnotations() {
+		if want, have := a.Timestamp, times[i].Time.UnixNano()/1e3; want != have {
+			t.Errorf("%s: want encoded timestamp %d, have %d", a.Value, want, have)
+		}
+	}
+}
+
+func TestSampledConcurrentAccess(t *testing.T) {
+	collector, err := zipkin.NewUDPCollector("127.0.0.1:1", zipkin.UDPSampleRate(zipkin.SampleRate(1, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer collector.Close()
+
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, span)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		collector.ShouldSample(span)
+		span.Annotate("foo")
+		span.SetDebug()
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			span.IsSampled()
+			span.Debug()
+			zipkin.NewChildSpan(ctx, collector, "child")
+		}
+	}()
+	wg.Wait()
+
+	if !span.Sampled() || !span.Debug() {
+		t.Errorf("want sampled debug span, have sampled %v, debug %v", span.Sampled(), span.Debug())
+	}
+}
+
+func TestHostEndpoint(t *testing.T) {
+	host := zipkin.MakeEndpoint("10.0.0.1:8080", "db")
+	if host == nil {
+		t.Fatal("want endpoint, have nil")
+	}
+	parent := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, parent)
+
+	child, _ := zipkin.NewChildSpan(ctx, nil, "query", zipkin.HostEndpoint(host))
+	child.Annotate("foo")
+	if want, have := host, child.Encode().GetAnnotations()[1].GetHost(); want != have {
+		t.Errorf("want host %v, have %v", want, have)
+	}
+
+	child, _ = zipkin.NewChildSpan(ctx, nil, "query", zipkin.HostEndpoint(nil))
+	child.Annotate("foo")
+	if want, have := "service", child.Encode().GetAnnotations()[1].GetHost().GetServiceName(); want != have {
+		t.Errorf("nil endpoint: want service %q, have %q", want, have)
+	}
+}
+
+func TestNewLocalChildSpan(t *testing.T) {
+	collector := zipkintest.NewRecorder()
+	parent := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	ctx := zipkin.NewContext(context.Background(), parent)
+
+	span, collect := zipkin.NewLocalChildSpan(ctx, collector, "compute")
+	span.Annotate("step")
+	collect()
+
+	encoded := collector.LastSpan()
+	if encoded == nil {
+		t.Fatal("span not collected")
+	}
+	if want, have := parent.SpanID(), encoded.GetParentId(); want != have {
+		t.Errorf("want parent %d, have %d", want, have)
+	}
+	var values []string
+	for _, a := range encoded.GetAnnotations() {
+		values = append(values, a.Value)
+	}
+	if want, have := []string{"step"}, values; !reflect.DeepEqual(want, have) {
+		t.Errorf("want annotations %v, without client annotations, have %v", want, have)
+	}
+	var lc string
+	for _, a := range encoded.GetBinaryAnnotations() {
+		if a.Key == zipkin.LocalComponent {
+			lc = string(a.Value)
+		}
+	}
+	if want, have := "service", lc; want != have {
+		t.Errorf("want local component %q, have %q", want, have)
+	}
+
+	if span, _ := zipkin.NewLocalChildSpan(context.Background(), collector, "compute"); span != nil {
+		t.Errorf("want no span without a parent, have %v", span)
+	}
+}
+
+func TestComposeOptions(t *testing.T) {
+	var (
+		first  = zipkin.MakeEndpoint("10.0.0.1:8080", "first")
+		second = zipkin.MakeEndpoint("10.0.0.2:8080", "second")
+		parent = zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+		ctx    = zipkin.NewContext(context.Background(), parent)
+	)
+	var applied []string
+	record := func(name string) zipkin.SpanOption {
+		return func(*zipkin.Span) { applied = append(applied, name) }
+	}
+	opts := zipkin.ComposeOptions(
+		record("a"),
+		zipkin.HostEndpoint(first),
+		nil,
+		zipkin.ComposeOptions(record("b"), zipkin.HostEndpoint(second)),
+		zipkin.Debug(true),
+		zipkin.WithSpanID(42),
+		record("c"),
+	)
+
+	child, _ := zipkin.NewChildSpan(ctx, nil, "query", opts)
+	if want, have := []string{"a", "b", "c"}, applied; !reflect.DeepEqual(want, have) {
+		t.Errorf("want options applied in order %v, have %v", want, have)
+	}
+	child.Annotate("foo")
+	if want, have := second, child.Encode().GetAnnotations()[1].GetHost(); want != have {
+		t.Errorf("want the last host %v, have %v", want, have)
+	}
+	if !child.Debug() {
+		t.Error("want debug span")
+	}
+	if want, have := int64(42), child.SpanID(); want != have {
+		t.Errorf("want span ID %d, have %d", want, have)
+	}
+}
+
+func BenchmarkNewChildSpanHost(b *testing.B) {
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		zipkin.NewChildSpan(ctx, nil, "query", zipkin.Host("localhost:5432", "db"))
+	}
+}
+
+func BenchmarkNewChildSpanHostEndpoint(b *testing.B) {
+	ctx := context.WithValue(context.Background(), zipkin.SpanContextKey, zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0))
+	host := zipkin.MakeEndpoint("localhost:5432", "db") // resolved once
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		zipkin.NewChildSpan(ctx, nil, "query", zipkin.HostEndpoint(host))
+	}
+}
+
+func TestMaxSpansPerTrace(t *testing.T) {
+	defer zipkin.SetMaxSpansPerTrace(0)
+	zipkin.SetMaxSpansPerTrace(5)
+
+	collector := zipkintest.NewRecorder()
+	root := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	root.Sample()
+	ctx := zipkin.NewContext(context.Background(), root)
+
+	// A runaway recursion, creating children and grandchildren concurrently.
+	var (
+		wg    sync.WaitGroup
+		mtx   sync.Mutex
+		spans []*zipkin.Span
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			child, collect := zipkin.NewChildSpan(ctx, collector, "child")
+			grandchild, collectGrandchild := zipkin.NewLocalChildSpan(zipkin.NewContext(ctx, child), collector, "grandchild")
+			grandchild.Annotate("step")
+			collectGrandchild()
+			collect()
+			mtx.Lock()
+			spans = append(spans, child, grandchild)
+			mtx.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// The root span takes one of the 5 spans of the budget.
+	if want, have := 4, len(collector.Spans()); want != have {
+		t.Errorf("want %d spans collected, have %d", want, have)
+	}
+	var noops int
+	for _, span := range spans {
+		if span.Sampled() {
+			continue
+		}
+		noops++
+		if span.SpanID() == 0 {
+			t.Error("want no-op span with an ID, have none")
+		}
+		if n := len(span.Encode().GetAnnotations()) + len(span.Encode().GetBinaryAnnotations()); n != 0 {
+			t.Errorf("want no annotations on a no-op span, have %d", n)
+		}
+		if want, have := "0", zipkin.B3Headers(span)["X-B3-Sampled"]; want != have {
+			t.Errorf("want no-op span propagated as unsampled %q, have %q", want, have)
+		}
+	}
+	if want, have := 36, noops; want != have {
+		t.Errorf("want %d no-op spans, have %d", want, have)
+	}
+
+	// Client spans of AnnotateClient count against the budget too.
+	zipkin.SetMaxSpansPerTrace(2)
+	root = zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	ctx = zipkin.NewContext(context.Background(), root)
+	collector = zipkintest.NewRecorder()
+	client := zipkin.AnnotateClient(zipkin.MakeNewSpanFunc("1.2.3.4:1234", "service", "call"), collector)(endpointtest.Nop)
+	for i := 0; i < 3; i++ {
+		client(ctx, struct{}{})
+	}
+	if want, have := 1, len(collector.Spans()); want != have {
+		t.Errorf("want %d client span collected, have %d", want, have)
+	}
+
+	// Spans created without a cap stay uncapped.
+	zipkin.SetMaxSpansPerTrace(0)
+	root = zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	root.Sample()
+	ctx = zipkin.NewContext(context.Background(), root)
+	for i := 0; i < 10; i++ {
+		if child, _ := zipkin.NewChildSpan(ctx, nil, "child"); !child.Sampled() {
+			t.Fatalf("want uncapped child %d sampled", i)
+		}
+	}
+}
+
+func TestSetKind(t *testing.T) {
+	for _, tc := range []struct {
+		kind        string
+		annotations []string
+	}{
+		{zipkin.KindClient, []string{zipkin.ClientSend, "retry", "done", zipkin.ClientReceive}},
+		{zipkin.KindServer, []string{zipkin.ServerReceive, "retry", "done", zipkin.ServerSend}},
+		{zipkin.KindProducer, []string{zipkin.MessageSend, "retry", "done"}},
+		{zipkin.KindConsumer, []string{zipkin.MessageReceive, "retry", "done"}},
+	} {
+		span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+		span.Annotate("retry")
+		span.Annotate("done")
+		span.SetKind(tc.kind)
+
+		encoded := span.Encode()
+		var values []string
+		for _, a := range encoded.GetAnnotations() {
+			values = append(values, a.Value)
+		}
+		if !reflect.DeepEqual(tc.annotations, values) {
+			t.Errorf("%s: want annotations %v, have %v", tc.kind, tc.annotations, values)
+		}
+		annotations := encoded.GetAnnotations()
+		first, last := annotations[0], annotations[len(annotations)-1]
+		if want, have := annotations[1].Timestamp, first.Timestamp; want != have {
+			t.Errorf("%s: want %s at %d, have %d", tc.kind, first.Value, want, have)
+		}
+		if want, have := annotations[2].Timestamp, last.Timestamp; want != have {
+			t.Errorf("%s: want %s at %d, have %d", tc.kind, last.Value, want, have)
+		}
+
+		var v2 []struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal([]byte(zipkin.EncodeV2JSON(encoded)), &v2); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 1, len(v2); want != have {
+			t.Fatalf("%s: want %d v2 span(s), have %d", tc.kind, want, have)
+		}
+		if want, have := tc.kind, v2[0].Kind; want != have {
+			t.Errorf("%s: want v2 kind %q, have %q", tc.kind, want, have)
+		}
+	}
+}
+
+func TestSetKindAnnotated(t *testing.T) {
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	span.Annotate(zipkin.ClientSend)
+	span.Annotate(zipkin.ClientReceive)
+	span.SetKind(zipkin.KindClient)
+	if want, have := 2, len(span.Encode().GetAnnotations()); want != have {
+		t.Errorf("want %d annotation(s), have %d", want, have)
+	}
+
+	// An unknown kind clears the kind.
+	span = zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	span.SetKind(zipkin.KindServer)
+	span.SetKind("SERVICE")
+	if want, have := 0, len(span.Encode().GetAnnotations()); want != have {
+		t.Errorf("want %d annotation(s), have %d", want, have)
+	}
+}
+
+func TestInheritAnnotations(t *testing.T) {
+	collector := zipkintest.NewRecorder()
+	parent := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	parent.AnnotateBinary("tenant.id", "acme")
+	parent.AnnotateBinary("tenant.tier", int64(3))
+	parent.AnnotateBinary("user.email", "jane@example.com")
+	ctx := zipkin.NewContext(context.Background(), parent)
+
+	_, collect := zipkin.NewChildSpan(ctx, collector, "query",
+		zipkin.InheritAnnotations("tenant.id", "tenant.tier", "tenant.region"),
+	)
+	collect()
+
+	have := map[string]*zipkincore.BinaryAnnotation{}
+	for _, a := range collector.LastSpan().GetBinaryAnnotations() {
+		have[a.Key] = a
+	}
+	if want, have := 2, len(have); want != have {
+		t.Fatalf("want %d binary annotations, have %d", want, have)
+	}
+	if a := have["tenant.id"]; a == nil || string(a.Value) != "acme" {
+		t.Errorf("want tenant.id inherited, have %v", a)
+	}
+	if a := have["tenant.tier"]; a == nil || a.AnnotationType != zipkincore.AnnotationType_I64 {
+		t.Errorf("want tenant.tier inherited as I64, have %v", a)
+	}
+
+	// The parent is left as it was.
+	if want, have := 3, len(parent.Encode().GetBinaryAnnotations()); want != have {
+		t.Errorf("want %d parent binary annotations, have %d", want, have)
+	}
+}
+
+func TestIntAnnotationWidth(t *testing.T) {
+	defer zipkin.IntAnnotationWidth(64)
+
+	for _, tc := range []struct {
+		bits  int
+		value interface{}
+		typ   zipkincore.AnnotationType
+		want  []byte
+	}{
+		{64, -2, zipkincore.AnnotationType_I64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}},
+		{64, uint(1 << 40), zipkincore.AnnotationType_I64, []byte{0, 0, 1, 0, 0, 0, 0, 0}},
+		{32, -2, zipkincore.AnnotationType_I32, []byte{0xff, 0xff, 0xff, 0xfe}},
+		{32, uint(404), zipkincore.AnnotationType_I32, []byte{0, 0, 0x01, 0x94}},
+	} {
+		zipkin.IntAnnotationWidth(tc.bits)
+		span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+		span.AnnotateBinary("n", tc.value)
+
+		a := span.Encode().GetBinaryAnnotations()[0]
+		if want, have := tc.typ, a.AnnotationType; want != have {
+			t.Errorf("%d bits, %T(%v): want type %s, have %s", tc.bits, tc.value, tc.value, want, have)
+		}
+		if want, have := tc.want, a.Value; !bytes.Equal(want, have) {
+			t.Errorf("%d bits, %T(%v): want bytes %x, have %x", tc.bits, tc.value, tc.value, want, have)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic on invalid width")
+		}
+	}()
+	zipkin.IntAnnotationWidth(16)
+}
@@ -0,0 +1,167 @@
+package zipkin
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+// defaultUDPMaxPacketSize is the default maximum size of a datagram. It's
+// the largest payload of a UDP datagram over IPv4, rounded down, like the
+// Jaeger agent's.
+const defaultUDPMaxPacketSize = 65000
+
+var (
+	errUDPSpanTooLarge = errors.New("span larger than max packet size; span dropped")
+	errUDPBufferFull   = errors.New("span buffer full; span dropped")
+)
+
+// UDPCollector implements Collector by sending each span, Thrift-encoded, as
+// a single UDP datagram to a local agent, which forwards it to Zipkin. It's
+// fire-and-forget: there's no connection to manage, and spans the agent
+// doesn't receive are lost. Collect never blocks; spans which don't fit in a
+// datagram, or arrive while the send buffer is full, are dropped and counted.
+type UDPCollector struct {
+	conn          net.Conn
+	maxPacketSize int
+	bufferSize    int
+	dropped       metrics.Counter
+	shouldSample  SpanSampler
+	logger        log.Logger
+	spanc         chan []byte
+	quit          chan struct{}
+	done          chan struct{}
+}
+
+// NewUDPCollector returns a new UDP-backed Collector. addr should be a UDP
+// endpoint of the form "host:port", usually of an agent on localhost.
+func NewUDPCollector(addr string, options ...UDPOption) (Collector, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &UDPCollector{
+		conn:          conn,
+		maxPacketSize: defaultUDPMaxPacketSize,
+		bufferSize:    1000,
+		dropped:       discard.NewCounter("udp_dropped_spans"),
+		shouldSample:  traceIDSampler(SampleRate(1.0, rand.Int63())),
+		logger:        log.NewNopLogger(),
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	c.spanc = make(chan []byte, c.bufferSize)
+	go c.loop()
+	return c, nil
+}
+
+// Collect implements Collector. It returns an error, and drops the span, if
+// the encoded span is larger than the max packet size, or if the buffer of
+// spans waiting to be sent is full.
+func (c *UDPCollector) Collect(s *Span) error {
+	if !c.ShouldSample(s) && !s.Debug() {
+		return nil
+	}
+	b := thriftSerialize(s)
+	if len(b) > c.maxPacketSize {
+		c.dropped.Add(1)
+		return errUDPSpanTooLarge
+	}
+	select {
+	case c.spanc <- b:
+		return nil
+	default:
+		c.dropped.Add(1)
+		return errUDPBufferFull
+	}
+}
+
+// ShouldSample implements Collector.
+func (c *UDPCollector) ShouldSample(s *Span) bool {
+	return s.sample(c.shouldSample)
+}
+
+// Close implements Collector. It sends the spans still buffered, and closes
+// the socket.
+func (c *UDPCollector) Close() error {
+	close(c.quit)
+	<-c.done
+	return c.conn.Close()
+}
+
+func (c *UDPCollector) loop() {
+	defer close(c.done)
+	for {
+		select {
+		case b := <-c.spanc:
+			c.send(b)
+		case <-c.quit:
+			for {
+				select {
+				case b := <-c.spanc:
+					c.send(b)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send writes the encoded span as a datagram. Errors, e.g. when no agent is
+// listening, are logged, and the span is lost.
+func (c *UDPCollector) send(b []byte) {
+	if _, err := c.conn.Write(b); err != nil {
+		c.logger.Log("err", err.Error())
+	}
+}
+
+// UDPOption sets a parameter for the UDPCollector.
+type UDPOption func(c *UDPCollector)
+
+// UDPMaxPacketSize sets the maximum size of a datagram. Spans larger than
+// this, once encoded, are dropped. The default is 65000 bytes; it should be
+// lowered to the path MTU if the agent isn't on the local host.
+func UDPMaxPacketSize(n int) UDPOption {
+	return func(c *UDPCollector) { c.maxPacketSize = n }
+}
+
+// UDPBufferSize sets the number of spans buffered while waiting to be sent.
+// Spans collected while the buffer is full are dropped. The default is 1000.
+func UDPBufferSize(n int) UDPOption {
+	return func(c *UDPCollector) { c.bufferSize = n }
+}
+
+// UDPDroppedCounter sets the counter incremented for each span dropped,
+// because it's too large, or the buffer is full. By default, dropped spans
+// aren't counted.
+func UDPDroppedCounter(counter metrics.Counter) UDPOption {
+	return func(c *UDPCollector) { c.dropped = counter }
+}
+
+// UDPSampleRate sets the sample rate used to determine if a trace will be
+// sent to the collector. By default, the sample rate is 1.0, i.e. all traces
+// are sent.
+func UDPSampleRate(sr Sampler) UDPOption {
+	return func(c *UDPCollector) { c.shouldSample = traceIDSampler(sr) }
+}
+
+// UDPSpanSampler sets the sampler used to determine if a trace will be sent
+// to the collector, based on the span itself, e.g. its method name. It
+// replaces the sample rate set by UDPSampleRate.
+func UDPSpanSampler(ss SpanSampler) UDPOption {
+	return func(c *UDPCollector) { c.shouldSample = ss }
+}
+
+// UDPLogger sets the logger used to report errors in the collection process.
+// By default, a no-op logger is used, i.e. no errors are logged anywhere.
+func UDPLogger(logger log.Logger) UDPOption {
+	return func(c *UDPCollector) { c.logger = logger }
+}
@@ -0,0 +1,106 @@
+package zipkin_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"gopkg.in/Shopify/sarama.v1"
+
+	"github.com/go-kit/kit/tracing/zipkin"
+)
+
+func TestUDPCollector(t *testing.T) {
+	agent := listenUDP(t)
+	defer agent.Close()
+
+	c, err := zipkin.NewUDPCollector(agent.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, want := range spans {
+		if err := c.Collect(want); err != nil {
+			t.Fatal(err)
+		}
+		have := deserializeSpan(t, sarama.ByteEncoder(readDatagram(t, agent)))
+		if want.TraceID() != have.GetTraceId() || want.SpanID() != have.GetId() || want.Name() != have.GetName() {
+			t.Errorf("want span %d/%d %q, have %d/%d %q", want.TraceID(), want.SpanID(), want.Name(), have.GetTraceId(), have.GetId(), have.GetName())
+		}
+	}
+}
+
+func TestUDPCollectorSpanTooLarge(t *testing.T) {
+	agent := listenUDP(t)
+	defer agent.Close()
+
+	dropped := &countingCounter{}
+	c, err := zipkin.NewUDPCollector(
+		agent.LocalAddr().String(),
+		zipkin.UDPMaxPacketSize(64),
+		zipkin.UDPDroppedCounter(dropped),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	span := zipkin.NewSpan("203.0.113.10:1234", "service", "method", 1, 2, 0)
+	span.AnnotateBinary("payload", string(make([]byte, 64)))
+	if err := c.Collect(span); err == nil {
+		t.Error("want error, have none")
+	}
+	if want, have := uint64(1), dropped.n; want != have {
+		t.Errorf("want %d dropped span(s), have %d", want, have)
+	}
+
+	agent.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if n, _, err := agent.ReadFrom(make([]byte, 65536)); err == nil {
+		t.Errorf("want no datagram, have %d bytes", n)
+	}
+}
+
+func TestUDPCollectorNoAgent(t *testing.T) {
+	agent := listenUDP(t)
+	addr := agent.LocalAddr().String()
+	agent.Close()
+
+	c, err := zipkin.NewUDPCollector(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Without an agent, spans are lost, but collecting never blocks.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10000; i++ {
+			c.Collect(zipkin.NewSpan("203.0.113.10:1234", "service", "method", 1, int64(i), 0))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Collect blocked")
+	}
+}
+
+func listenUDP(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func readDatagram(t *testing.T, conn net.PacketConn) []byte {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf[:n]
+}
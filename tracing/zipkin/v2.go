@@ -0,0 +1,246 @@
+package zipkin
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+
+	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/zipkincore"
+)
+
+// The v2 model of OpenZipkin, as accepted by the /api/v2/spans endpoint of
+// Zipkin 2.x servers, names what the v1 Thrift model encodes with core
+// annotations. A span has a kind, e.g. CLIENT or SERVER, a timestamp and duration,
+// a local and a remote endpoint, and string tags in place of binary
+// annotations. A v1 span shared by a client and a server, i.e. annotated
+// with both cs and sr, is two v2 spans with the same ID, the server's marked
+// as shared. Messaging spans, annotated with ms or mr, are of the PRODUCER
+// or CONSUMER kind. See https://zipkin.io/zipkin-api/#/default/post_spans.
+
+type v2Span struct {
+	TraceID        string            `json:"traceId"`
+	ParentID       string            `json:"parentId,omitempty"`
+	ID             string            `json:"id"`
+	Kind           string            `json:"kind,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Timestamp      int64             `json:"timestamp,omitempty"`
+	Duration       int64             `json:"duration,omitempty"`
+	Debug          bool              `json:"debug,omitempty"`
+	Shared         bool              `json:"shared,omitempty"`
+	LocalEndpoint  *v2Endpoint       `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *v2Endpoint       `json:"remoteEndpoint,omitempty"`
+	Annotations    []v2Annotation    `json:"annotations,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+type v2Endpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	Port        uint16 `json:"port,omitempty"`
+}
+
+type v2Annotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// encodeV2 converts a span of the v1 Thrift model to the v2 model. It
+// returns two spans for a span shared by a client and a server, the client's
+// first, and one otherwise.
+func encodeV2(zs *zipkincore.Span) []*v2Span {
+	newSpan := func() *v2Span {
+		s := &v2Span{
+			TraceID: fmt.Sprintf("%016x", uint64(zs.TraceId)),
+			ID:      fmt.Sprintf("%016x", uint64(zs.Id)),
+			Name:    zs.Name,
+			Debug:   zs.Debug,
+		}
+		if zs.ParentId != nil && *zs.ParentId != 0 {
+			s.ParentID = fmt.Sprintf("%016x", uint64(*zs.ParentId))
+		}
+		return s
+	}
+
+	var cs, cr, sr, ss, ms, mr *zipkincore.Annotation
+	var others []*zipkincore.Annotation
+	for _, a := range zs.Annotations {
+		switch {
+		case a.Value == ClientSend && cs == nil:
+			cs = a
+		case a.Value == ClientReceive && cr == nil:
+			cr = a
+		case a.Value == ServerReceive && sr == nil:
+			sr = a
+		case a.Value == ServerSend && ss == nil:
+			ss = a
+		case a.Value == MessageSend && ms == nil:
+			ms = a
+		case a.Value == MessageReceive && mr == nil:
+			mr = a
+		default:
+			others = append(others, a)
+		}
+	}
+
+	var client, server, local *v2Span
+	if cs != nil || cr != nil {
+		client = newSpan()
+		client.Kind = KindClient
+		client.LocalEndpoint = v2EndpointOf(firstHost(cs, cr))
+		client.Timestamp, client.Duration = timing(cs, cr)
+	}
+	if sr != nil || ss != nil {
+		server = newSpan()
+		server.Kind = KindServer
+		server.LocalEndpoint = v2EndpointOf(firstHost(sr, ss))
+		server.Timestamp, server.Duration = timing(sr, ss)
+		server.Shared = client != nil
+	}
+	var spans []*v2Span
+	for _, s := range []*v2Span{client, server} {
+		if s != nil {
+			spans = append(spans, s)
+		}
+	}
+	if len(spans) == 0 && (ms != nil || mr != nil) {
+		// A messaging span, timed from the message annotation to the last
+		// other annotation, if any.
+		messaging, kind := ms, KindProducer
+		if messaging == nil {
+			messaging, kind = mr, KindConsumer
+		}
+		local = newSpan()
+		local.Kind = kind
+		local.LocalEndpoint = v2EndpointOf(messaging.Host)
+		var end *zipkincore.Annotation
+		if len(others) > 0 {
+			end = others[len(others)-1]
+		}
+		local.Timestamp, local.Duration = timing(messaging, end)
+		if ms != nil && mr != nil {
+			others = append(others, mr) // a span has a single kind
+		}
+		spans = append(spans, local)
+	}
+	if len(spans) == 0 {
+		// A local span, e.g. of an operation within the service.
+		local = newSpan()
+		if len(others) > 0 {
+			first, last := others[0], others[len(others)-1]
+			local.LocalEndpoint = v2EndpointOf(first.Host)
+			local.Timestamp, local.Duration = timing(first, last)
+		}
+		spans = append(spans, local)
+	}
+	if local == nil {
+		// Messaging annotations of client or server spans are plain ones.
+		for _, a := range []*zipkincore.Annotation{ms, mr} {
+			if a != nil {
+				others = append(others, a)
+			}
+		}
+	}
+	primary := spans[0]
+
+	for _, a := range others {
+		primary.Annotations = append(primary.Annotations, v2Annotation{
+			Timestamp: a.Timestamp,
+			Value:     a.Value,
+		})
+	}
+
+	for _, a := range zs.BinaryAnnotations {
+		switch {
+		case a.Key == ServerAddress && a.AnnotationType == zipkincore.AnnotationType_BOOL:
+			if client != nil {
+				client.RemoteEndpoint = v2EndpointOf(a.Host)
+			} else {
+				primary.RemoteEndpoint = v2EndpointOf(a.Host)
+			}
+		case a.Key == ClientAddress && a.AnnotationType == zipkincore.AnnotationType_BOOL:
+			if server != nil {
+				server.RemoteEndpoint = v2EndpointOf(a.Host)
+			} else {
+				primary.RemoteEndpoint = v2EndpointOf(a.Host)
+			}
+		default:
+			if primary.Tags == nil {
+				primary.Tags = map[string]string{}
+			}
+			primary.Tags[a.Key] = v2TagValue(a)
+			if primary.LocalEndpoint == nil {
+				primary.LocalEndpoint = v2EndpointOf(a.Host)
+			}
+		}
+	}
+	return spans
+}
+
+// timing returns the timestamp of the begin annotation, and the duration
+// until the end annotation. Each is zero, i.e. omitted, if unknown.
+func timing(begin, end *zipkincore.Annotation) (timestamp, duration int64) {
+	if begin == nil {
+		return 0, 0
+	}
+	if end != nil && end.Timestamp > begin.Timestamp {
+		duration = end.Timestamp - begin.Timestamp
+	}
+	return begin.Timestamp, duration
+}
+
+func firstHost(annotations ...*zipkincore.Annotation) *zipkincore.Endpoint {
+	for _, a := range annotations {
+		if a != nil && a.Host != nil {
+			return a.Host
+		}
+	}
+	return nil
+}
+
+func v2EndpointOf(e *zipkincore.Endpoint) *v2Endpoint {
+	if e == nil {
+		return nil
+	}
+	v2 := &v2Endpoint{
+		ServiceName: e.ServiceName,
+		Port:        uint16(e.Port),
+	}
+	if e.Ipv4 != 0 {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(e.Ipv4))
+		v2.IPv4 = ip.String()
+	}
+	return v2
+}
+
+// v2TagValue renders the value of a binary annotation as a string, the only
+// type of v2 tags. Bytes are rendered in base64, like in the v1 JSON model.
+func v2TagValue(a *zipkincore.BinaryAnnotation) string {
+	v := a.Value
+	switch a.AnnotationType {
+	case zipkincore.AnnotationType_BOOL:
+		return strconv.FormatBool(len(v) > 0 && v[0] != 0)
+	case zipkincore.AnnotationType_I16:
+		if len(v) >= 2 {
+			return strconv.FormatInt(int64(int16(binary.BigEndian.Uint16(v))), 10)
+		}
+	case zipkincore.AnnotationType_I32:
+		if len(v) >= 4 {
+			return strconv.FormatInt(int64(int32(binary.BigEndian.Uint32(v))), 10)
+		}
+	case zipkincore.AnnotationType_I64:
+		if len(v) >= 8 {
+			return strconv.FormatInt(int64(binary.BigEndian.Uint64(v)), 10)
+		}
+	case zipkincore.AnnotationType_DOUBLE:
+		if len(v) >= 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(v)), 'g', -1, 64)
+		}
+	case zipkincore.AnnotationType_STRING:
+		return string(v)
+	}
+	return base64.StdEncoding.EncodeToString(v)
+}
@@ -0,0 +1,178 @@
+package zipkin_test
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/go-kit/kit/tracing/zipkin"
+	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/zipkincore"
+)
+
+var (
+	frontend = &zipkincore.Endpoint{Ipv4: 0x0a000001, Port: 8080, ServiceName: "frontend"}
+	backend  = &zipkincore.Endpoint{Ipv4: 0x0a000002, Port: 9000, ServiceName: "backend"}
+	database = &zipkincore.Endpoint{Ipv4: 0x0a000003, Port: 5432, ServiceName: "postgres"}
+)
+
+func annotation(ts int64, value string, host *zipkincore.Endpoint) *zipkincore.Annotation {
+	return &zipkincore.Annotation{Timestamp: ts, Value: value, Host: host}
+}
+
+func binaryAnnotation(key string, t zipkincore.AnnotationType, value []byte, host *zipkincore.Endpoint) *zipkincore.BinaryAnnotation {
+	return &zipkincore.BinaryAnnotation{Key: key, Value: value, AnnotationType: t, Host: host}
+}
+
+func i64(v int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
+
+func i32(v int32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(v))
+	return b
+}
+
+func TestEncodeV2(t *testing.T) {
+	parentID := int64(0x1)
+	for _, tc := range []struct {
+		name string
+		span *zipkincore.Span
+		want string
+	}{
+		{
+			name: "client",
+			span: &zipkincore.Span{
+				TraceId:  0x4e441824ec2b6a44,
+				Name:     "get",
+				Id:       0x2,
+				ParentId: &parentID,
+				Annotations: []*zipkincore.Annotation{
+					annotation(1472470996199000, zipkin.ClientSend, frontend),
+					annotation(1472470996238000, zipkin.ClientReceive, frontend),
+				},
+				BinaryAnnotations: []*zipkincore.BinaryAnnotation{
+					binaryAnnotation("http.path", zipkincore.AnnotationType_STRING, []byte("/api"), frontend),
+					binaryAnnotation(zipkin.ServerAddress, zipkincore.AnnotationType_BOOL, []byte{1}, backend),
+				},
+			},
+			want: `[{"traceId":"4e441824ec2b6a44","parentId":"0000000000000001","id":"0000000000000002","kind":"CLIENT","name":"get","timestamp":1472470996199000,"duration":39000,` +
+				`"localEndpoint":{"serviceName":"frontend","ipv4":"10.0.0.1","port":8080},` +
+				`"remoteEndpoint":{"serviceName":"backend","ipv4":"10.0.0.2","port":9000},` +
+				`"tags":{"http.path":"/api"}}]`,
+		},
+		{
+			name: "root server",
+			span: &zipkincore.Span{
+				TraceId: 0x4e441824ec2b6a44,
+				Name:    "get",
+				Id:      0x4e441824ec2b6a44,
+				Annotations: []*zipkincore.Annotation{
+					annotation(1472470996199000, zipkin.ServerReceive, backend),
+					annotation(1472470996210000, "cache.miss", backend),
+					annotation(1472470996238000, zipkin.ServerSend, backend),
+				},
+				BinaryAnnotations: []*zipkincore.BinaryAnnotation{
+					binaryAnnotation(zipkin.ClientAddress, zipkincore.AnnotationType_BOOL, []byte{1}, frontend),
+				},
+				Debug: true,
+			},
+			want: `[{"traceId":"4e441824ec2b6a44","id":"4e441824ec2b6a44","kind":"SERVER","name":"get","timestamp":1472470996199000,"duration":39000,"debug":true,` +
+				`"localEndpoint":{"serviceName":"backend","ipv4":"10.0.0.2","port":9000},` +
+				`"remoteEndpoint":{"serviceName":"frontend","ipv4":"10.0.0.1","port":8080},` +
+				`"annotations":[{"timestamp":1472470996210000,"value":"cache.miss"}]}]`,
+		},
+		{
+			name: "shared",
+			span: &zipkincore.Span{
+				TraceId:  0x1,
+				Name:     "get",
+				Id:       0x2,
+				ParentId: &parentID,
+				Annotations: []*zipkincore.Annotation{
+					annotation(100, zipkin.ClientSend, frontend),
+					annotation(110, zipkin.ServerReceive, backend),
+					annotation(190, zipkin.ServerSend, backend),
+					annotation(200, zipkin.ClientReceive, frontend),
+				},
+			},
+			want: `[{"traceId":"0000000000000001","parentId":"0000000000000001","id":"0000000000000002","kind":"CLIENT","name":"get","timestamp":100,"duration":100,` +
+				`"localEndpoint":{"serviceName":"frontend","ipv4":"10.0.0.1","port":8080}},` +
+				`{"traceId":"0000000000000001","parentId":"0000000000000001","id":"0000000000000002","kind":"SERVER","name":"get","timestamp":110,"duration":80,"shared":true,` +
+				`"localEndpoint":{"serviceName":"backend","ipv4":"10.0.0.2","port":9000}}]`,
+		},
+		{
+			name: "incomplete client",
+			span: &zipkincore.Span{
+				TraceId: 0x1,
+				Name:    "query",
+				Id:      0x2,
+				Annotations: []*zipkincore.Annotation{
+					annotation(100, zipkin.ClientSend, frontend),
+				},
+				BinaryAnnotations: []*zipkincore.BinaryAnnotation{
+					binaryAnnotation(zipkin.ServerAddress, zipkincore.AnnotationType_BOOL, []byte{1}, database),
+				},
+			},
+			want: `[{"traceId":"0000000000000001","id":"0000000000000002","kind":"CLIENT","name":"query","timestamp":100,` +
+				`"localEndpoint":{"serviceName":"frontend","ipv4":"10.0.0.1","port":8080},` +
+				`"remoteEndpoint":{"serviceName":"postgres","ipv4":"10.0.0.3","port":5432}}]`,
+		},
+		{
+			name: "local",
+			span: &zipkincore.Span{
+				TraceId: 0x1,
+				Name:    "render",
+				Id:      0x2,
+				Annotations: []*zipkincore.Annotation{
+					annotation(100, "begin", frontend),
+					annotation(150, "end", frontend),
+				},
+			},
+			want: `[{"traceId":"0000000000000001","id":"0000000000000002","name":"render","timestamp":100,"duration":50,` +
+				`"localEndpoint":{"serviceName":"frontend","ipv4":"10.0.0.1","port":8080},` +
+				`"annotations":[{"timestamp":100,"value":"begin"},{"timestamp":150,"value":"end"}]}]`,
+		},
+		{
+			name: "tag types",
+			span: &zipkincore.Span{
+				TraceId: 0x1,
+				Name:    "tags",
+				Id:      0x2,
+				BinaryAnnotations: []*zipkincore.BinaryAnnotation{
+					binaryAnnotation("bool", zipkincore.AnnotationType_BOOL, []byte{0}, frontend),
+					binaryAnnotation("bytes", zipkincore.AnnotationType_BYTES, []byte("raw"), frontend),
+					binaryAnnotation("double", zipkincore.AnnotationType_DOUBLE, i64(int64(math.Float64bits(1.5))), frontend),
+					binaryAnnotation("i32", zipkincore.AnnotationType_I32, i32(-42), frontend),
+					binaryAnnotation("i64", zipkincore.AnnotationType_I64, i64(1<<40), frontend),
+					binaryAnnotation("string", zipkincore.AnnotationType_STRING, []byte("value"), frontend),
+				},
+			},
+			want: `[{"traceId":"0000000000000001","id":"0000000000000002","name":"tags",` +
+				`"localEndpoint":{"serviceName":"frontend","ipv4":"10.0.0.1","port":8080},` +
+				`"tags":{"bool":"false","bytes":"cmF3","double":"1.5","i32":"-42","i64":"1099511627776","string":"value"}}]`,
+		},
+	} {
+		if want, have := tc.want, zipkin.EncodeV2JSON(tc.span); want != have {
+			t.Errorf("%s:\nwant %s\nhave %s", tc.name, want, have)
+		}
+	}
+}
+
+func TestEncodeV2AnnotatedSpan(t *testing.T) {
+	// Values annotated through the Span API round-trip to tags.
+	span := zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, 2, 0)
+	span.AnnotateBinary("int", 7)
+	span.AnnotateBinary("uint64", uint64(1)<<40)
+	span.AnnotateBinary("float", 0.25)
+	span.AnnotateBinary("bool", true)
+
+	want := `[{"traceId":"0000000000000001","id":"0000000000000002","name":"method",` +
+		`"localEndpoint":{"serviceName":"service","ipv4":"1.2.3.4","port":1234},` +
+		`"tags":{"bool":"true","float":"0.25","int":"7","uint64":"1099511627776"}}]`
+	if have := zipkin.EncodeV2JSON(span.Encode()); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
@@ -3,9 +3,15 @@ package zipkin
 import (
 	"math/rand"
 	"net/http"
+	"net/url"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/go-kit/kit/endpoint"
@@ -25,6 +31,9 @@ import (
 
 const (
 	// SpanContextKey holds the key used to store Zipkin spans in the context.
+	// It's a plain string, so code which can't import this package, e.g. to
+	// avoid a dependency cycle, can still look up the span, whose type is
+	// *Span. Otherwise, use FromContext and NewContext.
 	SpanContextKey = "Zipkin-Span"
 
 	// https://github.com/racker/tryfer#headers
@@ -32,12 +41,14 @@ const (
 	spanIDHTTPHeader       = "X-B3-SpanId"
 	parentSpanIDHTTPHeader = "X-B3-ParentSpanId"
 	sampledHTTPHeader      = "X-B3-Sampled"
+	flagsHTTPHeader        = "X-B3-Flags"
 
 	// gRPC keys are always lowercase
 	traceIDGRPCKey      = "x-b3-traceid"
 	spanIDGRPCKey       = "x-b3-spanid"
 	parentSpanIDGRPCKey = "x-b3-parentspanid"
 	sampledGRPCKey      = "x-b3-sampled"
+	flagsGRPCKey        = "x-b3-flags"
 
 	// ClientSend is the annotation value used to mark a client sending a
 	// request to a server.
@@ -55,6 +66,14 @@ const (
 	// of a completed request from a server.
 	ClientReceive = "cr"
 
+	// MessageSend is the annotation value used to mark a producer sending a
+	// message to a broker, like Kafka.
+	MessageSend = "ms"
+
+	// MessageReceive is the annotation value used to mark a consumer's
+	// receipt of a message from a broker.
+	MessageReceive = "mr"
+
 	// ServerAddress allows to annotate the server endpoint in case the server
 	// side trace is not instrumented as with resources like caches and
 	// databases.
@@ -63,25 +82,70 @@ const (
 	// ClientAddress allows to annotate the client origin in case the client was
 	// forwarded by a proxy which does not instrument itself.
 	ClientAddress = "ca"
+
+	// LocalComponent is the binary annotation key marking a local span, i.e.
+	// one of work within a service, like the spans of NewLocalChildSpan. Its
+	// value names the component doing the work.
+	LocalComponent = "lc"
+
+	// ServiceVersion is the binary annotation key spans are annotated with,
+	// once the version of the service is set by SetServiceVersion.
+	ServiceVersion = "service.version"
+
+	// LinkTraceID and LinkSpanID are the binary annotation keys of the IDs of
+	// a span linked to by Span.AddLink.
+	LinkTraceID = "link.traceId"
+	LinkSpanID  = "link.spanId"
+
+	// QueryParamPrefix prefixes the binary annotation keys of the query
+	// parameters recorded by AnnotateQueryParams.
+	QueryParamPrefix = "http.query."
+
+	// ResponseStatus is the binary annotation key of the status of responses,
+	// as read by the function given to StatusFromResponse.
+	ResponseStatus = "response.status"
+
+	// GRPCDeadline is the binary annotation key of the deadline of gRPC
+	// calls, in RFC 3339 format, as annotated by GRPCServerFinalizer.
+	GRPCDeadline = "grpc.deadline"
+
+	// Timeout and GRPCStatusCode are the binary annotation keys with which
+	// GRPCServerFinalizer marks gRPC calls which exceeded their deadline, as
+	// true and "DeadlineExceeded".
+	Timeout        = "timeout"
+	GRPCStatusCode = "grpc.status_code"
 )
 
 // AnnotateServer returns a server.Middleware that extracts a span from the
 // context, adds server-receive and server-send annotations at the boundaries,
 // and submits the span to the collector. If no span is found in the context,
-// a new span is generated and inserted.
-func AnnotateServer(newSpan NewSpanFunc, c Collector) endpoint.Middleware {
+// a new span is generated and inserted. If the endpoint returns an error, or a
+// response implementing endpoint.Failer which failed, the span is annotated
+// with it by AnnotateError.
+func AnnotateServer(newSpan NewSpanFunc, c Collector, options ...AnnotateOption) endpoint.Middleware {
+	config := newAnnotateConfig(options)
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
 			span, ok := FromContext(ctx)
 			if !ok {
 				traceID := newID()
 				span = newSpan(traceID, traceID, 0)
-				ctx = context.WithValue(ctx, SpanContextKey, span)
+				ctx = NewContext(ctx, span)
 			}
+			config.name(span, request)
 			c.ShouldSample(span)
 			span.Annotate(ServerReceive)
-			defer func() { span.Annotate(ServerSend); c.Collect(span) }()
-			return next(ctx, request)
+			collect := !ok || !config.collectInFinalizer
+			defer func() {
+				span.Annotate(ServerSend)
+				if collect {
+					c.Collect(span)
+				}
+			}()
+			response, err := next(ctx, request)
+			span.AnnotateError(failure(response, err))
+			config.status(span, response)
+			return response, err
 		}
 	}
 }
@@ -90,8 +154,10 @@ func AnnotateServer(newSpan NewSpanFunc, c Collector) endpoint.Middleware {
 // context, produces a client (child) span from it, adds client-send and
 // client-receive annotations at the boundaries, and submits the span to the
 // collector. If no span is found in the context, a new span is generated and
-// inserted.
-func AnnotateClient(newSpan NewSpanFunc, c Collector) endpoint.Middleware {
+// inserted. Errors, and failed endpoint.Failer responses, are annotated like
+// in AnnotateServer.
+func AnnotateClient(newSpan NewSpanFunc, c Collector, options ...AnnotateOption) endpoint.Middleware {
+	config := newAnnotateConfig(options)
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
 			var clientSpan *Span
@@ -100,6 +166,7 @@ func AnnotateClient(newSpan NewSpanFunc, c Collector) endpoint.Middleware {
 				clientSpan = newSpan(parentSpan.TraceID(), newID(), parentSpan.SpanID())
 				clientSpan.runSampler = false
 				clientSpan.sampled = c.ShouldSample(parentSpan)
+				clientSpan.inherit(parentSpan)
 			} else {
 				// Abnormal operation. Traces should always start server side.
 				// We create a root span but annotate with a warning.
@@ -108,10 +175,169 @@ func AnnotateClient(newSpan NewSpanFunc, c Collector) endpoint.Middleware {
 				c.ShouldSample(clientSpan)
 				clientSpan.AnnotateBinary("warning", "missing server side trace")
 			}
-			ctx = context.WithValue(ctx, SpanContextKey, clientSpan)                    // set
+			config.name(clientSpan, request)
+			ctx = NewContext(ctx, clientSpan)                                           // set
 			defer func() { ctx = context.WithValue(ctx, SpanContextKey, parentSpan) }() // reset
 			clientSpan.Annotate(ClientSend)
-			defer func() { clientSpan.Annotate(ClientReceive); c.Collect(clientSpan) }()
+			defer func() {
+				clientSpan.Annotate(ClientReceive)
+				if !clientSpan.noop {
+					c.Collect(clientSpan)
+				}
+			}()
+			response, err := next(ctx, request)
+			clientSpan.AnnotateError(failure(response, err))
+			config.status(clientSpan, response)
+			return response, err
+		}
+	}
+}
+
+// NamedAnnotateServer returns an endpoint.NamedMiddleware, which constructs an
+// AnnotateServer middleware for each endpoint of an endpoint.Set, creating
+// spans named after the endpoint. Spans found in the context, e.g. put there
+// by ToContext, keep their name.
+func NamedAnnotateServer(hostport, serviceName string, c Collector, options ...AnnotateOption) endpoint.NamedMiddleware {
+	return func(name string) endpoint.Middleware {
+		return AnnotateServer(MakeNewSpanFunc(hostport, serviceName, name), c, options...)
+	}
+}
+
+// NamedAnnotateClient returns an endpoint.NamedMiddleware, which constructs an
+// AnnotateClient middleware for each endpoint of an endpoint.Set, creating
+// client spans named after the endpoint.
+func NamedAnnotateClient(hostport, serviceName string, c Collector, options ...AnnotateOption) endpoint.NamedMiddleware {
+	return func(name string) endpoint.Middleware {
+		return AnnotateClient(MakeNewSpanFunc(hostport, serviceName, name), c, options...)
+	}
+}
+
+// AnnotateOption sets an optional parameter for AnnotateServer and
+// AnnotateClient.
+type AnnotateOption func(*annotateConfig)
+
+type annotateConfig struct {
+	nameFromRequest    bool
+	statusFunc         func(response interface{}) (code int, ok bool)
+	collectInFinalizer bool
+}
+
+func newAnnotateConfig(options []AnnotateOption) *annotateConfig {
+	config := &annotateConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+// SpanNameFromRequest names spans without a method name, e.g. created by a
+// NewSpanFunc made by MakeNewSpanFunc with an empty method name, after the Go
+// type of the request, e.g. "SumRequest" for a SumRequest or a *SumRequest.
+// It's intended for services with many endpoints sharing one transport. The
+// name of each type is derived by reflection once, and cached.
+func SpanNameFromRequest() AnnotateOption {
+	return func(config *annotateConfig) { config.nameFromRequest = true }
+}
+
+// name names the span after the request, if the configuration asks for it,
+// and the span has no name yet.
+func (config *annotateConfig) name(span *Span, request interface{}) {
+	if !config.nameFromRequest || span.Name() != "" || request == nil {
+		return
+	}
+	name := requestName(reflect.TypeOf(request))
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.methodName = name
+	span.encoded = nil
+}
+
+// StatusFromResponse annotates spans with the status of the response, under
+// the ResponseStatus key. As endpoints return an interface{}, there's no
+// generic way to find the status of a response: statusFunc teaches the
+// middleware how to read it from the responses of a service, e.g. from a
+// field, returning false if the response has no status. statusFunc is called
+// with the response even if the endpoint returned an error.
+func StatusFromResponse(statusFunc func(response interface{}) (code int, ok bool)) AnnotateOption {
+	return func(config *annotateConfig) { config.statusFunc = statusFunc }
+}
+
+// status annotates the span with the status of the response, if the
+// configuration knows how to read it, and the response has one.
+func (config *annotateConfig) status(span *Span, response interface{}) {
+	if config.statusFunc == nil {
+		return
+	}
+	if code, ok := config.statusFunc(response); ok {
+		span.AnnotateBinary(ResponseStatus, code)
+	}
+}
+
+// CollectInFinalizer leaves the collection of the spans AnnotateServer finds
+// in the context, like those put there by ToGRPCContext, to a finalizer of
+// the transport, like GRPCServerFinalizer, so the finalizer can annotate them
+// after the endpoint returned. Spans created by AnnotateServer are confined to
+// the endpoint, so they're still collected by it.
+func CollectInFinalizer() AnnotateOption {
+	return func(config *annotateConfig) { config.collectInFinalizer = true }
+}
+
+// requestNames caches the span names derived from request types.
+var requestNames = struct {
+	sync.RWMutex
+	m map[reflect.Type]string
+}{m: map[reflect.Type]string{}}
+
+// requestName returns the span name of a request type: the name of the type,
+// or of the type it points to. Unnamed types are named by their literal.
+func requestName(t reflect.Type) string {
+	requestNames.RLock()
+	name, ok := requestNames.m[t]
+	requestNames.RUnlock()
+	if ok {
+		return name
+	}
+
+	elem := t
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if name = elem.Name(); name == "" {
+		name = elem.String()
+	}
+
+	requestNames.Lock()
+	requestNames.m[t] = name
+	requestNames.Unlock()
+	return name
+}
+
+// failure returns the error of an endpoint's result: its error, or else the
+// failure of its response, if it implements endpoint.Failer.
+func failure(response interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	if f, ok := response.(endpoint.Failer); ok {
+		return f.Failed()
+	}
+	return nil
+}
+
+// FlushAfter returns a middleware that flushes the collector, if it
+// implements Flusher, each time the wrapped endpoint returns. It's intended
+// for batch jobs and other short-lived programs, which may exit before a
+// buffering collector sends its spans of its own accord. Install it outside of
+// AnnotateServer, so the server span is collected before the flush. Flush
+// errors don't affect the endpoint's result.
+func FlushAfter(c Collector) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			defer func() {
+				if f, ok := c.(Flusher); ok {
+					f.Flush()
+				}
+			}()
 			return next(ctx, request)
 		}
 	}
@@ -127,7 +353,7 @@ func ToContext(newSpan NewSpanFunc, logger log.Logger) func(ctx context.Context,
 		if span == nil {
 			return ctx
 		}
-		return context.WithValue(ctx, SpanContextKey, span)
+		return NewContext(ctx, span)
 	}
 }
 
@@ -141,7 +367,7 @@ func ToGRPCContext(newSpan NewSpanFunc, logger log.Logger) func(ctx context.Cont
 		if span == nil {
 			return ctx
 		}
-		return context.WithValue(ctx, SpanContextKey, span)
+		return NewContext(ctx, span)
 	}
 }
 
@@ -156,20 +382,23 @@ func ToRequest(newSpan NewSpanFunc) func(ctx context.Context, r *http.Request) c
 		if !ok {
 			return ctx
 		}
-		if id := span.TraceID(); id > 0 {
-			r.Header.Set(traceIDHTTPHeader, strconv.FormatInt(id, 16))
+		if id := span.TraceID(); id != 0 {
+			r.Header.Set(traceIDHTTPHeader, formatID(id))
 		}
-		if id := span.SpanID(); id > 0 {
-			r.Header.Set(spanIDHTTPHeader, strconv.FormatInt(id, 16))
+		if id := span.SpanID(); id != 0 {
+			r.Header.Set(spanIDHTTPHeader, formatID(id))
 		}
-		if id := span.ParentSpanID(); id > 0 {
-			r.Header.Set(parentSpanIDHTTPHeader, strconv.FormatInt(id, 16))
+		if id := span.ParentSpanID(); id != 0 {
+			r.Header.Set(parentSpanIDHTTPHeader, formatID(id))
 		}
 		if span.IsSampled() {
 			r.Header.Set(sampledHTTPHeader, "1")
 		} else {
 			r.Header.Set(sampledHTTPHeader, "0")
 		}
+		if span.Debug() {
+			r.Header.Set(flagsHTTPHeader, "1")
+		}
 		return ctx
 	}
 }
@@ -185,116 +414,217 @@ func ToGRPCRequest(newSpan NewSpanFunc) func(ctx context.Context, md *metadata.M
 		if !ok {
 			return ctx
 		}
-		if id := span.TraceID(); id > 0 {
-			(*md)[traceIDGRPCKey] = append((*md)[traceIDGRPCKey], strconv.FormatInt(id, 16))
+		if id := span.TraceID(); id != 0 {
+			(*md)[traceIDGRPCKey] = append((*md)[traceIDGRPCKey], formatID(id))
 		}
-		if id := span.SpanID(); id > 0 {
-			(*md)[spanIDGRPCKey] = append((*md)[spanIDGRPCKey], strconv.FormatInt(id, 16))
+		if id := span.SpanID(); id != 0 {
+			(*md)[spanIDGRPCKey] = append((*md)[spanIDGRPCKey], formatID(id))
 		}
-		if id := span.ParentSpanID(); id > 0 {
-			(*md)[parentSpanIDGRPCKey] = append((*md)[parentSpanIDGRPCKey], strconv.FormatInt(id, 16))
+		if id := span.ParentSpanID(); id != 0 {
+			(*md)[parentSpanIDGRPCKey] = append((*md)[parentSpanIDGRPCKey], formatID(id))
 		}
 		if span.IsSampled() {
 			(*md)[sampledGRPCKey] = append((*md)[sampledGRPCKey], "1")
 		} else {
 			(*md)[sampledGRPCKey] = append((*md)[sampledGRPCKey], "0")
 		}
+		if span.Debug() {
+			(*md)[flagsGRPCKey] = append((*md)[flagsGRPCKey], "1")
+		}
 		return ctx
 	}
 }
 
-func fromHTTP(newSpan NewSpanFunc, r *http.Request, logger log.Logger) *Span {
-	traceIDStr := r.Header.Get(traceIDHTTPHeader)
-	if traceIDStr == "" {
-		return nil
+// ToGRPCTrailer returns a function that satisfies transport/grpc.ResponseFunc.
+// It takes a Zipkin span from the context, and returns its trace ID, in hex,
+// to the client in the "x-b3-traceid" trailer, e.g. so users can quote it in
+// support tickets. It's designed to be wired into a server's GRPC transport
+// After stack. Only spans in the context of the transport, like those put
+// there by ToGRPCContext, are found; spans created by AnnotateServer are
+// confined to the endpoint.
+func ToGRPCTrailer() func(ctx context.Context, header *metadata.MD, trailer *metadata.MD) {
+	return func(ctx context.Context, _ *metadata.MD, trailer *metadata.MD) {
+		if span, ok := FromContext(ctx); ok {
+			(*trailer)[traceIDGRPCKey] = []string{formatID(span.TraceID())}
+		}
 	}
-	traceID, err := strconv.ParseInt(traceIDStr, 16, 64)
-	if err != nil {
-		logger.Log("msg", "invalid trace id found, ignoring trace", "err", err)
-		return nil
+}
+
+// GRPCServerFinalizer returns a function that satisfies
+// transport/grpc.ServerFinalizerFunc. It takes a Zipkin span from the context,
+// annotates it with the deadline of the call, if any, under the GRPCDeadline
+// key, and, if the deadline was exceeded, with Timeout and GRPCStatusCode, and
+// submits it to the collector. It's designed for spans put in the context by
+// ToGRPCContext, and annotated by an AnnotateServer given the
+// CollectInFinalizer option, which leaves their collection to the finalizer.
+func GRPCServerFinalizer(c Collector) func(ctx context.Context, err error) {
+	return func(ctx context.Context, _ error) {
+		span, ok := FromContext(ctx)
+		if !ok {
+			return
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			span.AnnotateBinary(GRPCDeadline, deadline.UTC().Format(time.RFC3339Nano))
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			span.AnnotateBinary(Timeout, true)
+			span.AnnotateBinary(GRPCStatusCode, codes.DeadlineExceeded.String())
+		}
+		c.Collect(span)
 	}
-	spanIDStr := r.Header.Get(spanIDHTTPHeader)
-	if spanIDStr == "" {
-		logger.Log("msg", "trace ID without span ID") // abnormal
-		spanIDStr = strconv.FormatInt(newID(), 64)    // deal with it
+}
+
+// TraceIDFromGRPCTrailer returns the trace ID set by ToGRPCTrailer in the
+// trailer of a GRPC response. It's designed to be called from a client's
+// transport/grpc.ClientResponseFunc. It returns false if the trailer has no
+// valid trace ID.
+func TraceIDFromGRPCTrailer(trailer metadata.MD) (int64, bool) {
+	values := trailer[traceIDGRPCKey]
+	if len(values) == 0 {
+		return 0, false
 	}
-	spanID, err := strconv.ParseInt(spanIDStr, 16, 64)
-	if err != nil {
-		logger.Log(spanIDHTTPHeader, spanIDStr, "err", err) // abnormal
-		spanID = newID()                                    // deal with it
+	traceID, err := parseID(values[0])
+	if err != nil || traceID == 0 {
+		return 0, false
 	}
-	parentSpanIDStr := r.Header.Get(parentSpanIDHTTPHeader)
-	if parentSpanIDStr == "" {
-		parentSpanIDStr = "0" // normal
+	return traceID, true
+}
+
+// B3Headers returns the B3 propagation headers for the span, keyed by their
+// canonical HTTP names. It's a transport-agnostic representation, useful for
+// logging, or for forwarding the trace to systems not using Go kit. The
+// parent span ID is omitted for root spans, and the flags, which only carry
+// the debug bit, are omitted unless the span is in debug mode.
+func B3Headers(s *Span) map[string]string {
+	h := map[string]string{
+		traceIDHTTPHeader: formatID(s.TraceID()),
+		spanIDHTTPHeader:  formatID(s.SpanID()),
+		sampledHTTPHeader: "0",
 	}
-	parentSpanID, err := strconv.ParseInt(parentSpanIDStr, 16, 64)
-	if err != nil {
-		logger.Log(parentSpanIDHTTPHeader, parentSpanIDStr, "err", err) // abnormal
-		parentSpanID = 0                                                // the only way to deal with it
+	if id := s.ParentSpanID(); id != 0 {
+		h[parentSpanIDHTTPHeader] = formatID(id)
 	}
-	span := newSpan(traceID, spanID, parentSpanID)
-	switch r.Header.Get(sampledHTTPHeader) {
-	case "0":
-		span.runSampler = false
-		span.sampled = false
-	case "1":
-		span.runSampler = false
-		span.sampled = true
-	default:
-		// we don't know if the upstream trace was sampled. use our sampler
-		span.runSampler = true
+	if s.Sampled() {
+		h[sampledHTTPHeader] = "1"
 	}
-	return span
+	if s.Debug() {
+		h[flagsHTTPHeader] = "1"
+	}
+	return h
 }
 
-func fromGRPC(newSpan NewSpanFunc, md metadata.MD, logger log.Logger) *Span {
-	traceIDSlc := md[traceIDGRPCKey]
-	pos := len(traceIDSlc) - 1
-	if pos < 0 {
-		return nil
+// InjectTextMapCarrier injects the span into carrier, as the B3 headers
+// returned by B3Headers, replacing any B3 headers already there, whatever
+// their case. It's designed for message transports, like Kafka or AMQP, whose
+// messages carry string headers, so a consumer can continue the trace of the
+// producer with FromTextMapCarrier.
+func InjectTextMapCarrier(s *Span, carrier map[string]string) {
+	headers := B3Headers(s)
+	for k := range carrier {
+		for h := range b3Keys {
+			if strings.EqualFold(k, h) {
+				delete(carrier, k)
+			}
+		}
 	}
-	traceID, err := strconv.ParseInt(traceIDSlc[pos], 16, 64)
-	if err != nil {
-		logger.Log("msg", "invalid trace id found, ignoring trace", "err", err)
-		return nil
+	for k, v := range headers {
+		carrier[k] = v
 	}
-	spanIDSlc := md[spanIDGRPCKey]
-	pos = len(spanIDSlc) - 1
-	if pos < 0 {
-		spanIDSlc = make([]string, 1)
-		pos = 0
+}
+
+// FromTextMapCarrier extracts a span from the B3 headers in carrier, e.g. put
+// there by InjectTextMapCarrier. Header names are matched regardless of case,
+// as transports and producers not using Go kit may change it. It returns nil
+// if the carrier holds no valid trace ID. A missing or invalid span ID is
+// replaced by a new one without a parent, and an invalid parent span ID is
+// ignored.
+func FromTextMapCarrier(carrier map[string]string, newSpan NewSpanFunc) *Span {
+	get := func(key string) string {
+		if v, ok := carrier[key]; ok {
+			return v
+		}
+		for k, v := range carrier {
+			if strings.EqualFold(k, key) {
+				return v
+			}
+		}
+		return ""
 	}
-	if spanIDSlc[pos] == "" {
-		logger.Log("msg", "trace ID without span ID")   // abnormal
-		spanIDSlc[pos] = strconv.FormatInt(newID(), 64) // deal with it
+	return fromB3(newSpan, get, log.NewNopLogger())
+}
+
+// b3Keys are the names of the B3 headers, as set by B3Headers.
+var b3Keys = map[string]bool{
+	traceIDHTTPHeader:      true,
+	spanIDHTTPHeader:       true,
+	parentSpanIDHTTPHeader: true,
+	sampledHTTPHeader:      true,
+	flagsHTTPHeader:        true,
+}
+
+// AnnotateQueryParams annotates the span with the query parameters named in
+// the whitelist, as string binary annotations keyed by QueryParamPrefix and
+// the parameter name, e.g. "http.query.tenant". Other parameters, which may
+// hold secrets like tokens, are never recorded. Multiple values of a parameter
+// are joined with commas. Absent parameters are skipped.
+func AnnotateQueryParams(s *Span, values url.Values, whitelist []string) {
+	for _, name := range whitelist {
+		if v, ok := values[name]; ok {
+			s.AnnotateBinary(QueryParamPrefix+name, strings.Join(v, ","))
+		}
 	}
-	spanID, err := strconv.ParseInt(spanIDSlc[pos], 16, 64)
-	if err != nil {
-		logger.Log(spanIDHTTPHeader, spanIDSlc, "err", err) // abnormal
-		spanID = newID()                                    // deal with it
+}
+
+func fromHTTP(newSpan NewSpanFunc, r *http.Request, logger log.Logger) *Span {
+	return fromB3(newSpan, r.Header.Get, logger)
+}
+
+func fromGRPC(newSpan NewSpanFunc, md metadata.MD, logger log.Logger) *Span {
+	get := func(key string) string {
+		// gRPC metadata keys are lowercase; the last value wins.
+		if values := md[strings.ToLower(key)]; len(values) > 0 {
+			return values[len(values)-1]
+		}
+		return ""
 	}
-	parentSpanIDSlc := md[parentSpanIDGRPCKey]
-	pos = len(parentSpanIDSlc) - 1
-	if pos < 0 {
-		parentSpanIDSlc = make([]string, 1)
-		pos = 0
+	return fromB3(newSpan, get, logger)
+}
+
+// fromB3 extracts a span from the B3 headers returned by get, which is called
+// with the canonical HTTP header names. It returns nil if there's no valid
+// trace ID. A trace ID without a valid span ID, as forwarded by some proxies,
+// continues the trace: the span gets a new span ID and no parent, as the
+// parent header can't be trusted without the span ID it belongs to.
+func fromB3(newSpan NewSpanFunc, get func(key string) string, logger log.Logger) *Span {
+	traceIDStr := get(traceIDHTTPHeader)
+	if traceIDStr == "" {
+		return nil
 	}
-	if parentSpanIDSlc[pos] == "" {
-		parentSpanIDSlc[pos] = "0" // normal
+	traceID, err := parseID(traceIDStr)
+	if err != nil || traceID == 0 {
+		logger.Log("msg", "invalid trace id found, ignoring trace", traceIDHTTPHeader, traceIDStr, "err", err)
+		return nil
 	}
-	parentSpanID, err := strconv.ParseInt(parentSpanIDSlc[pos], 16, 64)
-	if err != nil {
-		logger.Log(parentSpanIDHTTPHeader, parentSpanIDSlc, "err", err) // abnormal
-		parentSpanID = 0                                                // the only way to deal with it
+	var spanID, parentSpanID int64
+	spanIDStr := get(spanIDHTTPHeader)
+	if spanIDStr != "" {
+		spanID, err = parseID(spanIDStr)
+		if err != nil || spanID == 0 {
+			logger.Log(spanIDHTTPHeader, spanIDStr, "err", err) // abnormal
+			spanID = 0
+		}
 	}
-	span := newSpan(traceID, spanID, parentSpanID)
-	var sampledHdr string
-	sampledSlc := md[sampledGRPCKey]
-	pos = len(sampledSlc) - 1
-	if pos >= 0 {
-		sampledHdr = sampledSlc[pos]
+	if spanID == 0 {
+		spanID = newID() // trace ID only; continue the trace as a new root
+	} else if parentSpanIDStr := get(parentSpanIDHTTPHeader); parentSpanIDStr != "" {
+		parentSpanID, err = parseID(parentSpanIDStr)
+		if err != nil {
+			logger.Log(parentSpanIDHTTPHeader, parentSpanIDStr, "err", err) // abnormal
+			parentSpanID = 0                                                // the only way to deal with it
+		}
 	}
-	switch sampledHdr {
+	span := newSpan(traceID, spanID, parentSpanID)
+	switch get(sampledHTTPHeader) {
 	case "0":
 		span.runSampler = false
 		span.sampled = false
@@ -305,13 +635,43 @@ func fromGRPC(newSpan NewSpanFunc, md metadata.MD, logger log.Logger) *Span {
 		// we don't know if the upstream trace was sampled. use our sampler
 		span.runSampler = true
 	}
+	if debugFlag(get(flagsHTTPHeader)) {
+		span.debug = true
+	}
 	return span
 }
 
+// parseID parses a trace or span ID in hex. IDs are unsigned 64-bit values,
+// whose bits are kept in an int64, so IDs above math.MaxInt64 are negative.
+func parseID(s string) (int64, error) {
+	id, err := strconv.ParseUint(s, 16, 64)
+	return int64(id), err
+}
+
+// formatID formats a trace or span ID in hex, as the unsigned value parsed
+// by parseID.
+func formatID(id int64) string {
+	return strconv.FormatUint(uint64(id), 16)
+}
+
+// debugFlag reports whether the B3 flags, a decimal bit set, carry the debug
+// bit. Debug spans are collected regardless of the sampled flag.
+func debugFlag(flags string) bool {
+	f, err := strconv.ParseInt(flags, 10, 64)
+	return err == nil && f&1 == 1
+}
+
+// NewContext returns a copy of the context, which carries the span. Together
+// with FromContext, it's the supported way to pass spans through contexts.
+func NewContext(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, SpanContextKey, span)
+}
+
 // FromContext extracts an existing Zipkin span if it is stored in the provided
-// context. If you add context.Context as the first parameter in your service
-// methods you can annotate spans from within business logic. Typical use case
-// is to AnnotateDuration on interaction with resources like databases.
+// context, e.g. by NewContext. If you add context.Context as the first
+// parameter in your service methods you can annotate spans from within
+// business logic. Typical use case is to AnnotateDuration on interaction with
+// resources like databases.
 func FromContext(ctx context.Context) (*Span, bool) {
 	val := ctx.Value(SpanContextKey)
 	if val == nil {
@@ -324,9 +684,24 @@ func FromContext(ctx context.Context) (*Span, bool) {
 	return span, true
 }
 
+// IsSampledContext returns whether the span stored in the context, e.g. by
+// NewContext, is sampled, and false if the context carries no span. It lets
+// libraries called with the context decide whether to trace their work,
+// without handling spans.
+func IsSampledContext(ctx context.Context) bool {
+	span, ok := ctx.Value(SpanContextKey).(*Span)
+	return ok && span.Sampled()
+}
+
+// newID returns a random span or trace ID. It's never zero, which means "no
+// ID", e.g. no parent.
 func newID() int64 {
-	// https://github.com/wadey/go-zipkin/blob/46e5f01/trace.go#L183-188
-	// https://github.com/twitter/zipkin/issues/199
-	// :(
-	return rand.Int63() & 0x001fffffffffffff
+	for {
+		// https://github.com/wadey/go-zipkin/blob/46e5f01/trace.go#L183-188
+		// https://github.com/twitter/zipkin/issues/199
+		// :(
+		if id := rand.Int63() & 0x001fffffffffffff; id != 0 {
+			return id
+		}
+	}
 }
@@ -1,21 +1,29 @@
 package zipkin_test
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
+	stdgrpc "google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/tracing/zipkin"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	grpctest "github.com/go-kit/kit/transport/grpc/_grpc_test"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
 )
 
 func TestToContext(t *testing.T) {
@@ -109,6 +117,43 @@ func TestFromContext(t *testing.T) {
 	}
 }
 
+func TestIsSampledContext(t *testing.T) {
+	sampled := zipkin.NewSpan("5.5.5.5:5555", "foo-service", "foo-method", 14, 36, 58)
+	sampled.Sample()
+	unsampled := zipkin.NewSpan("5.5.5.5:5555", "foo-service", "foo-method", 14, 37, 58)
+
+	for name, tc := range map[string]struct {
+		ctx  context.Context
+		want bool
+	}{
+		"sampled":   {zipkin.NewContext(context.Background(), sampled), true},
+		"unsampled": {zipkin.NewContext(context.Background(), unsampled), false},
+		"no span":   {context.Background(), false},
+	} {
+		if want, have := tc.want, zipkin.IsSampledContext(tc.ctx); want != have {
+			t.Errorf("%s: want %v, have %v", name, want, have)
+		}
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	if span, ok := zipkin.FromContext(context.Background()); ok {
+		t.Errorf("want no span, have %v", span)
+	}
+
+	want := zipkin.NewSpan("5.5.5.5:5555", "foo-service", "foo-method", 14, 36, 58)
+	ctx := zipkin.NewContext(context.Background(), want)
+	have, ok := zipkin.FromContext(ctx)
+	if !ok || want != have {
+		t.Errorf("want span %p, have %p", want, have)
+	}
+
+	// Code which doesn't import the package finds the span under the key.
+	if have := ctx.Value("Zipkin-Span"); want != have {
+		t.Errorf("want span %p under the key, have %v", want, have)
+	}
+}
+
 func TestToGRPCContext(t *testing.T) {
 	const (
 		hostport           = "5.5.5.5:5555"
@@ -157,6 +202,121 @@ func TestToGRPCContext(t *testing.T) {
 	}
 }
 
+func TestUnsignedIDs(t *testing.T) {
+	// IDs above math.MaxInt64, whose high bit is set.
+	const (
+		traceIDStr      = "fedcba9876543210"
+		spanIDStr       = "8000000000000001"
+		parentSpanIDStr = "ffffffffffffffff"
+	)
+	var (
+		traceID      uint64 = 0xfedcba9876543210
+		spanID       uint64 = 0x8000000000000001
+		parentSpanID uint64 = 0xffffffffffffffff
+	)
+
+	newSpan := zipkin.MakeNewSpanFunc("5.5.5.5:5555", "foo-service", "foo-method")
+	logger := log.NewLogfmtLogger(ioutil.Discard)
+
+	r, _ := http.NewRequest("GET", "https://best.horse", nil)
+	r.Header.Set("X-B3-TraceId", traceIDStr)
+	r.Header.Set("X-B3-SpanId", spanIDStr)
+	r.Header.Set("X-B3-ParentSpanId", parentSpanIDStr)
+	md := metadata.MD{
+		"x-b3-traceid":      []string{traceIDStr},
+		"x-b3-spanid":       []string{spanIDStr},
+		"x-b3-parentspanid": []string{parentSpanIDStr},
+	}
+
+	for name, ctx := range map[string]context.Context{
+		"HTTP": zipkin.ToContext(newSpan, logger)(context.Background(), r),
+		"gRPC": zipkin.ToGRPCContext(newSpan, logger)(context.Background(), &md),
+	} {
+		span, ok := zipkin.FromContext(ctx)
+		if !ok {
+			t.Fatalf("%s: no span in context", name)
+		}
+		if want, have := int64(traceID), span.TraceID(); want != have {
+			t.Errorf("%s: want trace ID %d, have %d", name, want, have)
+		}
+		if want, have := int64(spanID), span.SpanID(); want != have {
+			t.Errorf("%s: want span ID %d, have %d", name, want, have)
+		}
+		if want, have := int64(parentSpanID), span.ParentSpanID(); want != have {
+			t.Errorf("%s: want parent span ID %d, have %d", name, want, have)
+		}
+
+		// The IDs round-trip.
+		want := map[string]string{
+			"X-B3-TraceId":      traceIDStr,
+			"X-B3-SpanId":       spanIDStr,
+			"X-B3-ParentSpanId": parentSpanIDStr,
+			"X-B3-Sampled":      "0",
+		}
+		if have := zipkin.B3Headers(span); !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want %v, have %v", name, want, have)
+		}
+		out, _ := http.NewRequest("GET", "https://best.horse", nil)
+		zipkin.ToRequest(newSpan)(ctx, out)
+		for _, key := range []string{"X-B3-TraceId", "X-B3-SpanId", "X-B3-ParentSpanId"} {
+			if want, have := want[key], out.Header.Get(key); want != have {
+				t.Errorf("%s: %s: want %q, have %q", name, key, want, have)
+			}
+		}
+	}
+
+	if have, ok := zipkin.TraceIDFromGRPCTrailer(metadata.MD{"x-b3-traceid": []string{traceIDStr}}); !ok || have != int64(traceID) {
+		t.Errorf("trailer: want trace ID %d, have %d", int64(traceID), have)
+	}
+}
+
+func TestTraceIDOnlyHeaders(t *testing.T) {
+	const traceID int64 = 12
+
+	newSpan := zipkin.MakeNewSpanFunc("5.5.5.5:5555", "foo-service", "foo-method")
+	logger := log.NewLogfmtLogger(ioutil.Discard)
+
+	r, _ := http.NewRequest("GET", "https://best.horse", nil)
+	r.Header.Set("X-B3-TraceId", strconv.FormatInt(traceID, 16))
+	r.Header.Set("X-B3-ParentSpanId", "38") // meaningless without a span ID
+	r.Header.Set("X-B3-Sampled", "1")
+	md := metadata.MD{
+		"x-b3-traceid": []string{strconv.FormatInt(traceID, 16)},
+		"x-b3-sampled": []string{"1"},
+	}
+
+	for name, ctx := range map[string]context.Context{
+		"HTTP": zipkin.ToContext(newSpan, logger)(context.Background(), r),
+		"gRPC": zipkin.ToGRPCContext(newSpan, logger)(context.Background(), &md),
+	} {
+		span, ok := zipkin.FromContext(ctx)
+		if !ok {
+			t.Fatalf("%s: no span in context", name)
+		}
+		if want, have := traceID, span.TraceID(); want != have {
+			t.Errorf("%s: want trace ID %d, have %d", name, want, have)
+		}
+		if span.SpanID() == 0 {
+			t.Errorf("%s: want a new span ID, have none", name)
+		}
+		if want, have := int64(0), span.ParentSpanID(); want != have {
+			t.Errorf("%s: want parent span ID %d, have %d", name, want, have)
+		}
+		if !span.IsSampled() {
+			t.Errorf("%s: want sampled span", name)
+		}
+
+		// Spans created downstream belong to the forwarded trace.
+		child, _ := zipkin.NewChildSpan(ctx, zipkin.NopCollector{}, "child")
+		if want, have := traceID, child.TraceID(); want != have {
+			t.Errorf("%s: want child trace ID %d, have %d", name, want, have)
+		}
+		if want, have := span.SpanID(), child.ParentSpanID(); want != have {
+			t.Errorf("%s: want child parent span ID %d, have %d", name, want, have)
+		}
+	}
+}
+
 func TestToRequest(t *testing.T) {
 	const (
 		hostport           = "5.5.5.5:5555"
@@ -222,6 +382,94 @@ func TestToGRPCRequest(t *testing.T) {
 
 }
 
+func TestDebugFlagPropagation(t *testing.T) {
+	var (
+		newSpan = zipkin.MakeNewSpanFunc("5.5.5.5:5555", "foo-service", "foo-method")
+		logger  = log.NewNopLogger()
+	)
+	for _, debug := range []bool{false, true} {
+		span := newSpan(20, 40, 90)
+		if debug {
+			span.SetDebug()
+		}
+		ctx := zipkin.NewContext(context.Background(), span)
+
+		// HTTP
+		r, _ := http.NewRequest("GET", "https://best.horse", nil)
+		zipkin.ToRequest(newSpan)(ctx, r)
+		extracted, ok := zipkin.FromContext(zipkin.ToContext(newSpan, logger)(context.Background(), r))
+		if !ok {
+			t.Fatal("no span extracted from the HTTP request")
+		}
+		if want, have := debug, extracted.Debug(); want != have {
+			t.Errorf("HTTP: want debug %v, have %v", want, have)
+		}
+
+		// gRPC
+		md := &metadata.MD{}
+		zipkin.ToGRPCRequest(newSpan)(ctx, md)
+		extracted, ok = zipkin.FromContext(zipkin.ToGRPCContext(newSpan, logger)(context.Background(), md))
+		if !ok {
+			t.Fatal("no span extracted from the gRPC metadata")
+		}
+		if want, have := debug, extracted.Debug(); want != have {
+			t.Errorf("gRPC: want debug %v, have %v", want, have)
+		}
+	}
+}
+
+func TestB3Headers(t *testing.T) {
+	newSpan := zipkin.MakeNewSpanFunc("5.5.5.5:5555", "foo-service", "foo-method")
+	for _, tc := range []struct {
+		name string
+		span func() *zipkin.Span
+		want map[string]string
+	}{
+		{
+			name: "sampled",
+			span: func() *zipkin.Span {
+				span := newSpan(20, 40, 90)
+				span.Sample()
+				return span
+			},
+			want: map[string]string{
+				"X-B3-TraceId":      "14",
+				"X-B3-SpanId":       "28",
+				"X-B3-ParentSpanId": "5a",
+				"X-B3-Sampled":      "1",
+			},
+		},
+		{
+			name: "debug",
+			span: func() *zipkin.Span {
+				span := newSpan(20, 40, 90)
+				span.SetDebug()
+				return span
+			},
+			want: map[string]string{
+				"X-B3-TraceId":      "14",
+				"X-B3-SpanId":       "28",
+				"X-B3-ParentSpanId": "5a",
+				"X-B3-Sampled":      "0",
+				"X-B3-Flags":        "1",
+			},
+		},
+		{
+			name: "root",
+			span: func() *zipkin.Span { return newSpan(20, 20, 0) },
+			want: map[string]string{
+				"X-B3-TraceId": "14",
+				"X-B3-SpanId":  "14",
+				"X-B3-Sampled": "0",
+			},
+		},
+	} {
+		if want, have := tc.want, zipkin.B3Headers(tc.span()); !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want %v, have %v", tc.name, want, have)
+		}
+	}
+}
+
 func TestAnnotateServer(t *testing.T) {
 	if err := testAnnotate(zipkin.AnnotateServer, zipkin.ServerReceive, zipkin.ServerSend); err != nil {
 		t.Fatal(err)
@@ -235,7 +483,7 @@ func TestAnnotateClient(t *testing.T) {
 }
 
 func testAnnotate(
-	annotate func(newSpan zipkin.NewSpanFunc, c zipkin.Collector) endpoint.Middleware,
+	annotate func(newSpan zipkin.NewSpanFunc, c zipkin.Collector, options ...zipkin.AnnotateOption) endpoint.Middleware,
 	wantAnnotations ...string,
 ) error {
 	const (
@@ -264,7 +512,36 @@ func testAnnotate(
 	return nil
 }
 
-type countingCollector struct{ annotations []string }
+func TestFlushAfter(t *testing.T) {
+	newSpan := zipkin.MakeNewSpanFunc("1.2.3.4:1234", "some-service", "some-method")
+	collector := &countingCollector{}
+
+	var e endpoint.Endpoint
+	e = func(_ context.Context, request interface{}) (interface{}, error) {
+		if err, ok := request.(error); ok {
+			return nil, err
+		}
+		return struct{}{}, nil
+	}
+	e = zipkin.AnnotateServer(newSpan, collector)(e)
+	e = zipkin.FlushAfter(collector)(e)
+
+	for i, request := range []interface{}{struct{}{}, errors.New("failure"), struct{}{}} {
+		e(context.Background(), request)
+		if want, have := i+1, len(collector.flushes); want != have {
+			t.Fatalf("call %d: want %d flush(es), have %d", i+1, want, have)
+		}
+		// Both annotations of each span must be collected before the flush.
+		if want, have := 2*(i+1), collector.flushes[i]; want != have {
+			t.Errorf("call %d: want %d annotations collected at flush, have %d", i+1, want, have)
+		}
+	}
+}
+
+type countingCollector struct {
+	annotations []string
+	flushes     []int // number of annotations at the time of each flush
+}
 
 func (c *countingCollector) Collect(s *zipkin.Span) error {
 	for _, annotation := range s.Encode().GetAnnotations() {
@@ -277,6 +554,352 @@ func (c *countingCollector) ShouldSample(s *zipkin.Span) bool {
 	return true
 }
 
+func (c *countingCollector) Flush() error {
+	c.flushes = append(c.flushes, len(c.annotations))
+	return nil
+}
+
 func (c *countingCollector) Close() error {
 	return nil
 }
+
+func TestAnnotateFailer(t *testing.T) {
+	newSpan := zipkin.MakeNewSpanFunc("1.2.3.4:1234", "some-service", "some-method")
+
+	for name, annotate := range map[string]func(zipkin.NewSpanFunc, zipkin.Collector, ...zipkin.AnnotateOption) endpoint.Middleware{
+		"server": zipkin.AnnotateServer,
+		"client": zipkin.AnnotateClient,
+	} {
+		for _, tc := range []struct {
+			response interface{}
+			err      error
+			want     string
+		}{
+			{failedResponse{nil}, nil, ""},
+			{failedResponse{errors.New("no such user")}, nil, "no such user"},
+			{nil, errors.New("connection refused"), "connection refused"},
+		} {
+			collector := &capturingCollector{}
+			e := annotate(newSpan, collector)(func(context.Context, interface{}) (interface{}, error) {
+				return tc.response, tc.err
+			})
+
+			response, err := e(context.Background(), struct{}{})
+			if response != tc.response || err != tc.err {
+				t.Errorf("%s: want %v, %v, have %v, %v", name, tc.response, tc.err, response, err)
+			}
+			var have string
+			for _, a := range collector.spans[0].Encode().GetBinaryAnnotations() {
+				if a.Key == "error.message" {
+					have = string(a.Value)
+				}
+			}
+			if want := tc.want; want != have {
+				t.Errorf("%s: want error annotation %q, have %q", name, want, have)
+			}
+		}
+	}
+}
+
+type SumRequest struct{ A, B int }
+
+type cachedRequest struct{}
+
+func TestSpanNameFromRequest(t *testing.T) {
+	zipkin.CacheRequestName(reflect.TypeOf(cachedRequest{}), "from-cache")
+
+	for name, annotate := range map[string]func(zipkin.NewSpanFunc, zipkin.Collector, ...zipkin.AnnotateOption) endpoint.Middleware{
+		"server": zipkin.AnnotateServer,
+		"client": zipkin.AnnotateClient,
+	} {
+		for _, tc := range []struct {
+			methodName string
+			request    interface{}
+			want       string
+		}{
+			{"", SumRequest{}, "SumRequest"},
+			{"", &SumRequest{}, "SumRequest"},
+			{"", struct{}{}, "struct {}"},
+			{"", cachedRequest{}, "from-cache"},
+			{"sum", SumRequest{}, "sum"},
+		} {
+			newSpan := zipkin.MakeNewSpanFunc("1.2.3.4:1234", "some-service", tc.methodName)
+			collector := &capturingCollector{}
+			e := annotate(newSpan, collector, zipkin.SpanNameFromRequest())(func(context.Context, interface{}) (interface{}, error) {
+				return struct{}{}, nil
+			})
+			if _, err := e(context.Background(), tc.request); err != nil {
+				t.Fatal(err)
+			}
+			if want, have := tc.want, collector.spans[0].Encode().GetName(); want != have {
+				t.Errorf("%s: %T: want %q, have %q", name, tc.request, want, have)
+			}
+		}
+	}
+}
+
+func TestNamedAnnotate(t *testing.T) {
+	for name, annotate := range map[string]func(string, string, zipkin.Collector, ...zipkin.AnnotateOption) endpoint.NamedMiddleware{
+		"server": zipkin.NamedAnnotateServer,
+		"client": zipkin.NamedAnnotateClient,
+	} {
+		collector := &capturingCollector{}
+		set := endpoint.NewSet()
+		for _, method := range []string{"sum", "concat"} {
+			set.Add(method, func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil })
+		}
+		set.WrapAllNamed(annotate("1.2.3.4:1234", "some-service", collector))
+
+		for _, method := range set.Names() {
+			e, _ := set.Get(method)
+			if _, err := e(context.Background(), struct{}{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var have []string
+		for _, span := range collector.spans {
+			have = append(have, span.Name())
+		}
+		if want := []string{"sum", "concat"}; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want spans %v, have %v", name, want, have)
+		}
+	}
+}
+
+// failedResponse implements endpoint.Failer.
+type failedResponse struct{ err error }
+
+func (r failedResponse) Failed() error { return r.err }
+
+type capturingCollector struct {
+	spans []*zipkin.Span
+}
+
+func (c *capturingCollector) Collect(s *zipkin.Span) error {
+	c.spans = append(c.spans, s)
+	return nil
+}
+
+func (c *capturingCollector) ShouldSample(*zipkin.Span) bool { return true }
+
+func (c *capturingCollector) Close() error { return nil }
+
+type statusResponse struct{ Code int }
+
+func TestStatusFromResponse(t *testing.T) {
+	newSpan := zipkin.MakeNewSpanFunc("1.2.3.4:1234", "some-service", "some-method")
+	statusFunc := func(response interface{}) (int, bool) {
+		r, ok := response.(statusResponse)
+		return r.Code, ok
+	}
+
+	for name, annotate := range map[string]func(zipkin.NewSpanFunc, zipkin.Collector, ...zipkin.AnnotateOption) endpoint.Middleware{
+		"server": zipkin.AnnotateServer,
+		"client": zipkin.AnnotateClient,
+	} {
+		for _, tc := range []struct {
+			response interface{}
+			want     string
+		}{
+			{statusResponse{Code: 404}, "404"},
+			{statusResponse{}, "0"},
+			{struct{}{}, ""},
+			{nil, ""},
+		} {
+			collector := &capturingCollector{}
+			e := annotate(newSpan, collector, zipkin.StatusFromResponse(statusFunc))(func(context.Context, interface{}) (interface{}, error) {
+				return tc.response, nil
+			})
+			if _, err := e(context.Background(), struct{}{}); err != nil {
+				t.Fatal(err)
+			}
+			var have string
+			for _, a := range collector.spans[0].Encode().GetBinaryAnnotations() {
+				if a.Key == zipkin.ResponseStatus {
+					have = fmt.Sprint(int64(binary.BigEndian.Uint64(a.Value)))
+				}
+			}
+			if want := tc.want; want != have {
+				t.Errorf("%s: %#v: want status %q, have %q", name, tc.response, want, have)
+			}
+		}
+	}
+}
+
+func TestGRPCServerFinalizer(t *testing.T) {
+	newSpan := zipkin.MakeNewSpanFunc("5.5.5.5:5555", "foo-service", "foo-method")
+	collector := &capturingCollector{}
+	e := zipkin.AnnotateServer(newSpan, collector, zipkin.CollectInFinalizer())(func(ctx context.Context, _ interface{}) (interface{}, error) {
+		<-ctx.Done() // the deadline expires mid-call
+		return nil, ctx.Err()
+	})
+	passThrough := func(_ context.Context, v interface{}) (interface{}, error) { return v, nil }
+	server := grpctransport.NewServer(context.Background(), e, passThrough, passThrough,
+		grpctransport.ServerBefore(zipkin.ToGRPCContext(newSpan, log.NewNopLogger())),
+		grpctransport.ServerFinalizer(zipkin.GRPCServerFinalizer(collector)),
+	)
+
+	md := metadata.Pairs("x-b3-traceid", "1", "x-b3-spanid", "2", "x-b3-sampled", "1")
+	ctx, cancel := context.WithTimeout(metadata.NewIncomingContext(context.Background(), md), 10*time.Millisecond)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	if _, _, err := server.ServeGRPC(ctx, struct{}{}); err != context.DeadlineExceeded {
+		t.Fatalf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+
+	if want, have := 1, len(collector.spans); want != have {
+		t.Fatalf("want %d span collected, have %d", want, have)
+	}
+	have := map[string]string{}
+	for _, a := range collector.spans[0].Encode().GetBinaryAnnotations() {
+		have[a.Key] = string(a.Value)
+	}
+	for key, want := range map[string]string{
+		zipkin.GRPCDeadline:   deadline.UTC().Format(time.RFC3339Nano),
+		zipkin.Timeout:        "\x01",
+		zipkin.GRPCStatusCode: "DeadlineExceeded",
+	} {
+		if want != have[key] {
+			t.Errorf("%s: want %q, have %q", key, want, have[key])
+		}
+	}
+	var values []string
+	for _, a := range collector.spans[0].Encode().GetAnnotations() {
+		values = append(values, a.Value)
+	}
+	if want := []string{zipkin.ServerReceive, zipkin.ServerSend}; !reflect.DeepEqual(want, values) {
+		t.Errorf("want annotations %v, have %v", want, values)
+	}
+}
+
+func TestTextMapCarrier(t *testing.T) {
+	newSpan := zipkin.MakeNewSpanFunc("5.5.5.5:5555", "foo-service", "foo-method")
+
+	span := newSpan(20, 40, 90)
+	span.Sample()
+	span.SetDebug()
+	carrier := map[string]string{
+		"content-type": "application/json",
+		"x-b3-flags":   "0", // stale, replaced whatever its case
+	}
+	zipkin.InjectTextMapCarrier(span, carrier)
+	want := map[string]string{
+		"content-type":      "application/json",
+		"X-B3-TraceId":      "14",
+		"X-B3-SpanId":       "28",
+		"X-B3-ParentSpanId": "5a",
+		"X-B3-Sampled":      "1",
+		"X-B3-Flags":        "1",
+	}
+	if !reflect.DeepEqual(want, carrier) {
+		t.Fatalf("want %v, have %v", want, carrier)
+	}
+
+	// Transports may change the case of header names.
+	lower := map[string]string{}
+	for k, v := range carrier {
+		lower[strings.ToLower(k)] = v
+	}
+	for name, c := range map[string]map[string]string{"canonical": carrier, "lowercase": lower} {
+		extracted := zipkin.FromTextMapCarrier(c, newSpan)
+		if extracted == nil {
+			t.Fatalf("%s: no span extracted", name)
+		}
+		if want, have := []int64{20, 40, 90}, []int64{extracted.TraceID(), extracted.SpanID(), extracted.ParentSpanID()}; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want IDs %v, have %v", name, want, have)
+		}
+		if !extracted.Sampled() {
+			t.Errorf("%s: want sampled span", name)
+		}
+		if !extracted.Debug() {
+			t.Errorf("%s: want debug span", name)
+		}
+	}
+}
+
+func TestFromTextMapCarrierAbnormal(t *testing.T) {
+	newSpan := zipkin.MakeNewSpanFunc("5.5.5.5:5555", "foo-service", "foo-method")
+
+	for _, carrier := range []map[string]string{
+		nil,
+		{},
+		{"X-B3-SpanId": "28"},
+		{"X-B3-TraceId": "not-hex", "X-B3-SpanId": "28"},
+	} {
+		if span := zipkin.FromTextMapCarrier(carrier, newSpan); span != nil {
+			t.Errorf("%v: want no span, have %v", carrier, span)
+		}
+	}
+
+	span := zipkin.FromTextMapCarrier(map[string]string{
+		"X-B3-TraceId":      "14",
+		"X-B3-ParentSpanId": "not-hex",
+		"X-B3-Sampled":      "0",
+	}, newSpan)
+	if span == nil {
+		t.Fatal("no span extracted")
+	}
+	if want, have := int64(20), span.TraceID(); want != have {
+		t.Errorf("want trace ID %d, have %d", want, have)
+	}
+	if span.SpanID() == 0 {
+		t.Error("want a new span ID, have none")
+	}
+	if want, have := int64(0), span.ParentSpanID(); want != have {
+		t.Errorf("want parent span ID %d, have %d", want, have)
+	}
+	if span.Sampled() {
+		t.Error("want unsampled span")
+	}
+}
+
+func TestGRPCTrailerTraceID(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSpan := zipkin.MakeNewSpanFunc("5.5.5.5:5555", "foo-service", "foo-method")
+	server := stdgrpc.NewServer()
+	pb.RegisterTestServer(server, grpctest.NewBinding(grpctest.NewService(),
+		grpctransport.ServerBefore(zipkin.ToGRPCContext(newSpan, log.NewNopLogger())),
+		grpctransport.ServerAfter(zipkin.ToGRPCTrailer()),
+	))
+	go server.Serve(ln)
+	defer server.Stop()
+	cc, err := stdgrpc.Dial(ln.Addr().String(), stdgrpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	var (
+		traceID int64
+		ok      bool
+	)
+	client := grpctest.NewClient(cc,
+		grpctransport.SetClientBefore(zipkin.ToGRPCRequest(newSpan)),
+		grpctransport.SetClientAfter(func(ctx context.Context, _ metadata.MD, trailer metadata.MD) context.Context {
+			traceID, ok = zipkin.TraceIDFromGRPCTrailer(trailer)
+			return ctx
+		}),
+	)
+	span := newSpan(0x4e441824ec2b6a44, 0x2, 0)
+	if _, err := client.Test(zipkin.NewContext(context.Background(), span), "foo", 1); err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("no trace ID in the trailer")
+	}
+	if want, have := span.TraceID(), traceID; want != have {
+		t.Errorf("want trace ID %x, have %x", want, have)
+	}
+
+	// Without a trace, there's no trailer.
+	ok = true
+	if _, err := client.Test(context.Background(), "foo", 1); err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Errorf("want no trace ID, have %x", traceID)
+	}
+}
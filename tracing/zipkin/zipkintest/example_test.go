@@ -0,0 +1,34 @@
+package zipkintest_test
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/tracing/zipkin"
+	"github.com/go-kit/kit/tracing/zipkin/zipkintest"
+)
+
+func ExampleRecorder() {
+	var (
+		recorder = zipkintest.NewRecorder()
+		newSpan  = zipkin.MakeNewSpanFunc("1.2.3.4:1234", "users", "get-user")
+	)
+
+	var e endpoint.Endpoint
+	e = func(context.Context, interface{}) (interface{}, error) { return "alice", nil }
+	e = zipkin.AnnotateServer(newSpan, recorder)(e)
+	e(context.Background(), struct{}{})
+
+	span := recorder.LastSpan()
+	fmt.Println(span.GetName())
+	for _, a := range span.GetAnnotations() {
+		fmt.Println(a.GetValue())
+	}
+
+	// Output:
+	// get-user
+	// sr
+	// ss
+}
@@ -0,0 +1,69 @@
+// Package zipkintest provides a collector recording spans, to assert on the
+// spans produced by instrumented code in tests.
+package zipkintest
+
+import (
+	"sync"
+
+	"github.com/go-kit/kit/tracing/zipkin"
+	"github.com/go-kit/kit/tracing/zipkin/_thrift/gen-go/zipkincore"
+)
+
+// Recorder implements zipkin.Collector by recording the spans it collects.
+// Spans are recorded encoded, as they're at the time they're collected, so
+// they can be asserted on as Zipkin would receive them. Every span is
+// sampled. A Recorder is safe for concurrent use.
+type Recorder struct {
+	mtx   sync.Mutex
+	spans []*zipkincore.Span
+}
+
+// NewRecorder returns a new, empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Collect implements zipkin.Collector.
+func (r *Recorder) Collect(s *zipkin.Span) error {
+	encoded := s.Encode()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.spans = append(r.spans, encoded)
+	return nil
+}
+
+// ShouldSample implements zipkin.Collector. It samples every span.
+func (r *Recorder) ShouldSample(s *zipkin.Span) bool {
+	s.Sample()
+	return true
+}
+
+// Close implements zipkin.Collector.
+func (r *Recorder) Close() error { return nil }
+
+// Spans returns the spans recorded so far, in the order they were collected.
+func (r *Recorder) Spans() []*zipkincore.Span {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]*zipkincore.Span{}, r.spans...)
+}
+
+// LastSpan returns the span collected last, or nil if none was.
+func (r *Recorder) LastSpan() *zipkincore.Span {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(r.spans) == 0 {
+		return nil
+	}
+	return r.spans[len(r.spans)-1]
+}
+
+// Flush returns the spans recorded so far, like Spans, and forgets them, so
+// the next assertions only see the spans collected afterwards.
+func (r *Recorder) Flush() []*zipkincore.Span {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	spans := r.spans
+	r.spans = nil
+	return spans
+}
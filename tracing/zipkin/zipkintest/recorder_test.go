@@ -0,0 +1,63 @@
+package zipkintest_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/tracing/zipkin"
+	"github.com/go-kit/kit/tracing/zipkin/zipkintest"
+)
+
+func TestRecorder(t *testing.T) {
+	r := zipkintest.NewRecorder()
+	if span := r.LastSpan(); span != nil {
+		t.Errorf("want no last span, have %v", span)
+	}
+
+	for _, name := range []string{"first", "second"} {
+		if err := r.Collect(zipkin.NewSpan("1.2.3.4:1234", "service", name, 1, 2, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	spans := r.Spans()
+	if want, have := 2, len(spans); want != have {
+		t.Fatalf("want %d spans, have %d", want, have)
+	}
+	if want, have := "first", spans[0].GetName(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "second", r.LastSpan().GetName(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	if want, have := 2, len(r.Flush()); want != have {
+		t.Errorf("want %d flushed spans, have %d", want, have)
+	}
+	if want, have := 0, len(r.Spans()); want != have {
+		t.Errorf("want %d spans after flush, have %d", want, have)
+	}
+}
+
+func TestRecorderConcurrentCollect(t *testing.T) {
+	const n = 100
+	r := zipkintest.NewRecorder()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			r.Collect(zipkin.NewSpan("1.2.3.4:1234", "service", "method", 1, id, 0))
+			r.LastSpan()
+		}(int64(i + 1))
+	}
+	wg.Wait()
+
+	seen := map[int64]bool{}
+	for _, span := range r.Spans() {
+		seen[span.GetId()] = true
+	}
+	if want, have := n, len(seen); want != have {
+		t.Errorf("want %d distinct spans, have %d", want, have)
+	}
+}
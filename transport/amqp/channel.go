@@ -0,0 +1,10 @@
+package amqp
+
+import "github.com/streadway/amqp"
+
+// Channel is the subset of *amqp.Channel methods used by this package. It's
+// satisfied by *amqp.Channel, and may be implemented by test doubles.
+type Channel interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+}
@@ -0,0 +1,6 @@
+// Package amqp implements an AMQP transport, for consuming and publishing
+// messages via a broker such as RabbitMQ. A Subscriber serves an endpoint to
+// the messages of a queue, optionally replying to the sender; a Publisher
+// turns a remote subscriber into an endpoint, using the RPC-over-AMQP pattern
+// of reply-to queues and correlation IDs.
+package amqp
@@ -0,0 +1,28 @@
+package amqp
+
+import (
+	"github.com/streadway/amqp"
+	"golang.org/x/net/context"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from an AMQP
+// delivery. It's designed to be used in subscribers, for server-side
+// endpoints. One straightforward DecodeRequestFunc could be something that
+// JSON decodes from the delivery body to the concrete request type.
+type DecodeRequestFunc func(context.Context, *amqp.Delivery) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the AMQP
+// publishing. It's designed to be used in publishers, for client-side
+// endpoints. One straightforward EncodeRequestFunc could be something that
+// JSON encodes the object directly to the publishing body.
+type EncodeRequestFunc func(context.Context, *amqp.Publishing, interface{}) error
+
+// EncodeResponseFunc encodes the passed response object into the AMQP
+// publishing that's sent as the reply. It's designed to be used in
+// subscribers, for server-side endpoints.
+type EncodeResponseFunc func(context.Context, *amqp.Publishing, interface{}) error
+
+// DecodeResponseFunc extracts a user-domain response object from the AMQP
+// delivery of a reply. It's designed to be used in publishers, for
+// client-side endpoints.
+type DecodeResponseFunc func(context.Context, *amqp.Delivery) (response interface{}, err error)
@@ -0,0 +1,229 @@
+package amqp
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// DirectReplyTo is RabbitMQ's pseudo-queue for replies, which doesn't need
+// to be declared. See https://www.rabbitmq.com/direct-reply-to.html.
+const DirectReplyTo = "amq.rabbitmq.reply-to"
+
+// ErrReplyConsumerClosed is returned by publisher endpoints when the
+// consumer of the reply queue has stopped, typically because the channel was
+// closed. The publisher must be recreated on a new channel.
+var ErrReplyConsumerClosed = errors.New("reply consumer closed")
+
+// Publisher wraps an AMQP exchange and routing key, and provides a method
+// that implements endpoint.Endpoint. Each request is published with a unique
+// correlation ID and a reply-to queue, and the endpoint returns once the
+// reply with the matching correlation ID arrives, or the timeout expires.
+type Publisher struct {
+	ch       Channel
+	exchange string
+	key      string
+	enc      EncodeRequestFunc
+	dec      DecodeResponseFunc
+	before   []PublishingFunc
+	after    []DeliveryFunc
+	timeout  time.Duration
+	replyTo  string
+
+	once     sync.Once
+	startErr error
+	prefix   string
+
+	mtx     sync.Mutex
+	seq     uint64
+	pending map[string]chan amqp.Delivery
+	closed  bool
+}
+
+// NewPublisher constructs a usable Publisher for a single remote endpoint,
+// i.e. subscriber, reachable via the exchange and routing key. Use the empty
+// exchange and the queue name as key to publish to a queue directly.
+func NewPublisher(
+	ch Channel,
+	exchange string,
+	key string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	options ...PublisherOption,
+) *Publisher {
+	p := &Publisher{
+		ch:       ch,
+		exchange: exchange,
+		key:      key,
+		enc:      enc,
+		dec:      dec,
+		timeout:  10 * time.Second,
+		replyTo:  DirectReplyTo,
+		prefix:   strconv.FormatInt(rand.Int63(), 16),
+		pending:  map[string]chan amqp.Delivery{},
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// PublisherOption sets an optional parameter for publishers.
+type PublisherOption func(*Publisher)
+
+// PublisherBefore sets the PublishingFuncs that are applied to the outgoing
+// publishing before it's published.
+func PublisherBefore(before ...PublishingFunc) PublisherOption {
+	return func(p *Publisher) { p.before = before }
+}
+
+// PublisherAfter sets the DeliveryFuncs that are applied to the reply
+// delivery before it's decoded.
+func PublisherAfter(after ...DeliveryFunc) PublisherOption {
+	return func(p *Publisher) { p.after = after }
+}
+
+// PublisherTimeout sets the time to wait for a reply. A timeout of zero or
+// less waits until the request context is done. The default timeout is 10
+// seconds.
+func PublisherTimeout(timeout time.Duration) PublisherOption {
+	return func(p *Publisher) { p.timeout = timeout }
+}
+
+// PublisherReplyTo sets the queue the publisher consumes replies from. It
+// must exist, and shouldn't be shared with other consumers, e.g. an
+// exclusive, server-named queue. By default, RabbitMQ's direct reply-to
+// pseudo-queue is used, which requires no setup.
+func PublisherReplyTo(queue string) PublisherOption {
+	return func(p *Publisher) { p.replyTo = queue }
+}
+
+// Endpoint returns a usable endpoint that publishes the request and waits for
+// the reply.
+func (p *Publisher) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		var cancel context.CancelFunc
+		if p.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
+		defer cancel()
+
+		if err := p.consumeReplies(); err != nil {
+			return nil, Error{Domain: DomainDo, Err: err}
+		}
+
+		pub := amqp.Publishing{
+			ReplyTo:       p.replyTo,
+			CorrelationId: p.nextCorrelationID(),
+		}
+		if err := p.enc(ctx, &pub, request); err != nil {
+			return nil, Error{Domain: DomainEncode, Err: err}
+		}
+
+		for _, f := range p.before {
+			ctx = f(ctx, &pub)
+		}
+
+		replyc, err := p.register(pub.CorrelationId)
+		if err != nil {
+			return nil, Error{Domain: DomainDo, Err: err}
+		}
+		defer p.unregister(pub.CorrelationId)
+
+		if err := p.ch.Publish(p.exchange, p.key, false, false, pub); err != nil {
+			return nil, Error{Domain: DomainPublish, Err: err}
+		}
+
+		var d amqp.Delivery
+		select {
+		case reply, ok := <-replyc:
+			if !ok {
+				return nil, Error{Domain: DomainDo, Err: ErrReplyConsumerClosed}
+			}
+			d = reply
+		case <-ctx.Done():
+			return nil, Error{Domain: DomainDo, Err: ctx.Err()}
+		}
+
+		for _, f := range p.after {
+			ctx = f(ctx, &d)
+		}
+
+		response, err := p.dec(ctx, &d)
+		if err != nil {
+			return nil, Error{Domain: DomainDecode, Err: err}
+		}
+
+		return response, nil
+	}
+}
+
+// consumeReplies starts consuming the reply queue on first use. RabbitMQ
+// requires the consumer of the direct reply-to queue to exist before the
+// first request is published, and the consumer to be in no-ack mode.
+func (p *Publisher) consumeReplies() error {
+	p.once.Do(func() {
+		deliveries, err := p.ch.Consume(p.replyTo, "", true, false, false, false, nil)
+		if err != nil {
+			p.startErr = err
+			return
+		}
+		go p.dispatch(deliveries)
+	})
+	return p.startErr
+}
+
+// dispatch hands each reply to the request waiting for it. Replies without a
+// waiting request, e.g. those arriving after the timeout, are dropped.
+func (p *Publisher) dispatch(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		p.mtx.Lock()
+		replyc, ok := p.pending[d.CorrelationId]
+		delete(p.pending, d.CorrelationId)
+		p.mtx.Unlock()
+		if ok {
+			replyc <- d // buffered
+		}
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.closed = true
+	for id, replyc := range p.pending {
+		close(replyc)
+		delete(p.pending, id)
+	}
+}
+
+func (p *Publisher) nextCorrelationID() string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.seq++
+	return p.prefix + "-" + strconv.FormatUint(p.seq, 10)
+}
+
+func (p *Publisher) register(id string) (<-chan amqp.Delivery, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.closed {
+		return nil, ErrReplyConsumerClosed
+	}
+	replyc := make(chan amqp.Delivery, 1)
+	p.pending[id] = replyc
+	return replyc, nil
+}
+
+func (p *Publisher) unregister(id string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	delete(p.pending, id)
+}
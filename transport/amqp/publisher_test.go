@@ -0,0 +1,138 @@
+package amqp_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"golang.org/x/net/context"
+
+	amqptransport "github.com/go-kit/kit/transport/amqp"
+)
+
+func TestPublisherRoundTrip(t *testing.T) {
+	broker := newFakeBroker()
+	ch := broker.channel()
+
+	sub := amqptransport.NewSubscriber(
+		context.Background(),
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			return strings.ToUpper(request.(string)) + " " + ctx.Value(userKey{}).(string), nil
+		},
+		decodeString,
+		encodeString,
+		amqptransport.SubscriberBefore(func(ctx context.Context, d *amqp.Delivery) context.Context {
+			return context.WithValue(ctx, userKey{}, d.Headers["x-user"])
+		}),
+	)
+	done := make(chan struct{})
+	go func() { sub.Serve(ch, "work"); close(done) }()
+	defer func() { broker.close("work"); <-done }()
+
+	pub := amqptransport.NewPublisher(
+		ch, "", "work",
+		encodeString,
+		decodeString,
+		amqptransport.PublisherBefore(amqptransport.SetHeader("x-user", "alice")),
+		amqptransport.PublisherAfter(func(ctx context.Context, d *amqp.Delivery) context.Context {
+			if d.CorrelationId == "" {
+				t.Error("reply without correlation ID")
+			}
+			return ctx
+		}),
+	)
+	e := pub.Endpoint()
+
+	// Concurrent requests share the reply queue; correlation IDs route each
+	// reply to the right caller.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			request := fmt.Sprintf("request %d", i)
+			response, err := e(context.Background(), request)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if want, have := strings.ToUpper(request)+" alice", response.(string); want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPublisherIgnoresUnknownCorrelationID(t *testing.T) {
+	broker := newFakeBroker()
+	ch := broker.channel()
+
+	// A subscriber that first replies with the wrong correlation ID.
+	go func() {
+		d := <-broker.queue("work")
+		ch.Publish("", d.ReplyTo, false, false, amqp.Publishing{CorrelationId: "bogus", Body: []byte("wrong")})
+		ch.Publish("", d.ReplyTo, false, false, amqp.Publishing{CorrelationId: d.CorrelationId, Body: []byte("right")})
+	}()
+
+	pub := amqptransport.NewPublisher(ch, "", "work", encodeString, decodeString)
+	response, err := pub.Endpoint()(context.Background(), "request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "right", response.(string); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestPublisherTimeout(t *testing.T) {
+	broker := newFakeBroker()
+	pub := amqptransport.NewPublisher(
+		broker.channel(), "", "work",
+		encodeString,
+		decodeString,
+		amqptransport.PublisherTimeout(10*time.Millisecond),
+	)
+
+	_, err := pub.Endpoint()(context.Background(), "request")
+	e, ok := err.(amqptransport.Error)
+	if !ok {
+		t.Fatalf("want %T, have %v", amqptransport.Error{}, err)
+	}
+	if want, have := amqptransport.DomainDo, e.Domain; want != have {
+		t.Errorf("want domain %q, have %q", want, have)
+	}
+	if want, have := context.DeadlineExceeded, e.Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestPublisherReplyConsumerClosed(t *testing.T) {
+	broker := newFakeBroker()
+	pub := amqptransport.NewPublisher(
+		broker.channel(), "", "work",
+		encodeString,
+		decodeString,
+		amqptransport.PublisherReplyTo("replies"),
+	)
+
+	errc := make(chan error)
+	go func() {
+		_, err := pub.Endpoint()(context.Background(), "request")
+		errc <- err
+	}()
+	<-broker.queue("work") // request published, reply pending
+	broker.close("replies")
+
+	select {
+	case err := <-errc:
+		if e, ok := err.(amqptransport.Error); !ok || e.Err != amqptransport.ErrReplyConsumerClosed {
+			t.Errorf("want %v, have %v", amqptransport.ErrReplyConsumerClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
@@ -0,0 +1,38 @@
+package amqp
+
+import (
+	"github.com/streadway/amqp"
+	"golang.org/x/net/context"
+)
+
+// DeliveryFunc may take information from an AMQP delivery and put it into a
+// request context. In subscribers, DeliveryFuncs are executed prior to
+// decoding the request. In publishers, they're executed on the reply, prior
+// to decoding the response.
+type DeliveryFunc func(context.Context, *amqp.Delivery) context.Context
+
+// PublishingFunc may take information from a request context and use it to
+// manipulate an AMQP publishing, e.g. to set headers. In publishers,
+// PublishingFuncs are executed after encoding the request but prior to
+// publishing it. In subscribers, they're executed on the reply, after
+// encoding the response but prior to publishing it.
+type PublishingFunc func(context.Context, *amqp.Publishing) context.Context
+
+// SetHeader returns a PublishingFunc that sets the specified header.
+func SetHeader(key string, val interface{}) PublishingFunc {
+	return func(ctx context.Context, p *amqp.Publishing) context.Context {
+		if p.Headers == nil {
+			p.Headers = amqp.Table{}
+		}
+		p.Headers[key] = val
+		return ctx
+	}
+}
+
+// SetContentType returns a PublishingFunc that sets the content type.
+func SetContentType(contentType string) PublishingFunc {
+	return func(ctx context.Context, p *amqp.Publishing) context.Context {
+		p.ContentType = contentType
+		return ctx
+	}
+}
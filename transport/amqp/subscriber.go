@@ -0,0 +1,165 @@
+package amqp
+
+import (
+	"github.com/streadway/amqp"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Subscriber wraps an endpoint and serves it to the deliveries of an AMQP
+// queue. Each delivery is acknowledged once the endpoint succeeded, and, if
+// the delivery names a reply-to queue, the response is published there with
+// the delivery's correlation ID. Failed deliveries are negatively
+// acknowledged, and requeued according to the requeue policy.
+type Subscriber struct {
+	ctx          context.Context
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []DeliveryFunc
+	after        []PublishingFunc
+	errorEncoder ErrorEncoder
+	requeue      func(error) bool
+	logger       log.Logger
+}
+
+// NewSubscriber constructs a new subscriber, which serves the provided
+// endpoint to AMQP deliveries.
+func NewSubscriber(
+	ctx context.Context,
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...SubscriberOption,
+) *Subscriber {
+	s := &Subscriber{
+		ctx:     ctx,
+		e:       e,
+		dec:     dec,
+		enc:     enc,
+		requeue: func(error) bool { return false },
+		logger:  log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore functions are executed on the AMQP delivery before the
+// request is decoded.
+func SubscriberBefore(before ...DeliveryFunc) SubscriberOption {
+	return func(s *Subscriber) { s.before = before }
+}
+
+// SubscriberAfter functions are executed on the reply publishing after the
+// response is encoded, but before it's published.
+func SubscriberAfter(after ...PublishingFunc) SubscriberOption {
+	return func(s *Subscriber) { s.after = after }
+}
+
+// SubscriberErrorEncoder is used to encode errors into a reply, which is
+// published to the reply-to queue of failed deliveries that aren't requeued.
+// By default, no reply is published for failed deliveries, and publishers
+// waiting for one time out.
+func SubscriberErrorEncoder(ee ErrorEncoder) SubscriberOption {
+	return func(s *Subscriber) { s.errorEncoder = ee }
+}
+
+// SubscriberRequeue sets the policy deciding whether a failed delivery is
+// requeued, to be delivered again, or discarded (or dead-lettered, if the
+// queue is so configured). The policy is passed the error, which is an Error
+// identifying the failed stage. By default, failed deliveries are never
+// requeued, as a delivery that can't be processed would otherwise be retried
+// forever.
+func SubscriberRequeue(requeue func(error) bool) SubscriberOption {
+	return func(s *Subscriber) { s.requeue = requeue }
+}
+
+// SubscriberErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged.
+func SubscriberErrorLogger(logger log.Logger) SubscriberOption {
+	return func(s *Subscriber) { s.logger = logger }
+}
+
+// ErrorEncoder is responsible for encoding an error into the reply publishing.
+type ErrorEncoder func(ctx context.Context, err error, p *amqp.Publishing)
+
+// Serve consumes the named queue on the channel, and serves each delivery in
+// turn. It returns when the channel is closed, or if consuming fails. Call
+// Serve from several goroutines, or on several channels, to process
+// deliveries concurrently.
+func (s Subscriber) Serve(ch Channel, queue string) error {
+	deliveries, err := ch.Consume(queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	for d := range deliveries {
+		s.ServeDelivery(ch, &d)
+	}
+	return nil
+}
+
+// ServeDelivery serves a single delivery, publishing any reply on the
+// channel. It's useful for consumer loops managed by the caller.
+func (s Subscriber) ServeDelivery(ch Channel, d *amqp.Delivery) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	for _, f := range s.before {
+		ctx = f(ctx, d)
+	}
+
+	request, err := s.dec(ctx, d)
+	if err != nil {
+		s.fail(ctx, ch, d, Error{Domain: DomainDecode, Err: err})
+		return
+	}
+
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.fail(ctx, ch, d, Error{Domain: DomainDo, Err: err})
+		return
+	}
+
+	if d.ReplyTo != "" {
+		p := amqp.Publishing{CorrelationId: d.CorrelationId}
+		if err := s.enc(ctx, &p, response); err != nil {
+			s.fail(ctx, ch, d, Error{Domain: DomainEncode, Err: err})
+			return
+		}
+		for _, f := range s.after {
+			ctx = f(ctx, &p)
+		}
+		if err := ch.Publish("", d.ReplyTo, false, false, p); err != nil {
+			s.fail(ctx, ch, d, Error{Domain: DomainPublish, Err: err})
+			return
+		}
+	}
+
+	if err := d.Ack(false); err != nil {
+		s.logger.Log("err", err)
+	}
+}
+
+func (s Subscriber) fail(ctx context.Context, ch Channel, d *amqp.Delivery, err error) {
+	s.logger.Log("err", err)
+
+	requeue := s.requeue(err)
+	if !requeue && s.errorEncoder != nil && d.ReplyTo != "" {
+		p := amqp.Publishing{CorrelationId: d.CorrelationId}
+		s.errorEncoder(ctx, err, &p)
+		if err := ch.Publish("", d.ReplyTo, false, false, p); err != nil {
+			s.logger.Log("err", err)
+		}
+	}
+
+	if err := d.Nack(false, requeue); err != nil {
+		s.logger.Log("err", err)
+	}
+}
@@ -0,0 +1,299 @@
+package amqp_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"golang.org/x/net/context"
+
+	amqptransport "github.com/go-kit/kit/transport/amqp"
+)
+
+type userKey struct{}
+
+func TestSubscriberReply(t *testing.T) {
+	broker := newFakeBroker()
+	ch := broker.channel()
+
+	sub := amqptransport.NewSubscriber(
+		context.Background(),
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			return strings.ToUpper(request.(string)) + " " + ctx.Value(userKey{}).(string), nil
+		},
+		decodeString,
+		encodeString,
+		amqptransport.SubscriberBefore(func(ctx context.Context, d *amqp.Delivery) context.Context {
+			return context.WithValue(ctx, userKey{}, d.Headers["x-user"])
+		}),
+		amqptransport.SubscriberAfter(amqptransport.SetHeader("x-served-by", "test")),
+	)
+
+	d := broker.delivery(amqp.Publishing{
+		Headers:       amqp.Table{"x-user": "alice"},
+		ReplyTo:       "replies",
+		CorrelationId: "42",
+		Body:          []byte("hello"),
+	})
+	sub.ServeDelivery(ch, &d)
+
+	if want, have := []ack{{tag: d.DeliveryTag}}, broker.acks(); !equalAcks(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	reply := <-broker.queue("replies")
+	if want, have := "42", reply.CorrelationId; want != have {
+		t.Errorf("want correlation ID %q, have %q", want, have)
+	}
+	if want, have := "HELLO alice", string(reply.Body); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "test", reply.Headers["x-served-by"]; want != have {
+		t.Errorf("want header %q, have %q", want, have)
+	}
+}
+
+func TestSubscriberNoReplyTo(t *testing.T) {
+	broker := newFakeBroker()
+
+	sub := amqptransport.NewSubscriber(
+		context.Background(),
+		func(context.Context, interface{}) (interface{}, error) { return "ignored", nil },
+		decodeString,
+		func(context.Context, *amqp.Publishing, interface{}) error {
+			t.Error("response encoded without reply-to queue")
+			return nil
+		},
+	)
+	d := broker.delivery(amqp.Publishing{Body: []byte("hello")})
+	sub.ServeDelivery(broker.channel(), &d)
+
+	if want, have := []ack{{tag: d.DeliveryTag}}, broker.acks(); !equalAcks(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 0, broker.published(); want != have {
+		t.Errorf("want %d publishing(s), have %d", want, have)
+	}
+}
+
+func TestSubscriberRequeue(t *testing.T) {
+	errTransient := errors.New("transient")
+	requeueTransient := func(err error) bool {
+		e, ok := err.(amqptransport.Error)
+		return ok && e.Err == errTransient
+	}
+
+	for _, tc := range []struct {
+		name    string
+		dec     amqptransport.DecodeRequestFunc
+		e       func(context.Context, interface{}) (interface{}, error)
+		options []amqptransport.SubscriberOption
+		requeue bool
+	}{
+		{
+			name:    "bad decode",
+			dec:     func(context.Context, *amqp.Delivery) (interface{}, error) { return nil, errors.New("malformed") },
+			options: []amqptransport.SubscriberOption{amqptransport.SubscriberRequeue(requeueTransient)},
+			requeue: false,
+		},
+		{
+			name:    "transient endpoint error",
+			e:       func(context.Context, interface{}) (interface{}, error) { return nil, errTransient },
+			options: []amqptransport.SubscriberOption{amqptransport.SubscriberRequeue(requeueTransient)},
+			requeue: true,
+		},
+		{
+			name:    "default policy",
+			e:       func(context.Context, interface{}) (interface{}, error) { return nil, errTransient },
+			requeue: false,
+		},
+	} {
+		if tc.dec == nil {
+			tc.dec = decodeString
+		}
+		if tc.e == nil {
+			tc.e = func(context.Context, interface{}) (interface{}, error) { return "", nil }
+		}
+		broker := newFakeBroker()
+		sub := amqptransport.NewSubscriber(context.Background(), tc.e, tc.dec, encodeString, tc.options...)
+		d := broker.delivery(amqp.Publishing{Body: []byte("hello")})
+		sub.ServeDelivery(broker.channel(), &d)
+
+		want := []ack{{tag: d.DeliveryTag, nack: true, requeue: tc.requeue}}
+		if have := broker.acks(); !equalAcks(want, have) {
+			t.Errorf("%s: want %v, have %v", tc.name, want, have)
+		}
+	}
+}
+
+func TestSubscriberErrorEncoder(t *testing.T) {
+	broker := newFakeBroker()
+	sub := amqptransport.NewSubscriber(
+		context.Background(),
+		func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("dang") },
+		decodeString,
+		encodeString,
+		amqptransport.SubscriberErrorEncoder(func(_ context.Context, err error, p *amqp.Publishing) {
+			p.Headers = amqp.Table{"error": err.Error()}
+		}),
+	)
+	d := broker.delivery(amqp.Publishing{ReplyTo: "replies", CorrelationId: "42", Body: []byte("hello")})
+	sub.ServeDelivery(broker.channel(), &d)
+
+	reply := <-broker.queue("replies")
+	if want, have := "42", reply.CorrelationId; want != have {
+		t.Errorf("want correlation ID %q, have %q", want, have)
+	}
+	if want, have := "Do: dang", reply.Headers["error"]; want != have {
+		t.Errorf("want error header %q, have %q", want, have)
+	}
+}
+
+func TestSubscriberServe(t *testing.T) {
+	broker := newFakeBroker()
+	ch := broker.channel()
+	for _, body := range []string{"a", "b", "c"} {
+		ch.Publish("", "work", false, false, amqp.Publishing{Body: []byte(body)})
+	}
+	broker.close("work")
+
+	var served []string
+	sub := amqptransport.NewSubscriber(
+		context.Background(),
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			served = append(served, request.(string))
+			return nil, nil
+		},
+		decodeString,
+		encodeString,
+	)
+	if err := sub.Serve(ch, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "a b c", strings.Join(served, " "); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 3, len(broker.acks()); want != have {
+		t.Errorf("want %d ack(s), have %d", want, have)
+	}
+}
+
+func decodeString(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	return string(d.Body), nil
+}
+
+func encodeString(_ context.Context, p *amqp.Publishing, v interface{}) error {
+	p.Body = []byte(v.(string))
+	return nil
+}
+
+// fakeBroker is an in-memory stand-in for an AMQP broker, supporting
+// publishing to queues via the default exchange.
+type fakeBroker struct {
+	mtx    sync.Mutex
+	queues map[string]chan amqp.Delivery
+	tag    uint64
+	count  int
+	record []ack
+}
+
+type ack struct {
+	tag     uint64
+	nack    bool
+	requeue bool
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{queues: map[string]chan amqp.Delivery{}}
+}
+
+func (b *fakeBroker) channel() amqptransport.Channel { return fakeChannel{b} }
+
+func (b *fakeBroker) queue(name string) chan amqp.Delivery {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	q, ok := b.queues[name]
+	if !ok {
+		q = make(chan amqp.Delivery, 100)
+		b.queues[name] = q
+	}
+	return q
+}
+
+func (b *fakeBroker) close(name string) { close(b.queue(name)) }
+
+func (b *fakeBroker) delivery(p amqp.Publishing) amqp.Delivery {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.tag++
+	return amqp.Delivery{
+		Acknowledger:  b,
+		DeliveryTag:   b.tag,
+		Headers:       p.Headers,
+		ContentType:   p.ContentType,
+		CorrelationId: p.CorrelationId,
+		ReplyTo:       p.ReplyTo,
+		Body:          p.Body,
+	}
+}
+
+func (b *fakeBroker) published() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.count
+}
+
+func (b *fakeBroker) acks() []ack {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return append([]ack{}, b.record...)
+}
+
+func (b *fakeBroker) Ack(tag uint64, multiple bool) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.record = append(b.record, ack{tag: tag})
+	return nil
+}
+
+func (b *fakeBroker) Nack(tag uint64, multiple bool, requeue bool) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.record = append(b.record, ack{tag: tag, nack: true, requeue: requeue})
+	return nil
+}
+
+func (b *fakeBroker) Reject(tag uint64, requeue bool) error {
+	return b.Nack(tag, false, requeue)
+}
+
+type fakeChannel struct{ b *fakeBroker }
+
+func (c fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if exchange != "" {
+		return errors.New("only the default exchange is supported")
+	}
+	d := c.b.delivery(msg)
+	c.b.mtx.Lock()
+	c.b.count++
+	c.b.mtx.Unlock()
+	c.b.queue(key) <- d
+	return nil
+}
+
+func (c fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	return c.b.queue(queue), nil
+}
+
+func equalAcks(a, b []ack) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package awslambda
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"golang.org/x/net/context"
+)
+
+// DecodeAPIGatewayRequest returns a DecodeRequestFunc for API Gateway proxy
+// integrations. It unmarshals the payload into an APIGatewayProxyRequest,
+// and passes it to the provided function for decoding into the request type.
+func DecodeAPIGatewayRequest(dec func(context.Context, events.APIGatewayProxyRequest) (interface{}, error)) DecodeRequestFunc {
+	return func(ctx context.Context, payload []byte) (interface{}, error) {
+		var r events.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return nil, err
+		}
+		return dec(ctx, r)
+	}
+}
+
+// APIGatewayRequestBody returns the body of the API Gateway proxy request,
+// decoding binary bodies, which API Gateway passes base64 encoded.
+func APIGatewayRequestBody(r events.APIGatewayProxyRequest) ([]byte, error) {
+	if r.IsBase64Encoded {
+		return base64.StdEncoding.DecodeString(r.Body)
+	}
+	return []byte(r.Body), nil
+}
+
+// EncodeAPIGatewayResponse returns an EncodeResponseFunc for API Gateway proxy
+// integrations. The provided function encodes the response into an
+// APIGatewayProxyResponse, which is marshaled into the payload.
+func EncodeAPIGatewayResponse(enc func(context.Context, interface{}) (events.APIGatewayProxyResponse, error)) EncodeResponseFunc {
+	return func(ctx context.Context, response interface{}) ([]byte, error) {
+		r, err := enc(ctx, response)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(r)
+	}
+}
+
+// EncodeJSONAPIGatewayResponse is an EncodeResponseFunc for API Gateway proxy
+// integrations, which serializes the response as the JSON body of an
+// APIGatewayProxyResponse. The status code is 200 OK, unless the response
+// implements StatusCoder.
+func EncodeJSONAPIGatewayResponse(ctx context.Context, response interface{}) ([]byte, error) {
+	return EncodeAPIGatewayResponse(func(_ context.Context, response interface{}) (events.APIGatewayProxyResponse, error) {
+		body, err := json.Marshal(response)
+		if err != nil {
+			return events.APIGatewayProxyResponse{}, err
+		}
+		code := http.StatusOK
+		if sc, ok := response.(StatusCoder); ok {
+			code = sc.StatusCode()
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: code,
+			Headers:    map[string]string{"Content-Type": "application/json; charset=utf-8"},
+			Body:       string(body),
+		}, nil
+	})(ctx, response)
+}
+
+// APIGatewayErrorEncoder is an ErrorEncoder for API Gateway proxy
+// integrations. It encodes the error into an APIGatewayProxyResponse with a
+// JSON body of the form {"error": "message"}. The status code is taken from
+// the error if it implements StatusCoder; otherwise, decoding errors yield
+// 400 Bad Request, and all others 500 Internal Server Error.
+func APIGatewayErrorEncoder(_ context.Context, err error) ([]byte, error) {
+	code := http.StatusInternalServerError
+	if e, ok := err.(Error); ok {
+		if e.Domain == DomainDecode {
+			code = http.StatusBadRequest
+		}
+		err = e.Err
+	}
+	if sc, ok := err.(StatusCoder); ok {
+		code = sc.StatusCode()
+	}
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return json.Marshal(events.APIGatewayProxyResponse{
+		StatusCode: code,
+		Headers:    map[string]string{"Content-Type": "application/json; charset=utf-8"},
+		Body:       string(body),
+	})
+}
@@ -0,0 +1,5 @@
+// Package awslambda implements an AWS Lambda transport. A Handler serves an
+// endpoint to Lambda invocations, and can be passed to lambda.StartHandler
+// from github.com/aws/aws-lambda-go/lambda. Helpers adapt the Handler to API
+// Gateway proxy events.
+package awslambda
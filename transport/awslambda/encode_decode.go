@@ -0,0 +1,17 @@
+package awslambda
+
+import "golang.org/x/net/context"
+
+// DecodeRequestFunc extracts a user-domain request object from the raw JSON
+// payload of a Lambda invocation. One straightforward DecodeRequestFunc could
+// be something that JSON decodes the payload to the concrete request type.
+type DecodeRequestFunc func(context.Context, []byte) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object to the raw JSON
+// payload returned from the Lambda invocation.
+type EncodeResponseFunc func(context.Context, interface{}) (payload []byte, err error)
+
+// ErrorEncoder is responsible for encoding an error into the returned payload.
+// It may instead return an error, which the Lambda runtime reports as a
+// function error.
+type ErrorEncoder func(ctx context.Context, err error) (payload []byte, encodeErr error)
@@ -0,0 +1,35 @@
+package awslambda
+
+import "fmt"
+
+const (
+	// DomainDecode is an error during request decoding.
+	DomainDecode = "Decode"
+
+	// DomainDo is an error during the execution phase of the request.
+	DomainDo = "Do"
+
+	// DomainEncode is an error during response encoding.
+	DomainEncode = "Encode"
+)
+
+// Error is an error that occurred at some phase within the transport.
+type Error struct {
+	// Domain is the phase in which the error was generated.
+	Domain string
+
+	// Err is the concrete error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Domain, e.Err)
+}
+
+// StatusCoder is checked by APIGatewayErrorEncoder and
+// EncodeJSONAPIGatewayResponse. Errors and responses implementing it set the
+// HTTP status code of the API Gateway response.
+type StatusCoder interface {
+	StatusCode() int
+}
@@ -0,0 +1,110 @@
+package awslambda
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Handler wraps an endpoint and implements the Lambda handler interface,
+// i.e. lambda.Handler from github.com/aws/aws-lambda-go/lambda.
+type Handler struct {
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []RequestFunc
+	after        []ResponseFunc
+	errorEncoder ErrorEncoder
+	logger       log.Logger
+}
+
+// NewHandler constructs a new handler, which implements lambda.Handler and
+// wraps the provided endpoint. Unlike other transports, it takes no base
+// context: each request is served in the context of its invocation, which
+// carries the deadline of the Lambda runtime.
+func NewHandler(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...HandlerOption,
+) *Handler {
+	h := &Handler{
+		e:            e,
+		dec:          dec,
+		enc:          enc,
+		errorEncoder: defaultErrorEncoder,
+		logger:       log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// HandlerOption sets an optional parameter for handlers.
+type HandlerOption func(*Handler)
+
+// HandlerBefore functions are executed on the invocation payload before the
+// request is decoded.
+func HandlerBefore(before ...RequestFunc) HandlerOption {
+	return func(h *Handler) { h.before = before }
+}
+
+// HandlerAfter functions are executed on the response payload after it's
+// encoded, but before it's returned.
+func HandlerAfter(after ...ResponseFunc) HandlerOption {
+	return func(h *Handler) { h.after = after }
+}
+
+// HandlerErrorEncoder is used to encode errors into the returned payload
+// whenever they're encountered in the processing of a request. By default,
+// errors are returned to the Lambda runtime, which reports them as function
+// errors. API Gateway proxy handlers should use APIGatewayErrorEncoder.
+func HandlerErrorEncoder(ee ErrorEncoder) HandlerOption {
+	return func(h *Handler) { h.errorEncoder = ee }
+}
+
+// HandlerErrorLogger is used to log non-terminal errors. By default, no errors
+// are logged.
+func HandlerErrorLogger(logger log.Logger) HandlerOption {
+	return func(h *Handler) { h.logger = logger }
+}
+
+// Invoke implements lambda.Handler.
+func (h Handler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, f := range h.before {
+		ctx = f(ctx, payload)
+	}
+
+	request, err := h.dec(ctx, payload)
+	if err != nil {
+		h.logger.Log("err", err)
+		return h.errorEncoder(ctx, Error{Domain: DomainDecode, Err: err})
+	}
+
+	response, err := h.e(ctx, request)
+	if err != nil {
+		h.logger.Log("err", err)
+		return h.errorEncoder(ctx, Error{Domain: DomainDo, Err: err})
+	}
+
+	responsePayload, err := h.enc(ctx, response)
+	if err != nil {
+		h.logger.Log("err", err)
+		return h.errorEncoder(ctx, Error{Domain: DomainEncode, Err: err})
+	}
+
+	for _, f := range h.after {
+		f(ctx, responsePayload)
+	}
+
+	return responsePayload, nil
+}
+
+func defaultErrorEncoder(_ context.Context, err error) ([]byte, error) {
+	return nil, err
+}
@@ -0,0 +1,151 @@
+package awslambda_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/transport/awslambda"
+)
+
+type sumRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type sumResponse struct {
+	V int `json:"v"`
+}
+
+// errNegative is reported with status 422 Unprocessable Entity.
+type errNegative struct{}
+
+func (errNegative) Error() string   { return "negative operand" }
+func (errNegative) StatusCode() int { return 422 }
+
+func sumEndpoint(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(sumRequest)
+	if req.A < 0 || req.B < 0 {
+		return nil, errNegative{}
+	}
+	return sumResponse{V: req.A + req.B}, nil
+}
+
+var decodeSumRequest = awslambda.DecodeAPIGatewayRequest(func(_ context.Context, r events.APIGatewayProxyRequest) (interface{}, error) {
+	body, err := awslambda.APIGatewayRequestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	var req sumRequest
+	err = json.Unmarshal(body, &req)
+	return req, err
+})
+
+func TestAPIGatewayEvents(t *testing.T) {
+	for _, tc := range []struct {
+		fixture    string
+		wantStatus int
+		wantBody   string
+	}{
+		{"apigw-sum.json", http.StatusOK, `{"v":3}`},
+		{"apigw-sum-base64.json", http.StatusOK, `{"v":42}`},
+		{"apigw-sum-malformed.json", http.StatusBadRequest, `{"error":"unexpected end of JSON input"}`},
+		{"apigw-sum-negative.json", 422, `{"error":"negative operand"}`},
+	} {
+		payload, err := ioutil.ReadFile(filepath.Join("testdata", tc.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var before, after []byte
+		handler := awslambda.NewHandler(
+			sumEndpoint,
+			decodeSumRequest,
+			awslambda.EncodeJSONAPIGatewayResponse,
+			awslambda.HandlerErrorEncoder(awslambda.APIGatewayErrorEncoder),
+			awslambda.HandlerBefore(func(ctx context.Context, payload []byte) context.Context {
+				before = payload
+				return ctx
+			}),
+			awslambda.HandlerAfter(func(_ context.Context, payload []byte) {
+				after = payload
+			}),
+		)
+
+		responsePayload, err := handler.Invoke(context.Background(), payload)
+		if err != nil {
+			t.Errorf("%s: %v", tc.fixture, err)
+			continue
+		}
+		if !bytes.Equal(payload, before) {
+			t.Errorf("%s: before func wasn't passed the invocation payload", tc.fixture)
+		}
+		if tc.wantStatus == http.StatusOK && !bytes.Equal(responsePayload, after) {
+			t.Errorf("%s: after func wasn't passed the response payload", tc.fixture)
+		}
+
+		var resp events.APIGatewayProxyResponse
+		if err := json.Unmarshal(responsePayload, &resp); err != nil {
+			t.Errorf("%s: %v", tc.fixture, err)
+			continue
+		}
+		if want, have := tc.wantStatus, resp.StatusCode; want != have {
+			t.Errorf("%s: want status %d, have %d", tc.fixture, want, have)
+		}
+		if want, have := tc.wantBody, resp.Body; want != have {
+			t.Errorf("%s: want body %s, have %s", tc.fixture, want, have)
+		}
+		if want, have := "application/json; charset=utf-8", resp.Headers["Content-Type"]; want != have {
+			t.Errorf("%s: want Content-Type %q, have %q", tc.fixture, want, have)
+		}
+	}
+}
+
+func TestHandlerDeadline(t *testing.T) {
+	deadline := time.Now().Add(3 * time.Second)
+	var have time.Time
+	handler := awslambda.NewHandler(
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			have, _ = ctx.Deadline()
+			return nil, nil
+		},
+		func(context.Context, []byte) (interface{}, error) { return nil, nil },
+		func(context.Context, interface{}) ([]byte, error) { return []byte("null"), nil },
+	)
+
+	// The Lambda runtime invokes the handler with the invocation deadline.
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if _, err := handler.Invoke(ctx, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if !have.Equal(deadline) {
+		t.Errorf("want deadline %v, have %v", deadline, have)
+	}
+}
+
+func TestHandlerDefaultErrorEncoder(t *testing.T) {
+	handler := awslambda.NewHandler(
+		func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("dang") },
+		func(context.Context, []byte) (interface{}, error) { return nil, nil },
+		func(context.Context, interface{}) ([]byte, error) { return nil, nil },
+	)
+
+	// The error is returned to the Lambda runtime, to report a function error.
+	_, err := handler.Invoke(context.Background(), []byte("{}"))
+	e, ok := err.(awslambda.Error)
+	if !ok {
+		t.Fatalf("want %T, have %v", awslambda.Error{}, err)
+	}
+	if want, have := awslambda.DomainDo, e.Domain; want != have {
+		t.Errorf("want domain %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,13 @@
+package awslambda
+
+import "golang.org/x/net/context"
+
+// RequestFunc may take information from the invocation payload and put it
+// into a request context. RequestFuncs are executed prior to decoding the
+// request.
+type RequestFunc func(ctx context.Context, payload []byte) context.Context
+
+// ResponseFunc may inspect the payload returned from the invocation, e.g. to
+// log or measure it. ResponseFuncs are executed after the response is
+// encoded, but prior to returning it.
+type ResponseFunc func(ctx context.Context, payload []byte)
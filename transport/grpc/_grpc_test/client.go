@@ -0,0 +1,38 @@
+package test
+
+import (
+	"golang.org/x/net/context"
+	stdgrpc "google.golang.org/grpc"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+type clientBinding struct {
+	test endpoint.Endpoint
+}
+
+// NewClient returns a Service backed by a Go kit gRPC client, constructed with
+// the given client options.
+func NewClient(cc *stdgrpc.ClientConn, options ...grpc.ClientOption) Service {
+	return &clientBinding{
+		test: grpc.NewClient(
+			cc,
+			"Test",
+			"Test",
+			encodeRequest,
+			decodeResponse,
+			&pb.TestResponse{},
+			options...,
+		).Endpoint(),
+	}
+}
+
+func (c *clientBinding) Test(ctx context.Context, a string, b int64) (string, error) {
+	response, err := c.test(ctx, TestRequest{A: a, B: b})
+	if err != nil {
+		return "", err
+	}
+	return response.(TestResponse).V, nil
+}
@@ -0,0 +1,186 @@
+// Code generated by protoc-gen-go.
+// source: test.proto
+// DO NOT EDIT!
+
+/*
+Package pb is a generated protocol buffer package.
+
+It is generated from these files:
+
+	test.proto
+
+It has these top-level messages:
+
+	TestRequest
+	TestResponse
+*/
+package pb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type TestRequest struct {
+	A string `protobuf:"bytes,1,opt,name=a" json:"a,omitempty"`
+	B int64  `protobuf:"varint,2,opt,name=b" json:"b,omitempty"`
+}
+
+func (m *TestRequest) Reset()         { *m = TestRequest{} }
+func (m *TestRequest) String() string { return proto.CompactTextString(m) }
+func (*TestRequest) ProtoMessage()    {}
+
+type TestResponse struct {
+	V string `protobuf:"bytes,1,opt,name=v" json:"v,omitempty"`
+}
+
+func (m *TestResponse) Reset()         { *m = TestResponse{} }
+func (m *TestResponse) String() string { return proto.CompactTextString(m) }
+func (*TestResponse) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterType((*TestRequest)(nil), "pb.TestRequest")
+	proto.RegisterType((*TestResponse)(nil), "pb.TestResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Test service
+
+type TestClient interface {
+	Test(ctx context.Context, in *TestRequest, opts ...grpc.CallOption) (*TestResponse, error)
+	TestStream(ctx context.Context, in *TestRequest, opts ...grpc.CallOption) (Test_TestStreamClient, error)
+}
+
+type testClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewTestClient(cc *grpc.ClientConn) TestClient {
+	return &testClient{cc}
+}
+
+func (c *testClient) Test(ctx context.Context, in *TestRequest, opts ...grpc.CallOption) (*TestResponse, error) {
+	out := new(TestResponse)
+	err := grpc.Invoke(ctx, "/pb.Test/Test", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *testClient) TestStream(ctx context.Context, in *TestRequest, opts ...grpc.CallOption) (Test_TestStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Test_serviceDesc.Streams[0], c.cc, "/pb.Test/TestStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &testTestStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Test_TestStreamClient interface {
+	Recv() (*TestResponse, error)
+	grpc.ClientStream
+}
+
+type testTestStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *testTestStreamClient) Recv() (*TestResponse, error) {
+	m := new(TestResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Test service
+
+type TestServer interface {
+	Test(context.Context, *TestRequest) (*TestResponse, error)
+	TestStream(*TestRequest, Test_TestStreamServer) error
+}
+
+func RegisterTestServer(s *grpc.Server, srv TestServer) {
+	s.RegisterService(&_Test_serviceDesc, srv)
+}
+
+func _Test_Test_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TestServer).Test(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Test/Test",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TestServer).Test(ctx, req.(*TestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Test_TestStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TestRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TestServer).TestStream(m, &testTestStreamServer{stream})
+}
+
+type Test_TestStreamServer interface {
+	Send(*TestResponse) error
+	grpc.ServerStream
+}
+
+type testTestStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *testTestStreamServer) Send(m *TestResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Test_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.Test",
+	HandlerType: (*TestServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Test",
+			Handler:    _Test_Test_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "TestStream",
+			Handler:       _Test_TestStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "test.proto",
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+// TestRequest is the domain request for the test service.
+type TestRequest struct {
+	A string
+	B int64
+}
+
+// TestResponse is the domain response for the test service.
+type TestResponse struct {
+	V string
+}
+
+func makeTestEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(TestRequest)
+		v, err := svc.Test(ctx, req.A, req.B)
+		if err != nil {
+			return nil, err
+		}
+		return TestResponse{V: v}, nil
+	}
+}
+
+func decodeRequest(_ context.Context, req interface{}) (interface{}, error) {
+	r := req.(*pb.TestRequest)
+	return TestRequest{A: r.A, B: r.B}, nil
+}
+
+func encodeRequest(_ context.Context, req interface{}) (interface{}, error) {
+	r := req.(TestRequest)
+	return &pb.TestRequest{A: r.A, B: r.B}, nil
+}
+
+func decodeResponse(_ context.Context, resp interface{}) (interface{}, error) {
+	r := resp.(*pb.TestResponse)
+	return TestResponse{V: r.V}, nil
+}
+
+func encodeResponse(_ context.Context, resp interface{}) (interface{}, error) {
+	r := resp.(TestResponse)
+	return &pb.TestResponse{V: r.V}, nil
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+type serverBinding struct {
+	test grpc.Handler
+}
+
+// NewBinding returns a pb.TestServer which serves the service via the Go kit
+// gRPC transport. Additional server options are applied to the handler.
+func NewBinding(svc Service, options ...grpc.ServerOption) pb.TestServer {
+	return &serverBinding{
+		test: grpc.NewServer(
+			context.Background(),
+			makeTestEndpoint(svc),
+			decodeRequest,
+			encodeResponse,
+			append([]grpc.ServerOption{
+				grpc.ServerBefore(
+					extractMetadata("x-correlation-id", CorrelationIDKey),
+					extractMetadata("authorization", AuthorizationKey),
+				),
+			}, options...)...,
+		),
+	}
+}
+
+func (b *serverBinding) Test(ctx context.Context, req *pb.TestRequest) (*pb.TestResponse, error) {
+	_, response, err := b.test.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return response.(*pb.TestResponse), nil
+}
+
+// TestStream sends b responses, the i-th being the response to Test(a, i).
+// The Go kit transport handles unary calls only, so the stream is built from
+// repeated calls to the unary handler.
+func (b *serverBinding) TestStream(req *pb.TestRequest, stream pb.Test_TestStreamServer) error {
+	for i := int64(1); i <= req.B; i++ {
+		_, response, err := b.test.ServeGRPC(stream.Context(), &pb.TestRequest{A: req.A, B: i})
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(response.(*pb.TestResponse)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractMetadata(key string, ctxKey contextKey) grpc.RequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if vals := (*md)[key]; len(vals) > 0 {
+			ctx = context.WithValue(ctx, ctxKey, vals[len(vals)-1])
+		}
+		return ctx
+	}
+}
@@ -0,0 +1,45 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+type contextKey string
+
+// ErrEmpty is returned by the test service when a is empty.
+var ErrEmpty = errors.New("empty a")
+
+const (
+	// CorrelationIDKey is the context key under which the server binding
+	// stores the correlation ID found in the request metadata.
+	CorrelationIDKey contextKey = "correlation-id"
+
+	// AuthorizationKey is the context key under which the server binding
+	// stores the authorization token found in the request metadata.
+	AuthorizationKey contextKey = "authorization"
+)
+
+// Service is the test service. It reports the values it received, along with
+// any correlation ID and authorization token found in the context.
+type Service interface {
+	Test(ctx context.Context, a string, b int64) (string, error)
+}
+
+type service struct{}
+
+// NewService returns the test service.
+func NewService() Service {
+	return service{}
+}
+
+func (service) Test(ctx context.Context, a string, b int64) (string, error) {
+	if a == "" {
+		return "", ErrEmpty
+	}
+	correlationID, _ := ctx.Value(CorrelationIDKey).(string)
+	authorization, _ := ctx.Value(AuthorizationKey).(string)
+	return fmt.Sprintf("%s = %d (correlation-id=%q authorization=%q)", a, b, correlationID, authorization), nil
+}
@@ -6,6 +6,8 @@ import (
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // register the gzip compressor
 	"google.golang.org/grpc/metadata"
 
 	"github.com/go-kit/kit/endpoint"
@@ -21,6 +23,8 @@ type Client struct {
 	dec         DecodeResponseFunc
 	grpcReply   reflect.Type
 	before      []RequestFunc
+	after       []ClientResponseFunc
+	callOptions []grpc.CallOption
 }
 
 // NewClient constructs a usable Client for a single remote endpoint.
@@ -66,6 +70,53 @@ func SetClientBefore(before ...RequestFunc) ClientOption {
 	return func(c *Client) { c.before = before }
 }
 
+// SetClientAfter sets the ClientResponseFuncs that are applied to the gRPC
+// response header and trailer before the response is decoded.
+func SetClientAfter(after ...ClientResponseFunc) ClientOption {
+	return func(c *Client) { c.after = after }
+}
+
+// SetCallOptions adds grpc.CallOptions that are passed to every invocation
+// of the gRPC method. Unlike SetClientBefore, it may be given several times;
+// the call options accumulate.
+//
+// Note that the :authority pseudo-header is a property of the connection in
+// grpc-go. To override it, dial the ClientConn with grpc.WithAuthority.
+func SetCallOptions(options ...grpc.CallOption) ClientOption {
+	return func(c *Client) { c.callOptions = append(c.callOptions, options...) }
+}
+
+// SetPerRPCCredentials attaches credentials to every invocation of the gRPC
+// method, e.g. an OAuth2 token source. The credentials are asked for their
+// request metadata on each call, so refreshed tokens are picked up without
+// reconstructing the client. Metadata injected by SetClientBefore functions
+// is sent alongside, and never replaces, the credential metadata.
+func SetPerRPCCredentials(creds credentials.PerRPCCredentials) ClientOption {
+	return SetCallOptions(grpc.PerRPCCredentials(creds))
+}
+
+// SetMaxRecvMsgSize sets the maximum size in bytes of a response message the
+// client accepts. By default, gRPC limits responses to 4MB. Larger responses
+// fail with a MessageTooLargeError.
+func SetMaxRecvMsgSize(bytes int) ClientOption {
+	return SetCallOptions(grpc.MaxCallRecvMsgSize(bytes))
+}
+
+// SetMaxSendMsgSize sets the maximum size in bytes of a request message the
+// client sends. Larger requests fail with a MessageTooLargeError without
+// being sent.
+func SetMaxSendMsgSize(bytes int) ClientOption {
+	return SetCallOptions(grpc.MaxCallSendMsgSize(bytes))
+}
+
+// SetCompression compresses request messages with the named compressor,
+// which must be registered with the google.golang.org/grpc/encoding package.
+// The "gzip" compressor is always registered. Servers reply using the same
+// compressor, provided it's registered in the server binary too.
+func SetCompression(name string) ClientOption {
+	return SetCallOptions(grpc.UseCompressor(name))
+}
+
 // Endpoint returns a usable endpoint that will invoke the gRPC specified by the
 // client.
 func (c Client) Endpoint() endpoint.Endpoint {
@@ -82,13 +133,22 @@ func (c Client) Endpoint() endpoint.Endpoint {
 		for _, f := range c.before {
 			ctx = f(ctx, md)
 		}
-		ctx = metadata.NewContext(ctx, *md)
+		ctx = metadata.NewOutgoingContext(ctx, *md)
 
+		var header, trailer metadata.MD
+		callOptions := append(c.callOptions[:len(c.callOptions):len(c.callOptions)], grpc.Header(&header), grpc.Trailer(&trailer))
 		grpcReply := reflect.New(c.grpcReply).Interface()
-		if err = grpc.Invoke(ctx, c.method, req, grpcReply, c.client); err != nil {
+		if err = grpc.Invoke(ctx, c.method, req, grpcReply, c.client, callOptions...); err != nil {
+			if isMessageTooLarge(err) {
+				return nil, MessageTooLargeError{Err: err}
+			}
 			return nil, fmt.Errorf("Invoke: %v", err)
 		}
 
+		for _, f := range c.after {
+			ctx = f(ctx, header, trailer)
+		}
+
 		response, err := c.dec(ctx, grpcReply)
 		if err != nil {
 			return nil, fmt.Errorf("Decode: %v", err)
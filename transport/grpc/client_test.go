@@ -0,0 +1,205 @@
+package grpc_test
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/context"
+	stdgrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/transport/grpc"
+	test "github.com/go-kit/kit/transport/grpc/_grpc_test"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+func TestClientPerRPCCredentials(t *testing.T) {
+	cc, stop := startTestServer(t, nil)
+	defer stop()
+
+	creds := &tokenCredentials{}
+	client := test.NewClient(
+		cc,
+		grpc.SetClientBefore(grpc.SetRequestHeader("X-Correlation-ID", "abc")),
+		grpc.SetPerRPCCredentials(creds),
+	)
+
+	// The credentials are consulted on every call, so a refreshed token must
+	// show up on the second call without rebuilding the client. The metadata
+	// set by the before function must survive alongside the credentials.
+	for i := 1; i <= 2; i++ {
+		have, err := client.Test(context.Background(), "foo", int64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf(`foo = %d (correlation-id="abc" authorization="Bearer token-%d")`, i, i)
+		if want != have {
+			t.Errorf("call %d: want %q, have %q", i, want, have)
+		}
+	}
+}
+
+func TestClientCallOptions(t *testing.T) {
+	cc, stop := startTestServer(t, nil)
+	defer stop()
+
+	creds := &tokenCredentials{}
+	client := test.NewClient(
+		cc,
+		grpc.SetCallOptions(stdgrpc.FailFast(false)),
+		grpc.SetPerRPCCredentials(creds),
+	)
+	have, err := client.Test(context.Background(), "foo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Call options accumulate rather than replace one another.
+	if want, have := int32(1), atomic.LoadInt32(&creds.calls); want != have {
+		t.Errorf("want %d credentials call(s), have %d", want, have)
+	}
+	if want := `foo = 1 (correlation-id="" authorization="Bearer token-1")`; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestClientMaxRecvMsgSize(t *testing.T) {
+	cc, stop := startTestServer(t, []stdgrpc.ServerOption{stdgrpc.MaxRecvMsgSize(8 << 20)})
+	defer stop()
+
+	// The response echoes the request, so it's over gRPC's 4MB default.
+	a := strings.Repeat("x", 5<<20)
+
+	_, err := test.NewClient(cc).Test(context.Background(), a, 1)
+	if _, ok := err.(grpc.MessageTooLargeError); !ok {
+		t.Fatalf("want %T, have %v", grpc.MessageTooLargeError{}, err)
+	}
+
+	have, err := test.NewClient(cc, grpc.SetMaxRecvMsgSize(8<<20)).Test(context.Background(), a, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(have, a) {
+		t.Errorf("want response echoing the %d byte request, have %d bytes", len(a), len(have))
+	}
+}
+
+func TestClientMaxSendMsgSize(t *testing.T) {
+	cc, stop := startTestServer(t, nil)
+	defer stop()
+
+	client := test.NewClient(cc, grpc.SetMaxSendMsgSize(1024))
+	if _, err := client.Test(context.Background(), "foo", 1); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Test(context.Background(), strings.Repeat("x", 2048), 1)
+	if _, ok := err.(grpc.MessageTooLargeError); !ok {
+		t.Fatalf("want %T, have %v", grpc.MessageTooLargeError{}, err)
+	}
+}
+
+func TestServerMaxMsgSize(t *testing.T) {
+	cc, stop := startTestServer(t, nil,
+		grpc.ServerMaxRecvMsgSize(4096),
+		grpc.ServerMaxSendMsgSize(2048),
+	)
+	defer stop()
+
+	client := test.NewClient(cc)
+	if _, err := client.Test(context.Background(), "foo", 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []string{
+		strings.Repeat("x", 3072), // request fits, response doesn't
+		strings.Repeat("x", 8192), // request doesn't fit
+	} {
+		_, err := client.Test(context.Background(), a, 1)
+		if _, ok := err.(grpc.MessageTooLargeError); !ok {
+			t.Errorf("%d bytes: want %T, have %v", len(a), grpc.MessageTooLargeError{}, err)
+		}
+	}
+}
+
+func TestClientCompression(t *testing.T) {
+	cc, stop := startTestServer(t, nil)
+	defer stop()
+
+	a := strings.Repeat("x", 1<<16)
+	have, err := test.NewClient(cc, grpc.SetCompression("gzip")).Test(context.Background(), a, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf(`%s = 1 (correlation-id="" authorization="")`, a); want != have {
+		t.Errorf("want %d bytes, have %d bytes", len(want), len(have))
+	}
+
+	_, err = test.NewClient(cc, grpc.SetCompression("bogus")).Test(context.Background(), "foo", 1)
+	if err == nil {
+		t.Error("want error for unregistered compressor, have none")
+	}
+}
+
+func TestServerAfterHeaderTrailer(t *testing.T) {
+	cc, stop := startTestServer(t, nil, grpc.ServerAfter(
+		grpc.SetResponseHeader("X-Served-By", "test"),
+		func(ctx context.Context, _ *metadata.MD, trailer *metadata.MD) {
+			if id, ok := ctx.Value(test.CorrelationIDKey).(string); ok {
+				(*trailer)["x-trace-id"] = []string{"trace-" + id}
+			}
+		},
+	))
+	defer stop()
+
+	var header, trailer metadata.MD
+	client := test.NewClient(
+		cc,
+		grpc.SetClientBefore(grpc.SetRequestHeader("X-Correlation-ID", "abc")),
+		grpc.SetClientAfter(func(ctx context.Context, h metadata.MD, t metadata.MD) context.Context {
+			header, trailer = h, t
+			return ctx
+		}),
+	)
+	if _, err := client.Test(context.Background(), "foo", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := []string{"test"}, header["x-served-by"]; fmt.Sprint(want) != fmt.Sprint(have) {
+		t.Errorf("header: want %v, have %v", want, have)
+	}
+	if want, have := []string{"trace-abc"}, trailer["x-trace-id"]; fmt.Sprint(want) != fmt.Sprint(have) {
+		t.Errorf("trailer: want %v, have %v", want, have)
+	}
+}
+
+func startTestServer(t *testing.T, serverOptions []stdgrpc.ServerOption, options ...grpc.ServerOption) (*stdgrpc.ClientConn, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := stdgrpc.NewServer(serverOptions...)
+	pb.RegisterTestServer(server, test.NewBinding(test.NewService(), options...))
+	go server.Serve(ln)
+
+	cc, err := stdgrpc.Dial(ln.Addr().String(), stdgrpc.WithInsecure())
+	if err != nil {
+		server.Stop()
+		t.Fatal(err)
+	}
+	return cc, func() { cc.Close(); server.Stop() }
+}
+
+// tokenCredentials implements credentials.PerRPCCredentials, handing out a
+// fresh token on every call to simulate a refreshing token source.
+type tokenCredentials struct {
+	calls int32
+}
+
+func (c *tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	n := atomic.AddInt32(&c.calls, 1)
+	return map[string]string{"authorization": fmt.Sprintf("Bearer token-%d", n)}, nil
+}
+
+func (c *tokenCredentials) RequireTransportSecurity() bool { return false }
@@ -0,0 +1,35 @@
+package grpc
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MessageTooLargeError is returned by client endpoints when a request or
+// response message exceeded a configured maximum size, on either side of the
+// connection. Callers can detect it with a type assertion and react, e.g. by
+// falling back to pagination. Raise the limits with SetMaxSendMsgSize and
+// SetMaxRecvMsgSize on the client, and grpc.MaxRecvMsgSize and
+// grpc.MaxSendMsgSize on the grpc.Server.
+type MessageTooLargeError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (err MessageTooLargeError) Error() string {
+	return err.Err.Error()
+}
+
+// isMessageTooLarge reports whether err is the ResourceExhausted status that
+// gRPC produces when a message overflows the maximum send or receive size.
+// ResourceExhausted is also used for e.g. quota failures, so the message text
+// is inspected as well.
+func isMessageTooLarge(err error) bool {
+	s, ok := status.FromError(err)
+	if !ok || s.Code() != codes.ResourceExhausted {
+		return false
+	}
+	return strings.Contains(s.Message(), "larger than max")
+}
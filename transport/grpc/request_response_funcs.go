@@ -19,16 +19,39 @@ const (
 type RequestFunc func(context.Context, *metadata.MD) context.Context
 
 // ResponseFunc may take information from a request context and use it to
-// manipulate the gRPC metadata header. ResponseFuncs are only executed in
-// servers, after invoking the endpoint but prior to writing a response.
-type ResponseFunc func(context.Context, *metadata.MD)
+// manipulate the gRPC response header and trailer metadata. ResponseFuncs are
+// only executed in servers, after invoking the endpoint but prior to writing
+// a response. The header and trailer are sent to the client with the
+// response, as if set with grpc.SetHeader and grpc.SetTrailer.
+type ResponseFunc func(ctx context.Context, header *metadata.MD, trailer *metadata.MD)
+
+// ServerFinalizerFunc can be used to perform work at the end of a gRPC
+// request, after the response has been encoded, e.g. to inspect the context
+// the endpoint was invoked with once it returned. The error is the one
+// returned to gRPC, or nil.
+type ServerFinalizerFunc func(ctx context.Context, err error)
+
+// ClientResponseFunc may take information from the gRPC response header and
+// trailer metadata and put it into the response context. ClientResponseFuncs
+// are only executed in clients, after the response is received, but prior to
+// decoding it.
+type ClientResponseFunc func(ctx context.Context, header metadata.MD, trailer metadata.MD) context.Context
 
 // SetResponseHeader returns a ResponseFunc that sets the specified metadata
-// key-value pair.
+// key-value pair in the response header.
 func SetResponseHeader(key, val string) ResponseFunc {
-	return func(_ context.Context, md *metadata.MD) {
+	return func(_ context.Context, header *metadata.MD, _ *metadata.MD) {
 		key, val := EncodeKeyValue(key, val)
-		(*md)[key] = append((*md)[key], val)
+		(*header)[key] = append((*header)[key], val)
+	}
+}
+
+// SetResponseTrailer returns a ResponseFunc that sets the specified metadata
+// key-value pair in the response trailer.
+func SetResponseTrailer(key, val string) ResponseFunc {
+	return func(_ context.Context, _ *metadata.MD, trailer *metadata.MD) {
+		key, val := EncodeKeyValue(key, val)
+		(*trailer)[key] = append((*trailer)[key], val)
 	}
 }
 
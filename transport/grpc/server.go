@@ -1,7 +1,10 @@
 package grpc
 
 import (
+	"github.com/golang/protobuf/proto"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/go-kit/kit/endpoint"
@@ -16,13 +19,17 @@ type Handler interface {
 
 // Server wraps an endpoint and implements grpc.Handler.
 type Server struct {
-	ctx    context.Context
-	e      endpoint.Endpoint
-	dec    DecodeRequestFunc
-	enc    EncodeResponseFunc
-	before []RequestFunc
-	after  []ResponseFunc
-	logger log.Logger
+	ctx       context.Context
+	e         endpoint.Endpoint
+	dec       DecodeRequestFunc
+	enc       EncodeResponseFunc
+	before    []RequestFunc
+	after     []ResponseFunc
+	finalizer []ServerFinalizerFunc
+	logger    log.Logger
+
+	maxRecvMsgSize int
+	maxSendMsgSize int
 }
 
 // NewServer constructs a new server, which implements grpc.Server and wraps
@@ -56,25 +63,56 @@ func ServerBefore(before ...RequestFunc) ServerOption {
 	return func(s *Server) { s.before = before }
 }
 
-// ServerAfter functions are executed on the HTTP response writer after the
-// endpoint is invoked, but before anything is written to the client.
+// ServerAfter functions are executed on the gRPC response header and trailer
+// after the endpoint is invoked, but before anything is written to the
+// client. Use them to return metadata to the caller, e.g. a trace ID.
 func ServerAfter(after ...ResponseFunc) ServerOption {
 	return func(s *Server) { s.after = after }
 }
 
+// ServerFinalizer is executed at the end of every gRPC request, after the
+// response is encoded or the request failed. By default, no finalizer is
+// registered.
+func ServerFinalizer(f ...ServerFinalizerFunc) ServerOption {
+	return func(s *Server) { s.finalizer = f }
+}
+
 // ServerErrorLogger is used to log non-terminal errors. By default, no errors
 // are logged.
 func ServerErrorLogger(logger log.Logger) ServerOption {
 	return func(s *Server) { s.logger = logger }
 }
 
-// ServeGRPC implements grpc.Handler
-func (s Server) ServeGRPC(grpcCtx context.Context, r interface{}) (context.Context, interface{}, error) {
+// ServerMaxRecvMsgSize rejects request messages larger than the given number
+// of bytes with a ResourceExhausted status, before they're decoded. Note that
+// the grpc.Server enforces its own limit, 4MB by default, before the request
+// reaches this handler; raise that with grpc.MaxRecvMsgSize to accept larger
+// messages. By default, the handler imposes no additional limit.
+func ServerMaxRecvMsgSize(bytes int) ServerOption {
+	return func(s *Server) { s.maxRecvMsgSize = bytes }
+}
+
+// ServerMaxSendMsgSize fails requests whose encoded response message is larger
+// than the given number of bytes with a ResourceExhausted status, which
+// clients see as a MessageTooLargeError. The grpc.Server may impose its own
+// limit, set with grpc.MaxSendMsgSize. By default, the handler imposes no
+// additional limit.
+func ServerMaxSendMsgSize(bytes int) ServerOption {
+	return func(s *Server) { s.maxSendMsgSize = bytes }
+}
+
+// ServeGRPC implements grpc.Handler. The deadline of the gRPC call, if any,
+// is propagated to the context the endpoint is invoked with.
+func (s Server) ServeGRPC(grpcCtx context.Context, r interface{}) (retCtx context.Context, retResp interface{}, retErr error) {
 	ctx, cancel := context.WithCancel(s.ctx)
 	defer cancel()
+	if deadline, ok := grpcCtx.Deadline(); ok {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
 
 	// retrieve gRPC metadata
-	md, ok := metadata.FromContext(grpcCtx)
+	md, ok := metadata.FromIncomingContext(grpcCtx)
 	if !ok {
 		md = metadata.MD{}
 	}
@@ -83,8 +121,21 @@ func (s Server) ServeGRPC(grpcCtx context.Context, r interface{}) (context.Conte
 		ctx = f(ctx, &md)
 	}
 
+	if len(s.finalizer) > 0 {
+		defer func() {
+			for _, f := range s.finalizer {
+				f(ctx, retErr)
+			}
+		}()
+	}
+
 	// store potentially updated metadata in the gRPC context
-	grpcCtx = metadata.NewContext(grpcCtx, md)
+	grpcCtx = metadata.NewOutgoingContext(grpcCtx, md)
+
+	if err := checkMsgSize(r, s.maxRecvMsgSize, "received"); err != nil {
+		s.logger.Log("err", err)
+		return grpcCtx, nil, err
+	}
 
 	request, err := s.dec(grpcCtx, r)
 	if err != nil {
@@ -98,21 +149,51 @@ func (s Server) ServeGRPC(grpcCtx context.Context, r interface{}) (context.Conte
 		return grpcCtx, nil, err
 	}
 
+	header, trailer := metadata.MD{}, metadata.MD{}
 	for _, f := range s.after {
-		f(ctx, &md)
+		f(ctx, &header, &trailer)
+	}
+	if len(header) > 0 {
+		if err := grpc.SetHeader(grpcCtx, header); err != nil {
+			s.logger.Log("err", err)
+		}
+	}
+	if len(trailer) > 0 {
+		if err := grpc.SetTrailer(grpcCtx, trailer); err != nil {
+			s.logger.Log("err", err)
+		}
 	}
-
-	// store potentially updated metadata in the gRPC context
-	grpcCtx = metadata.NewContext(grpcCtx, md)
 
 	grpcResp, err := s.enc(grpcCtx, response)
 	if err != nil {
 		s.logger.Log("err", err)
 		return grpcCtx, nil, err
 	}
+
+	if err := checkMsgSize(grpcResp, s.maxSendMsgSize, "trying to send"); err != nil {
+		s.logger.Log("err", err)
+		return grpcCtx, nil, err
+	}
 	return grpcCtx, grpcResp, nil
 }
 
+// checkMsgSize returns a ResourceExhausted error, worded like gRPC's own, if
+// msg is a protobuf message larger than max bytes. A max of zero or less means
+// no limit.
+func checkMsgSize(msg interface{}, max int, verb string) error {
+	if max <= 0 {
+		return nil
+	}
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return nil
+	}
+	if size := proto.Size(m); size > max {
+		return grpc.Errorf(codes.ResourceExhausted, "%s message larger than max (%d vs. %d)", verb, size, max)
+	}
+	return nil
+}
+
 // BadRequestError is an error in decoding the request.
 type BadRequestError struct {
 	Err error
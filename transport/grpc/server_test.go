@@ -0,0 +1,49 @@
+package grpc_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/transport/grpc"
+)
+
+func TestServerFinalizer(t *testing.T) {
+	var (
+		finalizerCtx context.Context
+		finalizerErr error
+	)
+	passThrough := func(_ context.Context, v interface{}) (interface{}, error) { return v, nil }
+	server := grpc.NewServer(
+		context.Background(),
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			<-ctx.Done() // the deadline of the call expires mid-call
+			return nil, ctx.Err()
+		},
+		passThrough,
+		passThrough,
+		grpc.ServerFinalizer(func(ctx context.Context, err error) {
+			finalizerCtx, finalizerErr = ctx, err
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := server.ServeGRPC(ctx, struct{}{}); err != context.DeadlineExceeded {
+		t.Fatalf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	if finalizerCtx == nil {
+		t.Fatal("finalizer not called")
+	}
+	if want, have := context.DeadlineExceeded, finalizerErr; want != have {
+		t.Errorf("want finalizer error %v, have %v", want, have)
+	}
+	if want, have := context.DeadlineExceeded, finalizerCtx.Err(); want != have {
+		t.Errorf("want finalizer context error %v, have %v", want, have)
+	}
+	want, _ := ctx.Deadline()
+	if have, ok := finalizerCtx.Deadline(); !ok || !want.Equal(have) {
+		t.Errorf("want deadline %v propagated, have %v", want, have)
+	}
+}
@@ -0,0 +1,237 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// WebHandler serves the services registered with a grpc.Server to browsers,
+// using the grpc-web protocol over plain HTTP/1.1. It supports unary and
+// server-streaming methods, in both the binary (application/grpc-web) and
+// base64 (application/grpc-web-text) wire formats. Client-streaming and
+// bidirectional methods can't be called from browsers and aren't supported.
+//
+// A WebHandler can be served on its own port, next to the native gRPC
+// listener of the same grpc.Server, which then serves both kinds of clients.
+//
+//	server := grpc.NewServer()
+//	pb.RegisterAddServer(server, binding)
+//	go server.Serve(ln)
+//	go http.ListenAndServe(":8081", kitgrpc.NewWebHandler(server))
+type WebHandler struct {
+	server         *grpc.Server
+	allowedOrigins []string
+}
+
+// NewWebHandler returns a grpc-web handler for the services registered with
+// the gRPC server.
+func NewWebHandler(server *grpc.Server, options ...WebHandlerOption) *WebHandler {
+	h := &WebHandler{server: server}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// WebHandlerOption sets an optional parameter for grpc-web handlers.
+type WebHandlerOption func(*WebHandler)
+
+// WebAllowedOrigins enables cross-origin requests from the given origins, e.g.
+// "https://app.example.com", including CORS preflight requests. The origin "*"
+// allows every origin. By default, only same-origin requests are served.
+func WebAllowedOrigins(origins ...string) WebHandlerOption {
+	return func(h *WebHandler) { h.allowedOrigins = append(h.allowedOrigins, origins...) }
+}
+
+// ServeHTTP implements http.Handler.
+func (h WebHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	allowed := h.allowOrigin(r.Header.Get("Origin"))
+	if allowed {
+		w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+		w.Header().Set("Access-Control-Expose-Headers", "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin")
+		w.Header().Add("Vary", "Origin")
+	}
+
+	if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+		if !allowed {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST, OPTIONS")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	grpcContentType, text, ok := fromWebContentType(contentType)
+	if !ok {
+		http.Error(w, "unsupported content type "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// The gRPC server handles HTTP/2 requests only. The protocol version is
+	// irrelevant to it beyond that check, as trailers are translated below.
+	req := *r
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/2.0", 2, 0
+	req.Header = make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", grpcContentType)
+	req.Header.Del("Content-Length")
+	if text {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{base64.NewDecoder(base64.StdEncoding, r.Body), r.Body}
+	}
+
+	ww := &webResponseWriter{
+		w:           w,
+		header:      http.Header{},
+		contentType: contentType,
+		text:        text,
+	}
+	h.server.ServeHTTP(ww, &req)
+	ww.writeTrailers()
+}
+
+func (h WebHandler) allowOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range h.allowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// fromWebContentType maps a grpc-web content type to the equivalent gRPC
+// content type, and reports whether the messages are base64 encoded.
+func fromWebContentType(contentType string) (grpcContentType string, text, ok bool) {
+	for _, prefix := range []string{"application/grpc-web-text", "application/grpc-web"} {
+		if !strings.HasPrefix(contentType, prefix) {
+			continue
+		}
+		subtype := contentType[len(prefix):]
+		if subtype != "" && subtype[0] != '+' && subtype[0] != ';' {
+			continue
+		}
+		return "application/grpc" + subtype, prefix == "application/grpc-web-text", true
+	}
+	return "", false, false
+}
+
+// webResponseWriter translates the response of the gRPC server to grpc-web,
+// which carries the trailers in a final, specially flagged message frame.
+type webResponseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+
+	wroteHeader bool
+	trailers    []string
+	buf         bytes.Buffer // pending output in text mode
+}
+
+func (ww *webResponseWriter) Header() http.Header { return ww.header }
+
+func (ww *webResponseWriter) WriteHeader(code int) {
+	if ww.wroteHeader {
+		return
+	}
+	ww.wroteHeader = true
+	h := ww.w.Header()
+	for k, v := range ww.header {
+		if k == "Trailer" {
+			for _, t := range v {
+				ww.trailers = append(ww.trailers, strings.Split(t, ",")...)
+			}
+			continue
+		}
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
+		h[k] = v
+	}
+	h.Set("Content-Type", ww.contentType)
+	ww.w.WriteHeader(code)
+}
+
+func (ww *webResponseWriter) Write(p []byte) (int, error) {
+	ww.WriteHeader(http.StatusOK)
+	if ww.text {
+		return ww.buf.Write(p)
+	}
+	return ww.w.Write(p)
+}
+
+// Flush implements http.Flusher, which the gRPC server requires. In text
+// mode, each flush emits the output written since the previous one as a
+// separately padded base64 chunk, which grpc-web clients accept.
+func (ww *webResponseWriter) Flush() {
+	ww.WriteHeader(http.StatusOK)
+	if ww.text && ww.buf.Len() > 0 {
+		ww.w.Write([]byte(base64.StdEncoding.EncodeToString(ww.buf.Bytes())))
+		ww.buf.Reset()
+	}
+	if f, ok := ww.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify implements http.CloseNotifier, which the gRPC server requires.
+func (ww *webResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := ww.w.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// writeTrailers sends the trailers set by the gRPC server, i.e. the status
+// and any trailing metadata, as the final frame of the response body.
+func (ww *webResponseWriter) writeTrailers() {
+	var block bytes.Buffer
+	for _, k := range ww.trailers {
+		k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+		for _, v := range ww.header[k] {
+			block.WriteString(strings.ToLower(k) + ":" + v + "\r\n")
+		}
+	}
+	var prefixed []string
+	for k := range ww.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			prefixed = append(prefixed, k)
+		}
+	}
+	sort.Strings(prefixed)
+	for _, k := range prefixed {
+		for _, v := range ww.header[k] {
+			block.WriteString(strings.ToLower(k[len(http.TrailerPrefix):]) + ":" + v + "\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+block.Len())
+	frame[0] = 1 << 7 // trailers flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(block.Len()))
+	ww.Write(append(frame, block.Bytes()...))
+	ww.Flush()
+}
@@ -0,0 +1,282 @@
+package grpc_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	stdgrpc "google.golang.org/grpc"
+
+	"github.com/go-kit/kit/transport/grpc"
+	test "github.com/go-kit/kit/transport/grpc/_grpc_test"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+// Request fixtures as recorded from the grpc-web JavaScript client, for
+// TestRequest{A: "foo", B: 1} and TestRequest{A: "foo", B: 3}.
+var (
+	webRequestFoo1 = []byte{0x00, 0x00, 0x00, 0x00, 0x07, 0x0a, 0x03, 'f', 'o', 'o', 0x10, 0x01}
+	webRequestFoo3 = []byte{0x00, 0x00, 0x00, 0x00, 0x07, 0x0a, 0x03, 'f', 'o', 'o', 0x10, 0x03}
+
+	webTextRequestFoo1 = "AAAAAAcKA2ZvbxAB"
+	webTextRequestFoo3 = "AAAAAAcKA2ZvbxAD"
+)
+
+func TestWebHandlerUnary(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		body        string
+	}{
+		{"application/grpc-web+proto", string(webRequestFoo1)},
+		{"application/grpc-web", string(webRequestFoo1)},
+		{"application/grpc-web-text+proto", webTextRequestFoo1},
+		{"application/grpc-web-text", webTextRequestFoo1},
+	} {
+		rec := serveWeb(t, grpc.NewWebHandler(newTestServer()), "/pb.Test/Test", tc.contentType, tc.body, http.Header{"X-Correlation-Id": {"abc"}})
+		if want, have := http.StatusOK, rec.Code; want != have {
+			t.Fatalf("%s: want HTTP %d, have %d", tc.contentType, want, have)
+		}
+		if want, have := tc.contentType, rec.HeaderMap.Get("Content-Type"); want != have {
+			t.Errorf("%s: want Content-Type %q, have %q", tc.contentType, want, have)
+		}
+		if have := rec.HeaderMap.Get("Trailer"); have != "" {
+			t.Errorf("%s: want no Trailer header, have %q", tc.contentType, have)
+		}
+
+		messages, trailers := readWebFrames(t, rec.Body.Bytes(), strings.Contains(tc.contentType, "-text"))
+		if want, have := 1, len(messages); want != have {
+			t.Fatalf("%s: want %d message(s), have %d", tc.contentType, want, have)
+		}
+		want := `foo = 1 (correlation-id="abc" authorization="")`
+		if have := messages[0].V; want != have {
+			t.Errorf("%s: want %q, have %q", tc.contentType, want, have)
+		}
+		if want, have := "grpc-status:0\r\n", trailers; want != have {
+			t.Errorf("%s: want trailers %q, have %q", tc.contentType, want, have)
+		}
+	}
+}
+
+func TestWebHandlerServerStreaming(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		body        string
+	}{
+		{"application/grpc-web+proto", string(webRequestFoo3)},
+		{"application/grpc-web-text+proto", webTextRequestFoo3},
+	} {
+		rec := serveWeb(t, grpc.NewWebHandler(newTestServer()), "/pb.Test/TestStream", tc.contentType, tc.body, nil)
+		messages, trailers := readWebFrames(t, rec.Body.Bytes(), strings.Contains(tc.contentType, "-text"))
+		if want, have := 3, len(messages); want != have {
+			t.Fatalf("%s: want %d message(s), have %d", tc.contentType, want, have)
+		}
+		for i, m := range messages {
+			if want := fmt.Sprintf("foo = %d ", i+1); !strings.HasPrefix(m.V, want) {
+				t.Errorf("%s: message %d: want prefix %q, have %q", tc.contentType, i, want, m.V)
+			}
+		}
+		if want, have := "grpc-status:0\r\n", trailers; want != have {
+			t.Errorf("%s: want trailers %q, have %q", tc.contentType, want, have)
+		}
+	}
+}
+
+func TestWebHandlerError(t *testing.T) {
+	// TestRequest{A: "", B: 1}, which the service rejects.
+	body := string([]byte{0x00, 0x00, 0x00, 0x00, 0x02, 0x10, 0x01})
+	rec := serveWeb(t, grpc.NewWebHandler(newTestServer()), "/pb.Test/Test", "application/grpc-web+proto", body, nil)
+
+	// Errors are reported in the trailers, not by the HTTP status code.
+	if want, have := http.StatusOK, rec.Code; want != have {
+		t.Fatalf("want HTTP %d, have %d", want, have)
+	}
+	messages, trailers := readWebFrames(t, rec.Body.Bytes(), false)
+	if want, have := 0, len(messages); want != have {
+		t.Errorf("want %d message(s), have %d", want, have)
+	}
+	if want, have := "grpc-status:2\r\ngrpc-message:"+test.ErrEmpty.Error()+"\r\n", trailers; want != have {
+		t.Errorf("want trailers %q, have %q", want, have)
+	}
+}
+
+func TestWebHandlerCORS(t *testing.T) {
+	h := grpc.NewWebHandler(newTestServer(), grpc.WebAllowedOrigins("https://app.example.com"))
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("OPTIONS", "http://localhost/pb.Test/Test", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web,x-correlation-id")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := preflight("https://app.example.com")
+	if want, have := http.StatusNoContent, rec.Code; want != have {
+		t.Errorf("want HTTP %d, have %d", want, have)
+	}
+	for k, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "POST",
+		"Access-Control-Allow-Headers": "content-type,x-grpc-web,x-correlation-id",
+	} {
+		if have := rec.HeaderMap.Get(k); want != have {
+			t.Errorf("%s: want %q, have %q", k, want, have)
+		}
+	}
+
+	if want, have := http.StatusForbidden, preflight("https://evil.example.com").Code; want != have {
+		t.Errorf("want HTTP %d, have %d", want, have)
+	}
+
+	rec = serveWeb(t, h, "/pb.Test/Test", "application/grpc-web+proto", string(webRequestFoo1), http.Header{"Origin": {"https://app.example.com"}})
+	if want, have := "https://app.example.com", rec.HeaderMap.Get("Access-Control-Allow-Origin"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if have := rec.HeaderMap.Get("Access-Control-Expose-Headers"); !strings.Contains(have, "Grpc-Status") {
+		t.Errorf("want Grpc-Status exposed, have %q", have)
+	}
+}
+
+func TestWebHandlerUnsupported(t *testing.T) {
+	h := grpc.NewWebHandler(newTestServer())
+	if want, have := http.StatusUnsupportedMediaType, serveWeb(t, h, "/pb.Test/Test", "application/json", "{}", nil).Code; want != have {
+		t.Errorf("want HTTP %d, have %d", want, have)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost/pb.Test/Test", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if want, have := http.StatusMethodNotAllowed, rec.Code; want != have {
+		t.Errorf("want HTTP %d, have %d", want, have)
+	}
+}
+
+func TestWebHandlerNativeListener(t *testing.T) {
+	// The same gRPC server serves native clients and grpc-web clients.
+	server := newTestServer()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	defer server.Stop()
+	web := httptest.NewServer(grpc.NewWebHandler(server))
+	defer web.Close()
+
+	cc, err := stdgrpc.Dial(ln.Addr().String(), stdgrpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	if _, err := test.NewClient(cc).Test(context.Background(), "foo", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Post(web.URL+"/pb.Test/Test", "application/grpc-web-text", strings.NewReader(webTextRequestFoo1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, trailers := readWebFrames(t, body, true)
+	if want, have := 1, len(messages); want != have {
+		t.Fatalf("want %d message(s), have %d", want, have)
+	}
+	if want, have := "grpc-status:0\r\n", trailers; want != have {
+		t.Errorf("want trailers %q, have %q", want, have)
+	}
+}
+
+func newTestServer() *stdgrpc.Server {
+	server := stdgrpc.NewServer()
+	pb.RegisterTestServer(server, test.NewBinding(test.NewService()))
+	return server
+}
+
+func serveWeb(t *testing.T, h http.Handler, path, contentType, body string, header http.Header) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "http://localhost"+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Grpc-Web", "1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// readWebFrames splits a grpc-web response body into its messages and the
+// trailer block, which must be the final frame.
+func readWebFrames(t *testing.T, body []byte, text bool) ([]*pb.TestResponse, string) {
+	if text {
+		// Each flush is a separately padded base64 chunk.
+		var decoded []byte
+		for len(body) > 0 {
+			n := bytes.IndexByte(body, '=')
+			chunk := body
+			if n >= 0 {
+				for n < len(body) && body[n] == '=' {
+					n++
+				}
+				chunk = body[:n]
+			}
+			b, err := base64.StdEncoding.DecodeString(string(chunk))
+			if err != nil {
+				t.Fatalf("decoding %q: %v", chunk, err)
+			}
+			decoded = append(decoded, b...)
+			body = body[len(chunk):]
+		}
+		body = decoded
+	}
+
+	var (
+		messages []*pb.TestResponse
+		trailers *string
+	)
+	for len(body) > 0 {
+		if trailers != nil {
+			t.Fatal("frame after trailers")
+		}
+		if len(body) < 5 {
+			t.Fatalf("short frame header %x", body)
+		}
+		flag, n := body[0], binary.BigEndian.Uint32(body[1:5])
+		if uint32(len(body)-5) < n {
+			t.Fatalf("short frame: want %d bytes, have %d", n, len(body)-5)
+		}
+		payload := body[5 : 5+n]
+		body = body[5+n:]
+		if flag&0x80 != 0 {
+			s := string(payload)
+			trailers = &s
+			continue
+		}
+		m := &pb.TestResponse{}
+		if err := proto.Unmarshal(payload, m); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, m)
+	}
+	if trailers == nil {
+		t.Fatal("no trailers")
+	}
+	return messages, *trailers
+}
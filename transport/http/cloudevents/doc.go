@@ -0,0 +1,10 @@
+// Package cloudevents provides helpers for serving and emitting CloudEvents
+// over the HTTP transport, following the HTTP protocol binding of CloudEvents
+// 1.0. See https://github.com/cloudevents/spec.
+//
+// DecodeCloudEventRequest decodes events in both the binary content mode,
+// with attributes in ce-* headers and the data as the body, and the
+// structured content mode, with the whole event as an
+// application/cloudevents+json body. A Router dispatches events to endpoints
+// by their type.
+package cloudevents
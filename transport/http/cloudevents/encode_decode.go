@@ -0,0 +1,275 @@
+package cloudevents
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+)
+
+const headerPrefix = "Ce-" // canonical form of ce-
+
+// DecodeCloudEventRequest is a transport/http.DecodeRequestFunc that decodes
+// a CloudEvent, in the binary or the structured content mode, into an Event.
+// Requests that aren't valid events fail with a ValidationError.
+func DecodeCloudEventRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var e Event
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case ContentType:
+		if e, err = decodeStructured(body); err != nil {
+			return nil, err
+		}
+	case BatchContentType:
+		return nil, ValidationError{Reason: "batched content mode not supported"}
+	default:
+		if e, err = decodeBinary(r.Header, body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func decodeBinary(h http.Header, body []byte) (Event, error) {
+	if h.Get(headerPrefix+"Specversion") == "" {
+		return Event{}, ValidationError{Reason: "no ce-specversion header, and not " + ContentType}
+	}
+	e := Event{DataContentType: h.Get("Content-Type")}
+	if len(body) > 0 {
+		e.Data = body
+	}
+	for key, values := range h {
+		if !strings.HasPrefix(key, headerPrefix) || len(values) == 0 {
+			continue
+		}
+		// Header values are percent-encoded, but mostly just printable ASCII.
+		value, err := url.PathUnescape(values[0])
+		if err != nil {
+			value = values[0]
+		}
+		if err := e.set(strings.ToLower(strings.TrimPrefix(key, headerPrefix)), value); err != nil {
+			return Event{}, err
+		}
+	}
+	return e, nil
+}
+
+func decodeStructured(body []byte) (Event, error) {
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(body, &attrs); err != nil {
+		return Event{}, ValidationError{Reason: err.Error()}
+	}
+
+	var e Event
+	for name, raw := range attrs {
+		switch name {
+		case "data", "data_base64":
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			if name != strings.ToLower(name) || !isScalar(raw) {
+				return Event{}, ValidationError{Attribute: name, Reason: "not a string"}
+			}
+			value = string(raw) // extensions may be integers or booleans
+		}
+		if err := e.set(name, value); err != nil {
+			return Event{}, err
+		}
+	}
+
+	data, hasData := attrs["data"]
+	data64, hasData64 := attrs["data_base64"]
+	switch {
+	case hasData && hasData64:
+		return Event{}, ValidationError{Attribute: "data", Reason: "both data and data_base64"}
+	case hasData64:
+		var s string
+		if err := json.Unmarshal(data64, &s); err != nil {
+			return Event{}, ValidationError{Attribute: "data_base64", Reason: "not a string"}
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return Event{}, ValidationError{Attribute: "data_base64", Reason: err.Error()}
+		}
+		e.Data = b
+	case hasData && !isJSON(e.DataContentType):
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return Event{}, ValidationError{Attribute: "data", Reason: "not a string, and data isn't JSON"}
+		}
+		e.Data = []byte(s)
+	case hasData:
+		e.Data = []byte(data)
+	}
+	return e, nil
+}
+
+// set sets the named attribute.
+func (e *Event) set(name, value string) error {
+	switch name {
+	case "specversion":
+		e.SpecVersion = value
+	case "type":
+		e.Type = value
+	case "source":
+		e.Source = value
+	case "id":
+		e.ID = value
+	case "datacontenttype":
+		e.DataContentType = value
+	case "time":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return ValidationError{Attribute: "time", Reason: "not an RFC 3339 timestamp"}
+		}
+		e.Time = t
+	default:
+		if e.Extensions == nil {
+			e.Extensions = map[string]string{}
+		}
+		e.Extensions[name] = value
+	}
+	return nil
+}
+
+func isScalar(raw json.RawMessage) bool {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return false
+	}
+	switch v.(type) {
+	case float64, bool:
+		return true
+	}
+	return false
+}
+
+// EncodeCloudEventResponse is a transport/http.EncodeResponseFunc that
+// encodes an Event, or *Event, in the binary content mode: attributes are
+// written to ce-* headers, and the data is the body. Responses that aren't
+// valid events fail.
+func EncodeCloudEventResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	e, err := eventOf(response)
+	if err != nil {
+		return err
+	}
+
+	h := w.Header()
+	for name, value := range e.attributes() {
+		if name == "datacontenttype" {
+			h.Set("Content-Type", value)
+			continue
+		}
+		h.Set(headerPrefix+name, escapeHeader(value))
+	}
+	if len(e.Data) > 0 {
+		h.Set("Content-Length", fmt.Sprint(len(e.Data)))
+	}
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(e.Data)
+	return err
+}
+
+// EncodeStructuredCloudEventResponse is a transport/http.EncodeResponseFunc
+// that encodes an Event, or *Event, in the structured content mode, as an
+// application/cloudevents+json body. Data is embedded as JSON if it's JSON,
+// as a string if it's text, and base64 encoded otherwise. Responses that
+// aren't valid events fail.
+func EncodeStructuredCloudEventResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	e, err := eventOf(response)
+	if err != nil {
+		return err
+	}
+
+	attrs := map[string]interface{}{}
+	for name, value := range e.attributes() {
+		attrs[name] = value
+	}
+	switch {
+	case len(e.Data) == 0:
+	case isJSON(e.DataContentType) && json.Valid(e.Data):
+		attrs["data"] = json.RawMessage(e.Data)
+	case utf8.Valid(e.Data) && !isJSON(e.DataContentType):
+		attrs["data"] = string(e.Data)
+	default:
+		attrs["data_base64"] = base64.StdEncoding.EncodeToString(e.Data)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(attrs); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", ContentType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func eventOf(response interface{}) (Event, error) {
+	var e Event
+	switch v := response.(type) {
+	case Event:
+		e = v
+	case *Event:
+		e = *v
+	default:
+		return Event{}, fmt.Errorf("response is %T, not a CloudEvent", response)
+	}
+	if err := e.Validate(); err != nil {
+		return Event{}, err
+	}
+	return e, nil
+}
+
+// attributes returns the event's attributes, by name, except data.
+func (e Event) attributes() map[string]string {
+	attrs := map[string]string{
+		"specversion": e.SpecVersion,
+		"type":        e.Type,
+		"source":      e.Source,
+		"id":          e.ID,
+	}
+	if !e.Time.IsZero() {
+		attrs["time"] = e.Time.Format(time.RFC3339Nano)
+	}
+	if e.DataContentType != "" {
+		attrs["datacontenttype"] = e.DataContentType
+	}
+	for name, value := range e.Extensions {
+		attrs[name] = value
+	}
+	return attrs
+}
+
+// escapeHeader percent-encodes spaces, double quotes, percent signs, and
+// anything but printable ASCII, as the spec requires of header values.
+func escapeHeader(s string) string {
+	var buf bytes.Buffer
+	for _, b := range []byte(s) {
+		if b <= ' ' || b > '~' || b == '%' || b == '"' {
+			fmt.Fprintf(&buf, "%%%02X", b)
+			continue
+		}
+		buf.WriteByte(b)
+	}
+	return buf.String()
+}
@@ -0,0 +1,282 @@
+package cloudevents_test
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/transport/http/cloudevents"
+)
+
+// The examples of the HTTP protocol binding and the JSON event format specs,
+// made valid where they aren't.
+var specExamples = []struct {
+	name    string
+	request string
+	want    cloudevents.Event
+}{
+	{
+		name: "binary",
+		request: `POST /someresource HTTP/1.1
+Host: webhook.example.com
+ce-specversion: 1.0
+ce-type: com.example.someevent
+ce-time: 2018-04-05T03:56:24Z
+ce-id: 1234-1234-1234
+ce-source: /mycontext/subcontext
+Content-Type: application/json; charset=utf-8
+
+{"appinfoA":"abc"}`,
+		want: cloudevents.Event{
+			SpecVersion:     "1.0",
+			Type:            "com.example.someevent",
+			Source:          "/mycontext/subcontext",
+			ID:              "1234-1234-1234",
+			Time:            time.Date(2018, 4, 5, 3, 56, 24, 0, time.UTC),
+			DataContentType: "application/json; charset=utf-8",
+			Data:            []byte(`{"appinfoA":"abc"}`),
+		},
+	},
+	{
+		name: "structured",
+		request: `POST /someresource HTTP/1.1
+Host: webhook.example.com
+Content-Type: application/cloudevents+json; charset=utf-8
+
+{
+    "specversion" : "1.0",
+    "type" : "com.example.someevent",
+    "time" : "2018-04-05T03:56:24Z",
+    "id" : "1234-1234-1234",
+    "source" : "/mycontext/subcontext",
+    "datacontenttype" : "application/xml",
+    "data" : "<much wow=\"xml\"/>"
+}`,
+		want: cloudevents.Event{
+			SpecVersion:     "1.0",
+			Type:            "com.example.someevent",
+			Source:          "/mycontext/subcontext",
+			ID:              "1234-1234-1234",
+			Time:            time.Date(2018, 4, 5, 3, 56, 24, 0, time.UTC),
+			DataContentType: "application/xml",
+			Data:            []byte(`<much wow="xml"/>`),
+		},
+	},
+	{
+		name: "structured extensions",
+		request: `POST / HTTP/1.1
+Content-Type: application/cloudevents+json
+
+{
+    "specversion" : "1.0",
+    "type" : "com.github.pull_request.opened",
+    "source" : "https://github.com/cloudevents/spec/pull",
+    "subject" : "123",
+    "id" : "A234-1234-1234",
+    "time" : "2018-04-05T17:31:00Z",
+    "comexampleextension1" : "value",
+    "comexampleothervalue" : 5,
+    "datacontenttype" : "text/xml",
+    "data" : "<much wow=\"xml\"/>"
+}`,
+		want: cloudevents.Event{
+			SpecVersion:     "1.0",
+			Type:            "com.github.pull_request.opened",
+			Source:          "https://github.com/cloudevents/spec/pull",
+			ID:              "A234-1234-1234",
+			Time:            time.Date(2018, 4, 5, 17, 31, 0, 0, time.UTC),
+			DataContentType: "text/xml",
+			Data:            []byte(`<much wow="xml"/>`),
+			Extensions: map[string]string{
+				"subject":              "123",
+				"comexampleextension1": "value",
+				"comexampleothervalue": "5",
+			},
+		},
+	},
+	{
+		name: "structured base64",
+		request: `POST / HTTP/1.1
+Content-Type: application/cloudevents+json
+
+{
+    "specversion" : "1.0",
+    "type" : "com.example.someevent",
+    "source" : "/mycontext",
+    "id" : "A234-1234-1234",
+    "datacontenttype" : "application/vnd.apache.thrift.binary",
+    "data_base64" : "CAABAAAAKgsAAgAAAANmb28A"
+}`,
+		want: cloudevents.Event{
+			SpecVersion:     "1.0",
+			Type:            "com.example.someevent",
+			Source:          "/mycontext",
+			ID:              "A234-1234-1234",
+			DataContentType: "application/vnd.apache.thrift.binary",
+			Data:            []byte("\x08\x00\x01\x00\x00\x00\x2a\x0b\x00\x02\x00\x00\x00\x03foo\x00"),
+		},
+	},
+	{
+		name: "structured JSON data",
+		request: `POST / HTTP/1.1
+Content-Type: application/cloudevents+json
+
+{
+    "specversion" : "1.0",
+    "type" : "com.example.someevent",
+    "source" : "/mycontext",
+    "id" : "C234-1234-1234",
+    "datacontenttype" : "application/json",
+    "data" : {"appinfoA":"abc","appinfoB":123}
+}`,
+		want: cloudevents.Event{
+			SpecVersion:     "1.0",
+			Type:            "com.example.someevent",
+			Source:          "/mycontext",
+			ID:              "C234-1234-1234",
+			DataContentType: "application/json",
+			Data:            []byte(`{"appinfoA":"abc","appinfoB":123}`),
+		},
+	},
+	{
+		name: "binary percent-encoded",
+		request: `POST / HTTP/1.1
+ce-specversion: 1.0
+ce-type: com.example.someevent
+ce-source: /my%20context
+ce-id: 1
+ce-comment: caf%C3%A9 50%25
+
+`,
+		want: cloudevents.Event{
+			SpecVersion: "1.0",
+			Type:        "com.example.someevent",
+			Source:      "/my context",
+			ID:          "1",
+			Extensions:  map[string]string{"comment": "café 50%"},
+		},
+	},
+}
+
+func TestDecodeCloudEventRequest(t *testing.T) {
+	for _, tc := range specExamples {
+		r := readRequest(t, tc.request)
+		have, err := cloudevents.DecodeCloudEventRequest(context.Background(), r)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(tc.want, have) {
+			t.Errorf("%s:\nwant %#v\nhave %#v", tc.name, tc.want, have)
+		}
+	}
+}
+
+func TestDecodeCloudEventRequestInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name, request, want string
+	}{
+		{
+			name:    "not an event",
+			request: "POST / HTTP/1.1\nContent-Type: application/json\n\n{}",
+			want:    "invalid CloudEvent: no ce-specversion header, and not application/cloudevents+json",
+		},
+		{
+			name:    "missing id",
+			request: "POST / HTTP/1.1\nce-specversion: 1.0\nce-type: t\nce-source: s\n\n",
+			want:    "invalid CloudEvent: id: missing",
+		},
+		{
+			name:    "old version",
+			request: "POST / HTTP/1.1\nce-specversion: 0.3\nce-type: t\nce-source: s\nce-id: 1\n\n",
+			want:    "invalid CloudEvent: specversion: unsupported version 0.3",
+		},
+		{
+			name:    "bad time",
+			request: "POST / HTTP/1.1\nce-specversion: 1.0\nce-type: t\nce-source: s\nce-id: 1\nce-time: 2018-04-05T03:56;24Z\n\n",
+			want:    "invalid CloudEvent: time: not an RFC 3339 timestamp",
+		},
+		{
+			name:    "structured missing source",
+			request: "POST / HTTP/1.1\nContent-Type: application/cloudevents+json\n\n{\"specversion\":\"1.0\",\"type\":\"t\",\"id\":\"1\"}",
+			want:    "invalid CloudEvent: source: missing",
+		},
+		{
+			name:    "structured data and data_base64",
+			request: "POST / HTTP/1.1\nContent-Type: application/cloudevents+json\n\n{\"data\":{},\"data_base64\":\"e30=\"}",
+			want:    "invalid CloudEvent: data: both data and data_base64",
+		},
+		{
+			name:    "batch",
+			request: "POST / HTTP/1.1\nContent-Type: application/cloudevents-batch+json\n\n[]",
+			want:    "invalid CloudEvent: batched content mode not supported",
+		},
+	} {
+		_, err := cloudevents.DecodeCloudEventRequest(context.Background(), readRequest(t, tc.request))
+		if _, ok := err.(cloudevents.ValidationError); !ok {
+			t.Errorf("%s: want ValidationError, have %v", tc.name, err)
+			continue
+		}
+		if want, have := tc.want, err.Error(); want != have {
+			t.Errorf("%s: want %q, have %q", tc.name, want, have)
+		}
+	}
+}
+
+func TestEncodeCloudEventResponse(t *testing.T) {
+	for _, tc := range specExamples {
+		for name, enc := range map[string]func(context.Context, http.ResponseWriter, interface{}) error{
+			"binary":     cloudevents.EncodeCloudEventResponse,
+			"structured": cloudevents.EncodeStructuredCloudEventResponse,
+		} {
+			rec := httptest.NewRecorder()
+			if err := enc(context.Background(), rec, &tc.want); err != nil {
+				t.Errorf("%s: %s: %v", tc.name, name, err)
+				continue
+			}
+
+			// Replay the response as a request, and decode it.
+			r, _ := http.NewRequest("POST", "/", rec.Body)
+			r.Header = rec.Header()
+			have, err := cloudevents.DecodeCloudEventRequest(context.Background(), r)
+			if err != nil {
+				t.Errorf("%s: %s: %v", tc.name, name, err)
+				continue
+			}
+			if !reflect.DeepEqual(tc.want, have) {
+				t.Errorf("%s: %s:\nwant %#v\nhave %#v", tc.name, name, tc.want, have)
+			}
+		}
+	}
+}
+
+func TestEncodeCloudEventResponseInvalid(t *testing.T) {
+	for _, response := range []interface{}{
+		cloudevents.Event{SpecVersion: "1.0", Type: "t", Source: "s"},
+		"not an event",
+	} {
+		if err := cloudevents.EncodeCloudEventResponse(context.Background(), httptest.NewRecorder(), response); err == nil {
+			t.Errorf("%#v: want error, have none", response)
+		}
+	}
+}
+
+// readRequest reads a request written with LF line endings, and without
+// Content-Length.
+func readRequest(t *testing.T, raw string) *http.Request {
+	parts := strings.SplitN(raw, "\n\n", 2)
+	head, body := strings.Replace(parts[0], "\n", "\r\n", -1), parts[1]
+	head += fmt.Sprintf("\r\nContent-Length: %d\r\n\r\n", len(body))
+	r, err := http.ReadRequest(bufio.NewReader(strings.NewReader(head + body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
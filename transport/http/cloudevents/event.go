@@ -0,0 +1,96 @@
+package cloudevents
+
+import (
+	"mime"
+	"strings"
+	"time"
+)
+
+const (
+	// SpecVersion is the version of the CloudEvents spec implemented.
+	SpecVersion = "1.0"
+
+	// ContentType is the media type of events in the structured content mode.
+	ContentType = "application/cloudevents+json"
+
+	// BatchContentType is the media type of batches of events, which aren't
+	// supported.
+	BatchContentType = "application/cloudevents-batch+json"
+)
+
+// Event is a CloudEvent. Attributes other than the ones with fields are kept
+// as extensions.
+type Event struct {
+	// SpecVersion is the version of the spec the event uses. Required.
+	SpecVersion string
+
+	// Type describes the kind of the event, e.g. "com.example.order.placed".
+	// Required.
+	Type string
+
+	// Source identifies the context in which the event happened. Required.
+	Source string
+
+	// ID identifies the event; it's unique per source. Required.
+	ID string
+
+	// Time is when the event happened. It may be zero.
+	Time time.Time
+
+	// DataContentType is the media type of the data. It may be empty.
+	DataContentType string
+
+	// Data is the event payload, encoded as per DataContentType.
+	Data []byte
+
+	// Extensions are any other attributes, by name.
+	Extensions map[string]string
+}
+
+// Validate returns a ValidationError if the event lacks a required attribute,
+// or uses an unsupported spec version.
+func (e Event) Validate() error {
+	switch {
+	case e.SpecVersion == "":
+		return ValidationError{Attribute: "specversion", Reason: "missing"}
+	case e.SpecVersion != SpecVersion:
+		return ValidationError{Attribute: "specversion", Reason: "unsupported version " + e.SpecVersion}
+	case e.Type == "":
+		return ValidationError{Attribute: "type", Reason: "missing"}
+	case e.Source == "":
+		return ValidationError{Attribute: "source", Reason: "missing"}
+	case e.ID == "":
+		return ValidationError{Attribute: "id", Reason: "missing"}
+	}
+	return nil
+}
+
+// ValidationError is returned for events that don't conform to the spec.
+// Returned by a DecodeRequestFunc, it's encoded with status 400 Bad Request
+// by the server's default error encoder.
+type ValidationError struct {
+	Attribute string
+	Reason    string
+}
+
+// Error implements the error interface.
+func (e ValidationError) Error() string {
+	if e.Attribute == "" {
+		return "invalid CloudEvent: " + e.Reason
+	}
+	return "invalid CloudEvent: " + e.Attribute + ": " + e.Reason
+}
+
+// isJSON reports whether the media type is JSON, in which case structured
+// events carry their data as JSON, not as a string. Events without a data
+// content type have JSON data.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
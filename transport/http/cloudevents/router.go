@@ -0,0 +1,51 @@
+package cloudevents
+
+import (
+	"errors"
+	"net/http"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// ErrUnknownType is returned by routers for events of a type they have no
+// route for.
+var ErrUnknownType = errors.New("no route for CloudEvent type")
+
+// Router returns an endpoint that takes an Event, e.g. as decoded by
+// DecodeCloudEventRequest, and invokes the endpoint routed to for its type.
+// Events of other types fail with ErrUnknownType.
+func Router(routes map[string]endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		var typ string
+		switch e := request.(type) {
+		case Event:
+			typ = e.Type
+		case *Event:
+			typ = e.Type
+		}
+		e, ok := routes[typ]
+		if !ok {
+			return nil, ErrUnknownType
+		}
+		return e(ctx, request)
+	}
+}
+
+// ErrorEncoder is a transport/http.ErrorEncoder that, in addition to the
+// server's default status codes, reports events of unknown types with status
+// 400 Bad Request, as redelivering them can't succeed.
+func ErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
+	code := http.StatusInternalServerError
+	if e, ok := err.(httptransport.Error); ok {
+		switch {
+		case e.Domain == httptransport.DomainDecode, e.Err == ErrUnknownType:
+			code = http.StatusBadRequest
+		case e.Domain == httptransport.DomainDo:
+			code = http.StatusServiceUnavailable
+		}
+	}
+	http.Error(w, err.Error(), code)
+}
@@ -0,0 +1,82 @@
+package cloudevents_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/kit/transport/http/cloudevents"
+)
+
+func TestRouter(t *testing.T) {
+	reply := func(typ string) endpoint.Endpoint {
+		return func(_ context.Context, request interface{}) (interface{}, error) {
+			e := request.(cloudevents.Event)
+			return cloudevents.Event{
+				SpecVersion: cloudevents.SpecVersion,
+				Type:        typ,
+				Source:      "/router",
+				ID:          e.ID,
+				Data:        e.Data,
+			}, nil
+		}
+	}
+	server := httptest.NewServer(httptransport.NewServer(
+		context.Background(),
+		cloudevents.Router(map[string]endpoint.Endpoint{
+			"com.example.order.placed":    reply("com.example.order.accepted"),
+			"com.example.order.cancelled": reply("com.example.order.refunded"),
+		}),
+		cloudevents.DecodeCloudEventRequest,
+		cloudevents.EncodeCloudEventResponse,
+		httptransport.ServerErrorEncoder(cloudevents.ErrorEncoder),
+	))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		typ, id  string
+		wantCode int
+		wantType string
+	}{
+		{"com.example.order.placed", "1", http.StatusOK, "com.example.order.accepted"},
+		{"com.example.order.cancelled", "2", http.StatusOK, "com.example.order.refunded"},
+		{"com.example.order.shipped", "3", http.StatusBadRequest, ""},
+		{"com.example.order.placed", "", http.StatusBadRequest, ""},
+	} {
+		req, _ := http.NewRequest("POST", server.URL, strings.NewReader(`{"sku":"42"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("ce-specversion", "1.0")
+		req.Header.Set("ce-type", tc.typ)
+		req.Header.Set("ce-source", "/shop")
+		req.Header.Set("ce-id", tc.id)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if want, have := tc.wantCode, resp.StatusCode; want != have {
+			t.Errorf("%s %q: want %d, have %d (%s)", tc.typ, tc.id, want, have, body)
+			continue
+		}
+		if tc.wantCode != http.StatusOK {
+			continue
+		}
+		if want, have := tc.wantType, resp.Header.Get("ce-type"); want != have {
+			t.Errorf("%s: want type %q, have %q", tc.typ, want, have)
+		}
+		if want, have := tc.id, resp.Header.Get("ce-id"); want != have {
+			t.Errorf("%s: want id %q, have %q", tc.typ, want, have)
+		}
+		if want, have := `{"sku":"42"}`, string(body); want != have {
+			t.Errorf("%s: want data %s, have %s", tc.typ, want, have)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// Client wraps a JSON-RPC method at a URL, and provides a method that
+// implements endpoint.Endpoint.
+type Client struct {
+	client *http.Client
+	tgt    *url.URL
+	method string
+	enc    EncodeRequestFunc
+	dec    DecodeResponseFunc
+	before []httptransport.RequestFunc
+	id     *uint64
+}
+
+// NewClient constructs a usable Client for a single JSON-RPC method.
+func NewClient(
+	tgt *url.URL,
+	method string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	options ...ClientOption,
+) *Client {
+	c := &Client{
+		client: http.DefaultClient,
+		tgt:    tgt,
+		method: method,
+		enc:    enc,
+		dec:    dec,
+		id:     new(uint64),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*Client)
+
+// SetClient sets the underlying HTTP client used for requests.
+// By default, http.DefaultClient is used.
+func SetClient(client *http.Client) ClientOption {
+	return func(c *Client) { c.client = client }
+}
+
+// SetClientBefore sets the RequestFuncs that are applied to the outgoing HTTP
+// request before it's invoked.
+func SetClientBefore(before ...httptransport.RequestFunc) ClientOption {
+	return func(c *Client) { c.before = before }
+}
+
+// Endpoint returns a usable endpoint that will invoke the JSON-RPC method
+// specified by the client. Each request gets the next ID of the client,
+// starting at 1. If the server replies with an error object, the endpoint
+// returns it as an Error.
+func (c Client) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		params, err := c.enc(ctx, request)
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainEncode, Err: err}
+		}
+
+		id := json.RawMessage(strconv.FormatUint(atomic.AddUint64(c.id, 1), 10))
+		body, err := json.Marshal(Request{
+			JSONRPC: Version,
+			Method:  c.method,
+			Params:  params,
+			ID:      id,
+		})
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainEncode, Err: err}
+		}
+
+		req, err := http.NewRequest("POST", c.tgt.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainNewRequest, Err: err}
+		}
+		req.Header.Set("Content-Type", ContentType)
+
+		for _, f := range c.before {
+			ctx = f(ctx, req)
+		}
+
+		resp, err := ctxhttp.Do(ctx, c.client, req)
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainDo, Err: err}
+		}
+		defer resp.Body.Close()
+
+		var rpcResp Response
+		if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainDecode, Err: fmt.Errorf("%s: %v", resp.Status, err)}
+		}
+		if rpcResp.Error != nil {
+			return nil, *rpcResp.Error
+		}
+		if !bytes.Equal(rpcResp.ID, id) {
+			return nil, httptransport.Error{Domain: httptransport.DomainDecode, Err: fmt.Errorf("response ID %s doesn't match request ID %s", rpcResp.ID, id)}
+		}
+
+		response, err := c.dec(ctx, rpcResp.Result)
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainDecode, Err: err}
+		}
+		return response, nil
+	}
+}
@@ -0,0 +1,119 @@
+package jsonrpc_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/kit/transport/http/jsonrpc"
+)
+
+func TestClientRoundTrip(t *testing.T) {
+	server := httptest.NewServer(jsonrpc.NewServer(context.Background(), jsonrpc.EndpointCodecMap{
+		"subtract": subtractCodec,
+	}))
+	defer server.Close()
+
+	subtract := newClient(t, server.URL, "subtract")
+	response, err := subtract(context.Background(), subtractRequest{Minuend: 42, Subtrahend: 23})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 19, response.(int); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestClientRequestIDs(t *testing.T) {
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req jsonrpc.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := jsonrpc.Version, req.JSONRPC; want != have {
+			t.Errorf("want version %q, have %q", want, have)
+		}
+		ids = append(ids, string(req.ID))
+		json.NewEncoder(w).Encode(jsonrpc.Response{JSONRPC: jsonrpc.Version, Result: json.RawMessage("0"), ID: req.ID})
+	}))
+	defer server.Close()
+
+	e := newClient(t, server.URL, "subtract")
+	for i := 0; i < 3; i++ {
+		if _, err := e(context.Background(), subtractRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want, have := "1 2 3", strings.Join(ids, " "); want != have {
+		t.Errorf("want IDs %q, have %q", want, have)
+	}
+}
+
+func TestClientErrorObject(t *testing.T) {
+	server := httptest.NewServer(jsonrpc.NewServer(context.Background(), jsonrpc.EndpointCodecMap{
+		"fail": {
+			Endpoint: func(context.Context, interface{}) (interface{}, error) {
+				return nil, jsonrpc.Error{Code: 42, Message: "dang", Data: "details"}
+			},
+			Decode: func(context.Context, json.RawMessage) (interface{}, error) { return nil, nil },
+			Encode: func(context.Context, interface{}) (json.RawMessage, error) { return nil, nil },
+		},
+	}))
+	defer server.Close()
+
+	for method, want := range map[string]jsonrpc.Error{
+		"fail":    {Code: 42, Message: "dang", Data: "details"},
+		"missing": {Code: jsonrpc.MethodNotFoundError, Message: "Method not found"},
+	} {
+		_, err := newClient(t, server.URL, method)(context.Background(), subtractRequest{})
+		have, ok := err.(jsonrpc.Error)
+		if !ok {
+			t.Errorf("%s: want %T, have %v", method, jsonrpc.Error{}, err)
+			continue
+		}
+		if want != have {
+			t.Errorf("%s: want %+v, have %+v", method, want, have)
+		}
+	}
+}
+
+func TestClientMismatchedID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jsonrpc.Response{JSONRPC: jsonrpc.Version, Result: json.RawMessage("0"), ID: json.RawMessage(`"bogus"`)})
+	}))
+	defer server.Close()
+
+	_, err := newClient(t, server.URL, "subtract")(context.Background(), subtractRequest{})
+	if e, ok := err.(httptransport.Error); !ok || e.Domain != httptransport.DomainDecode {
+		t.Errorf("want %s error, have %v", httptransport.DomainDecode, err)
+	}
+}
+
+func newClient(t *testing.T, rawurl, method string) func(context.Context, interface{}) (interface{}, error) {
+	tgt, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jsonrpc.NewClient(
+		tgt,
+		method,
+		func(_ context.Context, request interface{}) (json.RawMessage, error) {
+			return json.Marshal(request)
+		},
+		func(_ context.Context, result json.RawMessage) (interface{}, error) {
+			var v int
+			if err := json.Unmarshal(result, &v); err != nil {
+				return nil, errors.New("result must be a number")
+			}
+			return v, nil
+		},
+	).Endpoint()
+}
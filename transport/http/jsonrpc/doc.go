@@ -0,0 +1,6 @@
+// Package jsonrpc provides a JSON-RPC 2.0 transport over HTTP. See
+// http://www.jsonrpc.org/specification.
+//
+// A single Server serves many endpoints, dispatching each request on its
+// method field. Batch requests aren't supported yet.
+package jsonrpc
@@ -0,0 +1,39 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from the params
+// member of a JSON-RPC request. The params are nil if the request has none.
+// Returning an error yields an "Invalid params" error response.
+type DecodeRequestFunc func(context.Context, json.RawMessage) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object into the result
+// member of a JSON-RPC response.
+type EncodeResponseFunc func(context.Context, interface{}) (response json.RawMessage, err error)
+
+// EncodeRequestFunc encodes the passed request object into the params member
+// of a JSON-RPC request. It's designed to be used in clients. Params must be
+// a JSON array or object, or nil to omit them.
+type EncodeRequestFunc func(context.Context, interface{}) (request json.RawMessage, err error)
+
+// DecodeResponseFunc extracts a user-domain response object from the result
+// member of a JSON-RPC response. It's designed to be used in clients.
+type DecodeResponseFunc func(context.Context, json.RawMessage) (response interface{}, err error)
+
+// EndpointCodec defines a server endpoint, and its request decoder and
+// response encoder.
+type EndpointCodec struct {
+	Endpoint endpoint.Endpoint
+	Decode   DecodeRequestFunc
+	Encode   EncodeResponseFunc
+}
+
+// EndpointCodecMap maps JSON-RPC method names to the EndpointCodecs serving
+// them.
+type EndpointCodecMap map[string]EndpointCodec
@@ -0,0 +1,67 @@
+package jsonrpc
+
+// Error codes defined by the JSON-RPC 2.0 specification. Codes from -32000 to
+// -32099 are reserved for implementation-defined server errors.
+const (
+	// ParseError means invalid JSON was received by the server.
+	ParseError = -32700
+
+	// InvalidRequestError means the JSON sent isn't a valid request object.
+	InvalidRequestError = -32600
+
+	// MethodNotFoundError means the method doesn't exist or isn't available.
+	MethodNotFoundError = -32601
+
+	// InvalidParamsError means the method parameters are invalid.
+	InvalidParamsError = -32602
+
+	// InternalError means an internal JSON-RPC error occurred.
+	InternalError = -32603
+)
+
+var errorMessages = map[int]string{
+	ParseError:          "Parse error",
+	InvalidRequestError: "Invalid Request",
+	MethodNotFoundError: "Method not found",
+	InvalidParamsError:  "Invalid params",
+	InternalError:       "Internal error",
+}
+
+// Error is a JSON-RPC error object. Endpoints may return an Error to control
+// the error response. Clients return the Error of an error response.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	return e.Message
+}
+
+// ErrorCoder is checked by the server for errors returned by endpoints, which
+// aren't an Error. Errors implementing it are reported with the returned code,
+// all others as InternalError.
+type ErrorCoder interface {
+	ErrorCode() int
+}
+
+// newError returns an Error with the specification's message for the code.
+func newError(code int) *Error {
+	return &Error{Code: code, Message: errorMessages[code]}
+}
+
+// endpointError converts an error returned by an endpoint into an Error.
+func endpointError(err error) *Error {
+	switch e := err.(type) {
+	case Error:
+		return &e
+	case *Error:
+		return e
+	case ErrorCoder:
+		return &Error{Code: e.ErrorCode(), Message: err.Error()}
+	default:
+		return &Error{Code: InternalError, Message: err.Error()}
+	}
+}
@@ -0,0 +1,31 @@
+package jsonrpc
+
+import "encoding/json"
+
+// Version is the JSON-RPC protocol version this package implements.
+const Version = "2.0"
+
+// ContentType is the content type of JSON-RPC requests and responses.
+const ContentType = "application/json; charset=utf-8"
+
+// Request is a JSON-RPC request object. A request without an ID is a
+// notification, to which the server doesn't reply.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// Response is a JSON-RPC response object. Exactly one of Result and Error is
+// set.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// null is the JSON null value, used as the ID of responses to requests whose
+// ID couldn't be determined.
+var null = json.RawMessage("null")
@@ -0,0 +1,208 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// Server wraps a set of endpoints, and implements http.Handler. It dispatches
+// each JSON-RPC request to the endpoint registered for its method.
+type Server struct {
+	ctx    context.Context
+	ecm    EndpointCodecMap
+	before []httptransport.RequestFunc
+	after  []httptransport.ResponseFunc
+	logger log.Logger
+}
+
+// NewServer constructs a new server, which implements http.Handler and serves
+// the endpoints in the map.
+func NewServer(
+	ctx context.Context,
+	ecm EndpointCodecMap,
+	options ...ServerOption,
+) *Server {
+	s := &Server{
+		ctx:    ctx,
+		ecm:    ecm,
+		logger: log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerBefore functions are executed on the HTTP request object before the
+// request is decoded.
+func ServerBefore(before ...httptransport.RequestFunc) ServerOption {
+	return func(s *Server) { s.before = before }
+}
+
+// ServerAfter functions are executed on the HTTP response writer after the
+// endpoint is invoked, but before anything is written to the client.
+func ServerAfter(after ...httptransport.ResponseFunc) ServerOption {
+	return func(s *Server) { s.after = after }
+}
+
+// ServerErrorLogger is used to log non-terminal errors. By default, no errors
+// are logged.
+func ServerErrorLogger(logger log.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// ServeHTTP implements http.Handler. Errors are reported in JSON-RPC error
+// objects with status 200 OK. Notifications are answered with 204 No Content.
+func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	for _, f := range s.before {
+		ctx = f(ctx, r)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Log("err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := s.serve(ctx, body)
+
+	for _, f := range s.after {
+		f(ctx, w)
+	}
+
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Log("err", err)
+	}
+}
+
+// serve handles a single JSON-RPC request, and returns the response, or nil
+// for notifications.
+func (s Server) serve(ctx context.Context, body []byte) *Response {
+	req, rpcErr := parseRequest(body)
+	if rpcErr != nil {
+		id := null
+		if req != nil && req.ID != nil {
+			id = req.ID
+		}
+		return &Response{JSONRPC: Version, Error: rpcErr, ID: id}
+	}
+
+	result, rpcErr := s.call(ctx, req)
+	if req.ID == nil {
+		return nil // notification
+	}
+	if rpcErr != nil {
+		return &Response{JSONRPC: Version, Error: rpcErr, ID: req.ID}
+	}
+	if result == nil {
+		result = null
+	}
+	return &Response{JSONRPC: Version, Result: result, ID: req.ID}
+}
+
+func (s Server) call(ctx context.Context, req *Request) (json.RawMessage, *Error) {
+	ec, ok := s.ecm[req.Method]
+	if !ok {
+		return nil, newError(MethodNotFoundError)
+	}
+
+	request, err := ec.Decode(ctx, req.Params)
+	if err != nil {
+		s.logger.Log("method", req.Method, "err", err)
+		return nil, &Error{Code: InvalidParamsError, Message: err.Error()}
+	}
+
+	response, err := ec.Endpoint(ctx, request)
+	if err != nil {
+		s.logger.Log("method", req.Method, "err", err)
+		return nil, endpointError(err)
+	}
+
+	result, err := ec.Encode(ctx, response)
+	if err != nil {
+		s.logger.Log("method", req.Method, "err", err)
+		return nil, &Error{Code: InternalError, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// parseRequest parses and validates a JSON-RPC request object. If the request
+// is invalid, the returned Request carries its ID, if the ID could be
+// determined.
+func parseRequest(body []byte) (*Request, *Error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, newError(ParseError)
+	}
+	if raw[0] != '{' {
+		// Batches, i.e. arrays, aren't supported yet.
+		return nil, newError(InvalidRequestError)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, newError(InvalidRequestError)
+	}
+
+	req := &Request{}
+	if id, ok := fields["id"]; ok {
+		if err := validateID(id); err != nil {
+			return nil, newError(InvalidRequestError)
+		}
+		req.ID = id
+	}
+	if err := json.Unmarshal(fields["jsonrpc"], &req.JSONRPC); err != nil || req.JSONRPC != Version {
+		return req, newError(InvalidRequestError)
+	}
+	if err := json.Unmarshal(fields["method"], &req.Method); err != nil {
+		return req, newError(InvalidRequestError)
+	}
+	if params, ok := fields["params"]; ok {
+		if params[0] != '[' && params[0] != '{' {
+			return req, newError(InvalidRequestError)
+		}
+		req.Params = params
+	}
+	return req, nil
+}
+
+var errInvalidID = errors.New("id must be a string, number, or null")
+
+// validateID checks that the ID is a string, a number, or null.
+func validateID(id json.RawMessage) error {
+	var v interface{}
+	if err := json.Unmarshal(id, &v); err != nil {
+		return err
+	}
+	switch v.(type) {
+	case string, float64, nil:
+		return nil
+	default:
+		return errInvalidID
+	}
+}
@@ -0,0 +1,160 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/transport/http/jsonrpc"
+)
+
+// TestServerGolden replays the examples of the JSON-RPC 2.0 specification, and
+// a few more, from testdata. Each NAME.request is posted to the server, whose
+// reply must be equivalent to the JSON in NAME.response, or empty if that file
+// is empty.
+func TestServerGolden(t *testing.T) {
+	var (
+		mtx     sync.Mutex
+		updates [][]int
+	)
+	server := httptest.NewServer(jsonrpc.NewServer(context.Background(), jsonrpc.EndpointCodecMap{
+		"subtract": subtractCodec,
+		"update": {
+			Endpoint: func(_ context.Context, request interface{}) (interface{}, error) {
+				mtx.Lock()
+				defer mtx.Unlock()
+				updates = append(updates, request.([]int))
+				return nil, nil
+			},
+			Decode: func(_ context.Context, params json.RawMessage) (interface{}, error) {
+				var v []int
+				err := json.Unmarshal(params, &v)
+				return v, err
+			},
+			Encode: func(context.Context, interface{}) (json.RawMessage, error) { return nil, nil },
+		},
+		"fail": {
+			Endpoint: func(context.Context, interface{}) (interface{}, error) { return nil, serverError{errors.New("dang")} },
+			Decode:   func(context.Context, json.RawMessage) (interface{}, error) { return nil, nil },
+			Encode:   func(context.Context, interface{}) (json.RawMessage, error) { return nil, nil },
+		},
+	}))
+	defer server.Close()
+
+	requests, err := filepath.Glob(filepath.Join("testdata", "*.request"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) == 0 {
+		t.Fatal("no golden files")
+	}
+	for _, requestFile := range requests {
+		name := strings.TrimSuffix(filepath.Base(requestFile), ".request")
+		request, err := ioutil.ReadFile(requestFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := ioutil.ReadFile(strings.TrimSuffix(requestFile, ".request") + ".response")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.Post(server.URL, "application/json", bytes.NewReader(request))
+		if err != nil {
+			t.Fatal(err)
+		}
+		have, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if len(bytes.TrimSpace(want)) == 0 {
+			if want, have := http.StatusNoContent, resp.StatusCode; want != have {
+				t.Errorf("%s: want status %d, have %d", name, want, have)
+			}
+			if len(have) != 0 {
+				t.Errorf("%s: want no response, have %s", name, have)
+			}
+			continue
+		}
+		if want, have := http.StatusOK, resp.StatusCode; want != have {
+			t.Errorf("%s: want status %d, have %d", name, want, have)
+		}
+		if !equalJSON(t, want, have) {
+			t.Errorf("%s: want %s, have %s", name, bytes.TrimSpace(want), bytes.TrimSpace(have))
+		}
+	}
+
+	// The notification for an existing method was processed.
+	if want, have := [][]int{{1, 2, 3, 4, 5}}, updates; !reflect.DeepEqual(want, have) {
+		t.Errorf("updates: want %v, have %v", want, have)
+	}
+}
+
+func TestServerMethodNotAllowed(t *testing.T) {
+	server := httptest.NewServer(jsonrpc.NewServer(context.Background(), jsonrpc.EndpointCodecMap{}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusMethodNotAllowed, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+// subtractCodec implements the subtract method of the specification's
+// examples, with positional or named params.
+var subtractCodec = jsonrpc.EndpointCodec{
+	Endpoint: func(_ context.Context, request interface{}) (interface{}, error) {
+		req := request.(subtractRequest)
+		return req.Minuend - req.Subtrahend, nil
+	},
+	Decode: func(_ context.Context, params json.RawMessage) (interface{}, error) {
+		var positional []int
+		if err := json.Unmarshal(params, &positional); err == nil && len(positional) == 2 {
+			return subtractRequest{Minuend: positional[0], Subtrahend: positional[1]}, nil
+		}
+		var named subtractRequest
+		if err := json.Unmarshal(params, &named); err == nil && params[0] == '{' {
+			return named, nil
+		}
+		return nil, errors.New("params must be two numbers")
+	},
+	Encode: func(_ context.Context, response interface{}) (json.RawMessage, error) {
+		return json.Marshal(response)
+	},
+}
+
+type subtractRequest struct {
+	Minuend    int `json:"minuend"`
+	Subtrahend int `json:"subtrahend"`
+}
+
+// serverError is reported with an implementation-defined server error code.
+type serverError struct{ error }
+
+func (serverError) ErrorCode() int { return -32000 }
+
+func equalJSON(t *testing.T, a, b []byte) bool {
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Errorf("%s: %v", a, err)
+		return false
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Errorf("%s: %v", b, err)
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
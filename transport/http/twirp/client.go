@@ -0,0 +1,147 @@
+package twirp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// Client wraps a method of a Twirp service, and provides a method that
+// implements endpoint.Endpoint.
+type Client struct {
+	client      *http.Client
+	tgt         *url.URL
+	contentType string
+	enc         EncodeRequestFunc
+	dec         DecodeResponseFunc
+	newResponse func() proto.Message
+	before      []httptransport.RequestFunc
+}
+
+// NewClient constructs a usable Client for a single method of a Twirp
+// service. The target is the base URL of the server, e.g.
+// "http://localhost:8080", to which the method's route is appended. The
+// service name is qualified by its protobuf package. Responses are
+// unmarshaled into the messages returned by newResponse.
+func NewClient(
+	tgt *url.URL,
+	service string,
+	method string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	newResponse func() proto.Message,
+	options ...ClientOption,
+) *Client {
+	u := *tgt
+	u.Path = strings.TrimSuffix(u.Path, "/") + PathPrefix + service + "/" + method
+	c := &Client{
+		client:      http.DefaultClient,
+		tgt:         &u,
+		contentType: ContentTypeProtobuf,
+		enc:         enc,
+		dec:         dec,
+		newResponse: newResponse,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*Client)
+
+// SetClient sets the underlying HTTP client used for requests.
+// By default, http.DefaultClient is used.
+func SetClient(client *http.Client) ClientOption {
+	return func(c *Client) { c.client = client }
+}
+
+// SetClientBefore sets the RequestFuncs that are applied to the outgoing HTTP
+// request before it's invoked.
+func SetClientBefore(before ...httptransport.RequestFunc) ClientOption {
+	return func(c *Client) { c.before = before }
+}
+
+// ClientJSON makes the client encode messages as JSON, instead of protobuf.
+func ClientJSON() ClientOption {
+	return func(c *Client) { c.contentType = ContentTypeJSON }
+}
+
+// Endpoint returns a usable endpoint that will invoke the Twirp method
+// specified by the client. If the server replies with an error, the endpoint
+// returns it as an Error. Errors of responses which aren't Twirp errors, e.g.
+// from a proxy, are derived from their HTTP status code.
+func (c Client) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		msg, err := c.enc(ctx, request)
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainEncode, Err: err}
+		}
+		body, err := marshal(c.contentType, msg)
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainEncode, Err: err}
+		}
+
+		req, err := http.NewRequest("POST", c.tgt.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainNewRequest, Err: err}
+		}
+		req.Header.Set("Content-Type", c.contentType)
+		req.Header.Set("Accept", c.contentType)
+
+		for _, f := range c.before {
+			ctx = f(ctx, req)
+		}
+
+		resp, err := ctxhttp.Do(ctx, c.client, req)
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainDo, Err: err}
+		}
+		defer resp.Body.Close()
+
+		respBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainDecode, Err: err}
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, responseError(resp.StatusCode, respBody)
+		}
+
+		respMsg := c.newResponse()
+		if err := unmarshal(c.contentType, respBody, respMsg); err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainDecode, Err: err}
+		}
+		response, err := c.dec(ctx, respMsg)
+		if err != nil {
+			return nil, httptransport.Error{Domain: httptransport.DomainDecode, Err: err}
+		}
+		return response, nil
+	}
+}
+
+// responseError returns the Error of an error response, decoding its Twirp
+// error envelope, if it has one.
+func responseError(status int, body []byte) Error {
+	var e Error
+	if err := json.Unmarshal(body, &e); err != nil || e.Code == "" {
+		return intermediaryError(status, http.StatusText(status))
+	}
+	if _, ok := errorStatus[e.Code]; !ok {
+		return Error{Code: Internal, Msg: "invalid error code " + string(e.Code) + ": " + e.Msg, Meta: e.Meta}
+	}
+	return e
+}
@@ -0,0 +1,111 @@
+package twirp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/transport/http/twirp"
+)
+
+func newClient(t *testing.T, rawurl string, options ...twirp.ClientOption) *twirp.Client {
+	tgt, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return twirp.NewClient(
+		tgt,
+		service,
+		"MakeHat",
+		func(_ context.Context, request interface{}) (proto.Message, error) {
+			return &size{Inches: request.(int32)}, nil
+		},
+		func(_ context.Context, msg proto.Message) (interface{}, error) { return msg.(*hat), nil },
+		func() proto.Message { return &hat{} },
+		options...,
+	)
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	server := newServer(makeHat)
+	defer server.Close()
+
+	for name, options := range map[string][]twirp.ClientOption{
+		"protobuf": nil,
+		"JSON":     {twirp.ClientJSON()},
+	} {
+		response, err := newClient(t, server.URL, options...).Endpoint()(context.Background(), int32(12))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want, have := (&hat{Inches: 12, Color: "red"}), response.(*hat); !proto.Equal(want, have) {
+			t.Errorf("%s: want %v, have %v", name, want, have)
+		}
+	}
+}
+
+func TestClientError(t *testing.T) {
+	server := newServer(makeHat)
+	defer server.Close()
+
+	for name, options := range map[string][]twirp.ClientOption{
+		"protobuf": nil,
+		"JSON":     {twirp.ClientJSON()},
+	} {
+		_, err := newClient(t, server.URL, options...).Endpoint()(context.Background(), int32(-1))
+		e, ok := err.(twirp.Error)
+		if !ok {
+			t.Fatalf("%s: want twirp.Error, have %#v", name, err)
+		}
+		if want, have := twirp.InvalidArgument, e.Code; want != have {
+			t.Errorf("%s: want code %q, have %q", name, want, have)
+		}
+		if want, have := "inches must be positive", e.Msg; want != have {
+			t.Errorf("%s: want message %q, have %q", name, want, have)
+		}
+		if want, have := "inches", e.Meta["argument"]; want != have {
+			t.Errorf("%s: want meta %q, have %q", name, want, have)
+		}
+	}
+}
+
+func TestClientIntermediaryError(t *testing.T) {
+	for status, want := range map[int]twirp.ErrorCode{
+		http.StatusFound:              twirp.Internal,
+		http.StatusBadRequest:         twirp.Internal,
+		http.StatusUnauthorized:       twirp.Unauthenticated,
+		http.StatusForbidden:          twirp.PermissionDenied,
+		http.StatusNotFound:           twirp.BadRoute,
+		http.StatusTooManyRequests:    twirp.Unavailable,
+		http.StatusBadGateway:         twirp.Unavailable,
+		http.StatusServiceUnavailable: twirp.Unavailable,
+		http.StatusGatewayTimeout:     twirp.Unavailable,
+		http.StatusTeapot:             twirp.Unknown,
+	} {
+		code := status
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", "/elsewhere")
+			http.Error(w, "<html>proxy error</html>", code)
+		}))
+		client := newClient(t, proxy.URL, twirp.SetClient(&http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}))
+		_, err := client.Endpoint()(context.Background(), int32(12))
+		proxy.Close()
+
+		e, ok := err.(twirp.Error)
+		if !ok {
+			t.Fatalf("%d: want twirp.Error, have %#v", status, err)
+		}
+		if have := e.Code; want != have {
+			t.Errorf("%d: want code %q, have %q", status, want, have)
+		}
+		if want, have := "true", e.Meta["http_error_from_intermediary"]; want != have {
+			t.Errorf("%d: want intermediary meta %q, have %q", status, want, have)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+// Package twirp provides a Twirp transport over HTTP, without code
+// generation. See https://twitchtv.github.io/twirp/docs/spec_v5.html.
+//
+// A single Server serves the methods of one Twirp service, under the
+// /twirp/<package>.<Service>/<Method> routes, dispatching each request on
+// its method. Request and response messages are protobuf messages, encoded
+// as protobuf or JSON, as selected by the request's Content-Type. Errors are
+// reported in Twirp's JSON error envelope.
+package twirp
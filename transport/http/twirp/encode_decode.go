@@ -0,0 +1,45 @@
+package twirp
+
+import (
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from a Twirp
+// request message. It's designed to be used in Twirp servers, for server-side
+// endpoints.
+type DecodeRequestFunc func(context.Context, proto.Message) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object to the Twirp
+// response message. It's designed to be used in Twirp servers, for
+// server-side endpoints.
+type EncodeResponseFunc func(context.Context, interface{}) (response proto.Message, err error)
+
+// EncodeRequestFunc encodes the passed request object into the Twirp request
+// message. It's designed to be used in Twirp clients, for client-side
+// endpoints.
+type EncodeRequestFunc func(context.Context, interface{}) (request proto.Message, err error)
+
+// DecodeResponseFunc extracts a user-domain response object from a Twirp
+// response message. It's designed to be used in Twirp clients, for
+// client-side endpoints.
+type DecodeResponseFunc func(context.Context, proto.Message) (response interface{}, err error)
+
+// EndpointCodec defines a server endpoint, the request message its requests
+// are read into, and its request decoder and response encoder.
+type EndpointCodec struct {
+	Endpoint endpoint.Endpoint
+
+	// NewRequest returns an empty request message, into which the body of
+	// each request is unmarshaled.
+	NewRequest func() proto.Message
+
+	Decode DecodeRequestFunc
+	Encode EncodeResponseFunc
+}
+
+// EndpointCodecMap maps the method names of a Twirp service, e.g. "MakeHat",
+// to the EndpointCodecs serving them.
+type EndpointCodecMap map[string]EndpointCodec
@@ -0,0 +1,204 @@
+package twirp
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// ErrorCode is a Twirp error code.
+type ErrorCode string
+
+// Error codes defined by the Twirp specification.
+const (
+	// Canceled means the operation was cancelled, typically by the caller.
+	Canceled ErrorCode = "canceled"
+
+	// Unknown means an unknown error occurred.
+	Unknown ErrorCode = "unknown"
+
+	// InvalidArgument means the client specified an invalid argument.
+	InvalidArgument ErrorCode = "invalid_argument"
+
+	// Malformed means the client sent a message which couldn't be decoded.
+	Malformed ErrorCode = "malformed"
+
+	// DeadlineExceeded means the operation expired before completion.
+	DeadlineExceeded ErrorCode = "deadline_exceeded"
+
+	// NotFound means some requested entity wasn't found.
+	NotFound ErrorCode = "not_found"
+
+	// BadRoute means the requested URL path wasn't routable to a Twirp
+	// service and method.
+	BadRoute ErrorCode = "bad_route"
+
+	// AlreadyExists means an attempt to create an entity failed because one
+	// already exists.
+	AlreadyExists ErrorCode = "already_exists"
+
+	// PermissionDenied means the caller doesn't have permission to execute
+	// the operation.
+	PermissionDenied ErrorCode = "permission_denied"
+
+	// Unauthenticated means the request doesn't have valid authentication
+	// credentials.
+	Unauthenticated ErrorCode = "unauthenticated"
+
+	// ResourceExhausted means some resource has been exhausted.
+	ResourceExhausted ErrorCode = "resource_exhausted"
+
+	// FailedPrecondition means the operation was rejected because the system
+	// isn't in a state required for its execution.
+	FailedPrecondition ErrorCode = "failed_precondition"
+
+	// Aborted means the operation was aborted, typically due to a
+	// concurrency issue.
+	Aborted ErrorCode = "aborted"
+
+	// OutOfRange means the operation was attempted past the valid range.
+	OutOfRange ErrorCode = "out_of_range"
+
+	// Unimplemented means the operation isn't implemented or supported.
+	Unimplemented ErrorCode = "unimplemented"
+
+	// Internal means some invariant expected by the system has been broken.
+	Internal ErrorCode = "internal"
+
+	// Unavailable means the service is currently unavailable.
+	Unavailable ErrorCode = "unavailable"
+
+	// DataLoss means unrecoverable data loss or corruption.
+	DataLoss ErrorCode = "data_loss"
+)
+
+var errorStatus = map[ErrorCode]int{
+	Canceled:           http.StatusRequestTimeout,
+	Unknown:            http.StatusInternalServerError,
+	InvalidArgument:    http.StatusBadRequest,
+	Malformed:          http.StatusBadRequest,
+	DeadlineExceeded:   http.StatusRequestTimeout,
+	NotFound:           http.StatusNotFound,
+	BadRoute:           http.StatusNotFound,
+	AlreadyExists:      http.StatusConflict,
+	PermissionDenied:   http.StatusForbidden,
+	Unauthenticated:    http.StatusUnauthorized,
+	ResourceExhausted:  http.StatusTooManyRequests,
+	FailedPrecondition: http.StatusPreconditionFailed,
+	Aborted:            http.StatusConflict,
+	OutOfRange:         http.StatusBadRequest,
+	Unimplemented:      http.StatusNotImplemented,
+	Internal:           http.StatusInternalServerError,
+	Unavailable:        http.StatusServiceUnavailable,
+	DataLoss:           http.StatusInternalServerError,
+}
+
+// HTTPStatus returns the HTTP status code the specification assigns to the
+// error code. Invalid codes are reported as internal errors.
+func (c ErrorCode) HTTPStatus() int {
+	if status, ok := errorStatus[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is a Twirp error, as encoded in the JSON body of error responses.
+// Endpoints may return an Error to control the error response. Clients return
+// the Error of an error response.
+type Error struct {
+	Code ErrorCode         `json:"code"`
+	Msg  string            `json:"msg"`
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	return "twirp error " + string(e.Code) + ": " + e.Msg
+}
+
+// StatusCode implements StatusCoder, with the HTTP status of the error code.
+func (e Error) StatusCode() int {
+	return e.Code.HTTPStatus()
+}
+
+// StatusCoder is checked by the server for errors returned by endpoints,
+// which aren't an Error. Errors implementing it are reported with the error
+// code matching their HTTP status code, all others as Internal.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// statusErrorCode returns the error code best describing the HTTP status
+// code of a StatusCoder error.
+func statusErrorCode(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return InvalidArgument
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return PermissionDenied
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return DeadlineExceeded
+	case http.StatusConflict:
+		return AlreadyExists
+	case http.StatusPreconditionFailed:
+		return FailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return OutOfRange
+	case http.StatusTooManyRequests:
+		return ResourceExhausted
+	case http.StatusNotImplemented:
+		return Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return Unavailable
+	}
+	if status >= 400 && status < 500 {
+		return InvalidArgument
+	}
+	return Internal
+}
+
+// endpointError converts an error returned by an endpoint into an Error.
+func endpointError(err error) Error {
+	switch e := err.(type) {
+	case Error:
+		return e
+	case *Error:
+		return *e
+	case StatusCoder:
+		return Error{Code: statusErrorCode(e.StatusCode()), Msg: err.Error()}
+	default:
+		return Error{Code: Internal, Msg: err.Error()}
+	}
+}
+
+// intermediaryError returns the error of a response which isn't a Twirp
+// error, e.g. one from a proxy, based on its HTTP status code, as the
+// specification prescribes for clients.
+func intermediaryError(status int, msg string) Error {
+	code := Unknown
+	switch {
+	case status >= 300 && status < 400:
+		code = Internal
+	case status == http.StatusBadRequest:
+		code = Internal
+	case status == http.StatusUnauthorized:
+		code = Unauthenticated
+	case status == http.StatusForbidden:
+		code = PermissionDenied
+	case status == http.StatusNotFound:
+		code = BadRoute
+	case status == http.StatusTooManyRequests,
+		status == http.StatusBadGateway,
+		status == http.StatusServiceUnavailable,
+		status == http.StatusGatewayTimeout:
+		code = Unavailable
+	}
+	return Error{
+		Code: code,
+		Msg:  msg,
+		Meta: map[string]string{"http_error_from_intermediary": "true", "status_code": strconv.Itoa(status)},
+	}
+}
@@ -0,0 +1,202 @@
+package twirp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// Content types of Twirp requests and responses.
+const (
+	ContentTypeProtobuf = "application/protobuf"
+	ContentTypeJSON     = "application/json"
+)
+
+// PathPrefix is the prefix of the routes of all Twirp services.
+const PathPrefix = "/twirp/"
+
+// Server wraps the endpoints of a Twirp service, and implements
+// http.Handler. It dispatches each request to the endpoint registered for
+// its method.
+type Server struct {
+	ctx     context.Context
+	service string
+	ecm     EndpointCodecMap
+	before  []httptransport.RequestFunc
+	after   []httptransport.ResponseFunc
+	logger  log.Logger
+}
+
+// NewServer constructs a new server, which implements http.Handler and serves
+// the endpoints in the map as the methods of the service. The service name is
+// qualified by its protobuf package, e.g.
+// "twirp.example.haberdasher.Haberdasher".
+func NewServer(
+	ctx context.Context,
+	service string,
+	ecm EndpointCodecMap,
+	options ...ServerOption,
+) *Server {
+	s := &Server{
+		ctx:     ctx,
+		service: service,
+		ecm:     ecm,
+		logger:  log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerBefore functions are executed on the HTTP request object before the
+// request is decoded.
+func ServerBefore(before ...httptransport.RequestFunc) ServerOption {
+	return func(s *Server) { s.before = before }
+}
+
+// ServerAfter functions are executed on the HTTP response writer after the
+// endpoint is invoked, but before anything is written to the client.
+func ServerAfter(after ...httptransport.ResponseFunc) ServerOption {
+	return func(s *Server) { s.after = after }
+}
+
+// ServerErrorLogger is used to log non-terminal errors. By default, no errors
+// are logged.
+func ServerErrorLogger(logger log.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// PathPrefix returns the prefix of the routes of the service's methods, e.g.
+// "/twirp/twirp.example.haberdasher.Haberdasher/", which the server should be
+// mounted on.
+func (s Server) PathPrefix() string {
+	return PathPrefix + s.service + "/"
+}
+
+// ServeHTTP implements http.Handler. Requests which aren't a POST of a
+// protobuf or JSON message to the route of a known method are answered with
+// a bad_route error.
+func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	if r.Method != "POST" {
+		s.writeError(w, Error{Code: BadRoute, Msg: "unsupported method " + r.Method + " (only POST is allowed)"})
+		return
+	}
+	method := strings.TrimPrefix(r.URL.Path, s.PathPrefix())
+	ec, ok := s.ecm[method]
+	if !ok {
+		s.writeError(w, Error{Code: BadRoute, Msg: "no handler for path " + r.URL.Path})
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != ContentTypeProtobuf && contentType != ContentTypeJSON {
+		s.writeError(w, Error{Code: BadRoute, Msg: "unexpected Content-Type: " + r.Header.Get("Content-Type")})
+		return
+	}
+
+	for _, f := range s.before {
+		ctx = f(ctx, r)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Log("method", method, "err", err)
+		s.writeError(w, Error{Code: Malformed, Msg: "failed to read request body: " + err.Error()})
+		return
+	}
+	msg := ec.NewRequest()
+	if err := unmarshal(contentType, body, msg); err != nil {
+		s.logger.Log("method", method, "err", err)
+		s.writeError(w, Error{Code: Malformed, Msg: "the request could not be decoded: " + err.Error()})
+		return
+	}
+
+	request, err := ec.Decode(ctx, msg)
+	if err != nil {
+		s.logger.Log("method", method, "err", err)
+		s.writeError(w, Error{Code: InvalidArgument, Msg: err.Error()})
+		return
+	}
+
+	response, err := ec.Endpoint(ctx, request)
+	if err != nil {
+		s.logger.Log("method", method, "err", err)
+		s.writeError(w, endpointError(err))
+		return
+	}
+
+	respMsg, err := ec.Encode(ctx, response)
+	if err != nil {
+		s.logger.Log("method", method, "err", err)
+		s.writeError(w, Error{Code: Internal, Msg: err.Error()})
+		return
+	}
+	respBody, err := marshal(contentType, respMsg)
+	if err != nil {
+		s.logger.Log("method", method, "err", err)
+		s.writeError(w, Error{Code: Internal, Msg: "failed to marshal response: " + err.Error()})
+		return
+	}
+
+	for _, f := range s.after {
+		f(ctx, w)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(respBody); err != nil {
+		s.logger.Log("method", method, "err", err)
+	}
+}
+
+// writeError writes the error in Twirp's JSON error envelope, with the HTTP
+// status of its code.
+func (s Server) writeError(w http.ResponseWriter, e Error) {
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(e.StatusCode())
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		s.logger.Log("err", err)
+	}
+}
+
+// marshal encodes the message in the wire format of the content type. JSON
+// messages use the original protobuf field names, and include fields with
+// default values, like the reference implementation.
+func marshal(contentType string, msg proto.Message) ([]byte, error) {
+	if contentType == ContentTypeJSON {
+		var buf bytes.Buffer
+		m := jsonpb.Marshaler{OrigName: true, EmitDefaults: true}
+		if err := m.Marshal(&buf, msg); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return proto.Marshal(msg)
+}
+
+// unmarshal decodes the message from the wire format of the content type.
+// Unknown JSON fields are ignored, so old servers accept messages of newer
+// clients, as they do with protobuf.
+func unmarshal(contentType string, data []byte, msg proto.Message) error {
+	if contentType == ContentTypeJSON {
+		u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+		return u.Unmarshal(bytes.NewReader(data), msg)
+	}
+	return proto.Unmarshal(data, msg)
+}
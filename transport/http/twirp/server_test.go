@@ -0,0 +1,219 @@
+package twirp_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/transport/http/twirp"
+)
+
+const service = "example.haberdasher.Haberdasher"
+
+// size and hat are protobuf messages, as generated for
+//
+//	message Size { int32 inches = 1; }
+//	message Hat { int32 inches = 1; string color = 2; }
+type size struct {
+	Inches int32 `protobuf:"varint,1,opt,name=inches" json:"inches,omitempty"`
+}
+
+func (m *size) Reset()         { *m = size{} }
+func (m *size) String() string { return proto.CompactTextString(m) }
+func (*size) ProtoMessage()    {}
+
+type hat struct {
+	Inches int32  `protobuf:"varint,1,opt,name=inches" json:"inches,omitempty"`
+	Color  string `protobuf:"bytes,2,opt,name=color" json:"color,omitempty"`
+}
+
+func (m *hat) Reset()         { *m = hat{} }
+func (m *hat) String() string { return proto.CompactTextString(m) }
+func (*hat) ProtoMessage()    {}
+
+func newServer(e func(context.Context, interface{}) (interface{}, error)) *httptest.Server {
+	s := twirp.NewServer(context.Background(), service, twirp.EndpointCodecMap{
+		"MakeHat": twirp.EndpointCodec{
+			Endpoint:   e,
+			NewRequest: func() proto.Message { return &size{} },
+			Decode:     func(_ context.Context, msg proto.Message) (interface{}, error) { return msg.(*size).Inches, nil },
+			Encode:     func(_ context.Context, response interface{}) (proto.Message, error) { return response.(*hat), nil },
+		},
+	})
+	return httptest.NewServer(s)
+}
+
+func makeHat(_ context.Context, request interface{}) (interface{}, error) {
+	inches := request.(int32)
+	if inches <= 0 {
+		return nil, twirp.Error{Code: twirp.InvalidArgument, Msg: "inches must be positive", Meta: map[string]string{"argument": "inches"}}
+	}
+	return &hat{Inches: inches, Color: "red"}, nil
+}
+
+func TestServerJSON(t *testing.T) {
+	server := newServer(makeHat)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/twirp/"+service+"/MakeHat", "application/json", strings.NewReader(`{"inches":12,"unknown":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if want, have := http.StatusOK, resp.StatusCode; want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := "application/json", resp.Header.Get("Content-Type"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := float64(12), body["inches"]; want != have {
+		t.Errorf("inches: want %v, have %v", want, have)
+	}
+	if want, have := "red", body["color"]; want != have {
+		t.Errorf("color: want %v, have %v", want, have)
+	}
+}
+
+func TestServerBadRoute(t *testing.T) {
+	server := newServer(makeHat)
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name, method, path, contentType string
+	}{
+		{"GET", "GET", "/twirp/" + service + "/MakeHat", "application/json"},
+		{"unknown method", "POST", "/twirp/" + service + "/MakeShoe", "application/json"},
+		{"unknown service", "POST", "/twirp/example.Other/MakeHat", "application/json"},
+		{"content type", "POST", "/twirp/" + service + "/MakeHat", "text/plain"},
+	} {
+		req, _ := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", tc.contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := decodeError(t, resp)
+		if want, have := http.StatusNotFound, resp.StatusCode; want != have {
+			t.Errorf("%s: want status %d, have %d", tc.name, want, have)
+		}
+		if want, have := twirp.BadRoute, e.Code; want != have {
+			t.Errorf("%s: want code %q, have %q", tc.name, want, have)
+		}
+	}
+}
+
+func TestServerMalformed(t *testing.T) {
+	server := newServer(makeHat)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/twirp/"+service+"/MakeHat", "application/json", strings.NewReader(`{"inches":`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := decodeError(t, resp)
+	if want, have := http.StatusBadRequest, resp.StatusCode; want != have {
+		t.Errorf("want status %d, have %d", want, have)
+	}
+	if want, have := twirp.Malformed, e.Code; want != have {
+		t.Errorf("want code %q, have %q", want, have)
+	}
+}
+
+type statusError int
+
+func (e statusError) Error() string   { return http.StatusText(int(e)) }
+func (e statusError) StatusCode() int { return int(e) }
+
+func TestServerErrorCodes(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		code   twirp.ErrorCode
+		status int
+	}{
+		{twirp.Error{Code: twirp.NotFound, Msg: "no such hat"}, twirp.NotFound, 404},
+		{&twirp.Error{Code: twirp.Aborted, Msg: "try again"}, twirp.Aborted, 409},
+		{statusError(http.StatusBadRequest), twirp.InvalidArgument, 400},
+		{statusError(http.StatusUnauthorized), twirp.Unauthenticated, 401},
+		{statusError(http.StatusForbidden), twirp.PermissionDenied, 403},
+		{statusError(http.StatusNotFound), twirp.NotFound, 404},
+		{statusError(http.StatusConflict), twirp.AlreadyExists, 409},
+		{statusError(http.StatusPreconditionFailed), twirp.FailedPrecondition, 412},
+		{statusError(http.StatusTooManyRequests), twirp.ResourceExhausted, 429},
+		{statusError(http.StatusNotImplemented), twirp.Unimplemented, 501},
+		{statusError(http.StatusServiceUnavailable), twirp.Unavailable, 503},
+		{statusError(http.StatusGatewayTimeout), twirp.DeadlineExceeded, 408},
+		{statusError(http.StatusTeapot), twirp.InvalidArgument, 400},
+		{statusError(http.StatusInternalServerError), twirp.Internal, 500},
+		{errors.New("boom"), twirp.Internal, 500},
+	} {
+		err := tc.err
+		server := newServer(func(context.Context, interface{}) (interface{}, error) { return nil, err })
+		resp, herr := http.Post(server.URL+"/twirp/"+service+"/MakeHat", "application/json", strings.NewReader("{}"))
+		server.Close()
+		if herr != nil {
+			t.Fatal(herr)
+		}
+		e := decodeError(t, resp)
+		if want, have := tc.status, resp.StatusCode; want != have {
+			t.Errorf("%v: want status %d, have %d", err, want, have)
+		}
+		if want, have := tc.code, e.Code; want != have {
+			t.Errorf("%v: want code %q, have %q", err, want, have)
+		}
+	}
+}
+
+// TestErrorCodeStatus checks the HTTP status of each error code against the
+// table of the Twirp specification.
+func TestErrorCodeStatus(t *testing.T) {
+	for code, want := range map[twirp.ErrorCode]int{
+		"canceled":            408,
+		"unknown":             500,
+		"invalid_argument":    400,
+		"malformed":           400,
+		"deadline_exceeded":   408,
+		"not_found":           404,
+		"bad_route":           404,
+		"already_exists":      409,
+		"permission_denied":   403,
+		"unauthenticated":     401,
+		"resource_exhausted":  429,
+		"failed_precondition": 412,
+		"aborted":             409,
+		"out_of_range":        400,
+		"unimplemented":       501,
+		"internal":            500,
+		"unavailable":         503,
+		"data_loss":           500,
+		"no_such_code":        500,
+	} {
+		if have := code.HTTPStatus(); want != have {
+			t.Errorf("%s: want %d, have %d", code, want, have)
+		}
+	}
+}
+
+func decodeError(t *testing.T, resp *http.Response) twirp.Error {
+	defer resp.Body.Close()
+	if want, have := "application/json", resp.Header.Get("Content-Type"); want != have {
+		t.Errorf("want Content-Type %q, have %q", want, have)
+	}
+	var e twirp.Error
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Msg == "" {
+		t.Error("want error message, have none")
+	}
+	return e
+}
@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Message is a Kafka message, as consumed from a topic partition.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+	Timestamp time.Time
+}
+
+// Header is a Kafka record header. Keys may repeat.
+type Header struct {
+	Key   []byte
+	Value []byte
+}
+
+// ConsumerGroup is a member of a Kafka consumer group.
+type ConsumerGroup interface {
+	// Consume joins the group, and runs a session: it calls the handler's
+	// ConsumeClaim, each in its own goroutine, for the partitions claimed by
+	// this member. Consume returns once all ConsumeClaim calls have returned,
+	// which they must when the session's context is done, e.g. when the
+	// group rebalances, or ctx is done.
+	Consume(ctx context.Context, topics []string, handler ConsumerGroupHandler) error
+}
+
+// ConsumerGroupHandler handles the claims of a consumer group session.
+type ConsumerGroupHandler interface {
+	Setup(ConsumerGroupSession) error
+	Cleanup(ConsumerGroupSession) error
+	ConsumeClaim(ConsumerGroupSession, ConsumerGroupClaim) error
+}
+
+// ConsumerGroupSession is a consumer group session, lasting from one
+// rebalance to the next.
+type ConsumerGroupSession interface {
+	// Context is done when the session ends.
+	Context() context.Context
+
+	// MarkMessage marks the message, and all messages before it in its
+	// partition, as consumed, so their offsets are committed.
+	MarkMessage(msg *Message, metadata string)
+}
+
+// ConsumerGroupClaim is a partition claimed by the session.
+type ConsumerGroupClaim interface {
+	Topic() string
+	Partition() int32
+
+	// Messages returns the messages of the partition, in order. The channel
+	// is closed when the claim is revoked.
+	Messages() <-chan *Message
+}
+
+// Producer produces messages, e.g. to a dead-letter topic.
+type Producer interface {
+	Produce(*Message) error
+}
@@ -0,0 +1,8 @@
+// Package kafka implements a Kafka consumer transport. A Subscriber serves an
+// endpoint to the messages a consumer group claims, committing each message
+// once it's handled, retried to success, or dead-lettered.
+//
+// The package defines the small consumer group interface it needs, modeled
+// on sarama's, so any Kafka client can be adapted to it, and tests can mock
+// it.
+package kafka
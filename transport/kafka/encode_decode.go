@@ -0,0 +1,8 @@
+package kafka
+
+import "golang.org/x/net/context"
+
+// DecodeRequestFunc extracts a user-domain request object from a Kafka
+// message. One straightforward DecodeRequestFunc could be something that
+// JSON decodes the message value to the concrete request type.
+type DecodeRequestFunc func(context.Context, *Message) (request interface{}, err error)
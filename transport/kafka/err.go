@@ -0,0 +1,49 @@
+package kafka
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// DomainDecode is an error during message decoding.
+	DomainDecode = "Decode"
+
+	// DomainDo is an error during the execution phase of the request.
+	DomainDo = "Do"
+)
+
+// Error is an error that occurred at some phase within the transport.
+type Error struct {
+	// Domain is the phase in which the error was generated.
+	Domain string
+
+	// Err is the concrete error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Domain, e.Err)
+}
+
+// Action is what the subscriber does with a message that failed.
+type Action int
+
+const (
+	// Commit skips the message, committing its offset.
+	Commit Action = iota
+
+	// Retry handles the message again, after a backoff.
+	Retry
+
+	// DeadLetter produces the message to the dead-letter topic, and commits
+	// its offset.
+	DeadLetter
+)
+
+// ErrorHandler decides what to do with a message that failed. It's passed the
+// error, an Error identifying the failed stage, and the number of attempts
+// made so far, starting at 1.
+type ErrorHandler func(ctx context.Context, msg *Message, err error, attempt int) Action
@@ -0,0 +1,39 @@
+package kafka
+
+import "sync"
+
+// offsetTracker tracks the messages of a partition that are in flight, so an
+// offset is only marked once all messages before it are handled, even if
+// messages complete out of order.
+type offsetTracker struct {
+	mtx     sync.Mutex
+	pending []*trackedMessage
+}
+
+type trackedMessage struct {
+	msg  *Message
+	done bool
+}
+
+// add registers a message, in partition order.
+func (t *offsetTracker) add(msg *Message) *trackedMessage {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	m := &trackedMessage{msg: msg}
+	t.pending = append(t.pending, m)
+	return m
+}
+
+// done completes a message, and returns the last message whose offset may be
+// marked, or nil if none may be marked yet.
+func (t *offsetTracker) done(m *trackedMessage) *Message {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	m.done = true
+	var last *Message
+	for len(t.pending) > 0 && t.pending[0].done {
+		last = t.pending[0].msg
+		t.pending = t.pending[1:]
+	}
+	return last
+}
@@ -0,0 +1,23 @@
+package kafka
+
+import "golang.org/x/net/context"
+
+// RequestFunc may take information from a Kafka message and put it into a
+// request context. RequestFuncs are executed prior to decoding the message.
+type RequestFunc func(context.Context, *Message) context.Context
+
+type contextKey int
+
+const (
+	// ContextKeyTopic is populated in the context of each request with the
+	// topic of the message, as a string.
+	ContextKeyTopic contextKey = iota
+
+	// ContextKeyPartition is populated in the context of each request with
+	// the partition of the message, as an int32.
+	ContextKeyPartition
+
+	// ContextKeyOffset is populated in the context of each request with the
+	// offset of the message, as an int64.
+	ContextKeyOffset
+)
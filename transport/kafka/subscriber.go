@@ -0,0 +1,286 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Subscriber wraps an endpoint and serves it to the messages claimed by a
+// consumer group. Each message is committed once the endpoint succeeded, or
+// once the error handler gave up on it. Messages of a partition are processed
+// in order, one at a time, unless a worker pool is configured.
+type Subscriber struct {
+	ctx          context.Context
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	before       []RequestFunc
+	errorHandler ErrorHandler
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	deadLetter   Producer
+	dlqTopic     string
+	workers      int
+	logger       log.Logger
+}
+
+// NewSubscriber constructs a new subscriber, which serves the provided
+// endpoint to Kafka messages.
+func NewSubscriber(
+	ctx context.Context,
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	options ...SubscriberOption,
+) *Subscriber {
+	s := &Subscriber{
+		ctx:          ctx,
+		e:            e,
+		dec:          dec,
+		errorHandler: func(context.Context, *Message, error, int) Action { return Commit },
+		minBackoff:   100 * time.Millisecond,
+		maxBackoff:   10 * time.Second,
+		workers:      1,
+		logger:       log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore functions are executed on the Kafka message before the
+// request is decoded.
+func SubscriberBefore(before ...RequestFunc) SubscriberOption {
+	return func(s *Subscriber) { s.before = before }
+}
+
+// SubscriberErrorHandler sets the policy deciding what's done with a message
+// that failed: whether it's committed, retried, or dead-lettered. By default,
+// failed messages are logged and committed, as a message that can't be
+// processed would otherwise block its partition forever.
+func SubscriberErrorHandler(h ErrorHandler) SubscriberOption {
+	return func(s *Subscriber) { s.errorHandler = h }
+}
+
+// SubscriberRetryBackoff sets the delay before a message is retried. The delay
+// starts at min, and doubles with each attempt, up to max. By default, it
+// starts at 100ms, up to 10s.
+func SubscriberRetryBackoff(min, max time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.minBackoff, s.maxBackoff = min, max }
+}
+
+// SubscriberDeadLetter sets the producer and topic failed messages are
+// dead-lettered to, when the error handler says so. Dead-lettered messages
+// keep their key, value and headers, and get headers naming their original
+// topic, partition and offset, and the error. If producing fails, it's
+// retried with backoff, and the message isn't committed until it succeeds.
+// Without a dead-letter topic, dead-lettered messages are committed.
+func SubscriberDeadLetter(p Producer, topic string) SubscriberOption {
+	return func(s *Subscriber) { s.deadLetter, s.dlqTopic = p, topic }
+}
+
+// SubscriberWorkers processes the messages of each partition on a pool of n
+// workers. Messages are dispatched to workers by key, so messages with the
+// same key are still processed in order; messages without a key all go to
+// the same worker. Offsets are only committed up to the first message that's
+// still in flight. By default, there's one worker per partition.
+func SubscriberWorkers(n int) SubscriberOption {
+	return func(s *Subscriber) {
+		if n > 0 {
+			s.workers = n
+		}
+	}
+}
+
+// SubscriberErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged.
+func SubscriberErrorLogger(logger log.Logger) SubscriberOption {
+	return func(s *Subscriber) { s.logger = logger }
+}
+
+// Serve consumes the topics as a member of the consumer group, and serves the
+// messages of the claimed partitions. It rejoins the group after each
+// rebalance, and returns when ctx is done, or if consuming fails. Cancel ctx
+// to shut down gracefully: messages in flight are finished and committed, as
+// requests are served with the subscriber's context, not ctx.
+func (s Subscriber) Serve(ctx context.Context, group ConsumerGroup, topics ...string) error {
+	for {
+		if err := group.Consume(ctx, topics, s); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Setup implements ConsumerGroupHandler.
+func (s Subscriber) Setup(ConsumerGroupSession) error { return nil }
+
+// Cleanup implements ConsumerGroupHandler.
+func (s Subscriber) Cleanup(ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim implements ConsumerGroupHandler. It serves the messages of the
+// claim until the claim is revoked, or the session ends. Before returning, it
+// finishes the messages in flight, and marks their offsets, so the partition
+// is handed over to the next owner without duplicates. Messages being retried
+// when the session ends aren't committed, and are redelivered to the next
+// owner.
+func (s Subscriber) ConsumeClaim(sess ConsumerGroupSession, claim ConsumerGroupClaim) error {
+	var (
+		ctx     = sess.Context()
+		tracker = &offsetTracker{}
+		workers = make([]chan *trackedMessage, s.workers)
+		wg      sync.WaitGroup
+	)
+	for i := range workers {
+		workers[i] = make(chan *trackedMessage)
+		wg.Add(1)
+		go func(c <-chan *trackedMessage) {
+			defer wg.Done()
+			for m := range c {
+				if !s.serveMessage(ctx, m.msg) {
+					continue
+				}
+				if last := tracker.done(m); last != nil {
+					sess.MarkMessage(last, "")
+				}
+			}
+		}(workers[i])
+	}
+	defer func() {
+		for _, c := range workers {
+			close(c)
+		}
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			m := tracker.add(msg)
+			select {
+			case workers[s.worker(msg)] <- m:
+			case <-ctx.Done():
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s Subscriber) worker(msg *Message) int {
+	if s.workers == 1 || len(msg.Key) == 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write(msg.Key)
+	return int(h.Sum32() % uint32(s.workers))
+}
+
+// serveMessage serves the message until it's done with, and reports whether
+// its offset may be committed. Retries stop when ctx, the session context, is
+// done, leaving the message to the partition's next owner.
+func (s Subscriber) serveMessage(ctx context.Context, msg *Message) bool {
+	for attempt := 1; ; attempt++ {
+		err := s.handle(msg)
+		if err == nil {
+			return true
+		}
+		s.logger.Log("topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "attempt", attempt, "err", err)
+
+		switch s.errorHandler(s.ctx, msg, err, attempt) {
+		case Commit:
+			return true
+		case DeadLetter:
+			return s.produceDeadLetter(ctx, msg, err)
+		}
+		if !s.sleep(ctx, attempt) {
+			return false
+		}
+	}
+}
+
+func (s Subscriber) handle(msg *Message) error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	ctx = context.WithValue(ctx, ContextKeyTopic, msg.Topic)
+	ctx = context.WithValue(ctx, ContextKeyPartition, msg.Partition)
+	ctx = context.WithValue(ctx, ContextKeyOffset, msg.Offset)
+
+	for _, f := range s.before {
+		ctx = f(ctx, msg)
+	}
+
+	request, err := s.dec(ctx, msg)
+	if err != nil {
+		return Error{Domain: DomainDecode, Err: err}
+	}
+
+	if _, err := s.e(ctx, request); err != nil {
+		return Error{Domain: DomainDo, Err: err}
+	}
+	return nil
+}
+
+func (s Subscriber) produceDeadLetter(ctx context.Context, msg *Message, cause error) bool {
+	if s.deadLetter == nil {
+		return true
+	}
+	dl := &Message{
+		Topic: s.dlqTopic,
+		Key:   msg.Key,
+		Value: msg.Value,
+		Headers: append(append([]Header{}, msg.Headers...),
+			Header{Key: []byte("x-original-topic"), Value: []byte(msg.Topic)},
+			Header{Key: []byte("x-original-partition"), Value: []byte(strconv.FormatInt(int64(msg.Partition), 10))},
+			Header{Key: []byte("x-original-offset"), Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+			Header{Key: []byte("x-error"), Value: []byte(cause.Error())},
+		),
+		Timestamp: msg.Timestamp,
+	}
+	for attempt := 1; ; attempt++ {
+		err := s.deadLetter.Produce(dl)
+		if err == nil {
+			return true
+		}
+		s.logger.Log("topic", s.dlqTopic, "attempt", attempt, "err", err)
+		if !s.sleep(ctx, attempt) {
+			return false
+		}
+	}
+}
+
+// sleep waits out the backoff after the given attempt, and reports whether it
+// did so before the context was done.
+func (s Subscriber) sleep(ctx context.Context, attempt int) bool {
+	d := s.minBackoff
+	for i := 1; i < attempt && d < s.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.maxBackoff {
+		d = s.maxBackoff
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
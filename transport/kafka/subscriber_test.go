@@ -0,0 +1,381 @@
+package kafka_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/transport/kafka"
+)
+
+func TestSubscriberOrder(t *testing.T) {
+	var (
+		mtx  sync.Mutex
+		seen []string
+	)
+	sub := kafka.NewSubscriber(
+		context.Background(),
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			seen = append(seen, fmt.Sprintf("%s/%d/%d=%s",
+				ctx.Value(kafka.ContextKeyTopic),
+				ctx.Value(kafka.ContextKeyPartition),
+				ctx.Value(kafka.ContextKeyOffset),
+				request,
+			))
+			return nil, nil
+		},
+		decodeString,
+	)
+
+	claim := newMockClaim("events", 3, "a", "b", "c")
+	sess := newMockSession(context.Background())
+	if err := sub.ConsumeClaim(sess, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := "[events/3/0=a events/3/1=b events/3/2=c]", fmt.Sprint(seen); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := int64(2), sess.marked(3); want != have {
+		t.Errorf("want marked offset %d, have %d", want, have)
+	}
+}
+
+func TestSubscriberRetry(t *testing.T) {
+	var attempts []int
+	calls := 0
+	sub := kafka.NewSubscriber(
+		context.Background(),
+		func(context.Context, interface{}) (interface{}, error) {
+			if calls++; calls < 3 {
+				return nil, errors.New("unavailable")
+			}
+			return nil, nil
+		},
+		decodeString,
+		kafka.SubscriberRetryBackoff(time.Millisecond, 2*time.Millisecond),
+		kafka.SubscriberErrorHandler(func(_ context.Context, _ *kafka.Message, err error, attempt int) kafka.Action {
+			if e, ok := err.(kafka.Error); !ok || e.Domain != kafka.DomainDo {
+				t.Errorf("want Do error, have %v", err)
+			}
+			attempts = append(attempts, attempt)
+			return kafka.Retry
+		}),
+	)
+
+	sess := newMockSession(context.Background())
+	if err := sub.ConsumeClaim(sess, newMockClaim("events", 0, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, calls; want != have {
+		t.Errorf("want %d call(s), have %d", want, have)
+	}
+	if want, have := "[1 2]", fmt.Sprint(attempts); want != have {
+		t.Errorf("want attempts %s, have %s", want, have)
+	}
+	if want, have := int64(0), sess.marked(0); want != have {
+		t.Errorf("want marked offset %d, have %d", want, have)
+	}
+}
+
+func TestSubscriberDeadLetter(t *testing.T) {
+	producer := &mockProducer{failures: 1}
+	sub := kafka.NewSubscriber(
+		context.Background(),
+		func(context.Context, interface{}) (interface{}, error) { return nil, nil },
+		func(context.Context, *kafka.Message) (interface{}, error) { return nil, errors.New("malformed") },
+		kafka.SubscriberRetryBackoff(time.Millisecond, time.Millisecond),
+		kafka.SubscriberDeadLetter(producer, "events.dlq"),
+		kafka.SubscriberErrorHandler(func(context.Context, *kafka.Message, error, int) kafka.Action {
+			return kafka.DeadLetter
+		}),
+	)
+
+	claim := newMockClaim("events", 1, "poison")
+	claim.messages[0].Key = []byte("k")
+	claim.messages[0].Headers = []kafka.Header{{Key: []byte("trace"), Value: []byte("abc")}}
+	sess := newMockSession(context.Background())
+	if err := sub.ConsumeClaim(sess, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := 1, len(producer.produced); want != have {
+		t.Fatalf("want %d dead letter(s), have %d", want, have)
+	}
+	dl := producer.produced[0]
+	if want, have := "events.dlq", dl.Topic; want != have {
+		t.Errorf("want topic %q, have %q", want, have)
+	}
+	if want, have := "k=poison", string(dl.Key)+"="+string(dl.Value); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	headers := map[string]string{}
+	for _, h := range dl.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	for key, want := range map[string]string{
+		"trace":                "abc",
+		"x-original-topic":     "events",
+		"x-original-partition": "1",
+		"x-original-offset":    "0",
+		"x-error":              "Decode: malformed",
+	} {
+		if have := headers[key]; want != have {
+			t.Errorf("header %s: want %q, have %q", key, want, have)
+		}
+	}
+	if want, have := int64(0), sess.marked(1); want != have {
+		t.Errorf("want marked offset %d, have %d", want, have)
+	}
+}
+
+func TestSubscriberWorkers(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		seen  = map[string][]string{}
+		keys  = []string{"a", "b", "c", "d"}
+		count = 100
+	)
+	sub := kafka.NewSubscriber(
+		context.Background(),
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+			mtx.Lock()
+			defer mtx.Unlock()
+			key := request.(string)[:1]
+			seen[key] = append(seen[key], request.(string))
+			return nil, nil
+		},
+		decodeString,
+		kafka.SubscriberWorkers(4),
+	)
+
+	var values []string
+	for i := 0; i < count; i++ {
+		values = append(values, fmt.Sprintf("%s%03d", keys[i%len(keys)], i))
+	}
+	claim := newMockClaim("events", 0, values...)
+	for _, msg := range claim.messages {
+		msg.Key = msg.Value[:1]
+	}
+	sess := newMockSession(context.Background())
+	if err := sub.ConsumeClaim(sess, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range keys {
+		for i, v := range seen[key] {
+			if want, have := fmt.Sprintf("%s%03d", key, i*len(keys)+int(key[0]-'a')), v; want != have {
+				t.Fatalf("key %s: message %d: want %s, have %s", key, i, want, have)
+			}
+		}
+	}
+	if want, have := int64(count-1), sess.marked(0); want != have {
+		t.Errorf("want marked offset %d, have %d", want, have)
+	}
+	for i, offset := range sess.offsets(0) {
+		if i > 0 && offset <= sess.offsets(0)[i-1] {
+			t.Fatalf("marked offsets not increasing: %v", sess.offsets(0))
+		}
+	}
+}
+
+func TestSubscriberRebalance(t *testing.T) {
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	sub := kafka.NewSubscriber(
+		context.Background(),
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			close(started)
+			<-release
+			return nil, ctx.Err()
+		},
+		decodeString,
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	claim := newMockClaim("events", 0, "in-flight", "pending")
+	sess := newMockSession(ctx)
+	done := make(chan error)
+	go func() { done <- sub.ConsumeClaim(sess, claim) }()
+
+	<-started
+	cancel() // the session ends, e.g. on rebalance
+	select {
+	case <-done:
+		t.Fatal("claim returned with a message in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "[0]", fmt.Sprint(sess.offsets(0)); want != have {
+		t.Errorf("want marked offsets %s, have %s", want, have)
+	}
+}
+
+func TestSubscriberServe(t *testing.T) {
+	var (
+		mtx  sync.Mutex
+		seen = map[string]bool{}
+	)
+	sub := kafka.NewSubscriber(
+		context.Background(),
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			seen[request.(string)] = true
+			return nil, nil
+		},
+		decodeString,
+	)
+
+	group := &mockGroup{sessions: [][]*mockClaim{
+		{newMockClaim("a", 0, "a0"), newMockClaim("a", 1, "a1")},
+		{newMockClaim("b", 0, "b0")},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	group.onEmpty = cancel
+	if err := sub.Serve(ctx, group, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := []string{"a", "b"}, group.topics; fmt.Sprint(want) != fmt.Sprint(have) {
+		t.Errorf("want topics %v, have %v", want, have)
+	}
+	for _, v := range []string{"a0", "a1", "b0"} {
+		if !seen[v] {
+			t.Errorf("%s: not served", v)
+		}
+	}
+}
+
+func decodeString(_ context.Context, msg *kafka.Message) (interface{}, error) {
+	return string(msg.Value), nil
+}
+
+// mockGroup runs a session per element of sessions, and calls onEmpty once
+// they've all run.
+type mockGroup struct {
+	sessions [][]*mockClaim
+	onEmpty  func()
+	topics   []string
+}
+
+func (g *mockGroup) Consume(ctx context.Context, topics []string, h kafka.ConsumerGroupHandler) error {
+	g.topics = topics
+	if len(g.sessions) == 0 {
+		g.onEmpty()
+		return nil
+	}
+	claims := g.sessions[0]
+	g.sessions = g.sessions[1:]
+
+	sess := newMockSession(ctx)
+	if err := h.Setup(sess); err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for _, c := range claims {
+		wg.Add(1)
+		go func(c *mockClaim) {
+			defer wg.Done()
+			h.ConsumeClaim(sess, c)
+		}(c)
+	}
+	wg.Wait()
+	return h.Cleanup(sess)
+}
+
+type mockSession struct {
+	ctx   context.Context
+	mtx   sync.Mutex
+	marks map[int32][]int64
+}
+
+func newMockSession(ctx context.Context) *mockSession {
+	return &mockSession{ctx: ctx, marks: map[int32][]int64{}}
+}
+
+func (s *mockSession) Context() context.Context { return s.ctx }
+
+func (s *mockSession) MarkMessage(msg *kafka.Message, _ string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.marks[msg.Partition] = append(s.marks[msg.Partition], msg.Offset)
+}
+
+func (s *mockSession) offsets(partition int32) []int64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.marks[partition]
+}
+
+func (s *mockSession) marked(partition int32) int64 {
+	offsets := s.offsets(partition)
+	if len(offsets) == 0 {
+		return -1
+	}
+	return offsets[len(offsets)-1]
+}
+
+// mockClaim delivers its messages, and is revoked once they're consumed.
+type mockClaim struct {
+	topic     string
+	partition int32
+	messages  []*kafka.Message
+	c         chan *kafka.Message
+	once      sync.Once
+}
+
+func newMockClaim(topic string, partition int32, values ...string) *mockClaim {
+	c := &mockClaim{topic: topic, partition: partition}
+	for i, v := range values {
+		c.messages = append(c.messages, &kafka.Message{
+			Topic:     topic,
+			Partition: partition,
+			Offset:    int64(i),
+			Value:     []byte(v),
+		})
+	}
+	return c
+}
+
+func (c *mockClaim) Topic() string    { return c.topic }
+func (c *mockClaim) Partition() int32 { return c.partition }
+
+func (c *mockClaim) Messages() <-chan *kafka.Message {
+	c.once.Do(func() {
+		c.c = make(chan *kafka.Message, len(c.messages))
+		for _, msg := range c.messages {
+			c.c <- msg
+		}
+		close(c.c)
+	})
+	return c.c
+}
+
+type mockProducer struct {
+	failures int
+	produced []*kafka.Message
+}
+
+func (p *mockProducer) Produce(msg *kafka.Message) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	p.produced = append(p.produced, msg)
+	return nil
+}
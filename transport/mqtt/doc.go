@@ -0,0 +1,6 @@
+// Package mqtt implements an MQTT transport, using the Eclipse Paho client. A
+// Subscriber serves an endpoint to the messages matching its topic filters,
+// optionally replying on a topic derived from the request topic; a Publisher
+// turns a remote subscriber into an endpoint, publishing requests, and
+// optionally awaiting correlated replies.
+package mqtt
@@ -0,0 +1,27 @@
+package mqtt
+
+import (
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"golang.org/x/net/context"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from an MQTT
+// message. It's designed to be used in subscribers, for server-side
+// endpoints. One straightforward DecodeRequestFunc could be something that
+// JSON decodes from the message payload to the concrete request type.
+type DecodeRequestFunc func(context.Context, paho.Message) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the payload of
+// the MQTT message. It's designed to be used in publishers, for client-side
+// endpoints.
+type EncodeRequestFunc func(context.Context, interface{}) (payload []byte, err error)
+
+// EncodeResponseFunc encodes the passed response object into the payload of
+// the MQTT message that's published as the reply. It's designed to be used in
+// subscribers, for server-side endpoints.
+type EncodeResponseFunc func(context.Context, interface{}) (payload []byte, err error)
+
+// DecodeResponseFunc extracts a user-domain response object from the MQTT
+// message of a reply. It's designed to be used in publishers, for client-side
+// endpoints.
+type DecodeResponseFunc func(context.Context, paho.Message) (response interface{}, err error)
@@ -0,0 +1,222 @@
+package mqtt_test
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	broker "github.com/mochi-co/mqtt/server"
+	"github.com/mochi-co/mqtt/server/listeners"
+	"github.com/mochi-co/mqtt/server/listeners/auth"
+	"golang.org/x/net/context"
+
+	mqtttransport "github.com/go-kit/kit/transport/mqtt"
+)
+
+type topicKey struct{}
+
+func TestRequestReply(t *testing.T) {
+	addr := freeAddr(t)
+	b := startBroker(t, addr)
+	defer b.Close()
+
+	sub := mqtttransport.NewSubscriber(
+		context.Background(),
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			if !strings.HasPrefix(ctx.Value(topicKey{}).(string), "svc/upper/") {
+				t.Errorf("unexpected request topic %v", ctx.Value(topicKey{}))
+			}
+			return strings.ToUpper(request.(string)), nil
+		},
+		decodeString,
+		encodeString,
+		mqtttransport.SubscriberBefore(func(ctx context.Context, msg paho.Message) context.Context {
+			return context.WithValue(ctx, topicKey{}, msg.Topic())
+		}),
+		mqtttransport.SubscriberReplyTopic(mqtttransport.ReplyTopicSuffix("/reply")),
+	)
+	server := connect(t, addr, "server", sub.OnConnect(map[string]byte{"svc/upper/+": 1}))
+	defer server.Disconnect(0)
+	waitSubscribed(t, b, "svc/upper/probe")
+
+	client := connect(t, addr, "client", nil)
+	defer client.Disconnect(0)
+	pub := mqtttransport.NewPublisher(
+		client, "svc/upper", 1, encodeString, decodeString,
+		mqtttransport.PublisherReplyTopic(mqtttransport.ReplyTopicSuffix("/reply")),
+		mqtttransport.PublisherTimeout(5*time.Second),
+	)
+	for _, s := range []string{"hello", "world"} {
+		response, err := pub.Endpoint()(context.Background(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := strings.ToUpper(s), response.(string); want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestErrorReply(t *testing.T) {
+	addr := freeAddr(t)
+	b := startBroker(t, addr)
+	defer b.Close()
+
+	sub := mqtttransport.NewSubscriber(
+		context.Background(),
+		func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("device offline") },
+		decodeString,
+		encodeString,
+		mqtttransport.SubscriberReplyTopic(mqtttransport.ReplyTopicSuffix("/reply")),
+		mqtttransport.SubscriberErrorEncoder(func(_ context.Context, err error) []byte {
+			return []byte("error: " + err.Error())
+		}),
+	)
+	server := connect(t, addr, "server", sub.OnConnect(map[string]byte{"svc/cmd/+": 1}))
+	defer server.Disconnect(0)
+	waitSubscribed(t, b, "svc/cmd/probe")
+
+	client := connect(t, addr, "client", nil)
+	defer client.Disconnect(0)
+	pub := mqtttransport.NewPublisher(
+		client, "svc/cmd", 1, encodeString, decodeString,
+		mqtttransport.PublisherReplyTopic(mqtttransport.ReplyTopicSuffix("/reply")),
+	)
+	response, err := pub.Endpoint()(context.Background(), "reboot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "error: Do: device offline", response.(string); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestPublisherTimeout(t *testing.T) {
+	addr := freeAddr(t)
+	defer startBroker(t, addr).Close()
+
+	client := connect(t, addr, "client", nil)
+	defer client.Disconnect(0)
+	pub := mqtttransport.NewPublisher(
+		client, "svc/nobody", 1, encodeString, decodeString,
+		mqtttransport.PublisherReplyTopic(mqtttransport.ReplyTopicSuffix("/reply")),
+		mqtttransport.PublisherTimeout(50*time.Millisecond),
+	)
+	_, err := pub.Endpoint()(context.Background(), "hello")
+	e, ok := err.(mqtttransport.Error)
+	if !ok {
+		t.Fatalf("want mqtt.Error, have %v", err)
+	}
+	if want, have := mqtttransport.DomainDo, e.Domain; want != have {
+		t.Errorf("want domain %q, have %q", want, have)
+	}
+	if want, have := context.DeadlineExceeded, e.Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestResubscribeAfterBrokerRestart(t *testing.T) {
+	addr := freeAddr(t)
+	b := startBroker(t, addr)
+
+	received := make(chan string, 10)
+	sub := mqtttransport.NewSubscriber(
+		context.Background(),
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			received <- request.(string)
+			return nil, nil
+		},
+		decodeString,
+		encodeString,
+	)
+	server := connect(t, addr, "server", sub.OnConnect(map[string]byte{"events/#": 1}))
+	defer server.Disconnect(0)
+	waitSubscribed(t, b, "events/boot")
+
+	publish := func(payload string) {
+		client := connect(t, addr, "client-"+payload, nil)
+		defer client.Disconnect(0)
+		pub := mqtttransport.NewPublisher(client, "events/boot", 1, encodeString, decodeString)
+		if _, err := pub.Endpoint()(context.Background(), payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(want string) {
+		select {
+		case have := <-received:
+			if want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %q", want)
+		}
+	}
+
+	publish("before")
+	expect("before")
+
+	// The new broker knows nothing of the old subscriptions.
+	b.Close()
+	b = startBroker(t, addr)
+	defer b.Close()
+	waitSubscribed(t, b, "events/boot")
+
+	publish("after")
+	expect("after")
+}
+
+func decodeString(_ context.Context, msg paho.Message) (interface{}, error) {
+	return string(msg.Payload()), nil
+}
+
+func encodeString(_ context.Context, v interface{}) ([]byte, error) {
+	return []byte(v.(string)), nil
+}
+
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func startBroker(t *testing.T, addr string) *broker.Server {
+	b := broker.NewServer(nil)
+	if err := b.AddListener(listeners.NewTCP("tcp", addr), &listeners.Config{Auth: new(auth.Allow)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func connect(t *testing.T, addr, id string, onConnect paho.OnConnectHandler) paho.Client {
+	opts := paho.NewClientOptions().
+		AddBroker("tcp://" + addr).
+		SetClientID(id).
+		SetOnConnectHandler(onConnect).
+		SetConnectionLostHandler(func(paho.Client, error) {})
+	client := paho.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		t.Fatal(token.Error())
+	}
+	return client
+}
+
+// waitSubscribed waits for the broker to have a subscriber to the topic, as
+// subscriptions made by OnConnect handlers are asynchronous.
+func waitSubscribed(t *testing.T, b *broker.Server, topic string) {
+	deadline := time.Now().Add(10 * time.Second)
+	for len(b.Topics.Subscribers(topic)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for a subscriber to %s", topic)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,167 @@
+package mqtt
+
+import (
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Publisher wraps an MQTT topic, and provides a method that implements
+// endpoint.Endpoint. By default, the endpoint returns a nil response once the
+// request is published. With a reply topic configured, each request is
+// published to a topic unique to it, and the endpoint returns once the reply
+// on the derived reply topic arrives, or the timeout expires.
+type Publisher struct {
+	client     paho.Client
+	topic      string
+	qos        byte
+	enc        EncodeRequestFunc
+	dec        DecodeResponseFunc
+	after      []MessageFunc
+	replyTopic ReplyTopicFunc
+	timeout    time.Duration
+
+	prefix string
+	seq    uint64
+}
+
+// NewPublisher constructs a usable Publisher for a single remote endpoint,
+// i.e. subscriber, publishing to the topic with the QoS.
+func NewPublisher(
+	client paho.Client,
+	topic string,
+	qos byte,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	options ...PublisherOption,
+) *Publisher {
+	p := &Publisher{
+		client:  client,
+		topic:   topic,
+		qos:     qos,
+		enc:     enc,
+		dec:     dec,
+		timeout: 10 * time.Second,
+		prefix:  strconv.FormatInt(rand.Int63(), 16),
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// PublisherOption sets an optional parameter for publishers.
+type PublisherOption func(*Publisher)
+
+// PublisherAfter sets the MessageFuncs that are applied to the reply message
+// before it's decoded.
+func PublisherAfter(after ...MessageFunc) PublisherOption {
+	return func(p *Publisher) { p.after = after }
+}
+
+// PublisherReplyTopic makes the publisher await a reply to each request.
+// MQTT 3.1.1 messages have no properties to carry a correlation ID, so it's
+// carried in the topic instead: requests are published to the topic followed
+// by a level holding the correlation ID, e.g. "devices/42/cmd/<id>", and the
+// reply is awaited on the topic derived from that. Subscribers must subscribe
+// to the topic followed by a single-level wildcard, e.g. "devices/42/cmd/+",
+// and derive reply topics in the same way.
+func PublisherReplyTopic(f ReplyTopicFunc) PublisherOption {
+	return func(p *Publisher) { p.replyTopic = f }
+}
+
+// PublisherTimeout sets the time to wait for the request to be published,
+// and for the reply, if any. A timeout of zero or less waits until the
+// request context is done. The default timeout is 10 seconds.
+func PublisherTimeout(timeout time.Duration) PublisherOption {
+	return func(p *Publisher) { p.timeout = timeout }
+}
+
+// Endpoint returns a usable endpoint that publishes the request, and waits
+// for the reply, if the publisher awaits one.
+func (p *Publisher) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		var cancel context.CancelFunc
+		if p.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
+		defer cancel()
+
+		payload, err := p.enc(ctx, request)
+		if err != nil {
+			return nil, Error{Domain: DomainEncode, Err: err}
+		}
+
+		if p.replyTopic == nil {
+			if err := wait(ctx, p.client.Publish(p.topic, p.qos, false, payload)); err != nil {
+				return nil, Error{Domain: DomainPublish, Err: err}
+			}
+			return nil, nil
+		}
+
+		// Subscribe to the reply topic before publishing, so the reply can't
+		// arrive before the subscription.
+		topic := p.topic + "/" + p.nextCorrelationID()
+		replyTopic := p.replyTopic(topic)
+		replyc := make(chan paho.Message, 1)
+		handler := func(_ paho.Client, msg paho.Message) {
+			select {
+			case replyc <- msg:
+			default: // duplicate
+			}
+		}
+		if err := wait(ctx, p.client.Subscribe(replyTopic, p.qos, handler)); err != nil {
+			return nil, Error{Domain: DomainDo, Err: err}
+		}
+		defer p.client.Unsubscribe(replyTopic)
+
+		if err := wait(ctx, p.client.Publish(topic, p.qos, false, payload)); err != nil {
+			return nil, Error{Domain: DomainPublish, Err: err}
+		}
+
+		var msg paho.Message
+		select {
+		case msg = <-replyc:
+		case <-ctx.Done():
+			return nil, Error{Domain: DomainDo, Err: ctx.Err()}
+		}
+
+		for _, f := range p.after {
+			ctx = f(ctx, msg)
+		}
+
+		response, err := p.dec(ctx, msg)
+		if err != nil {
+			return nil, Error{Domain: DomainDecode, Err: err}
+		}
+
+		return response, nil
+	}
+}
+
+func (p *Publisher) nextCorrelationID() string {
+	return p.prefix + "-" + strconv.FormatUint(atomic.AddUint64(&p.seq, 1), 16)
+}
+
+// wait waits for the token to complete, or the context to be done.
+func wait(ctx context.Context, t paho.Token) error {
+	done := make(chan struct{})
+	go func() {
+		t.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return t.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,23 @@
+package mqtt
+
+import (
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"golang.org/x/net/context"
+)
+
+// MessageFunc may take information from an MQTT message and put it into a
+// request context. In subscribers, MessageFuncs are executed prior to
+// decoding the request. In publishers, they're executed on the reply, prior
+// to decoding the response.
+type MessageFunc func(context.Context, paho.Message) context.Context
+
+// ReplyTopicFunc derives the topic a reply is published to from the topic of
+// the request.
+type ReplyTopicFunc func(topic string) string
+
+// ReplyTopicSuffix returns a ReplyTopicFunc that appends the suffix to the
+// request topic, e.g. replying to requests on "devices/42/cmd" on
+// "devices/42/cmd/reply" for the suffix "/reply".
+func ReplyTopicSuffix(suffix string) ReplyTopicFunc {
+	return func(topic string) string { return topic + suffix }
+}
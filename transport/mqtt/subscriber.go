@@ -0,0 +1,164 @@
+package mqtt
+
+import (
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Subscriber wraps an endpoint and serves it to MQTT messages. If a reply
+// topic is configured, the response is published to the topic derived from
+// the topic of each request.
+type Subscriber struct {
+	ctx          context.Context
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []MessageFunc
+	replyTopic   ReplyTopicFunc
+	replyQoS     byte
+	errorEncoder ErrorEncoder
+	logger       log.Logger
+}
+
+// NewSubscriber constructs a new subscriber, which serves the provided
+// endpoint to MQTT messages.
+func NewSubscriber(
+	ctx context.Context,
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...SubscriberOption,
+) *Subscriber {
+	s := &Subscriber{
+		ctx:      ctx,
+		e:        e,
+		dec:      dec,
+		enc:      enc,
+		replyQoS: 1,
+		logger:   log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore functions are executed on the MQTT message before the
+// request is decoded.
+func SubscriberBefore(before ...MessageFunc) SubscriberOption {
+	return func(s *Subscriber) { s.before = before }
+}
+
+// SubscriberReplyTopic sets the function deriving the topic responses are
+// published to from the topic of the request. By default, responses are
+// discarded, and nothing is published.
+func SubscriberReplyTopic(f ReplyTopicFunc) SubscriberOption {
+	return func(s *Subscriber) { s.replyTopic = f }
+}
+
+// SubscriberReplyQoS sets the QoS replies are published with. The default is
+// 1, at least once.
+func SubscriberReplyQoS(qos byte) SubscriberOption {
+	return func(s *Subscriber) { s.replyQoS = qos }
+}
+
+// SubscriberErrorEncoder is used to encode errors into the payload of a
+// reply, which is published to the reply topic of failed requests. By
+// default, no reply is published for failed requests, and publishers waiting
+// for one time out.
+func SubscriberErrorEncoder(ee ErrorEncoder) SubscriberOption {
+	return func(s *Subscriber) { s.errorEncoder = ee }
+}
+
+// SubscriberErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged.
+func SubscriberErrorLogger(logger log.Logger) SubscriberOption {
+	return func(s *Subscriber) { s.logger = logger }
+}
+
+// ErrorEncoder is responsible for encoding an error into the payload of the
+// reply.
+type ErrorEncoder func(ctx context.Context, err error) []byte
+
+// Subscribe subscribes the client to the topic filters, each with its QoS,
+// and serves the matching messages. It returns once the broker acknowledged
+// the subscription.
+//
+// Subscriptions don't survive the client reconnecting with a clean session,
+// e.g. after a broker restart. Use OnConnect to subscribe on each connect.
+func (s Subscriber) Subscribe(client paho.Client, filters map[string]byte) error {
+	t := client.SubscribeMultiple(filters, s.ServeMessage)
+	t.Wait()
+	return t.Error()
+}
+
+// OnConnect returns a handler that subscribes the client to the topic
+// filters, each with its QoS, whenever it connects, so the subscriptions are
+// restored when the client reconnects, e.g. after a broker restart. Set it in
+// the client options before connecting. Errors subscribing are logged.
+func (s Subscriber) OnConnect(filters map[string]byte) paho.OnConnectHandler {
+	return func(client paho.Client) {
+		if err := s.Subscribe(client, filters); err != nil {
+			s.logger.Log("err", err)
+		}
+	}
+}
+
+// ServeMessage serves a single message, publishing any reply with the client.
+// It implements paho.MessageHandler.
+func (s Subscriber) ServeMessage(client paho.Client, msg paho.Message) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	for _, f := range s.before {
+		ctx = f(ctx, msg)
+	}
+
+	request, err := s.dec(ctx, msg)
+	if err != nil {
+		s.fail(ctx, client, msg, Error{Domain: DomainDecode, Err: err})
+		return
+	}
+
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.fail(ctx, client, msg, Error{Domain: DomainDo, Err: err})
+		return
+	}
+
+	if s.replyTopic == nil {
+		return
+	}
+
+	payload, err := s.enc(ctx, response)
+	if err != nil {
+		s.fail(ctx, client, msg, Error{Domain: DomainEncode, Err: err})
+		return
+	}
+	s.reply(client, msg, payload)
+}
+
+func (s Subscriber) fail(ctx context.Context, client paho.Client, msg paho.Message, err error) {
+	s.logger.Log("topic", msg.Topic(), "err", err)
+	if s.replyTopic != nil && s.errorEncoder != nil {
+		s.reply(client, msg, s.errorEncoder(ctx, err))
+	}
+}
+
+// reply publishes the reply without waiting for the broker's
+// acknowledgement, as message handlers block the client's delivery of
+// further messages.
+func (s Subscriber) reply(client paho.Client, msg paho.Message, payload []byte) {
+	t := client.Publish(s.replyTopic(msg.Topic()), s.replyQoS, false, payload)
+	go func() {
+		if t.Wait(); t.Error() != nil {
+			s.logger.Log("topic", msg.Topic(), "err", Error{Domain: DomainPublish, Err: t.Error()})
+		}
+	}()
+}
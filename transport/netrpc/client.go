@@ -0,0 +1,79 @@
+package netrpc
+
+import (
+	"fmt"
+	"net/rpc"
+	"reflect"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Client wraps a net/rpc client and provides a method that implements
+// endpoint.Endpoint.
+type Client struct {
+	client        *rpc.Client
+	serviceMethod string
+	enc           EncodeRequestFunc
+	dec           DecodeResponseFunc
+	rpcReply      reflect.Type
+}
+
+// NewClient constructs a usable Client for a single remote method, named
+// "Service.Method" as net/rpc expects. Pass a zero value of the net/rpc reply
+// type as the rpcReply argument; the DecodeResponseFunc is passed a value of
+// that type, not a pointer to it.
+func NewClient(
+	client *rpc.Client,
+	serviceMethod string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	rpcReply interface{},
+	options ...ClientOption,
+) *Client {
+	c := &Client{
+		client:        client,
+		serviceMethod: serviceMethod,
+		enc:           enc,
+		dec:           dec,
+		rpcReply:      reflect.TypeOf(reflect.Indirect(reflect.ValueOf(rpcReply)).Interface()),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*Client)
+
+// Endpoint returns a usable endpoint that will invoke the net/rpc method
+// specified by the client. net/rpc calls can't be canceled, so if the context
+// is done before the reply arrives, the endpoint returns the context's error
+// and abandons the call; its eventual reply is discarded.
+func (c Client) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, err := c.enc(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("Encode: %v", err)
+		}
+
+		rpcReply := reflect.New(c.rpcReply).Interface()
+		call := c.client.Go(c.serviceMethod, req, rpcReply, make(chan *rpc.Call, 1))
+		select {
+		case <-call.Done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if call.Error != nil {
+			return nil, fmt.Errorf("Call: %v", call.Error)
+		}
+
+		response, err := c.dec(ctx, reflect.ValueOf(rpcReply).Elem().Interface())
+		if err != nil {
+			return nil, fmt.Errorf("Decode: %v", err)
+		}
+		return response, nil
+	}
+}
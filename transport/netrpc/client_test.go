@@ -0,0 +1,139 @@
+package netrpc_test
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/transport/netrpc"
+)
+
+type SumRequest struct{ A, B int }
+
+type SumResponse struct{ V int }
+
+// testBinding is registered with net/rpc, and calls through to Go kit
+// handlers, as a service's net/rpc binding would.
+type testBinding struct {
+	sum, slow netrpc.Handler
+}
+
+func (b testBinding) Sum(request SumRequest, response *SumResponse) error {
+	resp, err := b.sum.ServeNetRPC(request)
+	if err != nil {
+		return err
+	}
+	*response = resp.(SumResponse)
+	return nil
+}
+
+func (b testBinding) Slow(request SumRequest, response *SumResponse) error {
+	resp, err := b.slow.ServeNetRPC(request)
+	if err != nil {
+		return err
+	}
+	*response = resp.(SumResponse)
+	return nil
+}
+
+func TestClient(t *testing.T) {
+	client := startTestServer(t, make(chan struct{}))
+	defer client.Close()
+
+	sum := netrpc.NewClient(client, "test.Sum", encodeSumRequest, decodeSumResponse, SumResponse{}).Endpoint()
+	response, err := sum(context.Background(), SumRequest{A: 1, B: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, response.(SumResponse).V; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	// Errors returned by the endpoint reach the client.
+	if _, err := sum(context.Background(), SumRequest{A: -1}); err == nil {
+		t.Error("want error, have none")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	client := startTestServer(t, release)
+	defer client.Close()
+
+	slow := netrpc.NewClient(client, "test.Slow", encodeSumRequest, decodeSumResponse, SumResponse{}).Endpoint()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	errc := make(chan error)
+	go func() {
+		_, err := slow(ctx, SumRequest{A: 1, B: 2})
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		if want, have := context.DeadlineExceeded, err; want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("call wasn't abandoned when the context was done")
+	}
+
+	// The abandoned call completes in the background, without disturbing
+	// subsequent calls on the same client.
+	close(release)
+	sum := netrpc.NewClient(client, "test.Sum", encodeSumRequest, decodeSumResponse, SumResponse{}).Endpoint()
+	response, err := sum(context.Background(), SumRequest{A: 3, B: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 7, response.(SumResponse).V; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+// startTestServer serves the test binding over TCP, and returns a client
+// connected to it. The Slow method blocks until release is closed.
+func startTestServer(t *testing.T, release chan struct{}) *rpc.Client {
+	sum := func(_ context.Context, request interface{}) (interface{}, error) {
+		req := request.(SumRequest)
+		if req.A < 0 {
+			return nil, errors.New("negative")
+		}
+		return SumResponse{V: req.A + req.B}, nil
+	}
+	slow := func(ctx context.Context, request interface{}) (interface{}, error) {
+		<-release
+		return sum(ctx, request)
+	}
+
+	s := rpc.NewServer()
+	if err := s.RegisterName("test", testBinding{
+		sum:  netrpc.NewServer(context.Background(), sum, decodeSumRequest, encodeSumResponse),
+		slow: netrpc.NewServer(context.Background(), slow, decodeSumRequest, encodeSumResponse),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Accept(ln)
+
+	client, err := rpc.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func decodeSumRequest(_ context.Context, r interface{}) (interface{}, error) { return r, nil }
+
+func encodeSumResponse(_ context.Context, r interface{}) (interface{}, error) { return r, nil }
+
+func encodeSumRequest(_ context.Context, r interface{}) (interface{}, error) { return r, nil }
+
+func decodeSumResponse(_ context.Context, r interface{}) (interface{}, error) { return r, nil }
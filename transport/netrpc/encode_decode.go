@@ -0,0 +1,25 @@
+package netrpc
+
+import "golang.org/x/net/context"
+
+// DecodeRequestFunc extracts a user-domain request object from a net/rpc
+// request argument. It's designed to be used in net/rpc bindings, for
+// server-side endpoints. One straightforward DecodeRequestFunc could be
+// something that converts the net/rpc argument to the concrete request type.
+type DecodeRequestFunc func(context.Context, interface{}) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the net/rpc
+// argument. It's designed to be used in net/rpc clients, for client-side
+// endpoints. The argument must be encodable by the client's codec, gob by
+// default.
+type EncodeRequestFunc func(context.Context, interface{}) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object into the net/rpc
+// reply. It's designed to be used in net/rpc bindings, for server-side
+// endpoints.
+type EncodeResponseFunc func(context.Context, interface{}) (response interface{}, err error)
+
+// DecodeResponseFunc extracts a user-domain response object from a net/rpc
+// reply. It's designed to be used in net/rpc clients, for client-side
+// endpoints.
+type DecodeResponseFunc func(context.Context, interface{}) (response interface{}, err error)
@@ -0,0 +1,6 @@
+// Package redisstream implements a Redis Streams consumer transport. A
+// Subscriber reads a stream as a member of a consumer group, serves an
+// endpoint to each entry, and acknowledges it once it's handled. Entries
+// left pending by a failure are claimed again after an idle threshold, and
+// dead-lettered after too many deliveries.
+package redisstream
@@ -0,0 +1,11 @@
+package redisstream
+
+import (
+	"github.com/go-redis/redis"
+	"golang.org/x/net/context"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from a stream
+// entry. One straightforward DecodeRequestFunc could be something that JSON
+// decodes one of the entry's field values to the concrete request type.
+type DecodeRequestFunc func(context.Context, redis.XMessage) (request interface{}, err error)
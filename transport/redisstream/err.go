@@ -0,0 +1,25 @@
+package redisstream
+
+import "fmt"
+
+const (
+	// DomainDecode is an error during entry decoding.
+	DomainDecode = "Decode"
+
+	// DomainDo is an error during the execution phase of the request.
+	DomainDo = "Do"
+)
+
+// Error is an error that occurred at some phase within the transport.
+type Error struct {
+	// Domain is the phase in which the error was generated.
+	Domain string
+
+	// Err is the concrete error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Domain, e.Err)
+}
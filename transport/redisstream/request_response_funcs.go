@@ -0,0 +1,22 @@
+package redisstream
+
+import (
+	"github.com/go-redis/redis"
+	"golang.org/x/net/context"
+)
+
+// RequestFunc may take information from a stream entry and put it into a
+// request context. RequestFuncs are executed prior to decoding the entry.
+type RequestFunc func(context.Context, redis.XMessage) context.Context
+
+type contextKey int
+
+const (
+	// ContextKeyStream is populated in the context of each request with the
+	// name of the stream the entry was read from, as a string.
+	ContextKeyStream contextKey = iota
+
+	// ContextKeyMessageID is populated in the context of each request with
+	// the ID of the entry, as a string.
+	ContextKeyMessageID
+)
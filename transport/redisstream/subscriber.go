@@ -0,0 +1,250 @@
+package redisstream
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Subscriber wraps an endpoint and serves it to the entries of a stream, read
+// as a member of a consumer group. Each entry is acknowledged once the
+// endpoint succeeded. Entries that failed stay pending, and are claimed and
+// served again once they've been idle long enough.
+type Subscriber struct {
+	ctx           context.Context
+	e             endpoint.Endpoint
+	dec           DecodeRequestFunc
+	before        []RequestFunc
+	block         time.Duration
+	count         int64
+	claimIdle     time.Duration
+	dlqStream     string
+	maxDeliveries int64
+	logger        log.Logger
+}
+
+// NewSubscriber constructs a new subscriber, which serves the provided
+// endpoint to stream entries.
+func NewSubscriber(
+	ctx context.Context,
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	options ...SubscriberOption,
+) *Subscriber {
+	s := &Subscriber{
+		ctx:       ctx,
+		e:         e,
+		dec:       dec,
+		block:     time.Second,
+		count:     10,
+		claimIdle: 30 * time.Second,
+		logger:    log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore functions are executed on the stream entry before the
+// request is decoded.
+func SubscriberBefore(before ...RequestFunc) SubscriberOption {
+	return func(s *Subscriber) { s.before = before }
+}
+
+// SubscriberBlock sets how long each read blocks waiting for new entries. It
+// bounds how long shutdown and claiming pending entries may be delayed. By
+// default, it's 1s.
+func SubscriberBlock(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		if d > 0 {
+			s.block = d
+		}
+	}
+}
+
+// SubscriberCount sets the maximum number of entries read, or claimed, at
+// once. By default, it's 10.
+func SubscriberCount(n int64) SubscriberOption {
+	return func(s *Subscriber) {
+		if n > 0 {
+			s.count = n
+		}
+	}
+}
+
+// SubscriberClaimIdle sets how long an entry must have been pending before
+// it's claimed and served again. Entries are pending when they failed, or
+// when the consumer they were delivered to went away. A zero duration
+// disables retries. By default, it's 30s.
+func SubscriberClaimIdle(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.claimIdle = d }
+}
+
+// SubscriberDeadLetter sets the stream entries are dead-lettered to, once
+// they've been delivered maxDeliveries times without success. Dead-lettered
+// entries keep their fields, and get fields naming their original stream and
+// ID, and their number of deliveries. They're acknowledged once they've been
+// added to the dead-letter stream. With an empty stream name, they're
+// dropped. By default, entries are retried forever.
+func SubscriberDeadLetter(stream string, maxDeliveries int) SubscriberOption {
+	return func(s *Subscriber) { s.dlqStream, s.maxDeliveries = stream, int64(maxDeliveries) }
+}
+
+// SubscriberErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged.
+func SubscriberErrorLogger(logger log.Logger) SubscriberOption {
+	return func(s *Subscriber) { s.logger = logger }
+}
+
+// Serve reads the stream as the named consumer of the group, and serves the
+// entries. If the group doesn't exist, it's created to read the stream from
+// its start, and so is the stream. It returns when ctx is done, or if a Redis
+// command fails. Cancel ctx to shut down gracefully: no new entries are read
+// or claimed, and the entries already read are finished and acknowledged, as
+// requests are served with the subscriber's context, not ctx.
+func (s Subscriber) Serve(ctx context.Context, client redis.Cmdable, stream, group, consumer string) error {
+	err := client.XGroupCreateMkStream(stream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	var nextClaim time.Time
+	for ctx.Err() == nil {
+		if s.claimIdle > 0 && !time.Now().Before(nextClaim) {
+			if err := s.claim(ctx, client, stream, group, consumer); err != nil {
+				return err
+			}
+			nextClaim = time.Now().Add(s.claimIdle / 2)
+		}
+
+		streams, err := client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: consumer,
+			Streams:  []string{stream, ">"},
+			Count:    s.count,
+			Block:    s.block,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, str := range streams {
+			for _, msg := range str.Messages {
+				s.serveMessage(client, stream, group, msg)
+			}
+		}
+	}
+	return nil
+}
+
+// claim takes over the entries of the group that have been pending for at
+// least the idle threshold, and serves them again, or dead-letters them if
+// they've been delivered too many times. It stops when ctx is done.
+func (s Subscriber) claim(ctx context.Context, client redis.Cmdable, stream, group, consumer string) error {
+	pending, err := client.XPendingExt(&redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  group,
+		Start:  "-",
+		End:    "+",
+		Count:  s.count,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	for _, p := range pending {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if p.Idle < s.claimIdle {
+			continue
+		}
+
+		// Claiming with the idle threshold guarantees only one consumer
+		// takes over the entry, even when it's about to be dead-lettered.
+		msgs, err := client.XClaim(&redis.XClaimArgs{
+			Stream:   stream,
+			Group:    group,
+			Consumer: consumer,
+			MinIdle:  s.claimIdle,
+			Messages: []string{p.Id},
+		}).Result()
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			if s.maxDeliveries > 0 && p.RetryCount >= s.maxDeliveries {
+				if err := s.deadLetter(client, stream, group, msg, p.RetryCount); err != nil {
+					return err
+				}
+				continue
+			}
+			s.serveMessage(client, stream, group, msg)
+		}
+	}
+	return nil
+}
+
+// serveMessage serves the entry, and acknowledges it if that succeeded.
+// Otherwise, the entry is left pending, to be claimed again.
+func (s Subscriber) serveMessage(client redis.Cmdable, stream, group string, msg redis.XMessage) {
+	if err := s.handle(stream, msg); err != nil {
+		s.logger.Log("stream", stream, "id", msg.ID, "err", err)
+		return
+	}
+	if err := client.XAck(stream, group, msg.ID).Err(); err != nil {
+		s.logger.Log("stream", stream, "id", msg.ID, "err", err)
+	}
+}
+
+func (s Subscriber) handle(stream string, msg redis.XMessage) error {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	ctx = context.WithValue(ctx, ContextKeyStream, stream)
+	ctx = context.WithValue(ctx, ContextKeyMessageID, msg.ID)
+
+	for _, f := range s.before {
+		ctx = f(ctx, msg)
+	}
+
+	request, err := s.dec(ctx, msg)
+	if err != nil {
+		return Error{Domain: DomainDecode, Err: err}
+	}
+
+	if _, err := s.e(ctx, request); err != nil {
+		return Error{Domain: DomainDo, Err: err}
+	}
+	return nil
+}
+
+func (s Subscriber) deadLetter(client redis.Cmdable, stream, group string, msg redis.XMessage, deliveries int64) error {
+	if s.dlqStream != "" {
+		values := make(map[string]interface{}, len(msg.Values)+3)
+		for k, v := range msg.Values {
+			values[k] = v
+		}
+		values["x-original-stream"] = stream
+		values["x-original-id"] = msg.ID
+		values["x-deliveries"] = strconv.FormatInt(deliveries, 10)
+		if err := client.XAdd(&redis.XAddArgs{Stream: s.dlqStream, Values: values}).Err(); err != nil {
+			return err
+		}
+	}
+	s.logger.Log("stream", stream, "id", msg.ID, "deliveries", deliveries, "dead_letter", s.dlqStream)
+	return client.XAck(stream, group, msg.ID).Err()
+}
@@ -0,0 +1,234 @@
+package redisstream_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/transport/redisstream"
+)
+
+const (
+	stream   = "orders"
+	group    = "billing"
+	consumer = "billing-1"
+)
+
+func decodeBody(_ context.Context, msg redis.XMessage) (interface{}, error) {
+	body, _ := msg.Values["body"].(string)
+	return body, nil
+}
+
+func TestSubscriberAck(t *testing.T) {
+	client := newClient(t)
+	add(t, client, "a", "b", "c")
+
+	var (
+		mtx  sync.Mutex
+		have []string
+		done = make(chan struct{})
+	)
+	e := func(_ context.Context, request interface{}) (interface{}, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		have = append(have, request.(string))
+		if len(have) == 3 {
+			close(done)
+		}
+		return nil, nil
+	}
+
+	sub := redisstream.NewSubscriber(context.Background(), e, decodeBody, redisstream.SubscriberBlock(10*time.Millisecond))
+	stop := serve(t, sub, client)
+	waitFor(t, done)
+	stop()
+
+	if want, have := "[a b c]", fmt.Sprint(have); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := int64(0), pendingCount(t, client); want != have {
+		t.Errorf("want %d pending, have %d", want, have)
+	}
+}
+
+func TestSubscriberRetry(t *testing.T) {
+	client := newClient(t)
+	add(t, client, "a")
+
+	var (
+		mtx      sync.Mutex
+		attempts int
+		done     = make(chan struct{})
+	)
+	e := func(context.Context, interface{}) (interface{}, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("transient")
+		}
+		close(done)
+		return nil, nil
+	}
+
+	sub := redisstream.NewSubscriber(context.Background(), e, decodeBody,
+		redisstream.SubscriberBlock(10*time.Millisecond),
+		redisstream.SubscriberClaimIdle(50*time.Millisecond),
+	)
+	stop := serve(t, sub, client)
+	waitFor(t, done)
+	stop()
+
+	if want, have := 2, attempts; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+	if want, have := int64(0), pendingCount(t, client); want != have {
+		t.Errorf("want %d pending, have %d", want, have)
+	}
+}
+
+func TestSubscriberDeadLetter(t *testing.T) {
+	client := newClient(t)
+	ids := add(t, client, "a")
+
+	var (
+		mtx      sync.Mutex
+		attempts int
+	)
+	e := func(context.Context, interface{}) (interface{}, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		attempts++
+		return nil, errors.New("permanent")
+	}
+
+	sub := redisstream.NewSubscriber(context.Background(), e, decodeBody,
+		redisstream.SubscriberBlock(10*time.Millisecond),
+		redisstream.SubscriberClaimIdle(20*time.Millisecond),
+		redisstream.SubscriberDeadLetter("orders-dlq", 3),
+	)
+	stop := serve(t, sub, client)
+
+	var dead []redis.XMessage
+	for deadline := time.Now().Add(5 * time.Second); len(dead) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for dead letter")
+		}
+		time.Sleep(10 * time.Millisecond)
+		var err error
+		if dead, err = client.XRange("orders-dlq", "-", "+").Result(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop()
+
+	if want, have := 3, attempts; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+	for k, want := range map[string]string{
+		"body":              "a",
+		"x-original-stream": stream,
+		"x-original-id":     ids[0],
+		"x-deliveries":      "3",
+	} {
+		if have := dead[0].Values[k]; want != have {
+			t.Errorf("%s: want %q, have %q", k, want, have)
+		}
+	}
+	if want, have := int64(0), pendingCount(t, client); want != have {
+		t.Errorf("want %d pending, have %d", want, have)
+	}
+}
+
+func TestSubscriberShutdown(t *testing.T) {
+	client := newClient(t)
+	add(t, client, "a")
+
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	e := func(context.Context, interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}
+
+	sub := redisstream.NewSubscriber(context.Background(), e, decodeBody, redisstream.SubscriberBlock(10*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- sub.Serve(ctx, client, stream, group, consumer) }()
+
+	waitFor(t, started)
+	cancel()
+	select {
+	case err := <-errc:
+		t.Fatalf("Serve returned with a request in flight: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if want, have := int64(0), pendingCount(t, client); want != have {
+		t.Errorf("want %d pending, have %d", want, have)
+	}
+}
+
+func newClient(t *testing.T) *redis.Client {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func add(t *testing.T, client *redis.Client, bodies ...string) []string {
+	var ids []string
+	for _, body := range bodies {
+		id, err := client.XAdd(&redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"body": body}}).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func serve(t *testing.T, sub *redisstream.Subscriber, client *redis.Client) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- sub.Serve(ctx, client, stream, group, consumer) }()
+	return func() {
+		cancel()
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func pendingCount(t *testing.T, client *redis.Client) int64 {
+	p, err := client.XPending(stream, group).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Count
+}
+
+func waitFor(t *testing.T, c <-chan struct{}) {
+	select {
+	case <-c:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+}
@@ -0,0 +1,5 @@
+// Package websocket implements a WebSocket transport, built on
+// github.com/gorilla/websocket. A Server serves an endpoint to the messages
+// of each connection, and writes the responses back onto it. Endpoints may
+// also push a stream of responses to the client.
+package websocket
@@ -0,0 +1,16 @@
+package websocket
+
+import "golang.org/x/net/context"
+
+// DecodeRequestFunc extracts a user-domain request object from a WebSocket
+// message. The message type is websocket.TextMessage or
+// websocket.BinaryMessage.
+type DecodeRequestFunc func(ctx context.Context, messageType int, data []byte) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object into a WebSocket
+// message, of type websocket.TextMessage or websocket.BinaryMessage.
+type EncodeResponseFunc func(ctx context.Context, response interface{}) (messageType int, data []byte, err error)
+
+// ErrorEncoder is responsible for encoding an error into a WebSocket message,
+// which is written to the connection in place of the response.
+type ErrorEncoder func(ctx context.Context, err error) (messageType int, data []byte)
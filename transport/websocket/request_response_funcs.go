@@ -0,0 +1,14 @@
+package websocket
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// RequestFunc may take information from the HTTP request that opens a
+// connection, e.g. credentials, and put it into the connection context,
+// which is the context of every request on the connection. RequestFuncs are
+// executed before the connection is upgraded; if one returns an error, the
+// connection is refused with 403 Forbidden.
+type RequestFunc func(context.Context, *http.Request) (context.Context, error)
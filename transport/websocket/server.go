@@ -0,0 +1,282 @@
+package websocket
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// writeWait is the time allowed to write a message or control frame.
+const writeWait = 10 * time.Second
+
+// Server wraps an endpoint and implements http.Handler. Each request upgrades
+// to a WebSocket connection, whose messages are decoded into requests, passed
+// to the endpoint, and whose responses are encoded and written back.
+//
+// Messages of a connection are served in turn. If the endpoint returns a
+// receive-only or bidirectional channel of interface{} as the response, the
+// server pushes each value received from it to the client, until the channel
+// is closed or the connection ends, while serving further messages.
+type Server struct {
+	ctx          context.Context
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []RequestFunc
+	upgrader     websocket.Upgrader
+	pingInterval time.Duration
+	pongWait     time.Duration
+	errorEncoder ErrorEncoder
+	logger       log.Logger
+}
+
+// NewServer constructs a new server, which implements http.Handler and wraps
+// the provided endpoint. Connections are closed with a going-away close
+// frame when ctx is done.
+func NewServer(
+	ctx context.Context,
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...ServerOption,
+) *Server {
+	s := &Server{
+		ctx:          ctx,
+		e:            e,
+		dec:          dec,
+		enc:          enc,
+		pingInterval: 30 * time.Second,
+		pongWait:     60 * time.Second,
+		errorEncoder: defaultErrorEncoder,
+		logger:       log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerBefore functions are executed on the HTTP request opening a
+// connection, before it's upgraded. They're typically used for
+// authentication.
+func ServerBefore(before ...RequestFunc) ServerOption {
+	return func(s *Server) { s.before = before }
+}
+
+// ServerUpgrader sets the upgrader used to establish connections, e.g. to
+// check the origin of requests, or to set buffer sizes. By default, the zero
+// Upgrader is used, which refuses cross-origin requests.
+func ServerUpgrader(upgrader websocket.Upgrader) ServerOption {
+	return func(s *Server) { s.upgrader = upgrader }
+}
+
+// ServerKeepalive sets the interval in which the server pings the client, and
+// how long it waits for a pong, or any other message, before it considers the
+// connection dead and closes it. The interval must be shorter than the wait.
+// The defaults are 30 and 60 seconds.
+func ServerKeepalive(pingInterval, pongWait time.Duration) ServerOption {
+	return func(s *Server) { s.pingInterval, s.pongWait = pingInterval, pongWait }
+}
+
+// ServerErrorEncoder is used to encode errors into messages written to the
+// connection, whenever they're encountered in the processing of a request.
+// By default, the error text is written as a text message.
+func ServerErrorEncoder(ee ErrorEncoder) ServerOption {
+	return func(s *Server) { s.errorEncoder = ee }
+}
+
+// ServerErrorLogger is used to log non-terminal errors. By default, no errors
+// are logged.
+func ServerErrorLogger(logger log.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// ServeHTTP implements http.Handler. It returns when the connection ends.
+func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	for _, f := range s.before {
+		var err error
+		if ctx, err = f(ctx, r); err != nil {
+			s.logger.Log("err", err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Log("err", err) // the upgrader replied with an HTTP error
+		return
+	}
+	defer ws.Close()
+
+	c := &conn{
+		s:          s,
+		ws:         ws,
+		ctx:        ctx,
+		outc:       make(chan message),
+		readerDone: make(chan struct{}),
+		writerDone: make(chan struct{}),
+	}
+	go c.writeLoop()
+	c.readLoop()
+	close(c.readerDone)
+	<-c.writerDone
+}
+
+func defaultErrorEncoder(_ context.Context, err error) (int, []byte) {
+	return websocket.TextMessage, []byte(err.Error())
+}
+
+type message struct {
+	messageType int
+	data        []byte
+}
+
+// conn serves a single connection. Only the writer goroutine writes messages
+// to the connection, as gorilla/websocket supports one concurrent writer.
+type conn struct {
+	s          Server
+	ws         *websocket.Conn
+	ctx        context.Context
+	outc       chan message
+	readerDone chan struct{}
+	writerDone chan struct{}
+}
+
+func (c *conn) readLoop() {
+	extend := func() { c.ws.SetReadDeadline(time.Now().Add(c.s.pongWait)) }
+	extend()
+	c.ws.SetPongHandler(func(string) error { extend(); return nil })
+
+	for {
+		messageType, data, err := c.ws.ReadMessage()
+		if err != nil {
+			// The default close handler has already replied to a close
+			// frame from the client, completing the close handshake.
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.s.logger.Log("err", err)
+			}
+			return
+		}
+		extend()
+		c.serve(messageType, data)
+	}
+}
+
+func (c *conn) serve(messageType int, data []byte) {
+	request, err := c.s.dec(c.ctx, messageType, data)
+	if err != nil {
+		c.fail(Error{Domain: DomainDecode, Err: err})
+		return
+	}
+
+	response, err := c.s.e(c.ctx, request)
+	if err != nil {
+		c.fail(Error{Domain: DomainDo, Err: err})
+		return
+	}
+
+	switch stream := response.(type) {
+	case <-chan interface{}:
+		go c.push(stream)
+	case chan interface{}:
+		go c.push(stream)
+	default:
+		c.respond(response)
+	}
+}
+
+// push writes each response received from the stream, until the stream is
+// closed or the connection ends.
+func (c *conn) push(stream <-chan interface{}) {
+	for {
+		select {
+		case response, ok := <-stream:
+			if !ok {
+				return
+			}
+			if !c.respond(response) {
+				return
+			}
+		case <-c.writerDone:
+			return
+		}
+	}
+}
+
+// respond encodes and writes the response. It returns false if the
+// connection has ended.
+func (c *conn) respond(response interface{}) bool {
+	messageType, data, err := c.s.enc(c.ctx, response)
+	if err != nil {
+		return c.fail(Error{Domain: DomainEncode, Err: err})
+	}
+	return c.write(message{messageType, data})
+}
+
+func (c *conn) fail(err error) bool {
+	c.s.logger.Log("err", err)
+	messageType, data := c.s.errorEncoder(c.ctx, err)
+	return c.write(message{messageType, data})
+}
+
+// write hands the message to the writer goroutine. It returns false if the
+// connection has ended.
+func (c *conn) write(m message) bool {
+	select {
+	case c.outc <- m:
+		return true
+	case <-c.writerDone:
+		return false
+	}
+}
+
+func (c *conn) writeLoop() {
+	defer close(c.writerDone)
+
+	ticker := time.NewTicker(c.s.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case m := <-c.outc:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteMessage(m.messageType, m.data); err != nil {
+				c.s.logger.Log("err", err)
+				c.ws.Close() // unblocks the reader
+				return
+			}
+
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.s.logger.Log("err", err)
+				c.ws.Close()
+				return
+			}
+
+		case <-c.s.ctx.Done():
+			// Start the close handshake; the reader returns once the
+			// client replies, or its read deadline passes.
+			c.ws.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+				time.Now().Add(writeWait),
+			)
+			return
+
+		case <-c.readerDone:
+			return
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package websocket_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/context"
+
+	wstransport "github.com/go-kit/kit/transport/websocket"
+)
+
+type userKey struct{}
+
+func TestServerExchange(t *testing.T) {
+	server, done := newServer(context.Background())
+	defer server.Close()
+
+	ws := dial(t, server.URL, http.Header{"X-User": {"alice"}})
+	for _, msg := range []string{"hello", "there", "bye"} {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := strings.ToUpper(msg)+" alice", readText(t, ws); want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+
+	// Errors are written as messages, and the connection remains usable.
+	ws.WriteMessage(websocket.BinaryMessage, []byte("binary"))
+	if want, have := "Decode: want text message", readText(t, ws); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// Close handshake: the server replies to the client's close frame, and
+	// stops serving the connection.
+	if err := ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ws.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("want normal closure, have %v", err)
+	}
+	waitDone(t, done)
+}
+
+func TestServerPush(t *testing.T) {
+	server, _ := newServer(context.Background())
+	defer server.Close()
+
+	ws := dial(t, server.URL, http.Header{"X-User": {"alice"}})
+	defer ws.Close()
+	ws.WriteMessage(websocket.TextMessage, []byte("subscribe 3"))
+	ws.WriteMessage(websocket.TextMessage, []byte("hello"))
+
+	// Pushed values and responses to later messages interleave.
+	var pushed int
+	var responses []string
+	for i := 0; i < 4; i++ {
+		msg := readText(t, ws)
+		if strings.HasPrefix(msg, "tick ") {
+			pushed++
+			continue
+		}
+		responses = append(responses, msg)
+	}
+	if want, have := 3, pushed; want != have {
+		t.Errorf("want %d pushed messages, have %d", want, have)
+	}
+	if want, have := "HELLO alice", strings.Join(responses, ","); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestServerBeforeRejects(t *testing.T) {
+	server, _ := newServer(context.Background())
+	defer server.Close()
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL(server.URL), nil)
+	if err != websocket.ErrBadHandshake {
+		t.Fatalf("want %v, have %v", websocket.ErrBadHandshake, err)
+	}
+	if want, have := http.StatusForbidden, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestServerKeepalive(t *testing.T) {
+	server, done := newServer(context.Background(), wstransport.ServerKeepalive(5*time.Millisecond, 50*time.Millisecond))
+	defer server.Close()
+
+	// A reading client answers pings with pongs, keeping the connection
+	// alive for longer than the pong wait.
+	ws := dial(t, server.URL, http.Header{"X-User": {"alice"}})
+	defer ws.Close()
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := ws.ReadMessage() // handles pings
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		t.Fatalf("connection ended: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// A client that stops reading doesn't answer pings, and is dropped.
+	idle := dial(t, server.URL, http.Header{"X-User": {"bob"}})
+	defer idle.Close()
+	waitDone(t, done)
+}
+
+func TestServerShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server, done := newServer(ctx)
+	defer server.Close()
+
+	ws := dial(t, server.URL, http.Header{"X-User": {"alice"}})
+	defer ws.Close()
+	cancel()
+
+	if _, _, err := ws.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("want going away, have %v", err)
+	}
+	ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+	waitDone(t, done)
+}
+
+// newServer serves an endpoint that uppercases text messages, and responds to
+// "subscribe N" by pushing N ticks. Connections must name a user. The
+// returned channel receives a value whenever a connection has been served.
+func newServer(ctx context.Context, options ...wstransport.ServerOption) (*httptest.Server, chan struct{}) {
+	e := func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(string)
+		var n int
+		if _, err := fmt.Sscanf(req, "subscribe %d", &n); err == nil {
+			ticks := make(chan interface{})
+			go func() {
+				defer close(ticks)
+				for i := 0; i < n; i++ {
+					select {
+					case ticks <- fmt.Sprintf("tick %d", i):
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+			return (<-chan interface{})(ticks), nil
+		}
+		return strings.ToUpper(req) + " " + ctx.Value(userKey{}).(string), nil
+	}
+	dec := func(_ context.Context, messageType int, data []byte) (interface{}, error) {
+		if messageType != websocket.TextMessage {
+			return nil, errors.New("want text message")
+		}
+		return string(data), nil
+	}
+	enc := func(_ context.Context, response interface{}) (int, []byte, error) {
+		return websocket.TextMessage, []byte(response.(string)), nil
+	}
+	auth := func(ctx context.Context, r *http.Request) (context.Context, error) {
+		user := r.Header.Get("X-User")
+		if user == "" {
+			return nil, errors.New("no user")
+		}
+		return context.WithValue(ctx, userKey{}, user), nil
+	}
+
+	handler := wstransport.NewServer(ctx, e, dec, enc, append([]wstransport.ServerOption{wstransport.ServerBefore(auth)}, options...)...)
+	done := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		done <- struct{}{}
+	}))
+	return server, done
+}
+
+func dial(t *testing.T, url string, header http.Header) *websocket.Conn {
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL(url), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+func wsURL(url string) string {
+	return "ws" + strings.TrimPrefix(url, "http")
+}
+
+func readText(t *testing.T, ws *websocket.Conn) string {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	messageType, data, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := websocket.TextMessage, messageType; want != have {
+		t.Errorf("want message type %d, have %d", want, have)
+	}
+	return string(data)
+}
+
+func waitDone(t *testing.T, done chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("connection still served")
+	}
+}